@@ -0,0 +1,321 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"net/netip"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// 访问控制：按来源 IP 放行或拒绝访问某条规则，如把暴露出去的管理后台限制为只有办公室 IP 可以访问
+// frpc 本身不能按来源过滤，因此开启后 frpc 以 PROXY protocol v2 把访问者地址告诉 Mole 在 127.0.0.1 上开的过滤代理，
+// 由它检查来源后去掉协议头再转发给本地服务 (或访问记录代理)，本地服务看到的仍是普通连接
+// 过滤代理启动失败时不生成 frpc.toml，避免规则在没有过滤的情况下暴露出去
+
+const (
+	maxAccessEntries      = 50
+	proxyHeaderTimeout    = 10 * time.Second
+	accessDenyLogInterval = time.Minute // 同一来源被拒绝时最多每分钟记一次日志
+)
+
+var proxyV2Signature = []byte("\r\n\r\n\x00\r\nQUIT\n")
+
+// hasAccessList 规则是否配置了访问控制
+func hasAccessList(p ProxyRule) bool {
+	return len(p.AllowIPs) > 0 || len(p.DenyIPs) > 0
+}
+
+// parseAccessEntry 解析 IP 或 CIDR，单个 IP 视为只含一个地址的网段
+func parseAccessEntry(s string) (netip.Prefix, error) {
+	if strings.Contains(s, "/") {
+		pfx, err := netip.ParsePrefix(s)
+		if err != nil {
+			return netip.Prefix{}, err
+		}
+		return pfx.Masked(), nil
+	}
+	addr, err := netip.ParseAddr(s)
+	if err != nil {
+		return netip.Prefix{}, err
+	}
+	addr = addr.Unmap()
+	return netip.PrefixFrom(addr, addr.BitLen()), nil
+}
+
+func normalizeAccessList(rule, kind string, list []string) ([]string, error) {
+	var out []string
+	seen := make(map[string]bool)
+	for _, s := range list {
+		s = strings.TrimSpace(s)
+		if s == "" {
+			continue
+		}
+		pfx, err := parseAccessEntry(s)
+		if err != nil {
+			return nil, fmt.Errorf("规则 \"%s\" 的%s地址无效: %s", rule, kind, s)
+		}
+		if s = pfx.String(); pfx.IsSingleIP() {
+			s = pfx.Addr().String()
+		}
+		if !seen[s] {
+			seen[s] = true
+			out = append(out, s)
+		}
+	}
+	if len(out) > maxAccessEntries {
+		return nil, fmt.Errorf("规则 \"%s\" 的%s最多填写 %d 项", rule, kind, maxAccessEntries)
+	}
+	return out, nil
+}
+
+// normalizeAccessLists 规整并校验规则的放行与拒绝列表
+func normalizeAccessLists(p ProxyRule) (ProxyRule, error) {
+	var err error
+	if p.AllowIPs, err = normalizeAccessList(p.Name, "放行", p.AllowIPs); err != nil {
+		return p, err
+	}
+	if p.DenyIPs, err = normalizeAccessList(p.Name, "拒绝", p.DenyIPs); err != nil {
+		return p, err
+	}
+	if !hasAccessList(p) {
+		return p, nil
+	}
+	switch {
+	case p.ProxyType == "udp":
+		return p, fmt.Errorf("规则 \"%s\": UDP 规则不支持访问控制", p.Name)
+	case p.Plugin != "" || (p.ProxyType == "https" && p.HTTPSTerminate):
+		return p, fmt.Errorf("规则 \"%s\": 使用客户端插件或由 frpc 终止 HTTPS 时不支持访问控制", p.Name)
+	}
+	return p, nil
+}
+
+// accessPolicy 规则的访问策略：命中拒绝列表即拒绝；放行列表非空时只放行其中的地址
+type accessPolicy struct {
+	allow, deny []netip.Prefix
+}
+
+func newAccessPolicy(p ProxyRule) accessPolicy {
+	var pol accessPolicy
+	for _, s := range p.AllowIPs {
+		if pfx, err := parseAccessEntry(s); err == nil {
+			pol.allow = append(pol.allow, pfx)
+		}
+	}
+	for _, s := range p.DenyIPs {
+		if pfx, err := parseAccessEntry(s); err == nil {
+			pol.deny = append(pol.deny, pfx)
+		}
+	}
+	return pol
+}
+
+func (pol accessPolicy) permits(ip netip.Addr) bool {
+	ip = ip.Unmap()
+	for _, pfx := range pol.deny {
+		if pfx.Contains(ip) {
+			return false
+		}
+	}
+	if len(pol.allow) == 0 {
+		return true
+	}
+	for _, pfx := range pol.allow {
+		if pfx.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// readProxyHeader 读取 PROXY protocol 头 (v1 或 v2)，返回访问者地址
+// frpc 的健康检查等不带地址的连接 (v2 LOCAL 命令) 返回无效地址
+func readProxyHeader(br *bufio.Reader) (netip.Addr, error) {
+	sig, err := br.Peek(len(proxyV2Signature))
+	if err != nil {
+		return netip.Addr{}, err
+	}
+	if bytes.Equal(sig, proxyV2Signature) {
+		hdr := make([]byte, 16)
+		if _, err := io.ReadFull(br, hdr); err != nil {
+			return netip.Addr{}, err
+		}
+		body := make([]byte, binary.BigEndian.Uint16(hdr[14:16]))
+		if _, err := io.ReadFull(br, body); err != nil {
+			return netip.Addr{}, err
+		}
+		if hdr[12]&0x0f == 0 { // LOCAL
+			return netip.Addr{}, nil
+		}
+		switch hdr[13] >> 4 {
+		case 1:
+			if len(body) >= 4 {
+				return netip.AddrFrom4([4]byte(body[:4])), nil
+			}
+		case 2:
+			if len(body) >= 16 {
+				return netip.AddrFrom16([16]byte(body[:16])).Unmap(), nil
+			}
+		}
+		return netip.Addr{}, fmt.Errorf("无法识别的 PROXY 协议地址")
+	}
+
+	line, err := br.ReadString('\n')
+	if err != nil {
+		return netip.Addr{}, err
+	}
+	fields := strings.Fields(line)
+	if len(fields) < 2 || fields[0] != "PROXY" {
+		return netip.Addr{}, fmt.Errorf("缺少 PROXY 协议头")
+	}
+	if fields[1] == "UNKNOWN" || len(fields) < 3 {
+		return netip.Addr{}, nil
+	}
+	return netip.ParseAddr(fields[2])
+}
+
+// accessFilter 一条规则的过滤代理
+type accessFilter struct {
+	ln     net.Listener
+	port   int
+	target string // 目标变化时重新监听，策略变化时原地替换，端口保持不变
+
+	mu      sync.Mutex
+	name    string
+	pol     accessPolicy
+	lastLog map[netip.Addr]time.Time
+}
+
+type accessFilterSet struct {
+	mu      sync.Mutex
+	filters map[string]*accessFilter // 规则 ID -> 过滤代理
+}
+
+func (f *accessFilter) serve(logf func(...string)) {
+	for {
+		conn, err := f.ln.Accept()
+		if err != nil {
+			return
+		}
+		go f.handle(conn, logf)
+	}
+}
+
+func (f *accessFilter) update(name string, pol accessPolicy) {
+	f.mu.Lock()
+	f.name, f.pol = name, pol
+	f.mu.Unlock()
+}
+
+func (f *accessFilter) handle(conn net.Conn, logf func(...string)) {
+	defer conn.Close()
+	f.mu.Lock()
+	name, pol := f.name, f.pol
+	f.mu.Unlock()
+	_ = conn.SetReadDeadline(time.Now().Add(proxyHeaderTimeout))
+	br := bufio.NewReader(conn)
+	ip, err := readProxyHeader(br)
+	if err != nil {
+		log.Printf("规则 %s 的访问控制读取来源失败: %v", name, err)
+		return
+	}
+	_ = conn.SetReadDeadline(time.Time{})
+	if !ip.IsValid() || !pol.permits(ip) {
+		f.logDenied(name, ip, logf)
+		return
+	}
+
+	up, err := net.DialTimeout("tcp", f.target, 5*time.Second)
+	if err != nil {
+		return
+	}
+	defer up.Close()
+	done := make(chan struct{})
+	go func() {
+		_, _ = io.Copy(up, br)
+		if tc, ok := up.(*net.TCPConn); ok {
+			_ = tc.CloseWrite()
+		}
+		close(done)
+	}()
+	_, _ = io.Copy(conn, up)
+	_ = conn.Close()
+	<-done
+}
+
+func (f *accessFilter) logDenied(name string, ip netip.Addr, logf func(...string)) {
+	f.mu.Lock()
+	last, seen := f.lastLog[ip]
+	if seen && time.Since(last) < accessDenyLogInterval {
+		f.mu.Unlock()
+		return
+	}
+	if len(f.lastLog) > 1000 {
+		f.lastLog = make(map[netip.Addr]time.Time)
+	}
+	f.lastLog[ip] = time.Now()
+	f.mu.Unlock()
+	who := ip.String()
+	if !ip.IsValid() {
+		who = "未知来源"
+	}
+	logf(fmt.Sprintf("访问控制：已拒绝 %s 访问规则 %s", who, name))
+}
+
+// sync 按规则启停过滤代理，返回规则 ID 到代理端口的映射；有规则的代理启动失败时返回错误
+func (a *accessFilterSet) sync(proxies []ProxyRule, logf func(...string)) (map[string]int, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if a.filters == nil {
+		a.filters = make(map[string]*accessFilter)
+	}
+
+	ports := make(map[string]int)
+	var firstErr error
+	for _, p := range proxies {
+		if !p.Enabled || !hasAccessList(p) {
+			continue
+		}
+		pol := newAccessPolicy(p)
+		target := net.JoinHostPort(p.LocalIP, strconv.Itoa(p.LocalPort))
+		if f, ok := a.filters[p.ID]; ok {
+			if f.target == target {
+				f.update(p.Name, pol)
+				ports[p.ID] = f.port
+				continue
+			}
+			_ = f.ln.Close()
+			delete(a.filters, p.ID)
+		}
+		ln, err := net.Listen("tcp", "127.0.0.1:0")
+		if err != nil {
+			if firstErr == nil {
+				firstErr = fmt.Errorf("规则 %s 的访问控制启动失败: %v", p.Name, err)
+			}
+			continue
+		}
+		f := &accessFilter{ln: ln, port: ln.Addr().(*net.TCPAddr).Port, target: target, lastLog: make(map[netip.Addr]time.Time)}
+		f.update(p.Name, pol)
+		go f.serve(logf)
+		a.filters[p.ID] = f
+		ports[p.ID] = f.port
+	}
+	for id, f := range a.filters {
+		if _, ok := ports[id]; !ok {
+			_ = f.ln.Close()
+			delete(a.filters, id)
+		}
+	}
+	return ports, firstErr
+}
+
+func (a *accessFilterSet) stopAll() {
+	_, _ = a.sync(nil, nil)
+}
@@ -0,0 +1,171 @@
+package main
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// 常见本地应用探测：按默认端口和特征识别 Jellyfin、Home Assistant 等，给出可一键添加的规则建议
+
+const (
+	appDetectTimeout = time.Second
+	maxProxyRules    = 3
+)
+
+// AppSuggestion 探测到的应用及建议的代理规则
+type AppSuggestion struct {
+	Key  string    `json:"key"`
+	App  string    `json:"app"`
+	Rule ProxyRule `json:"rule"`
+}
+
+// knownApp 一种应用的默认端口与识别方式
+type knownApp struct {
+	key        string
+	name       string
+	port       int
+	remotePort int    // 建议的远程端口，避开服务器上常用端口 (如服务器自己的 22)
+	httpPath   string // 非空时发 HTTP 请求，响应中包含 marker 才算命中
+	marker     string
+	banner     string // 非空时读取服务欢迎语前缀
+}
+
+var knownApps = []knownApp{
+	{key: "jellyfin", name: "Jellyfin", port: 8096, remotePort: 8096, httpPath: "/System/Info/Public", marker: "Jellyfin"},
+	{key: "homeassistant", name: "Home Assistant", port: 8123, remotePort: 8123, httpPath: "/", marker: "Home Assistant"},
+	{key: "qbittorrent", name: "qBittorrent", port: 8080, remotePort: 18080, httpPath: "/", marker: "qBittorrent"},
+	{key: "rdp", name: "远程桌面 (RDP)", port: 3389, remotePort: 13389},
+	{key: "ssh", name: "SSH", port: 22, remotePort: 6022, banner: "SSH-"},
+}
+
+// DetectLocalApps 探测本机上运行的常见应用
+func (s *MoleService) DetectLocalApps() []AppSuggestion {
+	s.countFeature("detect_apps")
+	var (
+		mu   sync.Mutex
+		list []AppSuggestion
+		wg   sync.WaitGroup
+	)
+	for _, app := range knownApps {
+		wg.Add(1)
+		go func(app knownApp) {
+			defer wg.Done()
+			if !app.detect("127.0.0.1") {
+				return
+			}
+			mu.Lock()
+			list = append(list, AppSuggestion{Key: app.key, App: app.name, Rule: app.rule()})
+			mu.Unlock()
+		}(app)
+	}
+	wg.Wait()
+
+	// 保持 knownApps 中的顺序，结果稳定
+	ordered := make([]AppSuggestion, 0, len(list))
+	for _, app := range knownApps {
+		for _, sug := range list {
+			if sug.Key == app.key {
+				ordered = append(ordered, sug)
+			}
+		}
+	}
+	return ordered
+}
+
+// AddSuggestedRule 把探测建议直接加入配置
+func (s *MoleService) AddSuggestedRule(key string) (ProxyRule, error) {
+	var app *knownApp
+	for i := range knownApps {
+		if knownApps[i].key == key {
+			app = &knownApps[i]
+		}
+	}
+	if app == nil {
+		return ProxyRule{}, fmt.Errorf("未知的应用: %s", key)
+	}
+
+	cfg := s.status().Config
+	if cfg == nil {
+		return ProxyRule{}, fmt.Errorf("未发现有效配置，请先保存服务器信息")
+	}
+	if len(cfg.Proxies) >= maxProxyRules {
+		return ProxyRule{}, fmt.Errorf("最多配置 %d 条代理规则", maxProxyRules)
+	}
+
+	newCfg := *cfg
+	rule := app.rule()
+	rule.Name = uniqueRuleName(cfg.Proxies, rule.Name)
+	newCfg.Proxies = append(append([]ProxyRule(nil), cfg.Proxies...), rule)
+	if err := s.saveUserConfig(newCfg); err != nil {
+		return ProxyRule{}, err
+	}
+	return rule, nil
+}
+
+func (a knownApp) rule() ProxyRule {
+	return ProxyRule{
+		ID:         newRuleID(),
+		Enabled:    true,
+		ProxyType:  "tcp",
+		Name:       a.key,
+		LocalIP:    "127.0.0.1",
+		LocalPort:  a.port,
+		RemotePort: a.remotePort,
+	}
+}
+
+func (a knownApp) detect(host string) bool {
+	addr := net.JoinHostPort(host, strconv.Itoa(a.port))
+
+	if a.httpPath != "" {
+		client := &http.Client{Timeout: appDetectTimeout}
+		resp, err := client.Get("http://" + addr + a.httpPath)
+		if err != nil {
+			return false
+		}
+		defer resp.Body.Close()
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 64*1024))
+		return strings.Contains(string(body), a.marker) || strings.Contains(resp.Header.Get("Server"), a.marker)
+	}
+
+	conn, err := net.DialTimeout("tcp", addr, appDetectTimeout)
+	if err != nil {
+		return false
+	}
+	defer conn.Close()
+	if a.banner == "" {
+		return true
+	}
+	_ = conn.SetReadDeadline(time.Now().Add(appDetectTimeout))
+	buf := make([]byte, len(a.banner))
+	n, _ := io.ReadFull(conn, buf)
+	return string(buf[:n]) == a.banner
+}
+
+// uniqueRuleName frpc 要求代理名唯一，重名时追加序号
+func uniqueRuleName(proxies []ProxyRule, name string) string {
+	taken := make(map[string]bool, len(proxies))
+	for _, p := range proxies {
+		taken[p.Name] = true
+	}
+	candidate := name
+	for i := 2; taken[candidate]; i++ {
+		candidate = name + "_" + strconv.Itoa(i)
+	}
+	return candidate
+}
+
+// newRuleID 生成规则 ID
+func newRuleID() string {
+	buf := make([]byte, 8)
+	_, _ = rand.Read(buf)
+	return hex.EncodeToString(buf)
+}
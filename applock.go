@@ -0,0 +1,212 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+// 应用锁：开启后界面需要输入 PIN 才能查看配置或进行修改，防止共用电脑上的 Token 泄露
+// 锁定状态在服务层校验，前端遮罩只是展示，绕过页面直接调用绑定同样会被拒绝
+// PIN 以 bcrypt 哈希保存在 <数据目录>/config/applock.json；锁只作用于界面进程，守护进程不受影响
+
+const (
+	appLockMinPIN      = 4
+	appLockMaxPIN      = 64
+	appLockMaxFailures = 5                // 连续输错次数上限
+	appLockBackoff     = 30 * time.Second // 达到上限后的等待时间
+)
+
+var errAppLocked = errors.New("应用已锁定，请先输入 PIN 解锁")
+
+// LockStatus 应用锁状态
+type LockStatus struct {
+	Enabled    bool `json:"enabled"`
+	Locked     bool `json:"locked"`
+	RetryAfter int  `json:"retryAfter"` // 输错次数过多时需等待的秒数
+}
+
+type appLockFile struct {
+	PINHash string `json:"pin_hash"`
+}
+
+type appLock struct {
+	mu       sync.Mutex
+	hash     []byte // 为空表示未开启
+	locked   bool
+	failures int
+	until    time.Time // 在此之前拒绝解锁尝试
+
+	lastActive time.Time // 界面最后一次操作时间，用于自动锁定
+}
+
+func appLockPath() string {
+	return filepath.Join(getAppDataDir(), "config", "applock.json")
+}
+
+// load 启动时读取 PIN，已开启则进入锁定状态
+func (l *appLock) load() {
+	data, err := os.ReadFile(appLockPath())
+	if err != nil {
+		return
+	}
+	var f appLockFile
+	if json.Unmarshal(data, &f) != nil || f.PINHash == "" {
+		return
+	}
+	l.mu.Lock()
+	l.hash = []byte(f.PINHash)
+	l.locked = true
+	l.mu.Unlock()
+}
+
+// status 调用方需持有 mu
+func (l *appLock) status() LockStatus {
+	st := LockStatus{Enabled: len(l.hash) > 0, Locked: l.locked}
+	if wait := time.Until(l.until); wait > 0 {
+		st.RetryAfter = int(wait.Seconds()) + 1
+	}
+	return st
+}
+
+// verify 校验 PIN 并计数失败次数，调用方需持有 mu
+func (l *appLock) verify(pin string) error {
+	if wait := time.Until(l.until); wait > 0 {
+		return fmt.Errorf("尝试次数过多，请 %d 秒后再试", int(wait.Seconds())+1)
+	}
+	if bcrypt.CompareHashAndPassword(l.hash, []byte(pin)) != nil {
+		l.failures++
+		if l.failures >= appLockMaxFailures {
+			l.failures = 0
+			l.until = time.Now().Add(appLockBackoff)
+			return fmt.Errorf("PIN 错误次数过多，请 %d 秒后再试", int(appLockBackoff.Seconds()))
+		}
+		return fmt.Errorf("PIN 错误，还可尝试 %d 次", appLockMaxFailures-l.failures)
+	}
+	l.failures = 0
+	return nil
+}
+
+// save 写入或删除 PIN 文件，调用方需持有 mu
+func (l *appLock) save() error {
+	if len(l.hash) == 0 {
+		if err := os.Remove(appLockPath()); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		return nil
+	}
+	data, err := json.Marshal(appLockFile{PINHash: string(l.hash)})
+	if err != nil {
+		return err
+	}
+	_ = os.MkdirAll(filepath.Dir(appLockPath()), 0755)
+	return os.WriteFile(appLockPath(), data, 0600)
+}
+
+// checkUnlocked 需要保护的绑定方法入口调用，锁定时返回 errAppLocked
+func (s *MoleService) checkUnlocked() error {
+	s.appLock.mu.Lock()
+	defer s.appLock.mu.Unlock()
+	if s.appLock.locked {
+		return errAppLocked
+	}
+	return nil
+}
+
+// GetLockStatus 返回应用锁状态
+func (s *MoleService) GetLockStatus() LockStatus {
+	s.appLock.mu.Lock()
+	defer s.appLock.mu.Unlock()
+	return s.appLock.status()
+}
+
+// SetAppLockPIN 开启应用锁或修改 PIN，必须在未锁定时调用；已设置 PIN 时需要输入当前 PIN，
+// 否则任何人都能在未锁定的界面上直接改掉 PIN
+func (s *MoleService) SetAppLockPIN(current, pin string) error {
+	// 只读模式下不允许设置 PIN，以免把管理员挡在配置之外
+	if err := s.checkMutable(); err != nil {
+		return err
+	}
+	if n := utf8.RuneCountInString(pin); n < appLockMinPIN || n > appLockMaxPIN {
+		return fmt.Errorf("PIN 长度需在 %d 到 %d 位之间", appLockMinPIN, appLockMaxPIN)
+	}
+	hash, err := bcrypt.GenerateFromPassword([]byte(pin), bcrypt.DefaultCost)
+	if err != nil {
+		return fmt.Errorf("保存 PIN 失败: %v", err)
+	}
+
+	s.appLock.mu.Lock()
+	defer s.appLock.mu.Unlock()
+	if s.appLock.locked {
+		return errAppLocked
+	}
+	if len(s.appLock.hash) > 0 {
+		if err := s.appLock.verify(current); err != nil {
+			return err
+		}
+	}
+	old := s.appLock.hash
+	s.appLock.hash = hash
+	s.appLock.lastActive = time.Now()
+	if err := s.appLock.save(); err != nil {
+		s.appLock.hash = old
+		return fmt.Errorf("保存 PIN 失败: %v", err)
+	}
+	s.events.Emit("app-lock", s.appLock.status())
+	return nil
+}
+
+// DisableAppLock 关闭应用锁，需要再次输入当前 PIN
+func (s *MoleService) DisableAppLock(pin string) error {
+	s.appLock.mu.Lock()
+	defer s.appLock.mu.Unlock()
+	if len(s.appLock.hash) == 0 {
+		return nil
+	}
+	if err := s.appLock.verify(pin); err != nil {
+		return err
+	}
+	old := s.appLock.hash
+	s.appLock.hash = nil
+	s.appLock.locked = false
+	if err := s.appLock.save(); err != nil {
+		s.appLock.hash = old
+		return fmt.Errorf("关闭应用锁失败: %v", err)
+	}
+	s.events.Emit("app-lock", s.appLock.status())
+	return nil
+}
+
+// UnlockApp 输入 PIN 解锁
+func (s *MoleService) UnlockApp(pin string) error {
+	s.appLock.mu.Lock()
+	defer s.appLock.mu.Unlock()
+	if !s.appLock.locked {
+		return nil
+	}
+	if err := s.appLock.verify(pin); err != nil {
+		return err
+	}
+	s.appLock.locked = false
+	s.appLock.lastActive = time.Now()
+	s.events.Emit("app-lock", s.appLock.status())
+	return nil
+}
+
+// LockApp 立即锁定，未开启应用锁时什么都不做
+func (s *MoleService) LockApp() {
+	s.appLock.mu.Lock()
+	defer s.appLock.mu.Unlock()
+	if len(s.appLock.hash) == 0 || s.appLock.locked {
+		return
+	}
+	s.appLock.locked = true
+	s.events.Emit("app-lock", s.appLock.status())
+}
@@ -0,0 +1,96 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"os/user"
+	"path/filepath"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+// 操作审计：办公室共用电脑上记录谁在什么时候改了配置、连接/断开、导入、查看 Token
+// 只追加写入本地数据库的 audit 桶 (见 store.go)，程序内不提供删除或修改
+// 审计在界面进程中记录，附着守护进程时守护进程执行的是同一操作，不再重复记录
+
+const (
+	auditConfigSave  = "config_save"
+	auditConnect     = "connect"
+	auditDisconnect  = "disconnect"
+	auditImport      = "import"
+	auditTokenReveal = "token_reveal"
+
+	auditDefaultLimit = 200
+)
+
+// AuditEntry 一条审计记录
+type AuditEntry struct {
+	Time   time.Time `json:"time"`
+	User   string    `json:"user"` // 操作系统登录用户
+	Action string    `json:"action"`
+	Detail string    `json:"detail,omitempty"`
+}
+
+// auditPath 旧版审计文件，现仅用于首次启动时导入数据库
+func auditPath() string {
+	return filepath.Join(getAppDataDir(), "config", "audit.log")
+}
+
+// auditUser 当前操作系统用户，取不到时退回环境变量
+func auditUser() string {
+	if u, err := user.Current(); err == nil && u.Username != "" {
+		return u.Username
+	}
+	if name := os.Getenv("USERNAME"); name != "" {
+		return name
+	}
+	return os.Getenv("USER")
+}
+
+// audit 追加一条审计记录，写入失败只记日志，不影响操作本身
+func (s *MoleService) audit(action, detail string) {
+	if appFlags.Daemon {
+		return
+	}
+	entry := AuditEntry{Time: time.Now(), User: auditUser(), Action: action, Detail: detail}
+	err := s.store.update(func(tx *bolt.Tx) error {
+		return appendJSON(tx, bucketAudit, entry)
+	})
+	if err != nil {
+		log.Printf("写入审计日志失败: %v", err)
+	}
+}
+
+// GetAuditLog 返回最近 limit 条审计记录，新的在前；limit <= 0 时取默认 200 条
+func (s *MoleService) GetAuditLog(limit int) ([]AuditEntry, error) {
+	if err := s.checkUnlocked(); err != nil {
+		return nil, err
+	}
+	if limit <= 0 {
+		limit = auditDefaultLimit
+	}
+	entries, err := latestJSON[AuditEntry](&s.store, bucketAudit, limit)
+	if err != nil {
+		return nil, fmt.Errorf("读取审计日志失败: %v", err)
+	}
+	return entries, nil
+}
+
+// RevealToken 前端点击“显示 Token”时调用，返回明文并记入审计
+// 开启应用锁时需再次验证身份：传入 PIN，或传空字符串使用 Touch ID / Windows Hello
+func (s *MoleService) RevealToken(pin string) (string, error) {
+	if err := s.checkMutable(); err != nil {
+		return "", err
+	}
+	if err := s.verifyIdentity(pin, "查看服务器 Token"); err != nil {
+		return "", err
+	}
+	cfg := s.status().Config
+	if cfg == nil {
+		return "", fmt.Errorf("未发现有效配置")
+	}
+	s.audit(auditTokenReveal, "配置页显示 Token")
+	return cfg.Server.Token, nil
+}
@@ -0,0 +1,78 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// 自动锁定：开启应用锁后，界面无操作超过设定分钟数或系统锁屏时回到锁定状态
+// 界面操作由前端节流后调用 TouchActivity 上报，系统锁屏状态按平台轮询 (sessionLocked)
+
+const autoLockCheckInterval = 10 * time.Second
+
+// TouchActivity 前端在用户操作界面时调用，刷新最后活动时间
+func (s *MoleService) TouchActivity() {
+	s.appLock.mu.Lock()
+	s.appLock.lastActive = time.Now()
+	s.appLock.mu.Unlock()
+}
+
+// SetAutoLock 设置无操作自动锁定的分钟数 (0 为不自动锁定) 以及是否随系统锁屏一起锁定
+func (s *MoleService) SetAutoLock(minutes int, onSessionLock bool) error {
+	if minutes < 0 || minutes > 24*60 {
+		return fmt.Errorf("自动锁定时间需在 0 到 1440 分钟之间")
+	}
+	cfg := s.status().Config
+	if cfg == nil {
+		return fmt.Errorf("未发现有效配置")
+	}
+	newCfg := *cfg
+	newCfg.Preferences.AutoLockMinutes = minutes
+	newCfg.Preferences.LockOnSessionLock = onSessionLock
+	return s.saveUserConfig(newCfg)
+}
+
+// runAutoLock 定期检查是否需要自动锁定，只在界面进程中运行
+func (s *MoleService) runAutoLock(ctx context.Context) {
+	if appFlags.Daemon {
+		return
+	}
+	s.TouchActivity()
+
+	ticker := time.NewTicker(autoLockCheckInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			s.checkAutoLock()
+		}
+	}
+}
+
+func (s *MoleService) checkAutoLock() {
+	st := s.GetLockStatus()
+	if !st.Enabled || st.Locked {
+		return
+	}
+	cfg := s.status().Config
+	if cfg == nil {
+		return
+	}
+	prefs := cfg.Preferences
+
+	s.appLock.mu.Lock()
+	idle := time.Since(s.appLock.lastActive)
+	s.appLock.mu.Unlock()
+
+	switch {
+	case prefs.AutoLockMinutes > 0 && idle >= time.Duration(prefs.AutoLockMinutes)*time.Minute:
+		s.LockApp()
+		s.emitLog(fmt.Sprintf("界面 %d 分钟无操作，已自动锁定", prefs.AutoLockMinutes))
+	case prefs.LockOnSessionLock && sessionLocked():
+		s.LockApp()
+		s.emitLog("系统已锁屏，应用随之锁定")
+	}
+}
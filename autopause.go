@@ -0,0 +1,151 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// 本地目标存活检测 + 自动暂停：本地服务没在监听时，frps 每来一个访问 frpc 都会报一次
+// "connect to local service error"，日志被刷屏。开启后定期检测每条规则的本地端口，
+// 连续失败时把规则从运行配置中摘掉 (热重载)，服务恢复后再自动加回
+
+const (
+	targetCheckInterval = 15 * time.Second
+	targetDialTimeout   = 2 * time.Second
+	targetDownThreshold = 2 // 连续失败次数达到后才暂停，避免服务重启时抖动
+)
+
+// autoPauser 记录被自动暂停的规则与连续失败次数
+type autoPauser struct {
+	mu       sync.Mutex
+	paused   map[string]bool // 规则 ID
+	failures map[string]int
+}
+
+func (a *autoPauser) isPaused(ruleID string) bool {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return a.paused[ruleID]
+}
+
+// observe 记录一次检测结果，返回该规则的暂停状态是否需要改变
+func (a *autoPauser) observe(ruleID string, up bool) (changed, paused bool) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if a.paused == nil {
+		a.paused = make(map[string]bool)
+		a.failures = make(map[string]int)
+	}
+
+	if up {
+		a.failures[ruleID] = 0
+		if a.paused[ruleID] {
+			delete(a.paused, ruleID)
+			return true, false
+		}
+		return false, false
+	}
+
+	a.failures[ruleID]++
+	if !a.paused[ruleID] && a.failures[ruleID] >= targetDownThreshold {
+		a.paused[ruleID] = true
+		return true, true
+	}
+	return false, a.paused[ruleID]
+}
+
+// reset 清空暂停状态，返回之前被暂停的规则
+func (a *autoPauser) reset() []string {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	var ids []string
+	for id := range a.paused {
+		ids = append(ids, id)
+	}
+	a.paused = nil
+	a.failures = nil
+	return ids
+}
+
+// runTargetWatcher frpc 运行期间定期检测本地目标，ctx 取消时退出
+func (s *MoleService) runTargetWatcher(ctx context.Context) {
+	ticker := time.NewTicker(targetCheckInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			s.checkTargets()
+		}
+	}
+}
+
+// dialTarget 尝试连接规则的本地目标，返回目标地址与是否在监听
+func dialTarget(p ProxyRule) (string, bool) {
+	target := net.JoinHostPort(envOr(p.LocalIP), strconv.Itoa(p.LocalPort))
+	conn, err := net.DialTimeout("tcp", target, targetDialTimeout)
+	if err != nil {
+		return target, false
+	}
+	_ = conn.Close()
+	return target, true
+}
+
+func (s *MoleService) checkTargets() {
+	s.mu.RLock()
+	if s.config == nil {
+		s.mu.RUnlock()
+		return
+	}
+	enabled := s.config.Preferences.AutoPauseDownTargets
+	frpc := s.frpCmd != nil
+	proxies := append([]ProxyRule(nil), s.config.Proxies...)
+	s.mu.RUnlock()
+
+	// 关闭开关或隧道已停止：恢复全部规则，下次启动时按完整配置生成
+	if !enabled || !frpc {
+		if ids := s.autoPause.reset(); len(ids) > 0 && frpc {
+			s.emitLog("已关闭自动暂停，恢复全部规则")
+			if err := s.hotReload(); err != nil {
+				log.Printf("恢复规则失败: %v", err)
+			}
+		}
+		return
+	}
+
+	changed := false
+	for _, p := range proxies {
+		// UDP 没有握手，无法判断本地服务是否在监听
+		if !p.Enabled || p.ProxyType == "udp" {
+			continue
+		}
+		target, up := dialTarget(p)
+		flip, paused := s.autoPause.observe(p.ID, up)
+		if !flip {
+			continue
+		}
+		changed = true
+		if paused {
+			s.emitLog(fmt.Sprintf("[%s] 本地服务 %s 无响应，已暂时移除该规则", p.Name, target))
+			s.setProxyState(p.Name, proxyStatePaused, "本地服务未在监听")
+		} else {
+			s.emitLog(fmt.Sprintf("[%s] 本地服务 %s 已恢复，重新加入该规则", p.Name, target))
+			s.setProxyState(p.Name, proxyStatePending, "")
+		}
+	}
+
+	if changed {
+		if err := s.hotReload(); err != nil {
+			s.emitLog("热重载失败：", err.Error())
+		}
+	}
+}
@@ -0,0 +1,59 @@
+package main
+
+import (
+	"fmt"
+	"time"
+)
+
+// 生物识别解锁：Touch ID / Windows Hello 作为 PIN 的替代，用于解锁应用和查看 Token
+// 平台实现见 biometric_windows.go / biometric_darwin.go，其他平台只能使用 PIN
+
+// BiometricStatus 当前设备可用的生物识别方式
+type BiometricStatus struct {
+	Available bool   `json:"available"`
+	Kind      string `json:"kind"` // Touch ID / Windows Hello
+}
+
+// GetBiometricStatus 返回生物识别是否可用
+func (s *MoleService) GetBiometricStatus() BiometricStatus {
+	kind := biometricKind()
+	return BiometricStatus{Available: kind != "", Kind: kind}
+}
+
+// UnlockAppBiometric 通过 Touch ID / Windows Hello 解锁
+func (s *MoleService) UnlockAppBiometric() error {
+	if st := s.GetLockStatus(); !st.Locked {
+		return nil
+	}
+	if biometricKind() == "" {
+		return fmt.Errorf("当前设备不支持生物识别解锁")
+	}
+	// 系统验证窗口可能停留很久，期间不持有锁
+	if err := biometricVerify("解锁 Mole"); err != nil {
+		return err
+	}
+
+	s.appLock.mu.Lock()
+	defer s.appLock.mu.Unlock()
+	s.appLock.locked = false
+	s.appLock.failures = 0
+	s.appLock.lastActive = time.Now()
+	s.events.Emit("app-lock", s.appLock.status())
+	return nil
+}
+
+// verifyIdentity 开启应用锁时，敏感操作前再次确认身份：pin 为空则使用生物识别
+func (s *MoleService) verifyIdentity(pin, reason string) error {
+	if !s.GetLockStatus().Enabled {
+		return nil
+	}
+	if pin == "" {
+		if biometricKind() == "" {
+			return fmt.Errorf("请输入 PIN")
+		}
+		return biometricVerify(reason)
+	}
+	s.appLock.mu.Lock()
+	defer s.appLock.mu.Unlock()
+	return s.appLock.verify(pin)
+}
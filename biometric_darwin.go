@@ -0,0 +1,58 @@
+//go:build darwin && cgo
+
+package main
+
+/*
+#cgo CFLAGS: -x objective-c
+#cgo LDFLAGS: -framework Foundation -framework LocalAuthentication
+#include <stdlib.h>
+#import <Foundation/Foundation.h>
+#import <LocalAuthentication/LocalAuthentication.h>
+
+static int touchIDAvailable(void) {
+	LAContext *ctx = [[LAContext alloc] init];
+	BOOL ok = [ctx canEvaluatePolicy:LAPolicyDeviceOwnerAuthenticationWithBiometrics error:nil];
+	[ctx release];
+	return ok ? 1 : 0;
+}
+
+// 回调在系统队列上执行，这里用信号量同步等待结果
+static int touchIDVerify(const char *reason) {
+	LAContext *ctx = [[LAContext alloc] init];
+	dispatch_semaphore_t sem = dispatch_semaphore_create(0);
+	__block int ok = 0;
+	[ctx evaluatePolicy:LAPolicyDeviceOwnerAuthenticationWithBiometrics
+	    localizedReason:[NSString stringWithUTF8String:reason]
+	              reply:^(BOOL success, NSError *error) {
+		ok = success ? 1 : 0;
+		dispatch_semaphore_signal(sem);
+	}];
+	dispatch_semaphore_wait(sem, DISPATCH_TIME_FOREVER);
+	dispatch_release(sem);
+	[ctx release];
+	return ok;
+}
+*/
+import "C"
+
+import (
+	"fmt"
+	"unsafe"
+)
+
+// biometricKind 设备支持并已录入指纹时返回 Touch ID，否则返回空
+func biometricKind() string {
+	if C.touchIDAvailable() == 1 {
+		return "Touch ID"
+	}
+	return ""
+}
+
+func biometricVerify(reason string) error {
+	cs := C.CString(reason)
+	defer C.free(unsafe.Pointer(cs))
+	if C.touchIDVerify(cs) != 1 {
+		return fmt.Errorf("Touch ID 验证未通过")
+	}
+	return nil
+}
@@ -0,0 +1,15 @@
+//go:build !windows && !(darwin && cgo)
+
+package main
+
+import "fmt"
+
+// Linux 桌面没有统一的生物识别接口 (fprintd 需要 PAM 配合)，只能使用 PIN
+
+func biometricKind() string {
+	return ""
+}
+
+func biometricVerify(reason string) error {
+	return fmt.Errorf("当前平台不支持生物识别解锁")
+}
@@ -0,0 +1,94 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/wailsapp/wails/v3/pkg/application"
+)
+
+// 剪贴板快捷操作：公网地址、frpc.toml、分享码都比较长，由后端直接写入系统剪贴板，
+// 结果通过 clipboard 事件通知前端提示
+
+// ClipboardEvent 复制结果通知
+type ClipboardEvent struct {
+	What    string `json:"what"` // url / frpc-toml / share-code
+	Success bool   `json:"success"`
+	Message string `json:"message"`
+}
+
+// CopyProxyURL 复制规则的公网访问地址
+func (s *MoleService) CopyProxyURL(ruleID string) error {
+	p, ok := s.findProxy(ruleID)
+	if !ok {
+		return fmt.Errorf("未找到规则: %s", ruleID)
+	}
+	cfg := s.status().Config
+	if cfg == nil {
+		return fmt.Errorf("未发现有效配置")
+	}
+	if p.ProxyType == "stcp" {
+		return fmt.Errorf("STCP 规则没有公网地址，访问方需要在自己的 frpc 中配置 visitor")
+	}
+	return s.copyToClipboard("url", publicURL(cfg.Server.Addr, p))
+}
+
+// CopyFrpcToml 复制当前配置对应的 frpc.toml，方便在其他机器上直接用 frpc 运行
+func (s *MoleService) CopyFrpcToml() error {
+	if err := s.checkMutable(); err != nil {
+		return err
+	}
+	cfg := s.status().Config
+	if cfg == nil {
+		return fmt.Errorf("未发现有效配置")
+	}
+	out, err := renderFrpcToml(cfg, frpcAdmin{}, nil)
+	if err != nil {
+		return fmt.Errorf("配置生成失败: %v", err)
+	}
+	s.audit(auditTokenReveal, "复制 frpc.toml")
+	return s.copyToClipboard("frpc-toml", string(out))
+}
+
+// CopyShareCode 复制配置分享码，includeToken 为 false 时对方需自行填写 Token
+func (s *MoleService) CopyShareCode(includeToken bool) error {
+	if err := s.checkMutable(); err != nil {
+		return err
+	}
+	s.countFeature("share_code")
+	cfg := s.status().Config
+	if cfg == nil {
+		return fmt.Errorf("未发现有效配置")
+	}
+	code, err := encodeShareCode(profileFromConfig(cfg, includeToken))
+	if err != nil {
+		return fmt.Errorf("生成分享码失败: %v", err)
+	}
+	if includeToken {
+		s.audit(auditTokenReveal, "复制包含 Token 的分享码")
+	}
+	// 自己复制出去的分享码切回窗口时不再提示导入
+	s.clipWatch.seen(code)
+	return s.copyToClipboard("share-code", code)
+}
+
+func (s *MoleService) copyToClipboard(what, text string) error {
+	app := application.Get()
+	if app == nil || !app.Clipboard.SetText(text) {
+		s.events.Emit("clipboard", ClipboardEvent{What: what, Message: "写入剪贴板失败"})
+		return fmt.Errorf("写入剪贴板失败")
+	}
+	s.events.Emit("clipboard", ClipboardEvent{What: what, Success: true, Message: "已复制到剪贴板"})
+	return nil
+}
+
+// publicURL 规则对外的访问地址：HTTP 为域名，TCPMUX 为经 HTTP CONNECT 访问的域名，TCP/UDP 为 服务器:远程端口
+func publicURL(serverAddr string, p ProxyRule) string {
+	if p.ProxyType == "tcpmux" {
+		return primaryDomain(p)
+	}
+	if usesDomains(p.ProxyType) && len(p.Domains) > 0 {
+		return "https://" + primaryDomain(p)
+	}
+	return serverAddr + ":" + strconv.Itoa(p.RemotePort)
+}
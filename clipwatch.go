@@ -0,0 +1,60 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/wailsapp/wails/v3/pkg/application"
+)
+
+// 剪贴板分享码识别：服务商让用户“复制这段代码”时，切回 Mole 窗口即弹出导入预览，不必再找粘贴入口
+// 只在主窗口获得焦点时读取一次剪贴板，不在后台持续轮询；默认关闭，需在偏好设置中开启
+// 同一段分享码只提示一次，自己复制出去的分享码不提示
+
+type clipWatch struct {
+	mu   sync.Mutex
+	last string // 最近一次处理过的分享码
+}
+
+// seen 记录分享码，返回此前是否已经处理过
+func (c *clipWatch) seen(code string) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if code == c.last {
+		return true
+	}
+	c.last = code
+	return false
+}
+
+// checkClipboardShareCode 主窗口获得焦点时调用
+func (s *MoleService) checkClipboardShareCode() {
+	cfg := s.status().Config
+	if cfg == nil || !cfg.Preferences.DetectShareCode || s.checkMutable() != nil {
+		return
+	}
+	app := application.Get()
+	if app == nil {
+		return
+	}
+	text, ok := app.Clipboard.Text()
+	text = strings.TrimSpace(text)
+	if !ok || !strings.HasPrefix(text, shareCodePrefix) || s.clipWatch.seen(text) {
+		return
+	}
+	if _, err := s.ImportShareCode(text); err != nil {
+		s.emitLog("剪贴板中的分享码无法识别：", err.Error())
+	}
+}
+
+// SetDetectShareCode 开启或关闭窗口获得焦点时识别剪贴板中的分享码
+func (s *MoleService) SetDetectShareCode(enabled bool) error {
+	cfg := s.status().Config
+	if cfg == nil {
+		return fmt.Errorf("未发现有效配置")
+	}
+	newCfg := *cfg
+	newCfg.Preferences.DetectShareCode = enabled
+	return s.saveUserConfig(newCfg)
+}
@@ -0,0 +1,343 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+)
+
+// 兼容性检查：frp 0.52 起改用 TOML/YAML/JSON 配置，选项名从 snake_case 改为 camelCase，部分选项移入嵌套段
+// 导入时识别旧写法：frpc.toml 里混用的旧选项名自动转换为新写法，frpc.ini 中 Mole 不使用的选项列出对应的新写法
+// 切换 frpc 版本 (手动替换数据目录中的 frpc) 时检查版本是否能读取 Mole 生成的 frpc.toml
+
+// frpcMinVersion Mole 生成的 frpc.toml (TOML 格式、webServer 段) 需要的最低 frpc 版本
+const frpcMinVersion = "0.52.0"
+
+// 兼容性问题的处理方式
+const (
+	compatTranslated = "translated" // 已自动转换为新写法
+	compatIgnored    = "ignored"    // 新版仍支持，但 Mole 不使用，导入后丢失
+	compatManual     = "manual"     // 语义有变化，无法自动转换
+)
+
+// CompatIssue 一项兼容性问题
+type CompatIssue struct {
+	Option      string `json:"option"`      // 原写法，规则内的选项带 [规则名] 前缀
+	Replacement string `json:"replacement"` // 新写法，点号表示嵌套
+	Action      string `json:"action"`
+}
+
+// legacyCommonOptions [common] 旧选项到新写法的对照
+var legacyCommonOptions = map[string]string{
+	"server_addr":                   "serverAddr",
+	"server_port":                   "serverPort",
+	"token":                         "auth.token",
+	"authentication_method":         "auth.method",
+	"oidc_client_id":                "auth.oidc.clientID",
+	"oidc_client_secret":            "auth.oidc.clientSecret",
+	"oidc_audience":                 "auth.oidc.audience",
+	"oidc_scope":                    "auth.oidc.scope",
+	"oidc_token_endpoint_url":       "auth.oidc.tokenEndpointURL",
+	"user":                          "user",
+	"login_fail_exit":               "loginFailExit",
+	"dns_server":                    "dnsServer",
+	"start":                         "start",
+	"protocol":                      "transport.protocol",
+	"connect_server_local_ip":       "transport.connectServerLocalIP",
+	"http_proxy":                    "transport.proxyURL",
+	"pool_count":                    "transport.poolCount",
+	"tcp_mux":                       "transport.tcpMux",
+	"tcp_mux_keepalive_interval":    "transport.tcpMuxKeepaliveInterval",
+	"dial_server_timeout":           "transport.dialServerTimeout",
+	"dial_server_keepalive":         "transport.dialServerKeepalive",
+	"heartbeat_interval":            "transport.heartbeatInterval",
+	"heartbeat_timeout":             "transport.heartbeatTimeout",
+	"tls_enable":                    "transport.tls.enable",
+	"tls_cert_file":                 "transport.tls.certFile",
+	"tls_key_file":                  "transport.tls.keyFile",
+	"tls_trusted_ca_file":           "transport.tls.trustedCaFile",
+	"tls_server_name":               "transport.tls.serverName",
+	"disable_custom_tls_first_byte": "transport.tls.disableCustomTLSFirstByte",
+	"admin_addr":                    "webServer.addr",
+	"admin_port":                    "webServer.port",
+	"admin_user":                    "webServer.user",
+	"admin_pwd":                     "webServer.password",
+	"assets_dir":                    "webServer.assetsDir",
+	"log_file":                      "log.to",
+	"log_level":                     "log.level",
+	"log_max_days":                  "log.maxDays",
+	"disable_log_color":             "log.disablePrintColor",
+	"udp_packet_size":               "udpPacketSize",
+	"nat_hole_stun_server":          "natHoleStunServer",
+	"includes":                      "includes",
+	"authenticate_heartbeats":       "auth.additionalScopes",
+	"authenticate_new_work_conns":   "auth.additionalScopes",
+}
+
+// legacyProxyOptions 代理小节旧选项到新写法的对照
+var legacyProxyOptions = map[string]string{
+	"type":                    "type",
+	"local_ip":                "localIP",
+	"local_port":              "localPort",
+	"remote_port":             "remotePort",
+	"custom_domains":          "customDomains",
+	"subdomain":               "subdomain",
+	"locations":               "locations",
+	"use_encryption":          "transport.useEncryption",
+	"use_compression":         "transport.useCompression",
+	"bandwidth_limit":         "transport.bandwidthLimit",
+	"bandwidth_limit_mode":    "transport.bandwidthLimitMode",
+	"proxy_protocol_version":  "transport.proxyProtocolVersion",
+	"group":                   "loadBalancer.group",
+	"group_key":               "loadBalancer.groupKey",
+	"health_check_type":       "healthCheck.type",
+	"health_check_timeout_s":  "healthCheck.timeoutSeconds",
+	"health_check_max_failed": "healthCheck.maxFailed",
+	"health_check_interval_s": "healthCheck.intervalSeconds",
+	"health_check_url":        "healthCheck.path",
+	"http_user":               "httpUser",
+	"http_pwd":                "httpPassword",
+	"host_header_rewrite":     "hostHeaderRewrite",
+	"route_by_http_user":      "routeByHTTPUser",
+	"multiplexer":             "multiplexer",
+	"role":                    "role",
+	"sk":                      "secretKey",
+	"allow_users":             "allowUsers",
+	"server_name":             "serverName",
+	"server_user":             "serverUser",
+	"bind_addr":               "bindAddr",
+	"bind_port":               "bindPort",
+	"plugin":                  "plugin.type",
+}
+
+// legacyPluginOptions 插件参数旧选项到新写法的对照，新版统一放在 plugin 段内
+var legacyPluginOptions = map[string]string{
+	"plugin_unix_path":           "plugin.unixPath",
+	"plugin_http_user":           "plugin.httpUser",
+	"plugin_http_passwd":         "plugin.httpPassword",
+	"plugin_user":                "plugin.username",
+	"plugin_passwd":              "plugin.password",
+	"plugin_local_path":          "plugin.localPath",
+	"plugin_strip_prefix":        "plugin.stripPrefix",
+	"plugin_local_addr":          "plugin.localAddr",
+	"plugin_crt_path":            "plugin.crtPath",
+	"plugin_key_path":            "plugin.keyPath",
+	"plugin_host_header_rewrite": "plugin.hostHeaderRewrite",
+}
+
+// 带前缀的旧选项，前缀之后的部分原样保留
+var legacyProxyPrefixes = [][2]string{
+	{"header_", "requestHeaders.set."},
+	{"meta_", "metadatas."},
+	{"plugin_header_", "plugin.requestHeaders.set."},
+}
+
+// 语义变化无法直接改名的选项
+var legacyManualOptions = map[string]bool{
+	"authenticate_heartbeats":     true,
+	"authenticate_new_work_conns": true,
+}
+
+// mole 从 frpc.ini 中直接读取的选项，不需要报告
+var iniOptionsUsed = map[string]bool{
+	"server_addr": true, "server_port": true, "token": true,
+	"type": true, "local_ip": true, "local_port": true, "remote_port": true, "custom_domains": true, "subdomain": true,
+}
+
+// legacyReplacement 查找旧选项的新写法，不是旧写法时返回 false
+func legacyReplacement(key string, proxy bool) (string, bool) {
+	table := legacyCommonOptions
+	if proxy {
+		table = legacyProxyOptions
+	}
+	if r, ok := table[key]; ok {
+		return r, true
+	}
+	if proxy {
+		if r, ok := legacyPluginOptions[key]; ok {
+			return r, true
+		}
+		for _, p := range legacyProxyPrefixes {
+			if rest, ok := strings.CutPrefix(key, p[0]); ok && rest != "" {
+				return p[1] + rest, true
+			}
+		}
+	}
+	return "", false
+}
+
+// iniCompatIssues 列出 frpc.ini 中 Mole 不使用的旧选项及其新写法
+func iniCompatIssues(sections map[string]map[string]string, order []string) []CompatIssue {
+	var issues []CompatIssue
+	for _, name := range order {
+		proxy := name != "common"
+		keys := make([]string, 0, len(sections[name]))
+		for k := range sections[name] {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			if iniOptionsUsed[k] {
+				continue
+			}
+			repl, ok := legacyReplacement(k, proxy)
+			if !ok {
+				continue
+			}
+			action := compatIgnored
+			if legacyManualOptions[k] {
+				action = compatManual
+			}
+			issues = append(issues, CompatIssue{Option: compatOption(name, k, proxy), Replacement: repl, Action: action})
+		}
+	}
+	return issues
+}
+
+// translateLegacyToml 把 frpc.toml 中混用的旧选项名就地改为新写法，新旧写法同时存在时保留新写法
+func translateLegacyToml(doc map[string]any) []CompatIssue {
+	var issues []CompatIssue
+	issues = append(issues, translateLegacyTable(doc, "", false)...)
+	if proxies, ok := doc["proxies"].([]map[string]any); ok {
+		for _, p := range proxies {
+			name, _ := p["name"].(string)
+			issues = append(issues, translateLegacyTable(p, name, true)...)
+		}
+	}
+	return issues
+}
+
+func translateLegacyTable(table map[string]any, name string, proxy bool) []CompatIssue {
+	keys := make([]string, 0, len(table))
+	for k := range table {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	var issues []CompatIssue
+	for _, k := range keys {
+		repl, ok := legacyReplacement(k, proxy)
+		if !ok || repl == k {
+			continue
+		}
+		// 新写法中的同名嵌套段 (如 [proxies.plugin])
+		if _, nested := table[k].(map[string]any); nested {
+			continue
+		}
+		issue := CompatIssue{Option: compatOption(name, k, proxy), Replacement: repl, Action: compatManual}
+		if !legacyManualOptions[k] {
+			if setNested(table, repl, legacyValue(k, table[k])) {
+				issue.Action = compatTranslated
+			}
+			delete(table, k)
+		}
+		issues = append(issues, issue)
+	}
+	return issues
+}
+
+// legacyValue 旧格式里逗号分隔的列表转为数组
+func legacyValue(key string, v any) any {
+	s, ok := v.(string)
+	if !ok || (key != "custom_domains" && key != "locations" && key != "allow_users") {
+		return v
+	}
+	var list []string
+	for _, item := range strings.Split(s, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			list = append(list, item)
+		}
+	}
+	return list
+}
+
+// setNested 按点号路径写入，目标已存在时不覆盖并返回 false
+func setNested(table map[string]any, path string, v any) bool {
+	parts := strings.Split(path, ".")
+	for _, p := range parts[:len(parts)-1] {
+		next, ok := table[p].(map[string]any)
+		if !ok {
+			if _, exists := table[p]; exists {
+				return false
+			}
+			next = make(map[string]any)
+			table[p] = next
+		}
+		table = next
+	}
+	last := parts[len(parts)-1]
+	if _, exists := table[last]; exists {
+		return false
+	}
+	table[last] = v
+	return true
+}
+
+func compatOption(section, key string, proxy bool) string {
+	if !proxy {
+		return key
+	}
+	return fmt.Sprintf("[%s] %s", section, key)
+}
+
+// normalizeFrpcToml 解析 frpc.toml 并转换旧选项名，返回转换后的内容
+func normalizeFrpcToml(data []byte) ([]byte, []CompatIssue, error) {
+	var doc map[string]any
+	if _, err := toml.Decode(string(data), &doc); err != nil {
+		return nil, nil, err
+	}
+	issues := translateLegacyToml(doc)
+	if len(issues) == 0 {
+		return data, nil, nil
+	}
+	var buf bytes.Buffer
+	if err := toml.NewEncoder(&buf).Encode(doc); err != nil {
+		return nil, nil, err
+	}
+	return buf.Bytes(), issues, nil
+}
+
+// frpcCompatIssues 检查 frpc 版本能否运行 Mole 生成的配置
+func frpcCompatIssues(installed, embedded string) []string {
+	issues := []string{}
+	if installed == "" {
+		return issues
+	}
+	if compareVersions(installed, frpcMinVersion) < 0 {
+		issues = append(issues, fmt.Sprintf("frpc %s 不支持 TOML 配置，Mole 需要 %s 及以上版本，请删除数据目录中的 frpc 以恢复内置版本", installed, frpcMinVersion))
+	}
+	if embedded != "" && installed != embedded {
+		issues = append(issues, fmt.Sprintf("正在使用的 frpc (%s) 与内置版本 (%s) 不同", installed, embedded))
+	}
+	return issues
+}
+
+// compareVersions 比较 x.y.z 形式的版本号，忽略预发布后缀
+func compareVersions(a, b string) int {
+	pa, pb := versionParts(a), versionParts(b)
+	for i := range pa {
+		if pa[i] != pb[i] {
+			if pa[i] < pb[i] {
+				return -1
+			}
+			return 1
+		}
+	}
+	return 0
+}
+
+func versionParts(v string) [3]int {
+	var parts [3]int
+	v = strings.TrimPrefix(v, "v")
+	if i := strings.IndexAny(v, "-+ "); i >= 0 {
+		v = v[:i]
+	}
+	for i, p := range strings.SplitN(v, ".", 3) {
+		parts[i], _ = strconv.Atoi(p)
+	}
+	return parts
+}
@@ -0,0 +1,142 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+	"github.com/wailsapp/wails/v3/pkg/application"
+	"gopkg.in/yaml.v3"
+)
+
+// 完整配置的导入导出：格式按扩展名判断
+// TOML 与 config.toml 相同 (snake_case 键名)；YAML / JSON 使用与界面一致的 camelCase 键名 (json 标签)，方便脚本生成
+// YAML 先转换为 JSON 再解析，两种格式共用同一套键名，不必给每个结构体再加 yaml 标签
+
+const (
+	configFormatTOML = "toml"
+	configFormatYAML = "yaml"
+	configFormatJSON = "json"
+)
+
+// configFormatByExt 按扩展名判断配置文件格式，无法识别时返回空字符串
+func configFormatByExt(path string) string {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".toml":
+		return configFormatTOML
+	case ".yaml", ".yml":
+		return configFormatYAML
+	case ".json":
+		return configFormatJSON
+	}
+	return ""
+}
+
+func encodeUserConfig(cfg *UserConfig, format string) ([]byte, error) {
+	switch format {
+	case configFormatTOML:
+		return toml.Marshal(cfg)
+	case configFormatJSON:
+		return json.MarshalIndent(cfg, "", "  ")
+	case configFormatYAML:
+		data, err := json.Marshal(cfg)
+		if err != nil {
+			return nil, err
+		}
+		// JSON 本身是合法的 YAML，解析为节点可以保留字段顺序，清除流式与引号风格后输出为常见的块格式
+		var node yaml.Node
+		if err := yaml.Unmarshal(data, &node); err != nil {
+			return nil, err
+		}
+		clearYAMLStyle(&node)
+		var buf bytes.Buffer
+		enc := yaml.NewEncoder(&buf)
+		enc.SetIndent(2)
+		if err := enc.Encode(&node); err != nil {
+			return nil, err
+		}
+		return buf.Bytes(), enc.Close()
+	}
+	return nil, fmt.Errorf("不支持的配置格式: %s", format)
+}
+
+func decodeUserConfig(data []byte, format string) (UserConfig, error) {
+	var cfg UserConfig
+	switch format {
+	case configFormatTOML:
+		if _, err := toml.Decode(string(data), &cfg); err != nil {
+			return cfg, fmt.Errorf("TOML 格式错误: %v", err)
+		}
+	case configFormatJSON:
+		if err := json.Unmarshal(data, &cfg); err != nil {
+			return cfg, fmt.Errorf("JSON 格式错误: %v", err)
+		}
+	case configFormatYAML:
+		var doc any
+		if err := yaml.Unmarshal(data, &doc); err != nil {
+			return cfg, fmt.Errorf("YAML 格式错误: %v", err)
+		}
+		raw, err := json.Marshal(doc)
+		if err != nil {
+			return cfg, fmt.Errorf("YAML 内容无法转换: %v", err)
+		}
+		if err := json.Unmarshal(raw, &cfg); err != nil {
+			return cfg, fmt.Errorf("YAML 内容错误: %v", err)
+		}
+	default:
+		return cfg, fmt.Errorf("不支持的配置格式: %s", format)
+	}
+	return cfg, nil
+}
+
+func clearYAMLStyle(n *yaml.Node) {
+	n.Style = 0
+	for _, c := range n.Content {
+		clearYAMLStyle(c)
+	}
+}
+
+// ExportConfig 弹出保存对话框导出完整配置，格式由所选文件的扩展名决定
+// 导出内容包含 Token 等凭据，文件只对当前用户可读；返回保存的路径，用户取消时返回空字符串
+func (s *MoleService) ExportConfig() (string, error) {
+	if err := s.checkMutable(); err != nil {
+		return "", err
+	}
+	cfg := s.status().Config
+	if cfg == nil {
+		return "", fmt.Errorf("未发现有效配置")
+	}
+	app := application.Get()
+	if app == nil {
+		return "", fmt.Errorf("当前模式不支持文件对话框")
+	}
+	path, err := app.Dialog.SaveFile().
+		SetFilename("mole-config.toml").
+		AddFilter("TOML", "*.toml").
+		AddFilter("YAML", "*.yaml;*.yml").
+		AddFilter("JSON", "*.json").
+		CanCreateDirectories(true).
+		PromptForSingleSelection()
+	if err != nil || path == "" {
+		return "", err
+	}
+	format := configFormatByExt(path)
+	if format == "" {
+		format = configFormatTOML
+		path += ".toml"
+	}
+	data, err := encodeUserConfig(cfg, format)
+	if err != nil {
+		return "", fmt.Errorf("配置文件格式化失败: %v", err)
+	}
+	if err := os.WriteFile(path, data, 0600); err != nil {
+		return "", fmt.Errorf("写入文件失败: %v", err)
+	}
+	s.audit(auditTokenReveal, "导出配置文件 "+filepath.Base(path))
+	s.countFeature("export_config")
+	return path, nil
+}
@@ -0,0 +1,119 @@
+package main
+
+import (
+	"context"
+	"log"
+	"os"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// 配置变更通知：内存中的配置每次被替换 (保存、启动加载、守护进程重新读取、外部修改文件) 都递增修订号，
+// 并发送 config-changed 事件，主窗口、迷你状态窗等多个视图据此同步，不必轮询 GetStatus
+// 外部修改通过定期比较 config.toml 的修改时间和大小发现，自己写入后会更新记录，不会误报
+
+const configWatchInterval = 5 * time.Second
+
+// 变更来源
+const (
+	configSourceSave   = "save"   // SaveUserConfig (含导入、恢复历史版本、命名配置切换)
+	configSourceLoad   = "load"   // 启动时加载
+	configSourceReload = "reload" // 守护进程收到重新读取的请求
+	configSourceFile   = "file"   // config.toml 被外部修改
+)
+
+// ConfigChangedEvent config-changed 事件内容
+type ConfigChangedEvent struct {
+	Revision uint64 `json:"revision"`
+	Source   string `json:"source"`
+	Time     string `json:"time"`
+}
+
+type configWatch struct {
+	rev atomic.Uint64
+
+	mu      sync.Mutex
+	modTime time.Time // 最近一次由本进程读写时 config.toml 的状态
+	size    int64
+}
+
+func configFilePath() string {
+	return filepath.Join(getAppDataDir(), "config", "config.toml")
+}
+
+// stamp 记录当前文件状态，读写配置文件后调用
+func (w *configWatch) stamp() {
+	info, err := os.Stat(configFilePath())
+	if err != nil {
+		return
+	}
+	w.mu.Lock()
+	w.modTime, w.size = info.ModTime(), info.Size()
+	w.mu.Unlock()
+}
+
+// changedOnDisk 文件状态与记录不一致
+func (w *configWatch) changedOnDisk() bool {
+	info, err := os.Stat(configFilePath())
+	if err != nil {
+		return false
+	}
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return !w.modTime.IsZero() && (!info.ModTime().Equal(w.modTime) || info.Size() != w.size)
+}
+
+// emitConfigChanged 递增修订号并通知前端
+func (s *MoleService) emitConfigChanged(source string) {
+	s.events.Emit("config-changed", ConfigChangedEvent{
+		Revision: s.configWatch.rev.Add(1),
+		Source:   source,
+		Time:     time.Now().Format(time.RFC3339),
+	})
+}
+
+// runConfigWatcher 发现 config.toml 被外部修改时重新加载，有未落盘的保存时跳过，以内存中的新配置为准
+func (s *MoleService) runConfigWatcher(ctx context.Context) {
+	ticker := time.NewTicker(configWatchInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		if s.checkExternalChange() {
+			s.emitLog("检测到配置文件被外部修改，已重新加载")
+			s.emitConfigChanged(configSourceFile)
+		}
+	}
+}
+
+// checkExternalChange 持有 persistMu，保证检查期间没有正在进行的写盘，记录的文件状态是最新的
+func (s *MoleService) checkExternalChange() bool {
+	s.persistMu.Lock()
+	defer s.persistMu.Unlock()
+
+	if !s.configWatch.changedOnDisk() {
+		return false
+	}
+	s.saveMu.Lock()
+	pending := s.savePending
+	s.saveMu.Unlock()
+	if pending {
+		return false
+	}
+
+	s.mu.Lock()
+	err := s.loadConfigFromDisk()
+	s.mu.Unlock()
+	if err != nil {
+		log.Printf("重新加载外部修改的配置失败: %v", err)
+		// 记录当前状态，文件再次变化时重试
+		s.configWatch.stamp()
+		return false
+	}
+	return true
+}
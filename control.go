@@ -0,0 +1,586 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// 本机控制接口：守护进程对外暴露，GUI 通过它附着控制
+// 只监听回环地址，另外用随机 token 防止同机其他程序随意调用
+// 默认端口被占用 (如同机其他用户也在运行) 时改用随机端口，实际地址写在 token 旁边
+const (
+	controlAddr      = "127.0.0.1:17600"
+	controlTokenFile = "control.token"
+	controlAddrFile  = "control.addr"
+)
+
+// controlEvent 通过 SSE 转发给 GUI 的事件
+type controlEvent struct {
+	Name string `json:"name"`
+	Data any    `json:"data"`
+}
+
+// eventHub 守护进程的事件发送器：写日志并广播给所有已连接的 GUI
+type eventHub struct {
+	mu   sync.Mutex
+	subs map[chan controlEvent]struct{}
+}
+
+func newEventHub() *eventHub {
+	return &eventHub{subs: make(map[chan controlEvent]struct{})}
+}
+
+func (h *eventHub) Emit(name string, data any) {
+	logEmitter{}.Emit(name, data)
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for ch := range h.subs {
+		// 订阅方消费过慢时直接丢弃，不能阻塞引擎
+		select {
+		case ch <- controlEvent{Name: name, Data: data}:
+		default:
+		}
+	}
+}
+
+func (h *eventHub) subscribe() (chan controlEvent, func()) {
+	ch := make(chan controlEvent, 64)
+	h.mu.Lock()
+	h.subs[ch] = struct{}{}
+	h.mu.Unlock()
+
+	return ch, func() {
+		h.mu.Lock()
+		delete(h.subs, ch)
+		h.mu.Unlock()
+	}
+}
+
+// =====================服务端 (守护进程) ===============================
+
+// startControlServer 启动控制接口，shutdown 用于响应客户端的退出请求
+func startControlServer(ms *MoleService, hub *eventHub, shutdown func()) (*http.Server, error) {
+	// 端口不再唯一，同一配置目录是否已有守护进程要靠记录的地址和 token 判断
+	if dialControl() != nil {
+		return nil, fmt.Errorf("后台服务已在运行")
+	}
+
+	token, err := writeControlToken(ms.getAppConfigDir())
+	if err != nil {
+		return nil, err
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /api/status", func(w http.ResponseWriter, r *http.Request) {
+		if st, ok := ms.initializingStatus(); ok {
+			writeJSON(w, st)
+			return
+		}
+		writeJSON(w, ms.status())
+	})
+	mux.HandleFunc("POST /api/connect", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, ms.Connect())
+	})
+	mux.HandleFunc("POST /api/disconnect", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, ms.Disconnect())
+	})
+	mux.HandleFunc("POST /api/restart", func(w http.ResponseWriter, r *http.Request) {
+		if err := ms.restartTunnel(); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		writeJSON(w, ms.status())
+	})
+	mux.HandleFunc("POST /api/reload", func(w http.ResponseWriter, r *http.Request) {
+		if err := ms.reloadConfig(); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		writeJSON(w, ms.status())
+	})
+	mux.HandleFunc("GET /api/config", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, ms.status().Config)
+	})
+	mux.HandleFunc("POST /api/config", func(w http.ResponseWriter, r *http.Request) {
+		var cfg UserConfig
+		if err := json.NewDecoder(r.Body).Decode(&cfg); err != nil {
+			http.Error(w, "配置格式错误: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+		if err := ms.SaveUserConfig(cfg); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		writeJSON(w, ms.status())
+	})
+	mux.HandleFunc("GET /api/proxy-states", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, ms.proxyStates.list())
+	})
+	mux.HandleFunc("GET /api/self-test", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, ms.selfTest.snapshot())
+	})
+	mux.HandleFunc("GET /api/log-issues", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, ms.logRepeat.list())
+	})
+	mux.HandleFunc("GET /api/runtime-config", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, ms.runtimeConfigStatus())
+	})
+	mux.HandleFunc("POST /api/runtime-config/rollback", func(w http.ResponseWriter, r *http.Request) {
+		if err := ms.rollbackRuntimeConfig(); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		writeJSON(w, ms.status())
+	})
+	mux.HandleFunc("GET /api/latency", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, ms.proxyLatency())
+	})
+	mux.HandleFunc("GET /api/frps/status", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, ms.frpsStatus())
+	})
+	mux.HandleFunc("POST /api/frps/start", func(w http.ResponseWriter, r *http.Request) {
+		if err := ms.startFrps(); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		writeJSON(w, ms.frpsStatus())
+	})
+	mux.HandleFunc("POST /api/frps/stop", func(w http.ResponseWriter, r *http.Request) {
+		ms.stopFrps()
+		writeJSON(w, ms.frpsStatus())
+	})
+	mux.HandleFunc("GET /api/quick-expose", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, ms.quick.list())
+	})
+	mux.HandleFunc("POST /api/quick-expose", func(w http.ResponseWriter, r *http.Request) {
+		var req quickExposeRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, "请求格式错误: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+		item, err := ms.QuickExpose(req.LocalPort, req.ProxyType)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		writeJSON(w, item)
+	})
+	mux.HandleFunc("POST /api/quick-expose/stop", func(w http.ResponseWriter, r *http.Request) {
+		var req quickExposeRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, "请求格式错误: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+		if err := ms.StopQuickExpose(req.ID); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		writeJSON(w, ms.quick.list())
+	})
+	mux.HandleFunc("GET /api/request-log", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, ms.reqLog.entries(r.URL.Query().Get("rule")))
+	})
+	mux.HandleFunc("DELETE /api/request-log", func(w http.ResponseWriter, r *http.Request) {
+		ms.reqLog.clear(r.URL.Query().Get("rule"))
+		writeJSON(w, []RequestLogEntry{})
+	})
+	mux.HandleFunc("POST /api/shutdown", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, ms.Disconnect())
+		// 先返回响应，再异步退出
+		go shutdown()
+	})
+	mux.HandleFunc("GET /api/events", func(w http.ResponseWriter, r *http.Request) {
+		serveEvents(w, r, hub)
+	})
+	registerRemoteRoutes(mux, ms)
+
+	ln, err := net.Listen("tcp", controlAddr)
+	if err != nil {
+		log.Printf("控制接口默认端口不可用，改用随机端口: %v", err)
+		if ln, err = net.Listen("tcp", "127.0.0.1:0"); err != nil {
+			return nil, err
+		}
+	}
+	addr := ln.Addr().String()
+	if err := os.WriteFile(filepath.Join(ms.getAppConfigDir(), controlAddrFile), []byte(addr), 0600); err != nil {
+		_ = ln.Close()
+		return nil, fmt.Errorf("写入控制接口地址失败: %v", err)
+	}
+
+	srv := &http.Server{Handler: requireToken(token, ms.fleetAccessToken, mux)}
+	go func() {
+		if err := srv.Serve(ln); err != nil && err != http.ErrServerClosed {
+			log.Printf("控制接口异常退出: %v", err)
+		}
+	}()
+
+	log.Printf("控制接口已监听: %s", addr)
+	return srv, nil
+}
+
+// writeControlToken 每次启动生成新 token，仅当前用户可读
+func writeControlToken(configDir string) (string, error) {
+	buf := make([]byte, 16)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	token := hex.EncodeToString(buf)
+
+	path := filepath.Join(configDir, controlTokenFile)
+	if err := os.WriteFile(path, []byte(token), 0600); err != nil {
+		return "", fmt.Errorf("写入控制 token 失败: %v", err)
+	}
+	return token, nil
+}
+
+// requireToken 本机 token 可以访问全部接口，设备管理的访问令牌只能访问 /api/remote/，见 fleet.go
+func requireToken(token string, accessToken func() string, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got := []byte(r.Header.Get("Authorization"))
+		if subtle.ConstantTimeCompare(got, []byte("Bearer "+token)) != 1 && !remoteAuthorized(r, accessToken()) {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// serveEvents 以 Server-Sent Events 方式持续推送事件，直到客户端断开
+func serveEvents(w http.ResponseWriter, r *http.Request, hub *eventHub) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "streaming unsupported", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	flusher.Flush()
+
+	ch, unsubscribe := hub.subscribe()
+	defer unsubscribe()
+
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case evt := <-ch:
+			data, err := json.Marshal(evt)
+			if err != nil {
+				continue
+			}
+			fmt.Fprintf(w, "data: %s\n\n", data)
+			flusher.Flush()
+		}
+	}
+}
+
+func writeJSON(w http.ResponseWriter, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+// =====================客户端 (GUI) ===============================
+
+// controlClient GUI 附着到守护进程时使用的客户端
+// 守护进程每次启动都会生成新 token，重启后客户端通过 refreshToken 重新读取
+type controlClient struct {
+	base   string
+	client *http.Client
+
+	tokenMu sync.RWMutex
+	token   string
+}
+
+// ensureDaemon GUI 启动时调用：已有守护进程则直接连接，否则拉起一个再连接
+// 失败时返回 nil，调用方退回进程内模式
+func ensureDaemon() *controlClient {
+	if c := dialControl(); c != nil {
+		return c
+	}
+
+	if err := spawnDaemon(); err != nil {
+		log.Printf("拉起后台服务失败: %v", err)
+		return nil
+	}
+
+	c := waitForDaemon(5 * time.Second)
+	if c == nil {
+		log.Println("等待后台服务就绪超时")
+	}
+	return c
+}
+
+// waitForDaemon 轮询直到守护进程的控制接口可用或超时
+func waitForDaemon(timeout time.Duration) *controlClient {
+	deadline := time.Now().Add(timeout)
+	for time.Now().Before(deadline) {
+		time.Sleep(200 * time.Millisecond)
+		if c := dialControl(); c != nil {
+			return c
+		}
+	}
+	return nil
+}
+
+// spawnDaemon 以 --daemon 参数启动自身的独立副本，脱离 GUI 进程组，GUI 退出后继续运行
+func spawnDaemon() error {
+	exePath, err := os.Executable()
+	if err != nil {
+		return err
+	}
+
+	args := []string{"--daemon"}
+	if appFlags.ConfigDir != "" {
+		args = append(args, "--config-dir", appFlags.ConfigDir)
+	}
+	if appFlags.Debug {
+		args = append(args, "--debug")
+	}
+
+	cmd := exec.Command(exePath, args...)
+	cmd.SysProcAttr = &syscall.SysProcAttr{}
+	setHideWindow(cmd.SysProcAttr)
+	setDetached(cmd.SysProcAttr)
+
+	// 守护进程的输出写入独立日志文件
+	logFile, err := os.OpenFile(filepath.Join(getAppLogDir(), "daemon.log"), os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err == nil {
+		cmd.Stdout = logFile
+		cmd.Stderr = logFile
+		defer logFile.Close()
+	}
+
+	if err := cmd.Start(); err != nil {
+		return err
+	}
+	log.Printf("已拉起后台服务，PID: %d", cmd.Process.Pid)
+	return cmd.Process.Release()
+}
+
+// dialControl 探测本机是否有守护进程在运行，没有则返回 nil
+func dialControl() *controlClient {
+	c := &controlClient{
+		base:   "http://" + readControlAddr(),
+		client: &http.Client{Timeout: 5 * time.Second},
+	}
+	if err := c.refreshToken(); err != nil {
+		return nil
+	}
+	if _, err := c.status(); err != nil {
+		return nil
+	}
+	return c
+}
+
+// readControlAddr 读取守护进程实际监听的地址，没有记录时使用默认地址
+func readControlAddr() string {
+	data, err := os.ReadFile(filepath.Join(getAppDataDir(), "config", controlAddrFile))
+	if err != nil {
+		return controlAddr
+	}
+	if addr := strings.TrimSpace(string(data)); addr != "" {
+		return addr
+	}
+	return controlAddr
+}
+
+// refreshToken 从配置目录重新读取守护进程当前的 token
+func (c *controlClient) refreshToken() error {
+	data, err := os.ReadFile(filepath.Join(getAppDataDir(), "config", controlTokenFile))
+	if err != nil {
+		return err
+	}
+
+	c.tokenMu.Lock()
+	c.token = strings.TrimSpace(string(data))
+	c.tokenMu.Unlock()
+	return nil
+}
+
+func (c *controlClient) authHeader() string {
+	c.tokenMu.RLock()
+	defer c.tokenMu.RUnlock()
+	return "Bearer " + c.token
+}
+
+func (c *controlClient) status() (ServiceStatus, error) {
+	var st ServiceStatus
+	err := c.call(http.MethodGet, "/api/status", &st)
+	return st, err
+}
+
+func (c *controlClient) connect() (ServiceStatus, error) {
+	var st ServiceStatus
+	err := c.call(http.MethodPost, "/api/connect", &st)
+	return st, err
+}
+
+func (c *controlClient) disconnect() (ServiceStatus, error) {
+	var st ServiceStatus
+	err := c.call(http.MethodPost, "/api/disconnect", &st)
+	return st, err
+}
+
+func (c *controlClient) restart() error {
+	return c.call(http.MethodPost, "/api/restart", nil)
+}
+
+func (c *controlClient) reload() error {
+	return c.call(http.MethodPost, "/api/reload", nil)
+}
+
+func (c *controlClient) saveConfig(cfg UserConfig) error {
+	return c.callWithBody(http.MethodPost, "/api/config", cfg, nil)
+}
+
+func (c *controlClient) shutdown() error {
+	return c.call(http.MethodPost, "/api/shutdown", nil)
+}
+
+// quickExposeRequest 快速分享接口的请求体
+type quickExposeRequest struct {
+	ID        string `json:"id,omitempty"`
+	LocalPort int    `json:"localPort,omitempty"`
+	ProxyType string `json:"proxyType,omitempty"`
+}
+
+func (c *controlClient) quickExpose(localPort int, proxyType string) (QuickExpose, error) {
+	var item QuickExpose
+	err := c.callWithBody(http.MethodPost, "/api/quick-expose", quickExposeRequest{LocalPort: localPort, ProxyType: proxyType}, &item)
+	return item, err
+}
+
+func (c *controlClient) stopQuickExpose(id string) error {
+	return c.callWithBody(http.MethodPost, "/api/quick-expose/stop", quickExposeRequest{ID: id}, nil)
+}
+
+func (c *controlClient) quickExposes() ([]QuickExpose, error) {
+	var list []QuickExpose
+	err := c.call(http.MethodGet, "/api/quick-expose", &list)
+	return list, err
+}
+
+func (c *controlClient) requestLog(ruleID string) ([]RequestLogEntry, error) {
+	var list []RequestLogEntry
+	err := c.call(http.MethodGet, requestLogPath(ruleID), &list)
+	return list, err
+}
+
+func (c *controlClient) clearRequestLog(ruleID string) error {
+	return c.call(http.MethodDelete, requestLogPath(ruleID), nil)
+}
+
+func (c *controlClient) rollbackRuntimeConfig() error {
+	return c.call(http.MethodPost, "/api/runtime-config/rollback", nil)
+}
+
+func (c *controlClient) call(method, path string, out any) error {
+	return c.callWithBody(method, path, nil, out)
+}
+
+func (c *controlClient) callWithBody(method, path string, in, out any) error {
+	var body io.Reader
+	if in != nil {
+		data, err := json.Marshal(in)
+		if err != nil {
+			return err
+		}
+		body = bytes.NewReader(data)
+	}
+
+	req, err := http.NewRequest(method, c.base+path, body)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", c.authHeader())
+	if in != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("守护进程返回错误: %s %s", resp.Status, strings.TrimSpace(string(msg)))
+	}
+	if out == nil {
+		return nil
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
+
+// subscribe 订阅守护进程事件并交给 emit 转发，断线后每 3 秒重连，直到 ctx 取消
+func (c *controlClient) subscribe(ctx context.Context, emit func(name string, data any)) {
+	for {
+		if err := c.readEvents(ctx, emit); err != nil && ctx.Err() == nil {
+			log.Printf("守护进程事件流中断: %v", err)
+		}
+		// 守护进程可能已重启 (崩溃后被服务管理器拉起)，重连前刷新 token
+		_ = c.refreshToken()
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(3 * time.Second):
+		}
+	}
+}
+
+func (c *controlClient) readEvents(ctx context.Context, emit func(name string, data any)) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.base+"/api/events", nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", c.authHeader())
+
+	// 事件流是长连接，不能使用带超时的 client
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("守护进程返回错误: %s", resp.Status)
+	}
+
+	scanner := bufio.NewScanner(resp.Body)
+	// 一批日志可能较长，放宽单行上限
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if !strings.HasPrefix(line, "data: ") {
+			continue
+		}
+
+		var evt controlEvent
+		if err := json.Unmarshal([]byte(strings.TrimPrefix(line, "data: ")), &evt); err != nil {
+			continue
+		}
+		emit(evt.Name, evt.Data)
+	}
+	return scanner.Err()
+}
@@ -0,0 +1,80 @@
+package main
+
+import (
+	"context"
+	"log"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/wailsapp/wails/v3/pkg/application"
+)
+
+// runDaemon 无界面运行隧道引擎，由 --daemon 参数触发
+// 作为 Windows 服务 / systemd 单元启动时，开机即建立隧道，无需用户登录；
+// GUI 启动时通过本机控制接口附着到该进程进行控制
+func runDaemon() {
+	runUnderServiceManager(daemonMain)
+}
+
+// daemonMain 守护进程主流程，ctx 取消或客户端请求退出时清理并返回
+func daemonMain(ctx context.Context) {
+	ctx, shutdown := context.WithCancel(ctx)
+	defer shutdown()
+
+	hub := newEventHub()
+	ms := NewMoleService(hub)
+
+	if err := ms.ServiceStartup(ctx, application.ServiceOptions{}); err != nil {
+		log.Printf("守护进程初始化失败: %v", err)
+		return
+	}
+
+	srv, err := startControlServer(ms, hub, shutdown)
+	if err != nil {
+		log.Printf("控制接口启动失败: %v", err)
+		ms.cleanup()
+		return
+	}
+
+	// 作为系统服务运行时开机即连接，不依赖 AutoStart 开关；
+	// 由 GUI 拉起时则沿用 ServiceStartup 中的 AutoStart 逻辑
+	<-ms.initWait
+	if appFlags.Connect {
+		status := ms.Connect()
+		log.Printf("守护进程已就绪: %s", status.Message)
+	}
+
+	<-ctx.Done()
+	log.Println("守护进程收到退出信号")
+	_ = srv.Close()
+	ms.cleanup()
+}
+
+// runInterruptible 在前台运行，收到 Ctrl+C / SIGTERM 时取消 ctx
+func runInterruptible(run func(ctx context.Context)) {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	run(ctx)
+}
+
+// handleServiceCommand 处理 --install-service / --uninstall-service，返回 true 表示已处理，程序应直接退出
+func handleServiceCommand() bool {
+	switch {
+	case appFlags.InstallService:
+		if err := installBackgroundService(); err != nil {
+			log.Printf("安装后台服务失败: %v", err)
+		} else {
+			log.Println("后台服务已安装并启动")
+		}
+		return true
+	case appFlags.UninstallService:
+		if err := uninstallBackgroundService(); err != nil {
+			log.Printf("卸载后台服务失败: %v", err)
+		} else {
+			log.Println("后台服务已卸载")
+		}
+		return true
+	}
+	return false
+}
@@ -0,0 +1,10 @@
+//go:build !windows
+
+package main
+
+import "context"
+
+// 非 Windows 平台由 systemd / launchd 直接管理进程，按普通前台进程处理信号即可
+func runUnderServiceManager(run func(ctx context.Context)) {
+	runInterruptible(run)
+}
@@ -0,0 +1,60 @@
+//go:build windows
+
+package main
+
+import (
+	"context"
+	"log"
+
+	"golang.org/x/sys/windows/svc"
+)
+
+// runUnderServiceManager 由 SCM 启动时走服务协议，否则按普通控制台程序运行
+func runUnderServiceManager(run func(ctx context.Context)) {
+	isService, err := svc.IsWindowsService()
+	if err != nil || !isService {
+		runInterruptible(run)
+		return
+	}
+
+	if err := svc.Run(serviceName, &windowsService{run: run}); err != nil {
+		log.Printf("服务运行失败: %v", err)
+	}
+}
+
+// windowsService 实现 svc.Handler，把 SCM 的停止请求转换为 ctx 取消
+type windowsService struct {
+	run func(ctx context.Context)
+}
+
+func (w *windowsService) Execute(args []string, r <-chan svc.ChangeRequest, changes chan<- svc.Status) (bool, uint32) {
+	changes <- svc.Status{State: svc.StartPending}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		w.run(ctx)
+	}()
+
+	changes <- svc.Status{State: svc.Running, Accepts: svc.AcceptStop | svc.AcceptShutdown}
+
+	for {
+		select {
+		case c := <-r:
+			switch c.Cmd {
+			case svc.Interrogate:
+				changes <- c.CurrentStatus
+			case svc.Stop, svc.Shutdown:
+				changes <- svc.Status{State: svc.StopPending}
+				cancel()
+				<-done
+				return false, 0
+			}
+		case <-done:
+			// 引擎自行退出 (如初始化失败)，返回非零码以便 SCM 按恢复策略重启
+			cancel()
+			return false, 1
+		}
+	}
+}
@@ -0,0 +1,81 @@
+package main
+
+import (
+	"io"
+	"log"
+	"log/slog"
+	"net/http"
+	"net/http/pprof"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// pprof 仅监听本机回环地址，避免把调试接口暴露到局域网
+const debugPprofAddr = "127.0.0.1:6060"
+
+// setupDebug 在 --debug 模式下初始化调试设施：
+// 1. 详细 slog 日志同时输出到控制台和 logs/debug.log
+// 2. 启动本机 pprof 端点
+// 非调试模式返回 nil，调用方沿用默认日志配置
+func setupDebug() (*slog.Logger, func()) {
+	if !appFlags.Debug {
+		return nil, func() {}
+	}
+
+	var out io.Writer = os.Stderr
+	closer := func() {}
+
+	logPath := filepath.Join(getAppLogDir(), "debug.log")
+	if f, err := os.OpenFile(logPath, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644); err != nil {
+		log.Printf("打开调试日志文件失败: %v", err)
+	} else {
+		out = io.MultiWriter(os.Stderr, f)
+		closer = func() { _ = f.Close() }
+	}
+
+	logger := slog.New(slog.NewTextHandler(out, &slog.HandlerOptions{
+		Level:     slog.LevelDebug,
+		AddSource: true,
+	}))
+	// 同时接管标准库 log 的输出，旧代码中的 log.Printf 也会进入调试日志
+	slog.SetDefault(logger)
+
+	go servePprof()
+
+	slog.Debug("调试模式已开启", "pprof", "http://"+debugPprofAddr+"/debug/pprof/", "logFile", logPath)
+	return logger, closer
+}
+
+func servePprof() {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/debug/pprof/", pprof.Index)
+	mux.HandleFunc("/debug/pprof/cmdline", pprof.Cmdline)
+	mux.HandleFunc("/debug/pprof/profile", pprof.Profile)
+	mux.HandleFunc("/debug/pprof/symbol", pprof.Symbol)
+	mux.HandleFunc("/debug/pprof/trace", pprof.Trace)
+
+	if err := http.ListenAndServe(debugPprofAddr, mux); err != nil {
+		slog.Error("pprof 服务启动失败", "err", err)
+	}
+}
+
+// trackTime 记录某个步骤的耗时，用法：defer trackTime("生成配置")()
+// 仅在调试模式下输出，正常运行时开销可以忽略
+func trackTime(step string) func() {
+	if !appFlags.Debug {
+		return func() {}
+	}
+	start := time.Now()
+	return func() {
+		slog.Debug("耗时统计", "step", step, "cost", time.Since(start))
+	}
+}
+
+func getAppLogDir() string {
+	appLogDir := filepath.Join(getAppDataDir(), "logs")
+
+	// 确保目录一定存在
+	_ = os.MkdirAll(appLogDir, 0755)
+	return appLogDir
+}
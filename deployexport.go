@@ -0,0 +1,193 @@
+package main
+
+import (
+	"archive/zip"
+	"bytes"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/BurntSushi/toml"
+	"github.com/wailsapp/wails/v3/pkg/application"
+)
+
+// 部署包导出：在桌面上调好的服务器与规则，打包成可以直接安装到服务器上的 systemd 单元或 Windows 服务
+// 包内是当前配置、服务定义与安装脚本，mole 可执行文件由用户放到同一目录 (服务器与桌面的系统、架构可能不同)
+// 服务以 --daemon --connect 无界面运行，与 --install-service 使用相同的参数
+
+const (
+	deployTargetSystemd = "systemd"
+	deployTargetWindows = "windows"
+
+	// 服务器上的安装位置
+	deployLinuxBin     = "/opt/mole/mole"
+	deployLinuxData    = "/var/lib/mole"
+	deployLinuxUser    = "mole"
+	deployWindowsBin   = `C:\Program Files\Mole\mole.exe`
+	deployWindowsData  = `C:\ProgramData\MoleApp\data`
+	deployReadmeHeader = "Mole 部署包，由 %s 于 %s 生成\n\n"
+)
+
+// deployFile 部署包中的一个文件
+type deployFile struct {
+	name string
+	data []byte
+	mode os.FileMode
+}
+
+func systemdInstallScript() string {
+	return fmt.Sprintf(`#!/bin/sh
+# 以 root 身份在服务器上运行，运行前把对应架构的 mole 可执行文件放到本目录
+set -e
+DIR=$(cd "$(dirname "$0")" && pwd)
+id %[3]s >/dev/null 2>&1 || useradd --system --home-dir %[2]s --shell /usr/sbin/nologin %[3]s
+install -D -m 0755 "$DIR/mole" %[1]s
+install -d -o %[3]s -g %[3]s %[2]s %[2]s/config
+install -m 0600 -o %[3]s -g %[3]s "$DIR/config/config.toml" %[2]s/config/config.toml
+install -m 0644 "$DIR/%[4]s.service" %[5]s
+systemctl daemon-reload
+systemctl enable --now %[4]s
+systemctl status --no-pager %[4]s
+`, deployLinuxBin, deployLinuxData, deployLinuxUser, serviceName, "/etc/systemd/system/"+serviceName+".service")
+}
+
+func windowsInstallScript() string {
+	// PowerShell 中 ` 为转义符，服务命令行中的引号用 + 拼接
+	return fmt.Sprintf(`# 在服务器上以管理员身份运行 PowerShell 执行，运行前把 mole.exe 放到本目录
+$ErrorActionPreference = "Stop"
+$dir = Split-Path -Parent $MyInvocation.MyCommand.Path
+$bin = "%[1]s"
+$data = "%[2]s"
+New-Item -ItemType Directory -Force -Path (Split-Path -Parent $bin), "$data\config" | Out-Null
+Copy-Item "$dir\mole.exe" $bin -Force
+Copy-Item "$dir\config\config.toml" "$data\config\config.toml" -Force
+$cmd = '"' + $bin + '" --daemon --connect --config-dir "' + $data + '"'
+New-Service -Name "%[3]s" -BinaryPathName $cmd -DisplayName "%[4]s" -Description "%[5]s" -StartupType Automatic | Out-Null
+# 进程异常退出时 5 秒后自动重启
+sc.exe failure "%[3]s" reset= 60 actions= restart/5000 | Out-Null
+Start-Service "%[3]s"
+Get-Service "%[3]s"
+`, deployWindowsBin, deployWindowsData, serviceName, serviceDisplayName, serviceDescription)
+}
+
+// buildDeployBundle 生成部署包 (zip)
+func buildDeployBundle(cfg *UserConfig, target string) ([]byte, error) {
+	// 服务以 --connect 启动，开机即连接，不依赖配置中的 AutoStart
+	conf, err := encodeUserConfig(cfg, configFormatTOML)
+	if err != nil {
+		return nil, fmt.Errorf("配置文件格式化失败: %v", err)
+	}
+	header := fmt.Sprintf(deployReadmeHeader, "Mole "+appVersion, time.Now().Format("2006-01-02 15:04"))
+
+	var files []deployFile
+	switch target {
+	case deployTargetSystemd:
+		files = []deployFile{
+			{serviceName + ".service", []byte(systemdUnit(deployLinuxBin, deployLinuxData, deployLinuxUser)), 0644},
+			{"install.sh", []byte(systemdInstallScript()), 0755},
+			{"README.txt", []byte(header + fmt.Sprintf(`1. 把服务器架构对应的 Linux 版 mole 可执行文件放到本目录，命名为 mole
+2. sudo ./install.sh
+3. 配置保存在 %s/config/config.toml，日志查看：journalctl -u %s -f
+`, deployLinuxData, serviceName)), 0644},
+		}
+	case deployTargetWindows:
+		files = []deployFile{
+			{"install.ps1", []byte(windowsInstallScript()), 0644},
+			{"README.txt", []byte(header + fmt.Sprintf(`1. 把服务器架构对应的 mole.exe 放到本目录
+2. 以管理员身份打开 PowerShell，执行 powershell -ExecutionPolicy Bypass -File install.ps1
+3. 配置保存在 %s\config\config.toml
+`, deployWindowsData)), 0644},
+		}
+	default:
+		return nil, fmt.Errorf("不支持的部署方式: %s", target)
+	}
+	files = append(files, deployFile{"config/config.toml", conf, 0600})
+
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for _, f := range files {
+		h := &zip.FileHeader{Name: f.name, Method: zip.Deflate, Modified: time.Now()}
+		h.SetMode(f.mode)
+		w, err := zw.CreateHeader(h)
+		if err != nil {
+			return nil, err
+		}
+		if _, err := w.Write(f.data); err != nil {
+			return nil, err
+		}
+	}
+	if err := zw.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// ExportDeployment 弹出保存对话框，把当前配置导出为 systemd 或 Windows 服务的部署包
+// 包内的配置包含 Token 等凭据；返回保存的路径，用户取消时返回空字符串
+func (s *MoleService) ExportDeployment(target string) (string, error) {
+	if err := s.checkMutable(); err != nil {
+		return "", err
+	}
+	cfg := s.status().Config
+	if cfg == nil {
+		return "", fmt.Errorf("未发现有效配置")
+	}
+	data, err := buildDeployBundle(cfg, target)
+	if err != nil {
+		return "", err
+	}
+	app := application.Get()
+	if app == nil {
+		return "", fmt.Errorf("当前模式不支持文件对话框")
+	}
+	path, err := app.Dialog.SaveFile().
+		SetFilename("mole-deploy-"+target+".zip").
+		AddFilter("ZIP", "*.zip").
+		CanCreateDirectories(true).
+		PromptForSingleSelection()
+	if err != nil || path == "" {
+		return "", err
+	}
+	if !strings.HasSuffix(strings.ToLower(path), ".zip") {
+		path += ".zip"
+	}
+	if err := os.WriteFile(path, data, 0600); err != nil {
+		return "", fmt.Errorf("写入文件失败: %v", err)
+	}
+	s.audit(auditTokenReveal, "导出部署包 "+filepath.Base(path))
+	s.countFeature("export_deploy")
+	return path, nil
+}
+
+// handleExportDeployCommand --export-deploy systemd|windows：读取本机配置，在当前目录生成部署包后退出
+func handleExportDeployCommand() bool {
+	target := appFlags.ExportDeploy
+	if target == "" {
+		return false
+	}
+	data, err := os.ReadFile(configFilePath())
+	if err != nil {
+		log.Printf("读取配置失败: %v", err)
+		return true
+	}
+	var cfg UserConfig
+	if err := toml.Unmarshal(data, &cfg); err != nil {
+		log.Printf("解析配置失败: %v", err)
+		return true
+	}
+	bundle, err := buildDeployBundle(&cfg, target)
+	if err != nil {
+		log.Printf("生成部署包失败: %v", err)
+		return true
+	}
+	out := "mole-deploy-" + target + ".zip"
+	if err := os.WriteFile(out, bundle, 0600); err != nil {
+		log.Printf("写入文件失败: %v", err)
+		return true
+	}
+	log.Printf("已生成部署包 %s (包含 Token 等凭据，请妥善保管)", out)
+	return true
+}
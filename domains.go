@@ -0,0 +1,191 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// HTTP / HTTPS / TCPMUX 规则的域名：支持精确域名和 *.example.com 形式的通配域名 (frp customDomains 原生支持)
+// frps 先查精确域名，再把最左侧一级逐次替换为 * 查通配域名，所以通配域名与其他规则的精确域名重叠时精确域名优先，
+// 保存时不算错误，但提示用户哪些请求实际会交给哪条规则
+
+const maxDomainsPerRule = 10
+
+// 规则说明的长度上限 (字符数)
+const maxRuleDescription = 500
+
+// TCPMUX 规则的多路复用方式：多个 TCP 服务共用 frps 的 tcpmuxHTTPConnectPort，按 HTTP CONNECT 请求中的域名区分，
+// 适合服务商只开放一个端口的情况；目前 frp 只支持 httpconnect 一种
+const tcpmuxHTTPConnect = "httpconnect"
+
+var reDomainLabel = regexp.MustCompile(`^[a-z0-9]([a-z0-9-]{0,61}[a-z0-9])?$`)
+
+// normalizeDomain 去掉空白与末尾的点并转为小写
+func normalizeDomain(d string) string {
+	return strings.TrimSuffix(strings.ToLower(strings.TrimSpace(d)), ".")
+}
+
+func isWildcardDomain(d string) bool {
+	return strings.HasPrefix(d, "*.")
+}
+
+// validateDomain 校验规整后的域名，通配符只能作为完整的最左一级，且其后至少还有两级 (不允许 *.com)
+func validateDomain(d string) error {
+	if d == "" {
+		return fmt.Errorf("域名为空")
+	}
+	if len(d) > 253 {
+		return fmt.Errorf("域名过长: %s", d)
+	}
+	labels := strings.Split(d, ".")
+	if labels[0] == "*" {
+		if len(labels) < 3 {
+			return fmt.Errorf("通配域名至少需要两级主域名，如 *.example.com: %s", d)
+		}
+		labels = labels[1:]
+	}
+	for _, l := range labels {
+		if !reDomainLabel.MatchString(l) {
+			if strings.Contains(l, "*") {
+				return fmt.Errorf("通配符只能写在最前面，如 *.example.com: %s", d)
+			}
+			return fmt.Errorf("域名格式无效: %s", d)
+		}
+	}
+	return nil
+}
+
+// wildcardCovers 通配域名是否匹配 d (任意层级的子域名都会匹配，与 frps 一致)
+func wildcardCovers(wildcard, d string) bool {
+	return isWildcardDomain(wildcard) && strings.HasSuffix(d, wildcard[1:])
+}
+
+// usesDomains 按域名路由的规则类型
+func usesDomains(proxyType string) bool {
+	return proxyType == "http" || proxyType == "https" || proxyType == "tcpmux"
+}
+
+// primaryDomain 用于展示访问地址的域名，优先取第一个精确域名
+func primaryDomain(p ProxyRule) string {
+	for _, d := range p.Domains {
+		if !isWildcardDomain(d) {
+			return d
+		}
+	}
+	if len(p.Domains) > 0 {
+		return p.Domains[0]
+	}
+	return ""
+}
+
+// normalizeProxyRules 规整并校验规则中的域名，返回新的切片，不修改调用方的数据
+// 必填项只检查启用的规则，停用的规则允许暂时不完整
+func normalizeProxyRules(rules []ProxyRule) ([]ProxyRule, error) {
+	out := make([]ProxyRule, len(rules))
+	for i, p := range rules {
+		p.Description = strings.TrimSpace(p.Description)
+		if utf8.RuneCountInString(p.Description) > maxRuleDescription {
+			return nil, fmt.Errorf("规则 \"%s\" 的说明最多 %d 个字符", p.Name, maxRuleDescription)
+		}
+		if err := validateRuleStyle(p); err != nil {
+			return nil, err
+		}
+		p.Plugin = strings.TrimSpace(p.Plugin)
+		p.PluginParams = normalizePluginParams(p.PluginParams)
+		if err := validatePlugin(p); err != nil {
+			return nil, err
+		}
+		if err := validateSTCP(p); err != nil {
+			return nil, err
+		}
+		var err error
+		if p, err = normalizeAccessLists(p); err != nil {
+			return nil, err
+		}
+		// 手动停用的规则不再保留限时分享的到期时间
+		if !p.Enabled {
+			p.ExpiresAt = time.Time{}
+		}
+		if usesDomains(p.ProxyType) {
+			domains := make([]string, 0, len(p.Domains))
+			seen := make(map[string]bool)
+			for _, d := range p.Domains {
+				d = normalizeDomain(d)
+				if d == "" || seen[d] {
+					continue
+				}
+				if err := validateDomain(d); err != nil {
+					return nil, fmt.Errorf("规则 \"%s\": %v", p.Name, err)
+				}
+				seen[d] = true
+				domains = append(domains, d)
+			}
+			if len(domains) > maxDomainsPerRule {
+				return nil, fmt.Errorf("规则 \"%s\" 最多填写 %d 个域名", p.Name, maxDomainsPerRule)
+			}
+			if len(domains) == 0 && p.Enabled {
+				return nil, fmt.Errorf("%s 规则 \"%s\" 必须填写域名", strings.ToUpper(p.ProxyType), p.Name)
+			}
+			p.Domains = domains
+		}
+		if p.ProxyType == "https" && p.Enabled {
+			if err := validateHTTPS(p); err != nil {
+				return nil, err
+			}
+		}
+		out[i] = p
+	}
+	if err := assignWireNames(out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// domainOverlaps 找出启用的规则之间重叠的域名
+// frps 中每种类型各有一套域名路由 (HTTP、HTTPS、TCPMUX 监听不同端口)，只比较同类型的规则
+func domainOverlaps(rules []ProxyRule) []string {
+	type owner struct {
+		domain string
+		rule   string
+		typ    string
+	}
+	var all []owner
+	for _, p := range rules {
+		if !p.Enabled || !usesDomains(p.ProxyType) {
+			continue
+		}
+		for _, d := range p.Domains {
+			all = append(all, owner{normalizeDomain(d), p.Name, p.ProxyType})
+		}
+	}
+
+	warnings := []string{}
+	for i, a := range all {
+		for _, b := range all[i+1:] {
+			if a.rule == b.rule || a.typ != b.typ {
+				continue
+			}
+			switch {
+			case a.domain == b.domain:
+				warnings = append(warnings, fmt.Sprintf("规则 \"%s\" 与 \"%s\" 使用了相同的域名 %s，frps 会拒绝后连接的规则", a.rule, b.rule, a.domain))
+			case wildcardCovers(a.domain, b.domain):
+				warnings = append(warnings, overlapMessage(a.domain, a.rule, b.domain, b.rule))
+			case wildcardCovers(b.domain, a.domain):
+				warnings = append(warnings, overlapMessage(b.domain, b.rule, a.domain, a.rule))
+			}
+		}
+	}
+	return warnings
+}
+
+func overlapMessage(wildcard, wildcardRule, specific, specificRule string) string {
+	return fmt.Sprintf("规则 \"%s\" 的 %s 包含了规则 \"%s\" 的 %s，访问 %s 时由更具体的 \"%s\" 处理", wildcardRule, wildcard, specificRule, specific, specific, specificRule)
+}
+
+// CheckDomainOverlaps 保存规则前检查域名重叠，只返回提示，不阻止保存
+func (s *MoleService) CheckDomainOverlaps(rules []ProxyRule) []string {
+	return domainOverlaps(rules)
+}
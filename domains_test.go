@@ -0,0 +1,37 @@
+package main
+
+import "testing"
+
+func TestValidateDomain(t *testing.T) {
+	tests := []struct {
+		domain string
+		ok     bool
+	}{
+		{"example.com", true},
+		{"a.b.example.com", true},
+		{"xn--fiqs8s.example.com", true},
+		{"*.example.com", true},
+		{"*.a.example.com", true},
+		{"", false},
+		{"*.com", false},
+		{"a.*.example.com", false},
+		{"*a.example.com", false},
+		{"-a.example.com", false},
+		{"a-.example.com", false},
+		{"a..example.com", false},
+		{"a_b.example.com", false},
+		{"Example.com", false}, // 调用前应先 normalizeDomain
+	}
+	for _, tt := range tests {
+		err := validateDomain(tt.domain)
+		if (err == nil) != tt.ok {
+			t.Errorf("validateDomain(%q) = %v，期望通过: %v", tt.domain, err, tt.ok)
+		}
+	}
+}
+
+func TestNormalizeDomain(t *testing.T) {
+	if got := normalizeDomain("  WWW.Example.COM. "); got != "www.example.com" {
+		t.Errorf("normalizeDomain = %q", got)
+	}
+}
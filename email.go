@@ -0,0 +1,249 @@
+package main
+
+import (
+	"context"
+	"crypto/tls"
+	"encoding/base64"
+	"fmt"
+	"mime"
+	"net"
+	"net/smtp"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// 邮件告警：隧道断开超过设定时长才发信，恢复后再发一封，短暂抖动不打扰
+// 订阅 tunnel-alert 事件；两封断线邮件之间至少间隔 MinIntervalMinutes，避免反复断线时邮件轰炸
+
+const (
+	emailDefaultDownAfter   = 5  // 分钟
+	emailDefaultMinInterval = 30 // 分钟
+	emailSendTimeout        = 20 * time.Second
+)
+
+// EmailConfig SMTP 邮件告警设置
+type EmailConfig struct {
+	Enabled  bool     `toml:"enabled" json:"enabled"`
+	Host     string   `toml:"host" json:"host"`
+	Port     int      `toml:"port" json:"port"` // 465 使用 SSL，其余端口在服务器支持时自动 STARTTLS
+	Username string   `toml:"username,omitempty" json:"username"`
+	Password string   `toml:"password,omitempty" json:"password"`
+	From     string   `toml:"from,omitempty" json:"from"` // 为空时使用 Username
+	To       []string `toml:"to" json:"to"`
+
+	DownAfterMinutes   int `toml:"down_after_minutes,omitempty" json:"downAfterMinutes"`     // 断开多久后发信，默认 5
+	MinIntervalMinutes int `toml:"min_interval_minutes,omitempty" json:"minIntervalMinutes"` // 两封断线邮件的最短间隔，默认 30
+}
+
+func (c EmailConfig) downAfter() time.Duration {
+	if c.DownAfterMinutes <= 0 {
+		return emailDefaultDownAfter * time.Minute
+	}
+	return time.Duration(c.DownAfterMinutes) * time.Minute
+}
+
+func (c EmailConfig) minInterval() time.Duration {
+	if c.MinIntervalMinutes <= 0 {
+		return emailDefaultMinInterval * time.Minute
+	}
+	return time.Duration(c.MinIntervalMinutes) * time.Minute
+}
+
+// sendMail 发送纯文本邮件
+func sendMail(ctx context.Context, c EmailConfig, subject, body string) error {
+	if c.Host == "" || len(c.To) == 0 {
+		return fmt.Errorf("请填写 SMTP 服务器和收件人")
+	}
+	port := c.Port
+	if port == 0 {
+		port = 587
+	}
+	from := c.From
+	if from == "" {
+		from = c.Username
+	}
+	addr := net.JoinHostPort(c.Host, strconv.Itoa(port))
+
+	dialer := &net.Dialer{}
+	var conn net.Conn
+	var err error
+	if port == 465 {
+		conn, err = (&tls.Dialer{NetDialer: dialer, Config: &tls.Config{ServerName: c.Host}}).DialContext(ctx, "tcp", addr)
+	} else {
+		conn, err = dialer.DialContext(ctx, "tcp", addr)
+	}
+	if err != nil {
+		return fmt.Errorf("连接 SMTP 服务器失败: %v", err)
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		_ = conn.SetDeadline(deadline)
+	}
+
+	client, err := smtp.NewClient(conn, c.Host)
+	if err != nil {
+		conn.Close()
+		return fmt.Errorf("连接 SMTP 服务器失败: %v", err)
+	}
+	defer client.Close()
+
+	if ok, _ := client.Extension("STARTTLS"); ok && port != 465 {
+		if err := client.StartTLS(&tls.Config{ServerName: c.Host}); err != nil {
+			return fmt.Errorf("STARTTLS 失败: %v", err)
+		}
+	}
+	if c.Username != "" {
+		if err := client.Auth(smtp.PlainAuth("", c.Username, c.Password, c.Host)); err != nil {
+			return fmt.Errorf("SMTP 认证失败: %v", err)
+		}
+	}
+	if err := client.Mail(from); err != nil {
+		return fmt.Errorf("发件人被拒绝: %v", err)
+	}
+	for _, to := range c.To {
+		if err := client.Rcpt(to); err != nil {
+			return fmt.Errorf("收件人 %s 被拒绝: %v", to, err)
+		}
+	}
+	w, err := client.Data()
+	if err != nil {
+		return err
+	}
+	msg := "From: " + from + "\r\n" +
+		"To: " + strings.Join(c.To, ", ") + "\r\n" +
+		"Subject: " + mime.BEncoding.Encode("UTF-8", subject) + "\r\n" +
+		"Date: " + time.Now().Format(time.RFC1123Z) + "\r\n" +
+		"MIME-Version: 1.0\r\n" +
+		"Content-Type: text/plain; charset=UTF-8\r\n" +
+		"Content-Transfer-Encoding: base64\r\n\r\n" +
+		wrapBase64(body)
+	if _, err := w.Write([]byte(msg)); err != nil {
+		return err
+	}
+	if err := w.Close(); err != nil {
+		return err
+	}
+	return client.Quit()
+}
+
+// wrapBase64 正文按 RFC 2045 每行 76 个字符编码
+func wrapBase64(text string) string {
+	enc := base64.StdEncoding.EncodeToString([]byte(text))
+	var b strings.Builder
+	for len(enc) > 76 {
+		b.WriteString(enc[:76] + "\r\n")
+		enc = enc[76:]
+	}
+	b.WriteString(enc)
+	return b.String()
+}
+
+// TestEmail 发送一封测试邮件，用于保存前确认 SMTP 设置
+func (s *MoleService) TestEmail(c EmailConfig) error {
+	ctx, cancel := context.WithTimeout(context.Background(), emailSendTimeout)
+	defer cancel()
+	host, _ := os.Hostname()
+	if err := sendMail(ctx, c, "[Mole] 测试邮件", "这是来自 "+host+" 的测试邮件，收到说明 SMTP 设置正确。"); err != nil {
+		return fmt.Errorf("发送测试邮件失败: %v", err)
+	}
+	return nil
+}
+
+// outageMailer 一次断线周期的邮件状态，只在 runEmailAlerts 协程中使用
+type outageMailer struct {
+	downSince  time.Time
+	downMsg    string
+	timer      *time.Timer
+	mailed     bool      // 本次断线已发出断线邮件，恢复时需要补发恢复邮件
+	lastMailed time.Time // 上一封断线邮件的时间，用于限流
+}
+
+// runEmailAlerts 断线超过阈值发送邮件，恢复后再发一封
+func (s *MoleService) runEmailAlerts(ctx context.Context) {
+	events, cancel := s.bus.subscribeEvents(32)
+	defer cancel()
+
+	var m outageMailer
+	fire := make(chan struct{}, 1)
+	stopTimer := func() {
+		if m.timer != nil {
+			m.timer.Stop()
+			m.timer = nil
+		}
+	}
+	defer stopTimer()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+
+		case <-fire:
+			m.timer = nil
+			cfg := s.emailConfig()
+			if s.stopRequested.Load() {
+				// 等待期间用户主动断开了隧道，不再算作故障
+				m.downSince, m.downMsg = time.Time{}, ""
+				continue
+			}
+			if !cfg.Enabled || m.downSince.IsZero() {
+				continue
+			}
+			if !m.lastMailed.IsZero() && time.Since(m.lastMailed) < cfg.minInterval() {
+				s.emitLog("隧道仍处于断开状态，距上一封告警邮件过近，本次不再发送")
+				continue
+			}
+			m.mailed, m.lastMailed = true, time.Now()
+			body := fmt.Sprintf("隧道自 %s 起已断开 %s。\n原因：%s",
+				m.downSince.Format("2006-01-02 15:04:05"), time.Since(m.downSince).Round(time.Second), m.downMsg)
+			go s.deliverEmail(ctx, cfg, "隧道已断开", body)
+
+		case ev := <-events:
+			alert, ok := ev.data.(TunnelAlert)
+			if ev.name != "tunnel-alert" || !ok {
+				continue
+			}
+			switch alert.Kind {
+			case alertDown:
+				cfg := s.emailConfig()
+				if !cfg.Enabled || !m.downSince.IsZero() {
+					continue
+				}
+				m.downSince, m.downMsg = alert.Time, alert.Message
+				m.timer = time.AfterFunc(cfg.downAfter(), func() {
+					select {
+					case fire <- struct{}{}:
+					default:
+					}
+				})
+			case alertUp, alertReconnect:
+				stopTimer()
+				if m.mailed {
+					body := fmt.Sprintf("隧道已于 %s 恢复，共断开 %s。",
+						alert.Time.Format("2006-01-02 15:04:05"), alert.Time.Sub(m.downSince).Round(time.Second))
+					go s.deliverEmail(ctx, s.emailConfig(), "隧道已恢复", body)
+				}
+				m.downSince, m.downMsg, m.mailed = time.Time{}, "", false
+			}
+		}
+	}
+}
+
+func (s *MoleService) emailConfig() EmailConfig {
+	if cfg := s.status().Config; cfg != nil {
+		return cfg.Email
+	}
+	return EmailConfig{}
+}
+
+func (s *MoleService) deliverEmail(ctx context.Context, cfg EmailConfig, title, body string) {
+	host, _ := os.Hostname()
+	sendCtx, cancel := context.WithTimeout(ctx, emailSendTimeout)
+	defer cancel()
+	if err := sendMail(sendCtx, cfg, "[Mole · "+host+"] "+title, body); err != nil {
+		s.emitLog(fmt.Sprintf("告警邮件发送失败: %v", err))
+		return
+	}
+	s.emitLog("已发送告警邮件: " + title)
+}
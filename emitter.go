@@ -0,0 +1,106 @@
+package main
+
+import (
+	"log"
+	"sync"
+
+	"github.com/wailsapp/wails/v3/pkg/application"
+)
+
+// EventEmitter 事件发送接口，MoleService 只通过它向外推送事件，不直接依赖窗口管理器
+// GUI 模式下由 Wails 事件系统实现；无界面运行或测试时可以替换为其他实现
+type EventEmitter interface {
+	Emit(name string, data any)
+}
+
+// wailsEmitter 把事件转发给 Wails 前端
+// app 在 application.New 之后才赋值，之前发出的事件会被丢弃
+type wailsEmitter struct {
+	app *application.App
+}
+
+func (e *wailsEmitter) Emit(name string, data any) {
+	if e.app == nil {
+		return
+	}
+	e.app.Event.Emit(name, data)
+}
+
+// logEmitter 无界面模式下把事件写入标准日志
+// 守护进程日志不轮转，只记录状态和生命周期事件；frp 日志、资源采样等高频事件
+// 以及可能带有配置内容的事件都不写入
+type logEmitter struct{}
+
+// loggedEvents 写入日志的事件，载荷都是简短的状态值
+var loggedEvents = map[string]bool{
+	"service-ready":  true,
+	"tunnel-state":   true,
+	"frp-status":     true,
+	"frps-status":    true,
+	"tunnel-alert":   true,
+	"config-changed": true,
+	"app-lock":       true,
+}
+
+func (logEmitter) Emit(name string, data any) {
+	if !loggedEvents[name] {
+		return
+	}
+	log.Printf("[event] %s: %v", name, data)
+}
+
+// eventBus 包装外部 Emitter，同时把事件分发给进程内的订阅者 (MQTT、消息通知等集成)
+// 订阅回调在 Emit 的调用方协程中同步执行，不能阻塞，耗时操作应转交给自己的协程
+type eventBus struct {
+	out EventEmitter
+
+	mu        sync.RWMutex
+	nextID    int
+	listeners map[int]func(name string, data any)
+}
+
+func newEventBus(out EventEmitter) *eventBus {
+	return &eventBus{out: out, listeners: make(map[int]func(name string, data any))}
+}
+
+func (b *eventBus) Emit(name string, data any) {
+	b.out.Emit(name, data)
+
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	for _, fn := range b.listeners {
+		fn(name, data)
+	}
+}
+
+// listen 注册订阅者，返回取消函数
+func (b *eventBus) listen(fn func(name string, data any)) func() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	id := b.nextID
+	b.nextID++
+	b.listeners[id] = fn
+	return func() {
+		b.mu.Lock()
+		delete(b.listeners, id)
+		b.mu.Unlock()
+	}
+}
+
+// busEvent 订阅者转交给自己协程处理的事件
+type busEvent struct {
+	name string
+	data any
+}
+
+// subscribeEvents 订阅事件并转入带缓冲的 channel，处理不过来时丢弃，不拖慢发送方
+func (b *eventBus) subscribeEvents(size int) (<-chan busEvent, func()) {
+	ch := make(chan busEvent, size)
+	cancel := b.listen(func(name string, data any) {
+		select {
+		case ch <- busEvent{name: name, data: data}:
+		default:
+		}
+	})
+	return ch, cancel
+}
@@ -0,0 +1,91 @@
+package main
+
+import (
+	"sync"
+	"testing"
+)
+
+// recordingEmitter 记录收到的事件，供测试检查
+type recordingEmitter struct {
+	mu     sync.Mutex
+	events []controlEvent
+}
+
+func (e *recordingEmitter) Emit(name string, data any) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.events = append(e.events, controlEvent{Name: name, Data: data})
+}
+
+// tunnelStates 按顺序取出 tunnel-state 事件
+func (e *recordingEmitter) tunnelStates() []TunnelStateEvent {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	var out []TunnelStateEvent
+	for _, ev := range e.events {
+		if ev.Name == "tunnel-state" {
+			out = append(out, ev.Data.(TunnelStateEvent))
+		}
+	}
+	return out
+}
+
+func TestSetTunnelStateEmitsEvents(t *testing.T) {
+	rec := &recordingEmitter{}
+	s := NewMoleService(rec)
+
+	s.setTunnelState(tunnelPreparing, "")
+	s.setTunnelState(tunnelConnecting, "")
+	s.setTunnelState(tunnelConnecting, "") // 状态未变，不发事件
+	s.setTunnelState(tunnelConnected, "")
+	s.setTunnelState(tunnelPreparing, "") // 不在转换表中，忽略
+	s.setTunnelState(tunnelReconnecting, "连接中断")
+	s.setTunnelState(tunnelStopping, "")
+	s.setTunnelState(tunnelIdle, "")
+
+	want := []struct{ state, previous, err string }{
+		{tunnelPreparing, tunnelIdle, ""},
+		{tunnelConnecting, tunnelPreparing, ""},
+		{tunnelConnected, tunnelConnecting, ""},
+		{tunnelReconnecting, tunnelConnected, "连接中断"},
+		{tunnelStopping, tunnelReconnecting, ""},
+		{tunnelIdle, tunnelStopping, ""},
+	}
+	got := rec.tunnelStates()
+	if len(got) != len(want) {
+		t.Fatalf("收到 %d 个 tunnel-state 事件，期望 %d 个: %+v", len(got), len(want), got)
+	}
+	for i, w := range want {
+		g := got[i]
+		if g.State != w.state || g.Previous != w.previous || g.Error != w.err {
+			t.Errorf("第 %d 个事件 = %s <- %s (%q)，期望 %s <- %s (%q)", i, g.State, g.Previous, g.Error, w.state, w.previous, w.err)
+		}
+		if g.Time.IsZero() {
+			t.Errorf("第 %d 个事件缺少时间", i)
+		}
+	}
+
+	var st ServiceStatus
+	s.tunnel.fill(&st)
+	if st.TunnelState != tunnelIdle || st.LastError != "连接中断" || st.ConnectedSince != nil {
+		t.Errorf("状态 = %s / %q / %v", st.TunnelState, st.LastError, st.ConnectedSince)
+	}
+}
+
+func TestEventBusForwardsToListeners(t *testing.T) {
+	rec := &recordingEmitter{}
+	bus := newEventBus(rec)
+
+	var heard []string
+	cancel := bus.listen(func(name string, data any) { heard = append(heard, name) })
+	bus.Emit("frp-status", "start")
+	cancel()
+	bus.Emit("frp-status", "stop")
+
+	if len(rec.events) != 2 {
+		t.Errorf("外部 Emitter 收到 %d 个事件，期望 2 个", len(rec.events))
+	}
+	if len(heard) != 1 || heard[0] != "frp-status" {
+		t.Errorf("取消订阅前后收到 %v", heard)
+	}
+}
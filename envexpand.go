@@ -0,0 +1,65 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+)
+
+// 环境变量展开：服务器地址、Token 与规则的本地地址中可以写 ${VAR}，生成 frpc.toml 时从环境变量读取
+// 便于脚本或批量部署时同一份配置在不同机器上取不同的值；只识别 ${VAR} 形式，Token 中单独的 $ 不受影响
+// 变量未设置时生成失败并提示变量名，避免带着空地址或空 Token 去连接
+// 配置文件中保留原文，SSH 传输、证书校验、本地探测等直接连接的地方同样使用展开后的值
+
+var reEnvRef = regexp.MustCompile(`\$\{(\w+)\}`)
+
+// expandEnvRefs 展开 ${VAR}，有变量未设置时返回错误
+func expandEnvRefs(s string) (string, error) {
+	if !strings.Contains(s, "${") {
+		return s, nil
+	}
+	var missing []string
+	out := reEnvRef.ReplaceAllStringFunc(s, func(m string) string {
+		name := reEnvRef.FindStringSubmatch(m)[1]
+		v, ok := os.LookupEnv(name)
+		if !ok {
+			missing = append(missing, name)
+		}
+		return v
+	})
+	if len(missing) > 0 {
+		return "", fmt.Errorf("环境变量 %s 未设置", strings.Join(missing, "、"))
+	}
+	return out, nil
+}
+
+// envOr 展开 ${VAR}，失败时返回原文，用于探测等不影响连接的地方
+func envOr(s string) string {
+	if out, err := expandEnvRefs(s); err == nil {
+		return out
+	}
+	return s
+}
+
+// expandConfigEnv 返回展开了环境变量的配置副本，不修改原配置
+func expandConfigEnv(cfg *UserConfig) (*UserConfig, error) {
+	out := *cfg
+	var err error
+	if out.Server.Addr, err = expandEnvRefs(cfg.Server.Addr); err != nil {
+		return nil, fmt.Errorf("服务器地址: %v", err)
+	}
+	if out.Server.Token, err = expandEnvRefs(cfg.Server.Token); err != nil {
+		return nil, fmt.Errorf("Token: %v", err)
+	}
+	out.Proxies = make([]ProxyRule, len(cfg.Proxies))
+	for i, p := range cfg.Proxies {
+		if p.Enabled {
+			if p.LocalIP, err = expandEnvRefs(p.LocalIP); err != nil {
+				return nil, fmt.Errorf("规则 \"%s\" 的本地地址: %v", p.Name, err)
+			}
+		}
+		out.Proxies[i] = p
+	}
+	return &out, nil
+}
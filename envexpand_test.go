@@ -0,0 +1,60 @@
+package main
+
+import "testing"
+
+func TestExpandEnvRefs(t *testing.T) {
+	t.Setenv("MOLE_TEST_HOST", "frps.example.com")
+	t.Setenv("MOLE_TEST_EMPTY", "")
+
+	tests := []struct {
+		in, want string
+		ok       bool
+	}{
+		{"", "", true},
+		{"frps.example.com", "frps.example.com", true},
+		{"${MOLE_TEST_HOST}", "frps.example.com", true},
+		{"tcp://${MOLE_TEST_HOST}:7000", "tcp://frps.example.com:7000", true},
+		{"a${MOLE_TEST_EMPTY}b", "ab", true},
+		{"pa$$word", "pa$$word", true},
+		{"$MOLE_TEST_HOST", "$MOLE_TEST_HOST", true}, // 只识别 ${VAR}
+		{"${MOLE_TEST_UNSET}", "", false},
+		{"${MOLE_TEST_HOST}${MOLE_TEST_UNSET}", "", false},
+	}
+	for _, tt := range tests {
+		got, err := expandEnvRefs(tt.in)
+		if (err == nil) != tt.ok {
+			t.Errorf("expandEnvRefs(%q) 错误 = %v，期望通过: %v", tt.in, err, tt.ok)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("expandEnvRefs(%q) = %q，期望 %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestExpandConfigEnv(t *testing.T) {
+	t.Setenv("MOLE_TEST_TOKEN", "secret")
+
+	cfg := &UserConfig{Proxies: []ProxyRule{
+		{Name: "on", Enabled: true, LocalIP: "${MOLE_TEST_TOKEN}"},
+		{Name: "off", Enabled: false, LocalIP: "${MOLE_TEST_UNSET}"},
+	}}
+	cfg.Server.Addr = "frps.example.com"
+	cfg.Server.Token = "${MOLE_TEST_TOKEN}"
+
+	out, err := expandConfigEnv(cfg)
+	if err != nil {
+		t.Fatalf("expandConfigEnv: %v", err)
+	}
+	if out.Server.Token != "secret" || out.Proxies[0].LocalIP != "secret" {
+		t.Errorf("展开结果 = %q / %q", out.Server.Token, out.Proxies[0].LocalIP)
+	}
+	if cfg.Server.Token != "${MOLE_TEST_TOKEN}" || cfg.Proxies[0].LocalIP != "${MOLE_TEST_TOKEN}" {
+		t.Error("不应修改原配置")
+	}
+
+	cfg.Server.Addr = "${MOLE_TEST_UNSET}"
+	if _, err := expandConfigEnv(cfg); err == nil {
+		t.Error("服务器地址中的变量未设置时应失败")
+	}
+}
@@ -0,0 +1,113 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+)
+
+// 环境报告：汇总目录、可执行文件、磁盘空间、后台服务和权限问题，供帮助页的自助排障使用
+// 只做检查，不创建缺失的目录，避免排障本身改变现场
+
+const lowDiskSpace = 100 << 20 // 可用空间低于此值时提示
+
+// PathCheck 单个目录或文件的检查结果
+type PathCheck struct {
+	Name     string `json:"name"`
+	Path     string `json:"path"`
+	Exists   bool   `json:"exists"`
+	Writable bool   `json:"writable"`
+}
+
+// EnvironmentInfo 运行环境报告
+type EnvironmentInfo struct {
+	Executable        string      `json:"executable"`
+	Paths             []PathCheck `json:"paths"`
+	DiskFree          uint64      `json:"diskFree"`          // 数据目录所在磁盘的可用空间 (字节)，未知时为 0
+	DiskTotal         uint64      `json:"diskTotal"`         // 同上，总容量
+	BackgroundService string      `json:"backgroundService"` // 后台服务状态，取值同 BackgroundServiceInfo.Status
+	AutoConnect       bool        `json:"autoConnect"`       // 软件启动时是否自动开启穿透
+	Attached          bool        `json:"attached"`          // 界面是否连接到后台服务
+	Problems          []string    `json:"problems"`
+}
+
+// GetEnvironmentInfo 收集运行环境信息并列出发现的问题
+func (s *MoleService) GetEnvironmentInfo() EnvironmentInfo {
+	dataDir := getAppDataDir()
+	info := EnvironmentInfo{Paths: []PathCheck{}, Problems: []string{}}
+	problem := func(format string, args ...any) {
+		info.Problems = append(info.Problems, fmt.Sprintf(format, args...))
+	}
+
+	exe, err := os.Executable()
+	if err != nil {
+		problem("无法确定程序路径: %v", err)
+	}
+	info.Executable = exe
+
+	dirs := []struct{ name, path string }{
+		{"数据目录", dataDir},
+		{"配置目录", filepath.Join(dataDir, "config")},
+		{"frpc 目录", filepath.Join(dataDir, "bin")},
+		{"日志目录", filepath.Join(dataDir, "logs")},
+		{"数据库目录", filepath.Dir(storePath())},
+	}
+	for _, d := range dirs {
+		c := PathCheck{Name: d.name, Path: d.path}
+		if st, err := os.Stat(d.path); err == nil && st.IsDir() {
+			c.Exists = true
+			c.Writable = dirWritable(d.path)
+			if !c.Writable {
+				problem("%s不可写: %s", d.name, d.path)
+			}
+		}
+		info.Paths = append(info.Paths, c)
+	}
+
+	frpcPath := effectiveFrpcPath(filepath.Join(dataDir, "bin"))
+	frpc := PathCheck{Name: "frpc", Path: frpcPath}
+	if st, err := os.Stat(frpcPath); err == nil {
+		frpc.Exists = true
+		frpc.Writable = st.Mode().Perm()&0200 != 0
+		if runtime.GOOS != "windows" && st.Mode().Perm()&0100 == 0 {
+			problem("frpc 没有执行权限: %s", frpcPath)
+		}
+	}
+	info.Paths = append(info.Paths, frpc)
+
+	// 数据目录尚未创建时向上查找已存在的目录来统计磁盘空间
+	for dir := dataDir; ; dir = filepath.Dir(dir) {
+		if free, total, err := diskSpace(dir); err == nil {
+			info.DiskFree, info.DiskTotal = free, total
+			if free < lowDiskSpace {
+				problem("数据目录所在磁盘剩余空间不足: %d MB", free>>20)
+			}
+			break
+		}
+		if filepath.Dir(dir) == dir {
+			break
+		}
+	}
+
+	info.BackgroundService = s.GetBackgroundServiceStatus().Status
+	info.Attached = s.remote != nil
+	if cfg := s.status().Config; cfg != nil {
+		info.AutoConnect = cfg.Server.AutoStart
+	} else {
+		problem("未发现有效配置")
+	}
+	return info
+}
+
+// dirWritable 通过创建临时文件判断目录是否可写，比检查权限位更可靠 (ACL、只读挂载等)
+func dirWritable(dir string) bool {
+	f, err := os.CreateTemp(dir, ".mole-write-check-")
+	if err != nil {
+		return false
+	}
+	name := f.Name()
+	f.Close()
+	os.Remove(name)
+	return true
+}
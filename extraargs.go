@@ -0,0 +1,60 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// frpc 额外命令行参数：Mole 没有建模的开关 (如 --strict_config) 可以直接追加到启动命令后面
+// 参数不经过 shell，但仍要拦下会改变 frpc 行为本身的写法：不能替换配置文件、不能改成执行子命令
+
+const (
+	maxExtraArgs   = 16
+	maxExtraArgLen = 200
+)
+
+// 由 Mole 管理或会让 frpc 不再运行隧道的参数
+var forbiddenExtraArgs = map[string]bool{
+	"c": true, "config": true, "config_dir": true,
+	"h": true, "help": true, "v": true, "version": true,
+}
+
+// normalizeExtraArgs 去掉空白参数；每个参数都必须是 -flag 或 --flag=value 形式，值与参数名写在一起，
+// 不接受单独的位置参数，避免 verify、reload 之类的子命令混进来
+func normalizeExtraArgs(args []string) ([]string, error) {
+	out := make([]string, 0, len(args))
+	for _, a := range args {
+		a = strings.TrimSpace(a)
+		if a == "" {
+			continue
+		}
+		if len(a) > maxExtraArgLen || strings.ContainsAny(a, "\x00\r\n") {
+			return nil, fmt.Errorf("frpc 参数无效: %.40s", a)
+		}
+		if !strings.HasPrefix(a, "-") {
+			return nil, fmt.Errorf("frpc 参数必须以 - 开头，参数值请写成 --name=value: %s", a)
+		}
+		name, _, _ := strings.Cut(strings.TrimLeft(a, "-"), "=")
+		if name == "" {
+			return nil, fmt.Errorf("frpc 参数无效: %s", a)
+		}
+		// 单横线参数按短参数解析，-cfoo、-c=foo 都等同于 -c foo，只看第一个字母
+		short := !strings.HasPrefix(a, "--")
+		if forbiddenExtraArgs[name] || short && forbiddenExtraArgs[name[:1]] {
+			return nil, fmt.Errorf("frpc 参数 %s 由 Mole 管理，不能自定义", a)
+		}
+		out = append(out, a)
+	}
+	if len(out) > maxExtraArgs {
+		return nil, fmt.Errorf("最多填写 %d 个 frpc 参数", maxExtraArgs)
+	}
+	if len(out) == 0 {
+		return nil, nil
+	}
+	return out, nil
+}
+
+// frpcArgs 启动 frpc 的完整参数
+func frpcArgs(tomlPath string, extra []string) []string {
+	return append([]string{"-c", tomlPath}, extra...)
+}
@@ -0,0 +1,62 @@
+package main
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestNormalizeExtraArgs(t *testing.T) {
+	tests := []struct {
+		args []string
+		want []string
+		ok   bool
+	}{
+		{nil, nil, true},
+		{[]string{" ", ""}, nil, true},
+		{[]string{" --strict_config "}, []string{"--strict_config"}, true},
+		{[]string{"--log_level=debug", "-x"}, []string{"--log_level=debug", "-x"}, true},
+		{[]string{"verify"}, nil, false},
+		{[]string{"--log_level", "debug"}, nil, false},
+		{[]string{"--"}, nil, false},
+		{[]string{"-=x"}, nil, false},
+		{[]string{"--config=/tmp/a.toml"}, nil, false},
+		{[]string{"--config_dir=/tmp"}, nil, false},
+		{[]string{"--help"}, nil, false},
+		{[]string{"-c"}, nil, false},
+		{[]string{"-c=/tmp/a.toml"}, nil, false},
+		{[]string{"-c/tmp/a.toml"}, nil, false},
+		{[]string{"-config"}, nil, false},
+		{[]string{"-hv"}, nil, false},
+		{[]string{"-v"}, nil, false},
+		{[]string{"--a\nb"}, nil, false},
+		{[]string{"--" + strings.Repeat("a", maxExtraArgLen)}, nil, false},
+		{tooManyExtraArgs(), nil, false},
+	}
+	for _, tt := range tests {
+		got, err := normalizeExtraArgs(tt.args)
+		if (err == nil) != tt.ok {
+			t.Errorf("normalizeExtraArgs(%q) 错误 = %v，期望通过: %v", tt.args, err, tt.ok)
+			continue
+		}
+		if tt.ok && !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("normalizeExtraArgs(%q) = %q，期望 %q", tt.args, got, tt.want)
+		}
+	}
+}
+
+func tooManyExtraArgs() []string {
+	args := make([]string, maxExtraArgs+1)
+	for i := range args {
+		args[i] = "--strict_config"
+	}
+	return args
+}
+
+func TestFrpcArgs(t *testing.T) {
+	got := frpcArgs("/tmp/frpc.toml", []string{"--strict_config"})
+	want := []string{"-c", "/tmp/frpc.toml", "--strict_config"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("frpcArgs = %q", got)
+	}
+}
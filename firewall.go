@@ -0,0 +1,119 @@
+package main
+
+import (
+	"fmt"
+	"net"
+	"strings"
+)
+
+// FirewallSuggestion 需要在本机防火墙放行的代理规则
+type FirewallSuggestion struct {
+	RuleID   string `json:"ruleID"`
+	Name     string `json:"name"`
+	Protocol string `json:"protocol"` // TCP / UDP
+	Port     int    `json:"port"`
+	Exists   bool   `json:"exists"` // 是否已创建过对应的放行规则
+}
+
+// GetFirewallSuggestions 列出目标是本机非回环地址的规则
+// 这类规则的流量从网卡进入，可能被系统防火墙拦截，导致隧道通了但访问不到
+func (s *MoleService) GetFirewallSuggestions() []FirewallSuggestion {
+	cfg := s.status().Config
+	if cfg == nil {
+		return nil
+	}
+
+	var list []FirewallSuggestion
+	for _, p := range cfg.Proxies {
+		if !p.Enabled || !isLocalNonLoopback(envOr(p.LocalIP)) {
+			continue
+		}
+		list = append(list, FirewallSuggestion{
+			RuleID:   p.ID,
+			Name:     p.Name,
+			Protocol: firewallProtocol(p),
+			Port:     p.LocalPort,
+			Exists:   firewallRuleExists(firewallRuleName(p.ID)),
+		})
+	}
+	return list
+}
+
+// AddFirewallRule 为指定代理规则的本地端口创建入站放行规则 (会弹出 UAC 提权)
+func (s *MoleService) AddFirewallRule(ruleID string) error {
+	if err := s.checkMutable(); err != nil {
+		return err
+	}
+	s.countFeature("firewall_rule")
+	p, ok := s.findProxy(ruleID)
+	if !ok {
+		return fmt.Errorf("未找到规则: %s", ruleID)
+	}
+	if p.LocalPort <= 0 {
+		return fmt.Errorf("规则 %s 本地端口无效", p.Name)
+	}
+	return addFirewallRule(firewallRuleName(p.ID), firewallProtocol(p), p.LocalPort)
+}
+
+// RemoveFirewallRule 删除之前为该规则创建的放行规则
+func (s *MoleService) RemoveFirewallRule(ruleID string) error {
+	if err := s.checkMutable(); err != nil {
+		return err
+	}
+
+	return removeFirewallRule(firewallRuleName(ruleID))
+}
+
+// findProxy 按 ID 查找代理规则，客户端模式下读取守护进程的配置
+func (s *MoleService) findProxy(id string) (ProxyRule, bool) {
+	cfg := s.status().Config
+	if cfg == nil {
+		return ProxyRule{}, false
+	}
+	for _, p := range cfg.Proxies {
+		if p.ID == id {
+			return p, true
+		}
+	}
+	return ProxyRule{}, false
+}
+
+// firewallRuleName 规则名会拼进 netsh 命令行，只保留字母数字和连字符
+func firewallRuleName(ruleID string) string {
+	safe := strings.Map(func(r rune) rune {
+		if r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '-' || r == '_' {
+			return r
+		}
+		return -1
+	}, ruleID)
+	return "mole-" + safe
+}
+
+func firewallProtocol(p ProxyRule) string {
+	if p.ProxyType == "udp" {
+		return "UDP"
+	}
+	return "TCP"
+}
+
+// isLocalNonLoopback 判断地址是否为本机网卡上的非回环地址 (含 0.0.0.0)
+func isLocalNonLoopback(host string) bool {
+	ip := net.ParseIP(strings.TrimSpace(host))
+	if ip == nil || ip.IsLoopback() {
+		return false
+	}
+	if ip.IsUnspecified() {
+		return true
+	}
+
+	addrs, err := net.InterfaceAddrs()
+	if err != nil {
+		return false
+	}
+	for _, addr := range addrs {
+		if ipNet, ok := addr.(*net.IPNet); ok && ipNet.IP.Equal(ip) {
+			return true
+		}
+	}
+	return false
+}
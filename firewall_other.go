@@ -0,0 +1,19 @@
+//go:build !windows
+
+package main
+
+import "fmt"
+
+// 目前只处理 Windows 防火墙，其他平台的防火墙 (ufw/firewalld/pf) 差异较大，交给用户自行配置
+
+func addFirewallRule(name, protocol string, port int) error {
+	return fmt.Errorf("仅支持 Windows 防火墙")
+}
+
+func removeFirewallRule(name string) error {
+	return fmt.Errorf("仅支持 Windows 防火墙")
+}
+
+func firewallRuleExists(name string) bool {
+	return false
+}
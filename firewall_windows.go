@@ -0,0 +1,55 @@
+//go:build windows
+
+package main
+
+import (
+	"fmt"
+	"os/exec"
+	"strings"
+	"syscall"
+)
+
+// addFirewallRule 通过 netsh 创建入站放行规则
+// netsh 需要管理员权限，这里用 PowerShell Start-Process -Verb RunAs 触发 UAC 提权
+func addFirewallRule(name, protocol string, port int) error {
+	if firewallRuleExists(name) {
+		return nil
+	}
+	args := fmt.Sprintf("advfirewall firewall add rule name=%s dir=in action=allow protocol=%s localport=%d", name, protocol, port)
+	if err := runElevatedNetsh(args); err != nil {
+		return err
+	}
+	if !firewallRuleExists(name) {
+		return fmt.Errorf("防火墙规则创建失败，可能取消了管理员授权")
+	}
+	return nil
+}
+
+func removeFirewallRule(name string) error {
+	if !firewallRuleExists(name) {
+		return nil
+	}
+	return runElevatedNetsh("advfirewall firewall delete rule name=" + name)
+}
+
+// firewallRuleExists 查询规则是否存在，查询操作不需要提权
+func firewallRuleExists(name string) bool {
+	cmd := exec.Command("netsh", "advfirewall", "firewall", "show", "rule", "name="+name)
+	cmd.SysProcAttr = &syscall.SysProcAttr{}
+	setHideWindow(cmd.SysProcAttr)
+
+	out, err := cmd.Output()
+	return err == nil && strings.Contains(string(out), name)
+}
+
+func runElevatedNetsh(args string) error {
+	script := fmt.Sprintf("Start-Process -FilePath netsh -ArgumentList '%s' -Verb RunAs -Wait -WindowStyle Hidden", args)
+	cmd := exec.Command("powershell", "-NoProfile", "-NonInteractive", "-Command", script)
+	cmd.SysProcAttr = &syscall.SysProcAttr{}
+	setHideWindow(cmd.SysProcAttr)
+
+	if out, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("执行 netsh 失败: %v %s", err, strings.TrimSpace(string(out)))
+	}
+	return nil
+}
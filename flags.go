@@ -0,0 +1,94 @@
+package main
+
+import (
+	"flag"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// AppFlags 启动参数
+type AppFlags struct {
+	Debug bool // 调试模式：开启 pprof、详细日志写文件、耗时统计
+
+	// --- 后台服务 ---
+	Daemon           bool   // 以无界面守护进程方式运行隧道引擎
+	Connect          bool   // 守护进程启动后立即连接 (系统服务使用)
+	Standalone       bool   // GUI 不使用守护进程，在本进程内管理 frpc (开发调试用)
+	ConfigDir        string // 覆盖应用数据目录，后台服务以其他账户运行时指向用户目录
+	InstallService   bool   // 安装为 Windows 服务 / systemd 单元后退出
+	UninstallService bool   // 卸载后台服务后退出
+	ExportDeploy     string // 把当前配置导出为 systemd / windows 服务部署包后退出，见 deployexport.go
+
+	// --- 全机安装 ---
+	InstallSharedFrpc   bool // 把内置 frpc 写到所有用户共用的程序数据目录后退出 (需要管理员权限)，见 sharedfrpc.go
+	UninstallSharedFrpc bool // 删除共享的 frpc 后退出
+
+	// --- 登录时启动 ---
+	Autostart bool // 由系统登录自启动项启动，不显示主窗口，见 loginitem.go
+
+	// --- 受管部署 ---
+	Kiosk bool // 只读模式：配置只能查看，只允许连接 / 断开
+
+	// --- 配置转换 ---
+	ConvertIni string // 把 frpc.ini 转换为 frpc.toml 后退出
+
+	// --- 模拟模式 (开发与演示) ---
+	Simulate bool   // 用内置的假 frpc 代替真实进程，不需要 frps 服务器与网络，见 simulate.go
+	FakeFrpc string // 内部使用：以假 frpc 运行，参数为 frpc.toml 路径
+
+	// --- 文件关联 ---
+	OpenFiles []string // 双击 .moleprofile 等文件启动时系统传入的文件路径
+}
+
+var appFlags = &AppFlags{}
+
+// parseFlags 解析命令行参数
+// 使用 ContinueOnError，避免 macOS 从 Finder 启动时附带的 -psn_xxx 等未知参数导致程序直接退出
+func parseFlags() {
+	fs := flag.NewFlagSet("mole", flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+
+	fs.BoolVar(&appFlags.Debug, "debug", false, "开启调试模式")
+	fs.BoolVar(&appFlags.Daemon, "daemon", false, "以无界面守护进程方式运行")
+	fs.BoolVar(&appFlags.Connect, "connect", false, "守护进程启动后立即连接")
+	fs.BoolVar(&appFlags.Standalone, "standalone", false, "不使用后台服务，在界面进程内运行隧道")
+	fs.StringVar(&appFlags.ConfigDir, "config-dir", "", "应用数据目录")
+	fs.BoolVar(&appFlags.InstallService, "install-service", false, "安装后台服务")
+	fs.BoolVar(&appFlags.UninstallService, "uninstall-service", false, "卸载后台服务")
+	fs.StringVar(&appFlags.ExportDeploy, "export-deploy", "", "导出服务器部署包 (systemd 或 windows)")
+	fs.BoolVar(&appFlags.InstallSharedFrpc, "install-shared-frpc", false, "为本机所有用户安装共享的 frpc")
+	fs.BoolVar(&appFlags.UninstallSharedFrpc, "uninstall-shared-frpc", false, "删除共享的 frpc")
+	fs.BoolVar(&appFlags.Autostart, "autostart", false, "由系统登录时启动，只在托盘中运行")
+	fs.BoolVar(&appFlags.Kiosk, "kiosk", false, "只读模式，只允许连接和断开")
+	fs.StringVar(&appFlags.ConvertIni, "convert-ini", "", "把 frpc.ini 转换为 frpc.toml")
+	fs.BoolVar(&appFlags.Simulate, "simulate", false, "模拟模式，使用内置的假 frpc")
+	fs.StringVar(&appFlags.FakeFrpc, "fake-frpc", "", "内部使用：以假 frpc 运行")
+
+	if err := fs.Parse(os.Args[1:]); err != nil {
+		log.Printf("解析启动参数失败: %v", err)
+	}
+	appFlags.OpenFiles = importableFiles(fs.Args())
+	// 模拟模式不能交给守护进程，否则运行的仍是真实 frpc
+	if appFlags.Simulate {
+		appFlags.Standalone = true
+	}
+}
+
+// importableFiles 从命令行参数中挑出可以导入的配置文件
+func importableFiles(args []string) []string {
+	var files []string
+	for _, arg := range args {
+		switch strings.ToLower(filepath.Ext(arg)) {
+		case ".moleprofile", ".toml", ".ini", ".yaml", ".yml", ".json":
+		default:
+			continue
+		}
+		if info, err := os.Stat(arg); err == nil && !info.IsDir() {
+			files = append(files, arg)
+		}
+	}
+	return files
+}
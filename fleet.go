@@ -0,0 +1,368 @@
+package main
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"crypto/tls"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// 设备管理：帮家人照看隧道时，把其他电脑上的 Mole 加入列表，集中查看状态并远程连接 / 断开
+// 被管理的一方生成访问令牌后，控制接口对该令牌只开放 /api/remote/ 下的状态与连接操作，返回内容不含配置与凭据
+// 控制接口只监听本机，可以添加一条指向控制接口地址 (默认 127.0.0.1:17600，见 control.addr) 的规则，通过隧道本身访问
+
+const (
+	fleetRemotePrefix = "/api/remote/"
+	fleetTimeout      = 5 * time.Second
+	maxFleetMembers   = 20
+)
+
+// FleetConfig 设备管理配置
+type FleetConfig struct {
+	AccessToken string        `toml:"access_token,omitempty" json:"accessToken"` // 允许其他 Mole 远程管理本机的访问令牌，空为不允许
+	Members     []FleetMember `toml:"members,omitempty" json:"members"`          // 本机管理的其他设备
+
+	// 局域网接口：以 HTTPS 在所有网卡上提供 /api/remote/，见 lanapi.go
+	LANEnabled bool `toml:"lan_enabled,omitempty" json:"lanEnabled"`
+	LANPort    int  `toml:"lan_port,omitempty" json:"lanPort"` // 0 为默认的 17601
+}
+
+// FleetMember 一台被管理的设备
+type FleetMember struct {
+	Name  string `toml:"name" json:"name"`
+	URL   string `toml:"url" json:"url"`     // 对方控制接口的地址，如 http://frps.example.com:17600
+	Token string `toml:"token" json:"token"` // 对方生成的访问令牌
+
+	// 对方局域网接口的证书指纹 (SHA-256)，https 地址使用自签名证书时按指纹校验
+	Fingerprint string `toml:"fingerprint,omitempty" json:"fingerprint"`
+}
+
+// RemoteNodeStatus /api/remote/status 的返回
+type RemoteNodeStatus struct {
+	Hostname       string       `json:"hostname"`
+	AppVersion     string       `json:"appVersion"`
+	IsRunning      bool         `json:"isRunning"`
+	TunnelState    string       `json:"tunnelState"`
+	LastError      string       `json:"lastError"`
+	ConnectedSince *time.Time   `json:"connectedSince"`
+	Server         string       `json:"server"` // 服务器别名，未设置时为地址
+	Proxies        []ProxyState `json:"proxies"`
+}
+
+// FleetMemberStatus 设备列表中的一项
+type FleetMemberStatus struct {
+	Name   string            `json:"name"`
+	URL    string            `json:"url"`
+	Online bool              `json:"online"`
+	Error  string            `json:"error,omitempty"`
+	Status *RemoteNodeStatus `json:"status,omitempty"`
+}
+
+func validateFleet(f FleetConfig) error {
+	if len(f.Members) > maxFleetMembers {
+		return fmt.Errorf("最多管理 %d 台设备", maxFleetMembers)
+	}
+	seen := make(map[string]bool, len(f.Members))
+	for _, m := range f.Members {
+		if m.Name == "" {
+			return fmt.Errorf("设备名称不能为空")
+		}
+		if seen[m.Name] {
+			return fmt.Errorf("设备名称重复: %s", m.Name)
+		}
+		seen[m.Name] = true
+		u, err := url.Parse(m.URL)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return fmt.Errorf("设备 %s 的地址无效: %s", m.Name, m.URL)
+		}
+		if m.Token == "" {
+			return fmt.Errorf("设备 %s 缺少访问令牌", m.Name)
+		}
+		if m.Fingerprint != "" {
+			if _, err := normalizeFingerprint(m.Fingerprint); err != nil {
+				return fmt.Errorf("设备 %s 的%v", m.Name, err)
+			}
+		}
+	}
+	return validateLANAPI(f)
+}
+
+// =====================被管理端 (控制接口) ===============================
+
+// fleetAccessToken 当前的访问令牌，每次请求时读取，生成或清除后立即生效
+func (s *MoleService) fleetAccessToken() string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if s.config == nil {
+		return ""
+	}
+	return s.config.Fleet.AccessToken
+}
+
+// remoteAuthorized 使用访问令牌的请求只能访问 /api/remote/ 下的接口
+func remoteAuthorized(r *http.Request, accessToken string) bool {
+	if accessToken == "" || !strings.HasPrefix(r.URL.Path, fleetRemotePrefix) {
+		return false
+	}
+	got := []byte(r.Header.Get("Authorization"))
+	return subtle.ConstantTimeCompare(got, []byte("Bearer "+accessToken)) == 1
+}
+
+func (s *MoleService) remoteNodeStatus() RemoteNodeStatus {
+	st := s.status()
+	host, _ := os.Hostname()
+	node := RemoteNodeStatus{
+		Hostname:       host,
+		AppVersion:     appVersion,
+		IsRunning:      st.IsRunning,
+		TunnelState:    st.TunnelState,
+		LastError:      st.LastError,
+		ConnectedSince: st.ConnectedSince,
+		Proxies:        s.proxyStates.list(),
+	}
+	if cfg := st.Config; cfg != nil {
+		node.Server = cfg.Server.Remark
+		if node.Server == "" {
+			node.Server = cfg.Server.Addr
+		}
+	}
+	sort.Slice(node.Proxies, func(i, j int) bool { return node.Proxies[i].Name < node.Proxies[j].Name })
+	return node
+}
+
+// registerRemoteRoutes 供其他设备调用的接口
+func registerRemoteRoutes(mux *http.ServeMux, ms *MoleService) {
+	mux.HandleFunc("GET "+fleetRemotePrefix+"status", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, ms.remoteNodeStatus())
+	})
+	mux.HandleFunc("POST "+fleetRemotePrefix+"connect", func(w http.ResponseWriter, r *http.Request) {
+		ms.emitLog("远程管理：来自 " + r.RemoteAddr + " 的连接请求")
+		if st := ms.Connect(); st.Locked {
+			http.Error(w, st.Message, http.StatusLocked)
+			return
+		}
+		writeJSON(w, ms.remoteNodeStatus())
+	})
+	mux.HandleFunc("POST "+fleetRemotePrefix+"disconnect", func(w http.ResponseWriter, r *http.Request) {
+		ms.emitLog("远程管理：来自 " + r.RemoteAddr + " 的断开请求")
+		if st := ms.Disconnect(); st.Locked {
+			http.Error(w, st.Message, http.StatusLocked)
+			return
+		}
+		writeJSON(w, ms.remoteNodeStatus())
+	})
+}
+
+// GenerateFleetAccessToken 生成新的访问令牌并返回，旧令牌立即失效
+func (s *MoleService) GenerateFleetAccessToken() (string, error) {
+	if err := s.checkMutable(); err != nil {
+		return "", err
+	}
+	cfg := s.status().Config
+	if cfg == nil {
+		return "", fmt.Errorf("未发现有效配置")
+	}
+	buf := make([]byte, 24)
+	if _, err := rand.Read(buf); err != nil {
+		return "", fmt.Errorf("生成访问令牌失败: %v", err)
+	}
+	newCfg := *cfg
+	newCfg.Fleet.AccessToken = hex.EncodeToString(buf)
+	if err := s.saveUserConfig(newCfg); err != nil {
+		return "", err
+	}
+	s.audit(auditTokenReveal, "生成远程管理访问令牌")
+	return newCfg.Fleet.AccessToken, nil
+}
+
+// ClearFleetAccessToken 清除访问令牌，不再允许其他设备管理本机，局域网接口随之关闭
+func (s *MoleService) ClearFleetAccessToken() error {
+	cfg := s.status().Config
+	if cfg == nil {
+		return fmt.Errorf("未发现有效配置")
+	}
+	newCfg := *cfg
+	newCfg.Fleet.AccessToken = ""
+	newCfg.Fleet.LANEnabled = false
+	return s.saveUserConfig(newCfg)
+}
+
+// =====================管理端 ===============================
+
+// fleetClient 填写了证书指纹时不走系统证书链，只比对对方证书的指纹
+func fleetClient(m FleetMember) *http.Client {
+	client := &http.Client{Timeout: fleetTimeout}
+	want, err := normalizeFingerprint(m.Fingerprint)
+	if m.Fingerprint == "" || err != nil {
+		return client
+	}
+	client.Transport = &http.Transport{TLSClientConfig: &tls.Config{
+		InsecureSkipVerify: true,
+		VerifyConnection: func(cs tls.ConnectionState) error {
+			if len(cs.PeerCertificates) == 0 {
+				return fmt.Errorf("对方未提供证书")
+			}
+			sum := sha256.Sum256(cs.PeerCertificates[0].Raw)
+			if hex.EncodeToString(sum[:]) != want {
+				return fmt.Errorf("证书指纹不一致，可能遭到中间人攻击 (当前 %s)", formatFingerprint(sum[:]))
+			}
+			return nil
+		},
+	}}
+	return client
+}
+
+func fleetCall(m FleetMember, method, path string) (*RemoteNodeStatus, error) {
+	req, err := http.NewRequest(method, strings.TrimRight(m.URL, "/")+fleetRemotePrefix+path, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Authorization", "Bearer "+m.Token)
+	resp, err := fleetClient(m).Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("无法连接: %v", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusUnauthorized {
+		return nil, fmt.Errorf("访问令牌无效或对方已关闭远程管理")
+	}
+	if resp.StatusCode == http.StatusLocked {
+		return nil, fmt.Errorf("对方已锁定，请在对方设备上解锁后重试")
+	}
+	if resp.StatusCode != http.StatusOK {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return nil, fmt.Errorf("对方返回错误: %s %s", resp.Status, strings.TrimSpace(string(msg)))
+	}
+	var st RemoteNodeStatus
+	if err := json.NewDecoder(resp.Body).Decode(&st); err != nil {
+		return nil, fmt.Errorf("解析返回内容失败: %v", err)
+	}
+	return &st, nil
+}
+
+func memberStatus(m FleetMember, st *RemoteNodeStatus, err error) FleetMemberStatus {
+	ms := FleetMemberStatus{Name: m.Name, URL: m.URL, Status: st, Online: err == nil}
+	if err != nil {
+		ms.Error = err.Error()
+	}
+	return ms
+}
+
+func (s *MoleService) fleetMember(name string) (FleetMember, error) {
+	if cfg := s.status().Config; cfg != nil {
+		for _, m := range cfg.Fleet.Members {
+			if m.Name == name {
+				return m, nil
+			}
+		}
+	}
+	return FleetMember{}, fmt.Errorf("设备不存在: %s", name)
+}
+
+// GetFleetStatus 并发查询所有设备的状态，按列表顺序返回
+func (s *MoleService) GetFleetStatus() []FleetMemberStatus {
+	cfg := s.status().Config
+	if cfg == nil {
+		return nil
+	}
+	members := cfg.Fleet.Members
+	list := make([]FleetMemberStatus, len(members))
+	var wg sync.WaitGroup
+	for i, m := range members {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			st, err := fleetCall(m, http.MethodGet, "status")
+			list[i] = memberStatus(m, st, err)
+		}()
+	}
+	wg.Wait()
+	return list
+}
+
+// AddFleetMember 添加设备，添加前先验证地址与令牌可用
+func (s *MoleService) AddFleetMember(m FleetMember) (FleetMemberStatus, error) {
+	// 只读或锁定时连探测请求也不发出
+	if err := s.checkMutable(); err != nil {
+		return FleetMemberStatus{}, err
+	}
+	cfg := s.status().Config
+	if cfg == nil {
+		return FleetMemberStatus{}, fmt.Errorf("未发现有效配置")
+	}
+	m.Name = strings.TrimSpace(m.Name)
+	m.URL = strings.TrimRight(strings.TrimSpace(m.URL), "/")
+	m.Token = strings.TrimSpace(m.Token)
+	m.Fingerprint = strings.TrimSpace(m.Fingerprint)
+	newCfg := *cfg
+	newCfg.Fleet.Members = append(append([]FleetMember(nil), cfg.Fleet.Members...), m)
+	if err := validateFleet(newCfg.Fleet); err != nil {
+		return FleetMemberStatus{}, err
+	}
+	st, err := fleetCall(m, http.MethodGet, "status")
+	if err != nil {
+		return FleetMemberStatus{}, err
+	}
+	if err := s.saveUserConfig(newCfg); err != nil {
+		return FleetMemberStatus{}, err
+	}
+	s.countFeature("fleet_add")
+	return memberStatus(m, st, nil), nil
+}
+
+// RemoveFleetMember 从列表中移除设备，不影响对方
+func (s *MoleService) RemoveFleetMember(name string) error {
+	cfg := s.status().Config
+	if cfg == nil {
+		return fmt.Errorf("未发现有效配置")
+	}
+	newCfg := *cfg
+	newCfg.Fleet.Members = nil
+	for _, m := range cfg.Fleet.Members {
+		if m.Name != name {
+			newCfg.Fleet.Members = append(newCfg.Fleet.Members, m)
+		}
+	}
+	return s.saveUserConfig(newCfg)
+}
+
+// FleetConnect 远程连接设备上的隧道
+func (s *MoleService) FleetConnect(name string) (FleetMemberStatus, error) {
+	return s.fleetAction(name, "connect", "远程连接设备 ")
+}
+
+// FleetDisconnect 远程断开设备上的隧道
+func (s *MoleService) FleetDisconnect(name string) (FleetMemberStatus, error) {
+	return s.fleetAction(name, "disconnect", "远程断开设备 ")
+}
+
+func (s *MoleService) fleetAction(name, path, auditDetail string) (FleetMemberStatus, error) {
+	if err := s.checkUnlocked(); err != nil {
+		return FleetMemberStatus{}, err
+	}
+	m, err := s.fleetMember(name)
+	if err != nil {
+		return FleetMemberStatus{}, err
+	}
+	action := auditConnect
+	if path == "disconnect" {
+		action = auditDisconnect
+	}
+	s.audit(action, auditDetail+name)
+	st, err := fleetCall(m, http.MethodPost, path)
+	if err != nil {
+		return memberStatus(m, nil, err), err
+	}
+	return memberStatus(m, st, nil), nil
+}
@@ -0,0 +1,140 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"unicode/utf8"
+)
+
+// 规则文件夹：规则较多时按用途或机器分组，文件夹可以嵌套，路径以 / 分隔 (如 "家里/NAS")
+// 文件夹的顺序与折叠状态保存在配置中，界面重开或换一台机器同步配置后保持原样；规则的顺序即 Proxies 的顺序
+// 文件夹只影响界面显示，与 frpc.toml 无关
+
+const (
+	maxFolderDepth   = 4
+	maxFolderNameLen = 40
+	maxFolders       = 50
+)
+
+// RuleFolder 一个文件夹，按在列表中的位置排序
+type RuleFolder struct {
+	Path      string `toml:"path" json:"path"`
+	Collapsed bool   `toml:"collapsed,omitempty" json:"collapsed"`
+}
+
+// normalizeFolderPath 去掉每一级两端的空白，拒绝空的层级与过深的嵌套；空字符串表示不在文件夹中
+func normalizeFolderPath(path string) (string, error) {
+	path = strings.Trim(strings.TrimSpace(path), "/")
+	if path == "" {
+		return "", nil
+	}
+	parts := strings.Split(path, "/")
+	if len(parts) > maxFolderDepth {
+		return "", fmt.Errorf("文件夹 \"%s\" 最多嵌套 %d 层", path, maxFolderDepth)
+	}
+	for i, part := range parts {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			return "", fmt.Errorf("文件夹 \"%s\" 包含空的层级", path)
+		}
+		if utf8.RuneCountInString(part) > maxFolderNameLen {
+			return "", fmt.Errorf("文件夹名称最多 %d 个字符: %s", maxFolderNameLen, part)
+		}
+		parts[i] = part
+	}
+	return strings.Join(parts, "/"), nil
+}
+
+// normalizeFolders 规整文件夹列表与规则所在的文件夹：去重，并补上被引用但尚未列出的文件夹及其上级
+// 补上的文件夹排在所属上级的最后，没有上级时排在列表末尾
+func normalizeFolders(folders []RuleFolder, rules []ProxyRule) ([]RuleFolder, []ProxyRule, error) {
+	out := make([]RuleFolder, 0, len(folders))
+	seen := make(map[string]bool)
+	add := func(f RuleFolder) {
+		if seen[f.Path] {
+			return
+		}
+		seen[f.Path] = true
+		// 插入到同一上级的最后一个后代之后
+		parent, _ := folderParent(f.Path)
+		at := len(out)
+		if parent != "" {
+			for i, o := range out {
+				if o.Path == parent || strings.HasPrefix(o.Path, parent+"/") {
+					at = i + 1
+				}
+			}
+		}
+		out = append(out, RuleFolder{})
+		copy(out[at+1:], out[at:])
+		out[at] = f
+	}
+	// 逐级补上上级，上级晚于下级出现时也沿用它自己的折叠状态
+	collapsed := make(map[string]bool)
+	ensure := func(path string) {
+		parts := strings.Split(path, "/")
+		for i := 1; i <= len(parts); i++ {
+			p := strings.Join(parts[:i], "/")
+			add(RuleFolder{Path: p, Collapsed: collapsed[p]})
+		}
+	}
+
+	paths := make([]string, 0, len(folders))
+	for _, f := range folders {
+		path, err := normalizeFolderPath(f.Path)
+		if err != nil {
+			return nil, nil, err
+		}
+		if path != "" {
+			collapsed[path] = collapsed[path] || f.Collapsed
+			paths = append(paths, path)
+		}
+	}
+	for _, path := range paths {
+		ensure(path)
+	}
+	rules = append([]ProxyRule(nil), rules...)
+	for i, p := range rules {
+		path, err := normalizeFolderPath(p.Folder)
+		if err != nil {
+			return nil, nil, fmt.Errorf("规则 \"%s\": %v", p.Name, err)
+		}
+		rules[i].Folder = path
+		if path != "" {
+			ensure(path)
+		}
+	}
+	if len(out) > maxFolders {
+		return nil, nil, fmt.Errorf("最多创建 %d 个文件夹", maxFolders)
+	}
+	return out, rules, nil
+}
+
+func folderParent(path string) (string, bool) {
+	i := strings.LastIndex(path, "/")
+	if i < 0 {
+		return "", false
+	}
+	return path[:i], true
+}
+
+// SetFolderCollapsed 记录文件夹的折叠状态，只修改这一项，不影响界面上尚未保存的编辑
+func (s *MoleService) SetFolderCollapsed(path string, collapsed bool) error {
+	cfg := s.status().Config
+	if cfg == nil {
+		return fmt.Errorf("未发现有效配置")
+	}
+	newCfg := *cfg
+	newCfg.Folders = append([]RuleFolder(nil), cfg.Folders...)
+	found := false
+	for i := range newCfg.Folders {
+		if newCfg.Folders[i].Path == path {
+			newCfg.Folders[i].Collapsed = collapsed
+			found = true
+		}
+	}
+	if !found {
+		return fmt.Errorf("未找到文件夹: %s", path)
+	}
+	return s.saveUserConfig(newCfg)
+}
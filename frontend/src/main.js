@@ -4,37 +4,49 @@
 
 import { Events, Browser } from "@wailsio/runtime";
 // 后端绑定方法
-import { SaveUserConfig, Connect, Disconnect, GetStatus } from "../bindings/mole/moleservice";
+import { SaveUserConfig, Connect, Disconnect, GetStatus, CheckRemotePort, ConfirmImport, CancelImport, GetPendingImports, GetTelemetryInfo, SetTelemetryEnabled, GetAuditLog, RevealToken, GetLockStatus, SetAppLockPIN, DisableAppLock, UnlockApp, LockApp, TouchActivity, SetAutoLock, GetBiometricStatus, UnlockAppBiometric, TestNotifyChannel, TestEmail, GetUsageReport, ExportStats, SearchLogs, SetLogHighlights, SetLogForwardLevel, SetLogTimeFormat, GetVersionInfo, GetEnvironmentInfo, SaveConvertedToml, ExportConfig, CheckDomainOverlaps, FetchServerCertificate, SetTrayClickAction, SaveProfile, DeleteProfile, SwitchProfile, SetTrayIconTheme, GetLaunchAtLogin, SetLaunchAtLogin, ToggleMiniWindow, PauseLogStream, ResumeLogStream, GetLogSnapshot, ExportRedactedConfig, GetTemplateVars, GetRuleIcons, SetFolderCollapsed, ImportFromQRImage, SetDetectShareCode, GetIncludeFiles, SetOutboundProxy, CheckForUpdates, SetUpdateChannel, DownloadUpdate, ExportDeployment, GetFleetStatus, AddFleetMember, RemoveFleetMember, FleetConnect, FleetDisconnect, GenerateFleetAccessToken, ClearFleetAccessToken, GetLANAPIInfo, SetLANAPI, TestPlugin, TestLifecycleHook, QuickExpose, StopQuickExpose, ListQuickExposes, SetQuickExposeDomain, GetRequestLog, ClearRequestLog, ShareRuleFor, GetSelfTest, GetRuntimeConfigStatus, RollbackRuntimeConfig, GetLogIssues } from "../bindings/mole/moleservice";
 
+// 开启应用锁或只读模式时凭据字段的占位符，与 redact.go 中的 redactedPlaceholder 一致
+const REDACTED_SECRET = "<redacted>";
 
 // 初始化全局命名空间
 window.App = {
     // 1. 内存状态快照
     state: {
         rawConfig: null,    // 后端原始备份
+        pendingImport: null, // 等待确认的导入预览
         proxyList: [],      // 当前 UI 代理列表快照
+        notifyChannels: [], // 当前 UI 消息通知渠道快照
+        plugins: [], // 当前 UI 事件插件快照
+        highlightRules: [], // 当前 UI 日志高亮规则快照
         isRunning: false,   // frp是否运行
+        tunnel: {},         // 连接状态、最近失败原因、连接开始时间
+        resource: null,     // frpc 进程最近一次的 CPU 与内存采样
+        templateVars: {},   // 本机的模板变量值，如 hostname -> mbp
+        ruleIcons: {},      // 可选的规则图标，名称 -> 字符
+        folders: [],        // 规则文件夹 { path, collapsed }，按显示顺序
         isLoaded: false,    // 是否加载完毕
+        initRetry: null,    // 后端初始化未完成时重新查询状态的定时器
         isProcessing: false, // 防止按钮连续点击（防抖）
+        proxyStates: {},    // 规则 ID -> 最近一次 proxy-state 事件
+        tokenHidden: false, // 开启应用锁后 Token 不随配置下发
+        readOnly: false,    // 只读 (Kiosk) 模式
+        biometric: null,    // 可用的生物识别方式 (Touch ID / Windows Hello)
+        logPaused: false,   // 日志推送已暂停，见 toggleLogPause
         logs: [], // 内存中的日志数组
         maxLogCount: 200 // 限制最大条数，防止内存溢出
     },
 
     /**
      * 核心方法：向内存添加日志并更新 UI
-     * 支持单条字符串 或 对象数组
+     * 支持单条字符串、字符串数组，或后端推送的 LogEntry 数组 ({ line, severity, color })
      */
     appendLogs(input) {
         // 1. 统一格式：将单条字符串转为数组，确保后续逻辑一致
         const incoming = Array.isArray(input) ? input : [input];
 
         // 2. 转换成标准的日志对象
-        const newEntries = incoming.map(line => ({
-            id: Date.now() + Math.random(),
-            time: new Date().toLocaleTimeString('zh-CN', { hour12: false }),
-            level: this.detectLogLevel(line), // 自动识别 [I]/[E] 等级别
-            content: line.trim()
-        }));
+        const newEntries = incoming.map(item => this.toLogEntry(item));
 
         // 3. 更新内存（追加并截断）
         this.state.logs = [...this.state.logs, ...newEntries].slice(-this.state.maxLogCount);
@@ -43,6 +55,21 @@ window.App = {
         this.renderNewLogs(newEntries);
     },
 
+    toLogEntry(item) {
+        // 后端级别 -> 样式类，debug / trace / system 统一按系统消息显示
+        const severityLevels = { info: 'success', warn: 'warning', error: 'error' };
+        const line = typeof item === 'string' ? item : item.line;
+        return {
+            id: Date.now() + Math.random(),
+            // 后端按偏好设置格式化好的接收时间，本地消息没有时用当前时间
+            time: item.stamp || (item.time ? new Date(item.time) : new Date()).toLocaleTimeString('zh-CN', { hour12: false }),
+            // 命中高亮规则时以规则的级别为准，其次是后端识别的级别，本地消息自动识别 [I]/[E] 等级别
+            level: severityLevels[item.severity] || (item.level ? severityLevels[item.level] || 'system' : this.detectLogLevel(line)),
+            color: item.color || '',
+            content: line.trim()
+        };
+    },
+
     // 辅助方法：识别日志等级
     detectLogLevel(line) {
         if (line.includes(' [I] ')) return 'success';
@@ -90,6 +117,78 @@ window.App = {
             this.refreshStatus();
         });
 
+        // 后端初始化完成
+        Events.On('service-ready', () => {
+            this.refreshStatus();
+        });
+
+        // 连接状态机的每次变化 (准备、连接中、已连接、重连、停止、出错) 都刷新状态卡片
+        Events.On('tunnel-state', () => {
+            this.refreshStatus();
+        });
+
+        // 启动自检的逐步进度，失败时指出卡在哪一步
+        Events.On('self-test', (event) => {
+            this.renderSelfTest(event.data);
+        });
+
+        // 反复出现的同一条警告或错误合并为持续问题
+        Events.On('log-issues', (event) => {
+            this.renderLogIssues(event.data || []);
+        });
+
+        // 新配置生成失败或让 frpc 很快退出时提示回滚
+        Events.On('runtime-config', (event) => {
+            this.renderRuntimeConfig(event.data);
+        });
+
+        // 快速分享增减或随断开连接结束
+        Events.On('quick-expose', (event) => {
+            this.renderQuickExposes(event.data || []);
+        });
+
+        // frpc 进程的 CPU 与内存采样，运行期间每 5 秒一次
+        Events.On('frpc-resource', (event) => {
+            this.state.resource = event.data;
+            this.renderResource();
+        });
+
+        // 安装包下载进度，中断后再次下载会从已下载的位置继续
+        Events.On('update-download', (event) => {
+            const ev = event.data;
+            if (ev.error || ev.done) return;
+            const mb = (n) => (n / 1048576).toFixed(1);
+            document.getElementById('update-status').innerText = `${ev.resumed ? '继续下载' : '正在下载'} ${mb(ev.received)}` + (ev.total ? ` / ${mb(ev.total)} MB` : ' MB');
+        });
+
+        // 配置异步落盘进度：pending -> saving -> saved / failed
+        Events.On('config-save', (event) => {
+            this.renderSaveState(event.data);
+        });
+
+        // 配置被其他来源替换 (外部修改文件、守护进程重新读取等) 时重新拉取；本页面自己的保存已在内存中
+        Events.On('config-changed', (event) => {
+            if (event.data.source !== 'save') this.refreshStatus();
+        });
+
+        // 逐条规则的运行状态，按规则 ID 更新卡片上的徽标
+        Events.On('proxy-state', (event) => {
+            const st = event.data;
+            this.state.proxyStates[st.ruleID] = st;
+            this.renderProxyState(st.ruleID);
+        });
+
+        // 应用锁状态变化：锁定时遮住界面，解锁后重新拉取被隐藏的配置
+        Events.On('app-lock', (event) => {
+            this.renderLockState(event.data);
+            if (!event.data.locked) this.afterUnlock();
+        });
+
+        // 拖入配置文件后，后端解析出预览等待确认
+        Events.On('import-preview', (event) => {
+            this.showImportPreview(event.data);
+        });
+
         Events.On('frp-logs', (event) => {
             console.log('frp logs,', event);
             // 1. 获取后端批量传递的数组
@@ -99,7 +198,661 @@ window.App = {
         });
 
         // 首次加载
+        GetSelfTest().then((r) => this.renderSelfTest(r)).catch(() => {});
+        GetRuntimeConfigStatus().then((st) => this.renderRuntimeConfig(st)).catch(() => {});
+        GetLogIssues().then((list) => this.renderLogIssues(list || [])).catch(() => {});
+        this.loadTemplateVars();
+        this.loadRuleIcons();
+        this.loadIncludeFiles();
         this.refreshStatus();
+
+        // 每分钟刷新一次“已连接时长”
+        setInterval(() => this.renderConnectButton(), 60 * 1000);
+
+        // 双击配置文件启动时，导入预览早于页面加载生成，这里补上
+        this.loadPendingImports();
+
+        GetLockStatus().then(st => this.renderLockState(st));
+        GetBiometricStatus().then(bio => {
+            this.state.biometric = bio;
+            const btn = document.getElementById('lock-biometric');
+            btn.textContent = `使用 ${bio.kind} 解锁`;
+            btn.style.display = bio.available ? '' : 'none';
+        });
+        this.watchActivity();
+        this.loadTelemetry();
+        this.loadVersionInfo();
+        this.loadEnvironment();
+        GetLaunchAtLogin().then(on => { document.getElementById('launch-at-login').checked = on; });
+        this.loadAuditLog();
+        this.loadUsageReport();
+        this.loadFleet();
+        ListQuickExposes().then(list => this.renderQuickExposes(list || []));
+        this.loadRequestLog();
+    },
+
+    loadPendingImports() {
+        GetPendingImports().then(list => {
+            if (list && list.length) this.showImportPreview(list[list.length - 1]);
+        });
+    },
+
+    // 应用锁：锁屏遮罩与设置卡片
+    renderLockState(st) {
+        document.getElementById('lock-overlay').style.display = st.locked ? 'flex' : 'none';
+        document.getElementById('applock-state').textContent = st.enabled ? '已开启' : '未开启';
+        document.getElementById('applock-disable').disabled = !st.enabled;
+        document.getElementById('applock-current-pin').style.display = st.enabled ? '' : 'none';
+        document.getElementById('applock-now').disabled = !st.enabled;
+        if (st.locked) {
+            // 锁定后清掉页面上已有的配置，Token 不留在输入框里
+            this.state.rawConfig = null;
+            document.getElementById('server-token').value = '';
+            document.getElementById('lock-pin').focus();
+        }
+    },
+
+    async unlockApp() {
+        const input = document.getElementById('lock-pin');
+        const errBox = document.getElementById('lock-error');
+        try {
+            await UnlockApp(input.value);
+            input.value = '';
+            errBox.textContent = '';
+        } catch (err) {
+            errBox.textContent = err?.message || String(err);
+        }
+    },
+
+    async unlockAppBiometric() {
+        const errBox = document.getElementById('lock-error');
+        try {
+            await UnlockAppBiometric();
+            errBox.textContent = '';
+        } catch (err) {
+            errBox.textContent = err?.message || String(err);
+        }
+    },
+
+    async afterUnlock() {
+        await this.refreshStatus();
+        this.loadPendingImports();
+        this.loadAuditLog();
+        this.loadUsageReport();
+    },
+
+    async setAppLockPIN() {
+        const input = document.getElementById('applock-pin');
+        const current = document.getElementById('applock-current-pin');
+        try {
+            await SetAppLockPIN(current.value, input.value);
+            input.value = '';
+            current.value = '';
+            this.appendLogs('应用锁 PIN 已保存');
+        } catch (err) {
+            this.appendLogs('设置 PIN 失败: ' + (err?.message || err));
+        }
+    },
+
+    async disableAppLock() {
+        const input = document.getElementById('applock-current-pin');
+        try {
+            await DisableAppLock(input.value);
+            input.value = '';
+            this.appendLogs('应用锁已关闭');
+        } catch (err) {
+            this.appendLogs('关闭应用锁失败: ' + (err?.message || err));
+        }
+    },
+
+    lockApp() {
+        LockApp();
+    },
+
+    // 界面操作上报给后端用于无操作自动锁定，30 秒内最多一次
+    watchActivity() {
+        let last = 0;
+        const touch = () => {
+            const now = Date.now();
+            if (now - last < 30000) return;
+            last = now;
+            TouchActivity();
+        };
+        ['mousemove', 'keydown', 'click', 'wheel'].forEach(ev => document.addEventListener(ev, touch, { passive: true }));
+    },
+
+    async saveAutoLock() {
+        const minutes = parseInt(document.getElementById('autolock-minutes').value, 10) || 0;
+        const onSessionLock = document.getElementById('autolock-session').checked;
+        try {
+            await SetAutoLock(minutes, onSessionLock);
+            await this.refreshStatus();
+        } catch (err) {
+            this.appendLogs('保存自动锁定设置失败: ' + (err?.message || err));
+        }
+    },
+
+    async saveTrayClickAction() {
+        try {
+            await SetTrayClickAction(document.getElementById('tray-click-action').value);
+            await this.refreshStatus();
+        } catch (err) {
+            this.appendLogs('保存托盘设置失败: ' + (err?.message || err));
+        }
+    },
+
+    // 无边框置顶的小窗口，只显示状态与连接开关
+    async toggleMiniWindow() {
+        try {
+            await ToggleMiniWindow();
+        } catch (err) {
+            this.appendLogs('打开迷你状态窗失败: ' + (err?.message || err));
+        }
+    },
+
+    async saveLaunchAtLogin(enabled) {
+        try {
+            await SetLaunchAtLogin(enabled);
+        } catch (err) {
+            this.appendLogs('设置登录时启动失败: ' + (err?.message || err));
+            document.getElementById('launch-at-login').checked = await GetLaunchAtLogin();
+        }
+    },
+
+    async saveTrayIconTheme() {
+        try {
+            await SetTrayIconTheme(document.getElementById('tray-icon-theme').value);
+            await this.refreshStatus();
+        } catch (err) {
+            this.appendLogs('保存托盘设置失败: ' + (err?.message || err));
+        }
+    },
+
+    async saveDetectShareCode(enabled) {
+        try {
+            await SetDetectShareCode(enabled);
+            await this.refreshStatus();
+        } catch (err) {
+            this.appendLogs('保存设置失败: ' + (err?.message || err));
+        }
+    },
+
+    async saveOutboundProxy() {
+        const mode = document.getElementById('outbound-proxy-mode').value;
+        const proxy = mode === 'custom' ? document.getElementById('outbound-proxy-url').value.trim() : mode;
+        try {
+            await SetOutboundProxy(proxy);
+            await this.refreshStatus();
+            this.appendLogs('网络代理设置已保存');
+        } catch (err) {
+            this.appendLogs('保存设置失败: ' + (err?.message || err));
+        }
+    },
+
+    renderProfiles() {
+        const list = this.state.rawConfig?.profiles || [];
+        document.getElementById('profile-list').innerHTML = list.map((p, i) => `
+            <li>
+                <span>${this.escapeHTML(p.name)} · ${this.escapeHTML(p.addr)}:${p.port} · ${(p.proxies || []).length} 条规则</span>
+                <button class="btn-toolbar" onclick="App.switchProfile(${i})">切换</button>
+                <button class="btn-delete-text" onclick="App.deleteProfile(${i})">删除</button>
+            </li>`).join('');
+    },
+
+    // 快速分享：临时暴露本地端口
+    async quickExpose() {
+        const port = parseInt(document.getElementById('quick-expose-port').value) || 0;
+        try {
+            const item = await QuickExpose(port, document.getElementById('quick-expose-type').value);
+            document.getElementById('quick-expose-port').value = '';
+            await navigator.clipboard.writeText(item.endpoint).catch(() => {});
+            this.appendLogs(`已分享，访问地址已复制: ${item.endpoint}`);
+        } catch (err) {
+            this.appendLogs('快速分享失败: ' + (err?.message || err));
+        }
+    },
+
+    renderQuickExposes(list) {
+        document.getElementById('quick-expose-list').innerHTML = list.map(q => {
+            const id = this.escapeHTML(JSON.stringify(q.id));
+            const endpoint = this.escapeHTML(JSON.stringify(q.endpoint));
+            return `
+            <li>
+                <span>本地 ${q.localPort} (${this.escapeHTML(q.proxyType)}) → ${this.escapeHTML(q.endpoint)}</span>
+                <button class="btn-toolbar" onclick="navigator.clipboard.writeText(${endpoint})">复制</button>
+                <button class="btn-delete-text" onclick="App.stopQuickExpose(${id})">结束</button>
+            </li>`;
+        }).join('');
+    },
+
+    async stopQuickExpose(id) {
+        try {
+            await StopQuickExpose(id);
+        } catch (err) {
+            this.appendLogs('结束快速分享失败: ' + (err?.message || err));
+        }
+    },
+
+    async saveQuickExposeDomain() {
+        try {
+            await SetQuickExposeDomain(document.getElementById('quick-expose-domain').value.trim());
+            await this.refreshStatus();
+        } catch (err) {
+            this.appendLogs('保存设置失败: ' + (err?.message || err));
+        }
+    },
+
+    // 访问记录：开启了记录的 HTTP 规则最近的请求
+    async loadRequestLog() {
+        const select = document.getElementById('request-log-rule');
+        const rules = (this.state.rawConfig?.proxies || []).filter(p => p.proxyType === 'http' && p.requestLog);
+        const current = select.value;
+        select.innerHTML = '<option value="">全部规则</option>' + rules.map(p =>
+            `<option value="${this.escapeHTML(p.id)}">${this.escapeHTML(p.name)}</option>`).join('');
+        select.value = rules.some(p => p.id === current) ? current : '';
+        const names = Object.fromEntries(rules.map(p => [p.id, p.name]));
+        try {
+            const list = await GetRequestLog(select.value) || [];
+            document.getElementById('request-log-body').innerHTML = list.map(e => `
+                <tr class="${e.status >= 500 ? 'status-error' : ''}">
+                    <td>${new Date(e.time).toLocaleTimeString()}</td>
+                    <td>${this.escapeHTML(names[e.ruleId] || e.ruleId)}</td>
+                    <td>${this.escapeHTML(e.clientIP)}</td>
+                    <td>${this.escapeHTML(e.method)} ${this.escapeHTML(e.path)}</td>
+                    <td>${e.status}</td>
+                    <td>${e.durationMs} ms</td>
+                </tr>`).join('');
+            document.getElementById('request-log-empty').style.display = list.length ? 'none' : '';
+        } catch (err) {
+            this.appendLogs('读取访问记录失败: ' + (err?.message || err));
+        }
+    },
+
+    async clearRequestLog() {
+        try {
+            await ClearRequestLog(document.getElementById('request-log-rule').value);
+            this.loadRequestLog();
+        } catch (err) {
+            this.appendLogs('清空访问记录失败: ' + (err?.message || err));
+        }
+    },
+
+    // 设备管理：列出其他设备的隧道状态
+    async loadFleet() {
+        try {
+            this.renderFleet(await GetFleetStatus() || []);
+        } catch (err) {
+            console.error('查询设备状态失败:', err);
+        }
+    },
+
+    renderFleet(list) {
+        const states = { connected: '已连接', connecting: '连接中', reconnecting: '重连中', error: '出错', idle: '未连接' };
+        document.getElementById('fleet-list').innerHTML = list.map(m => {
+            const st = m.status;
+            const desc = !m.online ? `离线：${m.error || ''}`
+                : `${st.hostname} · ${states[st.tunnelState] || st.tunnelState || (st.isRunning ? '运行中' : '未连接')}` +
+                  (st.server ? ` · ${st.server}` : '') + ` · ${(st.proxies || []).length} 条规则` + (st.lastError ? ` · ${st.lastError}` : '');
+            const name = this.escapeHTML(JSON.stringify(m.name));
+            return `
+            <li>
+                <span>${this.escapeHTML(m.name)} · ${this.escapeHTML(desc)}</span>
+                ${m.online ? (st.isRunning
+                    ? `<button class="btn-toolbar" onclick="App.fleetAction(${name}, false)">断开</button>`
+                    : `<button class="btn-toolbar" onclick="App.fleetAction(${name}, true)">连接</button>`) : ''}
+                <button class="btn-delete-text" onclick="App.removeFleetMember(${name})">移除</button>
+            </li>`;
+        }).join('');
+    },
+
+    async addFleetMember() {
+        const member = {
+            name: document.getElementById('fleet-name').value.trim(),
+            url: document.getElementById('fleet-url').value.trim(),
+            token: document.getElementById('fleet-token').value.trim(),
+            fingerprint: document.getElementById('fleet-fingerprint').value.trim()
+        };
+        try {
+            await AddFleetMember(member);
+            ['fleet-name', 'fleet-url', 'fleet-token', 'fleet-fingerprint'].forEach(id => document.getElementById(id).value = '');
+            await this.refreshStatus();
+            this.loadFleet();
+        } catch (err) {
+            this.appendLogs('添加设备失败: ' + (err?.message || err));
+        }
+    },
+
+    async removeFleetMember(name) {
+        if (!confirm(`从列表中移除设备 ${name}？`)) return;
+        try {
+            await RemoveFleetMember(name);
+            await this.refreshStatus();
+            this.loadFleet();
+        } catch (err) {
+            this.appendLogs('移除设备失败: ' + (err?.message || err));
+        }
+    },
+
+    async fleetAction(name, connect) {
+        try {
+            await (connect ? FleetConnect(name) : FleetDisconnect(name));
+        } catch (err) {
+            this.appendLogs(`${connect ? '远程连接' : '远程断开'} ${name} 失败: ` + (err?.message || err));
+        }
+        this.loadFleet();
+    },
+
+    renderFleetAccess() {
+        const token = this.state.rawConfig?.fleet?.accessToken || '';
+        const shown = token === REDACTED_SECRET ? '已隐藏' : token;
+        document.getElementById('fleet-access-token').textContent = token ? `本机访问令牌：${shown}` : '';
+        document.getElementById('fleet-token-clear').style.display = token ? '' : 'none';
+    },
+
+    // 局域网接口：显示访问地址与证书指纹，供手机端填写
+    async renderLANAPI() {
+        const info = await GetLANAPIInfo();
+        document.getElementById('lan-api-enabled').checked = info.enabled;
+        document.getElementById('lan-api-port').value = this.state.rawConfig?.fleet?.lanPort || "";
+        const pre = document.getElementById('lan-api-info');
+        pre.style.display = info.enabled ? '' : 'none';
+        pre.textContent = [
+            ...(info.urls || []).map(u => `地址: ${u}`),
+            `证书指纹 (SHA-256): ${info.fingerprint || '尚未生成'}`
+        ].join('\n');
+    },
+
+    async saveLANAPI() {
+        try {
+            await SetLANAPI(document.getElementById('lan-api-enabled').checked, parseInt(document.getElementById('lan-api-port').value) || 0);
+            await this.refreshStatus();
+        } catch (err) {
+            this.appendLogs('保存设置失败: ' + (err?.message || err));
+            this.renderLANAPI();
+        }
+    },
+
+    async generateFleetToken() {
+        if (this.state.rawConfig?.fleet?.accessToken && !confirm('重新生成后旧令牌立即失效，继续？')) return;
+        try {
+            await GenerateFleetAccessToken();
+            await this.refreshStatus();
+        } catch (err) {
+            this.appendLogs('生成访问令牌失败: ' + (err?.message || err));
+        }
+    },
+
+    async clearFleetToken() {
+        try {
+            await ClearFleetAccessToken();
+            await this.refreshStatus();
+        } catch (err) {
+            this.appendLogs('保存设置失败: ' + (err?.message || err));
+        }
+    },
+
+    async saveProfile() {
+        const input = document.getElementById('profile-name');
+        try {
+            await SaveProfile(input.value);
+            input.value = '';
+            await this.refreshStatus();
+        } catch (err) {
+            this.appendLogs('保存命名配置失败: ' + (err?.message || err));
+        }
+    },
+
+    async switchProfile(index) {
+        const p = this.state.rawConfig?.profiles?.[index];
+        if (!p) return;
+        try {
+            await SwitchProfile(p.name, this.state.isRunning);
+            await this.refreshStatus();
+        } catch (err) {
+            this.appendLogs('切换配置失败: ' + (err?.message || err));
+        }
+    },
+
+    async deleteProfile(index) {
+        const p = this.state.rawConfig?.profiles?.[index];
+        if (!p) return;
+        try {
+            await DeleteProfile(p.name);
+            await this.refreshStatus();
+        } catch (err) {
+            this.appendLogs('删除命名配置失败: ' + (err?.message || err));
+        }
+    },
+
+    // 显示 / 隐藏 Token，显示明文会记入操作记录
+    // 开启应用锁时 Token 不随配置下发，需先用生物识别或 PIN 验证
+    async toggleTokenReveal(e) {
+        e.preventDefault();
+        const input = document.getElementById('server-token');
+        if (input.type === 'text') {
+            input.type = 'password';
+            e.target.textContent = '显示';
+            return;
+        }
+        if (this.state.tokenHidden && !this.state.biometric?.available) {
+            document.getElementById('reveal-modal').style.display = 'flex';
+            document.getElementById('reveal-pin').focus();
+            return;
+        }
+        try {
+            this.showRevealedToken(await RevealToken(''));
+        } catch (err) {
+            this.appendLogs('显示 Token 失败: ' + (err?.message || err));
+        }
+    },
+
+    async confirmRevealToken() {
+        const pin = document.getElementById('reveal-pin');
+        try {
+            this.showRevealedToken(await RevealToken(pin.value));
+            this.closeRevealModal();
+        } catch (err) {
+            document.getElementById('reveal-error').textContent = err?.message || String(err);
+        }
+    },
+
+    closeRevealModal() {
+        document.getElementById('reveal-pin').value = '';
+        document.getElementById('reveal-error').textContent = '';
+        document.getElementById('reveal-modal').style.display = 'none';
+    },
+
+    showRevealedToken(token) {
+        const input = document.getElementById('server-token');
+        if (!input.value) input.value = token;
+        input.type = 'text';
+        document.querySelector('.token-reveal').textContent = '隐藏';
+    },
+
+    // 最近 50 条操作记录
+    async loadAuditLog() {
+        const actions = {
+            config_save: '保存配置',
+            connect: '连接',
+            disconnect: '断开',
+            import: '导入',
+            token_reveal: '查看 Token'
+        };
+        const list = document.getElementById('audit-list');
+        let entries = [];
+        try {
+            entries = await GetAuditLog(50) || [];
+        } catch (err) {
+            console.error('读取操作记录失败:', err);
+        }
+        list.innerHTML = entries.length ? entries.map(e => `
+            <li>
+                <span class="audit-time">${this.escapeHTML(new Date(e.time).toLocaleString())}</span>
+                <span class="audit-user">${this.escapeHTML(e.user)}</span>
+                <span class="audit-action">${this.escapeHTML(actions[e.action] || e.action)}</span>
+                <span class="audit-detail">${this.escapeHTML(e.detail || '')}</span>
+            </li>`).join('') : '<li class="audit-empty">暂无记录</li>';
+    },
+
+    // 月度报表：默认本月，按在线时长列出各条规则
+    async loadUsageReport() {
+        const input = document.getElementById('usage-month');
+        if (!input.value) {
+            const now = new Date();
+            input.value = `${now.getFullYear()}-${String(now.getMonth() + 1).padStart(2, '0')}`;
+        }
+        let report;
+        try {
+            report = await GetUsageReport(input.value);
+        } catch (err) {
+            document.getElementById('usage-summary').innerText = '读取报表失败: ' + err;
+            return;
+        }
+        const hours = (sec) => (sec / 3600).toFixed(1) + ' 小时';
+        document.getElementById('usage-summary').innerText =
+            `隧道共运行 ${hours(report.tunnelSeconds)}，连接 ${report.sessions} 次，有记录 ${report.days} 天`;
+        document.getElementById('usage-proxies').innerHTML = report.proxies.length ? report.proxies.map(p => `
+            <tr>
+                <td>${this.escapeHTML(p.name || p.ruleID)}</td>
+                <td>${hours(p.onlineSeconds)}</td>
+                <td>${p.days}</td>
+            </tr>`).join('') : '<tr><td colspan="3" class="audit-empty">暂无数据</td></tr>';
+    },
+
+    // 导出报表所选月份的每日统计与会话记录
+    async exportStats(format) {
+        const month = document.getElementById('usage-month').value;
+        const [y, m] = month.split('-').map(Number);
+        const last = new Date(y, m, 0).getDate();
+        try {
+            const path = await ExportStats(format, { from: `${month}-01`, to: `${month}-${String(last).padStart(2, '0')}` });
+            if (path) this.appendLogs("统计数据已导出到 " + path);
+        } catch (err) {
+            this.appendLogs("导出失败: " + err);
+        }
+    },
+
+    // 版本信息：侧边栏版本号与帮助页的详细信息
+    async loadVersionInfo() {
+        const v = await GetVersionInfo();
+        document.getElementById('app-version').innerText = 'v' + v.appVersion;
+        const lines = [
+            `Mole: ${v.appVersion}` + (v.commit ? ` (${v.commit})` : '') + (v.buildDate ? `，构建于 ${v.buildDate}` : ''),
+            `系统: ${v.os}/${v.arch}，${v.goVersion}`,
+            `frpc (内置): ${v.embeddedFrpc || '未知'}`,
+            `frpc (已安装): ${v.installedFrpc || '尚未释放'}`,
+            `frpc 路径: ${v.frpcPath}`
+        ];
+        (v.issues || []).forEach(i => lines.push('⚠️ ' + i));
+        document.getElementById('version-info').textContent = lines.join('\n');
+    },
+
+    async saveUpdateChannel(channel) {
+        try {
+            await SetUpdateChannel(channel);
+            await this.refreshStatus();
+            document.getElementById('update-status').innerText = "";
+        } catch (err) {
+            this.appendLogs('保存设置失败: ' + (err?.message || err));
+        }
+    },
+
+    // 检查更新：有新版本 (或从测试版回退到正式版) 时打开发布页下载
+    async checkForUpdates() {
+        const status = document.getElementById('update-status');
+        status.innerText = "正在检查...";
+        try {
+            const u = await CheckForUpdates();
+            if (!u.available) {
+                status.innerText = `已是最新版本 (${u.currentVersion})`;
+                return;
+            }
+            status.innerText = u.downgrade ? `可回退到正式版 ${u.latestVersion}` : `发现新版本 ${u.latestVersion}` + (u.prerelease ? ' (测试版)' : '');
+            const action = u.downgrade ? `当前为测试版 ${u.currentVersion}，是否前往下载正式版 ${u.latestVersion}？` : `发现新版本 ${u.latestVersion}，是否前往下载？`;
+            if (!confirm(action + (u.notes ? '\n\n' + u.notes.slice(0, 500) : ''))) return;
+            // 有适用于本机的安装包时由后端下载 (支持断点续传)，否则打开发布页
+            if (!u.asset) {
+                this.openExternal(u.pageURL);
+                return;
+            }
+            const path = await DownloadUpdate();
+            status.innerText = `已下载到 ${path}`;
+        } catch (err) {
+            status.innerText = "检查失败";
+            this.appendLogs('检查更新失败: ' + (err?.message || err));
+        }
+    },
+
+    async exportDeployment(target) {
+        try {
+            const path = await ExportDeployment(target);
+            if (path) this.appendLogs("部署包已导出: " + path);
+        } catch (err) {
+            this.appendLogs("导出失败: " + (err?.message || err));
+        }
+    },
+
+    async copyVersionInfo() {
+        try {
+            await navigator.clipboard.writeText(document.getElementById('version-info').textContent);
+            this.appendLogs("版本信息已复制");
+        } catch (err) {
+            this.appendLogs("复制失败: " + err);
+        }
+    },
+
+    // 脱敏配置：config.toml 与 frpc.toml 按 Markdown 代码块复制，可直接贴到 Issue
+    async copyRedactedConfig() {
+        try {
+            const r = await ExportRedactedConfig();
+            const text = [
+                '**config.toml**', '```toml', r.config.trim(), '```',
+                '**frpc.toml**', '```toml', r.frpcToml.trim(), '```'
+            ].join('\n');
+            await navigator.clipboard.writeText(text);
+            this.appendLogs("脱敏配置已复制");
+        } catch (err) {
+            this.appendLogs("复制失败: " + err);
+        }
+    },
+
+    // 运行环境：列出发现的问题和各目录状态
+    async loadEnvironment() {
+        const env = await GetEnvironmentInfo();
+        const problems = document.getElementById('env-problems');
+        problems.innerHTML = env.problems.length
+            ? env.problems.map(p => `<li>${this.escapeHTML(p)}</li>`).join('')
+            : '<li class="env-ok">未发现问题</li>';
+
+        const gb = n => (n / (1 << 30)).toFixed(1) + ' GB';
+        const lines = [`程序: ${env.executable}`];
+        env.paths.forEach(p => {
+            const state = !p.exists ? '不存在' : (p.writable ? '可写' : '只读');
+            lines.push(`${p.name}: ${p.path} (${state})`);
+        });
+        if (env.diskTotal) lines.push(`磁盘空间: 可用 ${gb(env.diskFree)} / 共 ${gb(env.diskTotal)}`);
+        lines.push(`后台服务: ${env.backgroundService}${env.attached ? '，界面已连接' : ''}`);
+        lines.push(`启动时自动连接: ${env.autoConnect ? '是' : '否'}`);
+        document.getElementById('env-info').textContent = lines.join('\n');
+    },
+
+    // 匿名统计：展示开关状态和将要上报的内容
+    async loadTelemetry() {
+        const info = await GetTelemetryInfo();
+        document.getElementById('telemetry-enabled').checked = info.enabled;
+        const preview = document.getElementById('telemetry-preview');
+        preview.textContent = JSON.stringify(info.preview, null, 2)
+            + (info.endpoint ? '' : '\n\n// 当前版本未配置上报地址，数据只保存在本机');
+    },
+
+    async toggleTelemetry(enabled) {
+        try {
+            await SetTelemetryEnabled(enabled);
+        } catch (err) {
+            console.error('切换匿名统计失败:', err);
+        }
+        await this.refreshStatus();
+        this.loadTelemetry();
     },
 
     // 刷新状态
@@ -107,13 +860,37 @@ window.App = {
         // 1. 从后端获取当前真实的运行快照
         const status = await GetStatus();
         console.log('后端状态：', status);
+        // 后端仍在读取配置与数据库：先显示初始化中，就绪后由 service-ready 事件重新拉取，事件错过时定时重试
+        if (status.initializing) {
+            document.getElementById('status-text').innerText = "Starting";
+            document.getElementById('status-msg').innerText = status.message;
+            clearTimeout(this.state.initRetry);
+            this.state.initRetry = setTimeout(() => this.refreshStatus(), 1000);
+            return;
+        }
+        clearTimeout(this.state.initRetry);
+        // 锁定时后端不返回配置，只显示锁屏
+        if (status.locked) {
+            this.renderLockState({ enabled: true, locked: true });
+            return;
+        }
         // 2. 将后端真实状态同步到内存 state
         this.state.isRunning = status.isRunning; // 核心：捕获后端已启动的状态
+        this.state.tunnel = {
+            state: status.tunnelState,
+            lastError: status.lastError,
+            connectedSince: status.connectedSince ? new Date(status.connectedSince) : null,
+        };
+        this.state.resource = status.frpcResource;
+        this.renderResource();
+        this.state.tokenHidden = status.tokenHidden;
+        this.state.readOnly = status.readOnly;
         this.state.rawConfig = JSON.parse(JSON.stringify(status.config));
+        this.state.folders = (status.config.folders || []).map(f => ({ ...f }));
         this.state.proxyList = (status.config.proxies || []).map(p => ({
             ...p,
             type: p.proxyType || p.type,
-            customDomains: Array.isArray(p.domains) ? (p.domains[0] || "") : (p.customDomains || "")
+            customDomains: Array.isArray(p.domains) ? p.domains.join(', ') : (p.customDomains || "")
         }));
 
         // 3. 执行全局渲染
@@ -153,6 +930,19 @@ window.App = {
         this.renderConnectButton();
         this.renderConfigFields();
         this.renderProxies();
+        this.renderReadOnly();
+    },
+
+    // 只读模式：禁用配置页所有输入，隐藏添加 / 保存 / 删除按钮
+    renderReadOnly() {
+        const ro = !!this.state.readOnly;
+        document.getElementById('readonly-banner').style.display = ro ? '' : 'none';
+        document.querySelectorAll('#config input, #config select, #config textarea').forEach(el => {
+            el.disabled = ro;
+        });
+        document.querySelectorAll('#add-proxy-btn, #save-all-config, #config .btn-delete-text, .token-reveal').forEach(el => {
+            el.style.display = ro ? 'none' : '';
+        });
     },
 
     // 渲染连接按钮
@@ -181,16 +971,142 @@ window.App = {
             return;
         }
 
-        // C. 运行状态切换
-        btn.disabled = false;
-        btn.style.opacity = "1";
-        card.classList.toggle('running', this.state.isRunning);
-        btn.classList.toggle('btn-danger', this.state.isRunning);
+        // C. 运行状态切换
+        btn.disabled = false;
+        btn.style.opacity = "1";
+        card.classList.toggle('running', this.state.isRunning);
+        btn.classList.toggle('btn-danger', this.state.isRunning);
+
+        text.innerText = this.state.isRunning ? "Running" : "Ready";
+        text.style.color = this.state.isRunning ? "var(--primary)" : "var(--text-main)";
+        btn.querySelector('.btn-text').innerText = this.state.isRunning ? "断开穿透隧道" : "立即建立连接";
+        msg.innerText = this.tunnelMessage();
+        msg.title = this.state.tunnel.lastError ? `最近一次失败：${this.state.tunnel.lastError}` : "";
+    },
+
+    // 数据目录 conf.d 中手写的附加配置，随 frpc.toml 一起加载
+    async loadIncludeFiles() {
+        try {
+            const info = await GetIncludeFiles();
+            document.getElementById('include-info').innerText = info.files.length
+                ? `另外引用了 ${info.dir} 中的 ${info.files.length} 个附加配置：${info.files.join('，')}`
+                : `可以在 ${info.dir} 中放入手写的 *.toml (只包含 [[proxies]])，与上方规则一起生效`;
+        } catch (err) {
+            console.error('获取附加配置失败:', err);
+        }
+    },
+
+    async loadRuleIcons() {
+        try {
+            this.state.ruleIcons = await GetRuleIcons();
+            if (this.state.isLoaded) this.renderProxies();
+        } catch (err) {
+            console.error('获取规则图标失败:', err);
+        }
+    },
+
+    // 模板变量：规则名称与备注中的 {{hostname}} 等在生成 frpc.toml 时展开
+    async loadTemplateVars() {
+        try {
+            this.state.templateVars = await GetTemplateVars();
+        } catch (err) {
+            console.error('获取模板变量失败:', err);
+        }
+        document.getElementById('server-remark').title = this.templateHint();
+    },
+
+    templateHint() {
+        const vars = Object.entries(this.state.templateVars).map(([k, v]) => `{{${k}}} = ${v}`);
+        return vars.length ? '可使用模板变量：' + vars.join('，') : '';
+    },
+
+    // 状态卡片下方的提示：已连接时长，或连接中 / 失败原因
+    tunnelMessage() {
+        const t = this.state.tunnel;
+        switch (t.state) {
+            case 'preparing':
+                return "正在准备...";
+            case 'connecting':
+                return "正在连接服务器...";
+            case 'connected':
+                return t.connectedSince ? `已连接 ${this.formatDuration(Date.now() - t.connectedSince.getTime())}` : "已连接";
+            case 'reconnecting':
+                return `连接中断，正在重连：${t.lastError || ''}`;
+            case 'stopping':
+                return "正在断开...";
+            case 'error':
+                return `连接失败：${t.lastError || '未知原因'}`;
+        }
+        return t.lastError ? `上次失败：${t.lastError}` : "准备好建立隧道";
+    },
+
+    // 状态卡片下方的 frpc 资源占用，未运行时不显示
+    renderResource() {
+        const r = this.state.isRunning ? this.state.resource : null;
+        document.getElementById('status-resource').innerText = r
+            ? `frpc CPU ${r.cpuPercent.toFixed(1)}% · 内存 ${(r.rssBytes / 1048576).toFixed(1)} MB`
+            : "";
+    },
+
+    // 启动自检：进行中或失败时列出每一步，全部通过后收起
+    renderSelfTest(report) {
+        const list = document.getElementById('self-test');
+        const steps = report?.steps || [];
+        if (!steps.length || (report.done && !report.failed)) {
+            list.style.display = 'none';
+            return;
+        }
+        const icons = { pending: '○', running: '…', ok: '✓', failed: '✗', skipped: '–' };
+        list.innerHTML = steps.map(st => `
+            <li class="self-test-step ${st.state}">
+                <span class="self-test-icon">${icons[st.state] || ''}</span>
+                <span>${this.escapeHTML(st.title)}</span>
+                ${st.message ? `<span class="self-test-msg">${this.escapeHTML(st.message)}</span>` : ''}
+            </li>`).join('');
+        list.style.display = '';
+    },
+
+    // 持续问题：同一条警告或错误的出现次数与首次、最近出现时间
+    renderLogIssues(list) {
+        const box = document.getElementById('log-issues');
+        box.style.display = list.length ? '' : 'none';
+        box.innerHTML = list.map(it => `
+            <div class="log-issue ${it.level}">
+                <div class="log-issue-msg">${this.escapeHTML(it.message)}</div>
+                <div class="log-issue-meta">已出现 ${it.count} 次 · 首次 ${new Date(it.firstSeen).toLocaleTimeString()} · 最近 ${new Date(it.lastSeen).toLocaleTimeString()}</div>
+            </div>`).join('');
+    },
+
+    // 回滚提示：有失败原因时给出回滚按钮，正在使用回滚的配置时说明原因
+    renderRuntimeConfig(st) {
+        const banner = document.getElementById('rollback-banner');
+        if (st?.failure && st.hasLastGood) {
+            document.getElementById('rollback-text').innerText = `${st.failure}，上次可用的配置保存于 ${new Date(st.lastGoodAt).toLocaleString()}`;
+            document.getElementById('rollback-btn').style.display = '';
+            banner.style.display = '';
+        } else if (st?.pinned) {
+            document.getElementById('rollback-text').innerText = '正在使用上次可用的 frpc.toml，保存配置或断开连接后恢复按当前配置生成';
+            document.getElementById('rollback-btn').style.display = 'none';
+            banner.style.display = '';
+        } else {
+            banner.style.display = 'none';
+        }
+    },
+
+    async rollbackRuntimeConfig() {
+        try {
+            await RollbackRuntimeConfig();
+        } catch (err) {
+            this.appendLogs('回滚失败: ' + (err?.message || err));
+        }
+    },
 
-        text.innerText = this.state.isRunning ? "Running" : "Ready";
-        text.style.color = this.state.isRunning ? "var(--primary)" : "var(--text-main)";
-        btn.querySelector('.btn-text').innerText = this.state.isRunning ? "断开穿透隧道" : "立即建立连接";
-        msg.innerText = this.state.isRunning ? "服务正在运行中" : "准备好建立隧道";
+    // 毫秒 -> "3h 12m" / "5m"
+    formatDuration(ms) {
+        const minutes = Math.floor(ms / 60000);
+        const h = Math.floor(minutes / 60);
+        if (h >= 24) return `${Math.floor(h / 24)}d ${h % 24}h`;
+        return h > 0 ? `${h}h ${minutes % 60}m` : `${minutes}m`;
     },
 
     // 渲染服务器配置
@@ -199,10 +1115,121 @@ window.App = {
         console.log('服务器配置：', s);
         document.getElementById('server-addr').value = s.addr || "";
         document.getElementById('server-port').value = s.port || 7000;
-        document.getElementById('server-token').value = s.token || "";
+        const tokenInput = document.getElementById('server-token');
+        tokenInput.value = s.token || "";
+        // 开启应用锁时凭据以占位符下发，原样保存由后端沿用原值，清空则真正清除
+        tokenInput.placeholder = "Authentication Token";
         document.getElementById('server-remark').value = s.remark || "";
         const auto = document.getElementById('server-autostart');
         if (auto) auto.checked = !!s.autoStart;
+        document.getElementById('pref-autopause').checked = !!this.state.rawConfig?.preferences?.autoPauseDownTargets;
+        const retry = this.state.rawConfig?.retry || {};
+        document.getElementById('retry-max').value = retry.maxRetries || 0;
+        document.getElementById('retry-interval').value = retry.interval || "";
+        document.getElementById('retry-multiplier').value = retry.multiplier || "";
+        document.getElementById('pref-restart-at').value = this.state.rawConfig?.preferences?.restartAt || "";
+        document.getElementById('pref-wait-local').checked = !!this.state.rawConfig?.preferences?.waitForLocal;
+        document.getElementById('pref-wait-local-timeout').value = this.state.rawConfig?.preferences?.waitForLocalTimeout || "";
+        document.getElementById('pref-frpc-low-priority').checked = !!this.state.rawConfig?.preferences?.frpcLowPriority;
+        document.getElementById('pref-frpc-affinity').value = (this.state.rawConfig?.preferences?.frpcCpuAffinity || []).join(",");
+        document.getElementById('pref-frpc-memory-limit').value = this.state.rawConfig?.preferences?.frpcMemoryLimitMB || "";
+        document.getElementById('pref-frpc-memory-minutes').value = this.state.rawConfig?.preferences?.frpcMemoryLimitMinutes || "";
+        document.getElementById('autolock-minutes').value = this.state.rawConfig?.preferences?.autoLockMinutes || 0;
+        document.getElementById('tray-click-action').value = this.state.rawConfig?.preferences?.trayClickAction || "show";
+        document.getElementById('tray-icon-theme').value = this.state.rawConfig?.preferences?.trayIconTheme || "";
+        document.getElementById('detect-share-code').checked = !!this.state.rawConfig?.preferences?.detectShareCode;
+        document.getElementById('update-channel').value = this.state.rawConfig?.preferences?.updateChannel || "";
+        const outbound = this.state.rawConfig?.preferences?.outboundProxy || "";
+        document.getElementById('outbound-proxy-mode').value = outbound === "" || outbound === "direct" ? outbound : "custom";
+        document.getElementById('outbound-proxy-url').value = outbound === "direct" ? "" : outbound;
+        document.getElementById('outbound-proxy-url').style.display = outbound === "" || outbound === "direct" ? "none" : "";
+        this.renderProfiles();
+        this.renderFleetAccess();
+        this.renderLANAPI();
+        document.getElementById('quick-expose-domain').value = this.state.rawConfig?.preferences?.quickExposeDomain || "";
+        document.getElementById('autolock-session').checked = !!this.state.rawConfig?.preferences?.lockOnSessionLock;
+
+        const ssh = this.state.rawConfig?.ssh || {};
+        document.getElementById('server-transport').value = s.transport || "frp";
+        document.getElementById('server-token-source').value = s.tokenSource || "";
+        document.getElementById('server-extra-args').value = (s.extraArgs || []).join(" ");
+        document.getElementById('server-user').value = s.user || "";
+        document.getElementById('server-name-prefix').value = s.namePrefix || "";
+        document.getElementById('server-tls-name').value = s.tlsServerName || "";
+        document.getElementById('server-tls-first-byte').value = s.tlsDisableCustomFirstByte == null ? "" : String(s.tlsDisableCustomFirstByte);
+        document.getElementById('server-tls-ca').value = s.tlsTrustedCAFile || "";
+        document.getElementById('server-tls-pin').value = s.tlsPinSHA256 || "";
+        document.getElementById('ssh-port').value = ssh.port || "";
+        document.getElementById('ssh-user').value = ssh.user || "";
+        document.getElementById('ssh-password').value = ssh.password || "";
+        document.getElementById('ssh-keyfile').value = ssh.keyFile || "";
+        document.getElementById('ssh-hostkey').value = ssh.hostKeyFingerprint || "";
+
+        const mqtt = this.state.rawConfig?.mqtt || {};
+        document.getElementById('mqtt-enabled').checked = !!mqtt.enabled;
+        document.getElementById('mqtt-ha').checked = !!mqtt.homeAssistant;
+        document.getElementById('mqtt-broker').value = mqtt.broker || "";
+        document.getElementById('mqtt-prefix').value = mqtt.topicPrefix || "";
+        document.getElementById('mqtt-username').value = mqtt.username || "";
+        document.getElementById('mqtt-password').value = mqtt.password || "";
+
+        const email = this.state.rawConfig?.email || {};
+        document.getElementById('email-enabled').checked = !!email.enabled;
+        document.getElementById('email-host').value = email.host || "";
+        document.getElementById('email-port').value = email.port || "";
+        document.getElementById('email-username').value = email.username || "";
+        document.getElementById('email-password').value = email.password || "";
+        document.getElementById('email-to').value = (email.to || []).join(", ");
+        document.getElementById('email-down-after').value = email.downAfterMinutes || "";
+        document.getElementById('email-min-interval').value = email.minIntervalMinutes || "";
+
+        document.getElementById('log-forward-level').value = this.state.rawConfig?.preferences?.logForwardLevel || '';
+        document.getElementById('log-time-zone').value = this.state.rawConfig?.preferences?.logTimeZone || '';
+        document.getElementById('log-time-format').value = this.state.rawConfig?.preferences?.logTimeFormat || '';
+        document.getElementById('log-strip-frpc-time').checked = !!this.state.rawConfig?.preferences?.logStripFrpcTime;
+        this.state.highlightRules = JSON.parse(JSON.stringify(this.state.rawConfig?.preferences?.logHighlights || []));
+        this.renderHighlightRules();
+
+        this.state.notifyChannels = JSON.parse(JSON.stringify(this.state.rawConfig?.notify?.channels || []));
+        this.renderNotifyChannels();
+        this.state.plugins = JSON.parse(JSON.stringify(this.state.rawConfig?.plugins || []));
+        this.renderPlugins();
+        const hooks = this.state.rawConfig?.hooks || {};
+        [['pre-connect', hooks.preConnect], ['post-connect', hooks.postConnect], ['post-disconnect', hooks.postDisconnect]].forEach(([kind, h]) => {
+            document.getElementById(`hook-${kind}-command`).value = h?.command || '';
+            document.getElementById(`hook-${kind}-args`).value = (h?.args || []).join(' ');
+            document.getElementById(`hook-${kind}-timeout`).value = h?.timeout || '';
+        });
+        this.renderTransportFields();
+    },
+
+    // 获取 frps 当前证书指纹 (首次信任)，用户核对后保存即生效
+    async fetchServerFingerprint(event) {
+        event.preventDefault();
+        try {
+            const cert = await FetchServerCertificate(
+                document.getElementById('server-addr').value.trim(),
+                parseInt(document.getElementById('server-port').value) || 0,
+                document.getElementById('server-tls-name').value.trim(),
+                document.getElementById('server-tls-first-byte').value === 'false'
+            );
+            document.getElementById('server-tls-pin').value = cert.fingerprint;
+            this.appendLogs(`已获取服务器证书：${cert.subject}，有效期至 ${new Date(cert.notAfter).toLocaleDateString()}，请与服务器上的证书核对后保存`);
+        } catch (err) {
+            this.appendLogs("获取证书失败: " + err);
+        }
+    },
+
+    // SSH 参数仅在选择 SSH 传输方式时显示，TLS 参数仅用于 frp
+    renderTransportFields() {
+        const transport = document.getElementById('server-transport').value;
+        const isSSH = transport === 'ssh' || transport === 'frp-ssh';
+        document.querySelectorAll('.ssh-only').forEach(el => {
+            el.style.display = isSSH ? "" : "none";
+        });
+        document.querySelectorAll('.frp-only').forEach(el => {
+            el.style.display = transport === 'frp' ? "" : "none";
+        });
     },
 
     // 渲染添加代理按钮
@@ -219,11 +1246,12 @@ window.App = {
     renderProxies() {
         const container = document.getElementById('proxy-container');
         container.innerHTML = '';
+        const cards = [];
 
         this.state.proxyList.forEach((p, index) => {
-            const isHTTP = p.type === 'http';
+            const isHTTP = ['http', 'https', 'tcpmux'].includes(p.type); // 按域名路由的类型
             const card = document.createElement('div');
-            card.className = `card proxy-card`;
+            card.className = `card proxy-card` + (p.color ? ` rule-color-${p.color}` : '');
             card.setAttribute('data-type', p.type); // 保留属性，用于 CSS 变色
 
             card.innerHTML = `
@@ -231,11 +1259,30 @@ window.App = {
                     <div class="header-left">
                         <select class="p-type-select" onchange="App.updateProxyType(${index}, this.value)">
                             <option value="http" ${p.type === 'http' ? 'selected' : ''}>HTTP</option>
+                            <option value="https" ${p.type === 'https' ? 'selected' : ''}>HTTPS</option>
                             <option value="tcp" ${p.type === 'tcp' ? 'selected' : ''}>TCP</option>
                             <option value="udp" ${p.type === 'udp' ? 'selected' : ''}>UDP</option>
+                            <option value="tcpmux" ${p.type === 'tcpmux' ? 'selected' : ''}>TCPMUX</option>
+                            <option value="stcp" ${p.type === 'stcp' ? 'selected' : ''}>STCP</option>
                         </select>
                         <span class="proxy-type-tag type-${p.type}">${p.type.toUpperCase()}</span>
+                        <span class="proxy-state-badge" data-rule-id="${p.id || ''}"></span>
+                    </div>
+                    <div class="rule-style">
+                        <select title="图标" onchange="App.state.proxyList[${index}].icon = this.value">
+                            <option value="">无图标</option>
+                            ${Object.entries(this.state.ruleIcons).map(([k, v]) =>
+                                `<option value="${k}" ${p.icon === k ? 'selected' : ''}>${v} ${k}</option>`).join('')}
+                        </select>
+                        <select title="颜色" onchange="App.state.proxyList[${index}].color = this.value; App.renderProxies()">
+                            ${['', 'red', 'orange', 'yellow', 'green', 'blue', 'purple'].map(c =>
+                                `<option value="${c}" ${(p.color || '') === c ? 'selected' : ''}>${{ '': '无颜色', red: '红', orange: '橙', yellow: '黄', green: '绿', blue: '蓝', purple: '紫' }[c]}</option>`).join('')}
+                        </select>
                     </div>
+                    ${p.id ? `<button class="btn-toolbar" onclick="App.shareRuleFor(${index})"
+                        title="${p.expiresAt && !p.expiresAt.startsWith('0001') ? '将于 ' + new Date(p.expiresAt).toLocaleString() + ' 自动停用' : '启用规则，到期后自动停用'}">
+                        ${p.expiresAt && !p.expiresAt.startsWith('0001') ? '⏰ ' + new Date(p.expiresAt).toLocaleTimeString([], { hour: '2-digit', minute: '2-digit' }) + ' 到期' : '限时分享'}
+                    </button>` : ''}
                     <button class="btn-delete-text" onclick="App.removeProxy(${index})">
                         <span class="icon">🗑️</span> 删除
                     </button>
@@ -244,7 +1291,10 @@ window.App = {
                 <div class="form-grid-2">
                     <div class="form-group-mini">
                         <label>规则名称</label>
-                        <input type="text" value="${p.name || ''}" oninput="App.state.proxyList[${index}].name = this.value">
+                        <input type="text" value="${p.name || ''}" title="${this.templateHint()}" oninput="App.state.proxyList[${index}].name = this.value">
+                        <input type="text" class="wire-name-input" value="${this.escapeHTML(p.wireName || '')}" placeholder="代理名：名称含中文、空格等时自动生成"
+                               title="写入 frpc.toml 的代理名，只能包含字母、数字、点、下划线与连字符；留空时使用规则名称或自动生成"
+                               oninput="App.state.proxyList[${index}].wireName = this.value.trim()">
                     </div>
                     <div class="form-group-mini">
                         <label>本地端口</label>
@@ -254,19 +1304,121 @@ window.App = {
     
                 <!-- 根据类型切换显示的参数组 -->
                 <div class="domain-group" style="display: ${isHTTP ? 'block' : 'none'}; margin-top: 10px;">
-                    <label>自定义域名 (Custom Domains)</label>
-                    <input type="text" placeholder="e.g. web.example.com" 
+                    <label>自定义域名 (多个用逗号分隔，支持 *.example.com)</label>
+                    <input type="text" placeholder="e.g. web.example.com, *.example.com" 
                            value="${p.customDomains || ''}" 
                            oninput="App.state.proxyList[${index}].customDomains = this.value">
+                    <p class="telemetry-desc" style="display: ${p.type === 'tcpmux' ? 'block' : 'none'};">多个 TCP 服务共用服务器的 tcpmuxHTTPConnectPort，访问方通过 HTTP CONNECT 代理按域名连接</p>
                 </div>
     
-                <div class="port-group" style="display: ${!isHTTP ? 'block' : 'none'}; margin-top: 10px;">
+                <div class="form-grid-2 access-group" style="display: ${p.type === 'udp' ? 'none' : 'grid'}; margin-top: 10px;"
+                     title="按访问者 IP 过滤，支持 CIDR，多个用逗号分隔；放行列表非空时只允许其中的地址">
+                    <div class="form-group-mini">
+                        <label>只允许来源</label>
+                        <input type="text" placeholder="不限制，如 203.0.113.8, 10.0.0.0/8" value="${this.escapeHTML((p.allowIPs || []).join(', '))}"
+                               oninput="App.state.proxyList[${index}].allowIPs = this.value.split(',').map(s => s.trim()).filter(Boolean)">
+                    </div>
+                    <div class="form-group-mini">
+                        <label>拒绝来源</label>
+                        <input type="text" placeholder="不拒绝" value="${this.escapeHTML((p.denyIPs || []).join(', '))}"
+                               oninput="App.state.proxyList[${index}].denyIPs = this.value.split(',').map(s => s.trim()).filter(Boolean)">
+                    </div>
+                </div>
+
+                <div class="request-log-group" style="display: ${p.type === 'http' ? 'block' : 'none'}; margin-top: 10px;">
+                    <label class="mini-switch" title="经本机记录代理转发，可在主页“访问记录”中查看最近的请求">
+                        <input type="checkbox" ${p.requestLog ? 'checked' : ''} onchange="App.state.proxyList[${index}].requestLog = this.checked">
+                        <span class="mini-switch-text">记录访问请求 (路径、状态码、来源 IP)</span>
+                    </label>
+                </div>
+
+                <div class="https-group" style="display: ${p.type === 'https' ? 'block' : 'none'}; margin-top: 10px;">
+                    <label class="mini-switch">
+                        <input type="checkbox" ${p.httpsTerminate ? 'checked' : ''} onchange="App.state.proxyList[${index}].httpsTerminate = this.checked; App.renderProxies()">
+                        <span class="mini-switch-text">由 frpc 终止 HTTPS (本地服务只需提供 HTTP)</span>
+                    </label>
+                    <div class="form-grid-2" style="display: ${p.httpsTerminate ? 'grid' : 'none'}; margin-top: 6px;">
+                        <div class="form-group-mini">
+                            <label>证书文件</label>
+                            <input type="text" placeholder="/path/to/fullchain.pem" value="${this.escapeHTML(p.certFile || '')}"
+                                   oninput="App.state.proxyList[${index}].certFile = this.value.trim()">
+                        </div>
+                        <div class="form-group-mini">
+                            <label>私钥文件</label>
+                            <input type="text" placeholder="/path/to/privkey.pem" value="${this.escapeHTML(p.keyFile || '')}"
+                                   oninput="App.state.proxyList[${index}].keyFile = this.value.trim()">
+                        </div>
+                    </div>
+                </div>
+
+                <div class="form-grid-2 plugin-group" style="margin-top: 10px;">
+                    <div class="form-group-mini">
+                        <label>客户端插件</label>
+                        <input type="text" list="frpc-plugin-list" placeholder="不使用插件" value="${this.escapeHTML(p.plugin || '')}"
+                               oninput="App.state.proxyList[${index}].plugin = this.value.trim()">
+                    </div>
+                    <div class="form-group-mini">
+                        <label>插件参数 (每行一个 key=value)</label>
+                        <textarea rows="2" placeholder="如 localPath=/srv/files" oninput="App.state.proxyList[${index}].pluginParams = App.parsePluginParams(this.value)">${this.escapeHTML(this.formatPluginParams(p.pluginParams))}</textarea>
+                    </div>
+                </div>
+
+                <div class="stcp-group" style="display: ${p.type === 'stcp' ? 'block' : 'none'}; margin-top: 10px;">
+                    <div class="form-grid-2">
+                        <div class="form-group-mini">
+                            <label>密钥 (访问方 visitor 填写相同的值)</label>
+                            <input type="password" value="${this.escapeHTML(p.secretKey || '')}"
+                                   oninput="App.state.proxyList[${index}].secretKey = this.value.trim()">
+                        </div>
+                        <div class="form-group-mini">
+                            <label>密钥来源 (可选)</label>
+                            <input type="text" placeholder="如 env:MOLE_STCP_KEY" value="${this.escapeHTML(p.secretKeySource || '')}"
+                                   oninput="App.state.proxyList[${index}].secretKeySource = this.value.trim()">
+                        </div>
+                    </div>
+                    <div class="form-group-mini" style="margin-top: 6px;">
+                        <label>允许的其他 frp 用户 (多个用逗号分隔，* 为所有用户)</label>
+                        <input type="text" placeholder="只允许同一用户" value="${this.escapeHTML((p.allowUsers || []).join(', '))}"
+                               oninput="App.state.proxyList[${index}].allowUsers = this.value.split(',').map(s => s.trim()).filter(Boolean)">
+                    </div>
+                    <p class="telemetry-desc">服务器不开放公网端口，访问方需在自己的 frpc 中添加 stcp visitor，serverName 填写本规则的代理名</p>
+                </div>
+
+                <div class="port-group" style="display: ${!isHTTP && p.type !== 'stcp' ? 'block' : 'none'}; margin-top: 10px;">
                     <label>远程端口 (Remote Port)</label>
                     <input type="number" placeholder="e.g. 8080" 
                            value="${p.remotePort || ''}" 
                            oninput="App.state.proxyList[${index}].remotePort = parseInt(this.value)||8080">
                 </div>
     
+                <div class="form-group-mini" style="margin-top: 10px;">
+                    <label>说明</label>
+                    <textarea class="rule-description" rows="2" maxlength="500" placeholder="记录这条规则的用途，例如：给客户演示用的测试站"
+                              oninput="App.state.proxyList[${index}].description = this.value">${this.escapeHTML(p.description || '')}</textarea>
+                </div>
+
+                <div class="form-group-mini" style="margin-top: 10px;">
+                    <label>文件夹 (多级用 / 分隔)</label>
+                    <input type="text" list="rule-folder-list" placeholder="不放入文件夹" value="${this.escapeHTML(p.folder || '')}"
+                           onchange="App.state.proxyList[${index}].folder = this.value.trim(); App.renderProxies()">
+                </div>
+
+                <div class="form-grid-2" style="margin-top: 10px;">
+                    <div class="form-group-mini">
+                        <label>启动顺序</label>
+                        <select onchange="App.state.proxyList[${index}].startAfter = this.value">
+                            <option value="">不依赖其他规则</option>
+                            ${this.state.proxyList.filter(o => o.id && o.id !== p.id).map(o =>
+                                `<option value="${o.id}" ${p.startAfter === o.id ? 'selected' : ''}>在 "${this.escapeHTML(o.name)}" 上线之后</option>`).join('')}
+                        </select>
+                    </div>
+                    <div class="form-group-mini">
+                        <label>启动延迟 (秒)</label>
+                        <input type="number" min="0" max="600" value="${p.startDelay || 0}"
+                               oninput="App.state.proxyList[${index}].startDelay = parseInt(this.value)||0">
+                    </div>
+                </div>
+
                 <div class="proxy-footer">
                     <div class="status-indicator">
                         <span class="tiny-label">Local IP</span>
@@ -275,12 +1427,102 @@ window.App = {
                     </div>
                 </div>
             `;
-            container.appendChild(card);
+            cards.push(card);
         });
+        this.layoutProxies(container, cards);
+        this.state.proxyList.forEach(p => this.renderProxyState(p.id));
 
         this.renderAddButton(); // 更新“添加”按钮状态
     },
 
+    // 插件参数在界面上按 key=value 每行一个编辑，原样交给后端
+    parsePluginParams(text) {
+        const params = {};
+        text.split('\n').forEach(line => {
+            const i = line.indexOf('=');
+            if (i > 0) params[line.slice(0, i).trim()] = line.slice(i + 1).trim();
+        });
+        return params;
+    },
+
+    formatPluginParams(params) {
+        return Object.entries(params || {}).map(([k, v]) => `${k}=${v}`).join('\n');
+    },
+
+    // 按文件夹排列规则卡片：不在文件夹中的规则在最前，文件夹按保存的顺序逐级缩进，折叠的文件夹隐藏其内容
+    layoutProxies(container, cards) {
+        // 界面上新填写的文件夹先排在末尾，保存后由后端补齐上级
+        const folders = [...this.state.folders];
+        this.state.proxyList.forEach(p => {
+            if (p.folder && !folders.some(f => f.path === p.folder)) folders.push({ path: p.folder, collapsed: false });
+        });
+        const collapsed = new Set(folders.filter(f => f.collapsed).map(f => f.path));
+        const hidden = path => path.split('/').some((_, i, parts) => collapsed.has(parts.slice(0, i + 1).join('/')));
+        const parentHidden = path => path.includes('/') && hidden(path.slice(0, path.lastIndexOf('/')));
+
+        this.state.proxyList.forEach((p, i) => { if (!p.folder) container.appendChild(cards[i]); });
+        folders.forEach(f => {
+            const depth = f.path.split('/').length - 1;
+            const rules = this.state.proxyList.map((p, i) => [p, i]).filter(([p]) => p.folder === f.path);
+            const header = document.createElement('div');
+            header.className = 'rule-folder' + (f.collapsed ? ' collapsed' : '');
+            header.style.marginLeft = `${depth * 16}px`;
+            header.style.display = parentHidden(f.path) ? 'none' : '';
+            header.innerHTML = `
+                <span class="rule-folder-arrow">${f.collapsed ? '▸' : '▾'}</span>
+                <span class="rule-folder-name">📂 ${this.escapeHTML(f.path.split('/').pop())}</span>
+                <span class="rule-folder-count">${rules.length}</span>
+            `;
+            header.onclick = () => this.toggleFolder(f.path);
+            container.appendChild(header);
+            rules.forEach(([, i]) => {
+                cards[i].style.marginLeft = `${(depth + 1) * 16}px`;
+                cards[i].style.display = hidden(f.path) ? 'none' : '';
+                container.appendChild(cards[i]);
+            });
+        });
+
+        let list = document.getElementById('rule-folder-list');
+        if (!list) {
+            list = document.createElement('datalist');
+            list.id = 'rule-folder-list';
+            document.body.appendChild(list);
+        }
+        list.innerHTML = folders.map(f => `<option value="${this.escapeHTML(f.path)}"></option>`).join('');
+    },
+
+    // 折叠 / 展开文件夹；已保存的文件夹立即记录状态，新建的文件夹随配置一起保存
+    async toggleFolder(path) {
+        let folder = this.state.folders.find(f => f.path === path);
+        if (!folder) {
+            folder = { path, collapsed: false };
+            this.state.folders.push(folder);
+        }
+        folder.collapsed = !folder.collapsed;
+        this.renderProxies();
+        if ((this.state.rawConfig?.folders || []).some(f => f.path === path)) {
+            try {
+                await SetFolderCollapsed(path, folder.collapsed);
+                const saved = this.state.rawConfig.folders.find(f => f.path === path);
+                saved.collapsed = folder.collapsed;
+            } catch (err) {
+                console.error('保存文件夹状态失败:', err);
+            }
+        }
+    },
+
+    // 渲染单条规则的运行状态徽标
+    renderProxyState(ruleID) {
+        if (!ruleID) return;
+        const badge = document.querySelector(`.proxy-state-badge[data-rule-id="${ruleID}"]`);
+        if (!badge) return;
+        const st = this.state.proxyStates[ruleID];
+        const labels = { pending: "连接中", running: "在线", error: "异常", removed: "已移除", stopped: "已停止", paused: "已暂停", waiting: "等待中" };
+        badge.className = "proxy-state-badge" + (st ? ` state-${st.state}` : "");
+        badge.innerText = st ? (labels[st.state] || st.state) : "";
+        badge.title = st?.message || "";
+    },
+
     // 添加代理
     addProxy() {
         if (this.state.proxyList.length >= 3) {
@@ -288,6 +1530,7 @@ window.App = {
             return;
         }
         const newProxy = {
+            enabled: true,
             name: "web_" + Math.floor(Math.random() * 1000),
             type: "http",
             localIP: "127.0.0.1",
@@ -299,12 +1542,232 @@ window.App = {
     },
 
     // 删除代理
+    // 限时分享：启用规则并在 N 小时后自动停用，输入 0 取消到期时间
+    async shareRuleFor(index) {
+        const p = this.state.proxyList[index];
+        const input = prompt(`分享规则 "${p.name}" 多少小时？到期后自动停用 (0 为取消限时)\n未保存的修改会被丢弃`, '2');
+        if (input === null) return;
+        try {
+            await ShareRuleFor(p.id, parseInt(input) || 0);
+            await this.refreshStatus();
+        } catch (err) {
+            this.appendLogs('限时分享失败: ' + (err?.message || err));
+        }
+    },
+
     removeProxy(index) {
         this.state.proxyList.splice(index, 1);
         this.renderProxies();
         this.appendLogs("规则已移除快照，请点击保存生效");
     },
 
+    // 渲染消息通知渠道：Telegram 填 Bot Token + Chat ID，其余填 Webhook 地址
+    renderNotifyChannels() {
+        const container = document.getElementById('notify-list');
+        if (!container) return;
+        container.innerHTML = '';
+
+        const types = { telegram: "Telegram", slack: "Slack", dingtalk: "钉钉", wecom: "企业微信" };
+        const events = { down: "断开", up: "连接", reconnect: "恢复", expiring: "分享到期" };
+
+        this.state.notifyChannels.forEach((c, index) => {
+            const row = document.createElement('div');
+            row.className = 'notify-row';
+            const isTelegram = c.type === 'telegram';
+            const watched = c.events?.length ? c.events : Object.keys(events);
+
+            row.innerHTML = `
+                <div class="notify-row-head">
+                    <select onchange="App.updateNotifyType(${index}, this.value)">
+                        ${Object.entries(types).map(([v, label]) => `<option value="${v}" ${c.type === v ? 'selected' : ''}>${label}</option>`).join('')}
+                    </select>
+                    <label class="mini-switch">
+                        <input type="checkbox" ${c.enabled ? 'checked' : ''} onchange="App.state.notifyChannels[${index}].enabled = this.checked">
+                        <span class="mini-switch-text">启用</span>
+                    </label>
+                    ${Object.entries(events).map(([k, label]) => `
+                        <label class="mini-switch">
+                            <input type="checkbox" ${watched.includes(k) ? 'checked' : ''} onchange="App.toggleNotifyEvent(${index}, '${k}', this.checked)">
+                            <span class="mini-switch-text">${label}</span>
+                        </label>`).join('')}
+                    <button class="btn btn-outline" onclick="App.testNotifyChannel(${index})">发送测试</button>
+                    <button class="btn-delete-text" onclick="App.removeNotifyChannel(${index})">
+                        <span class="icon">🗑️</span> 删除
+                    </button>
+                </div>
+                <div class="form-grid-2" style="display: ${isTelegram ? 'grid' : 'none'};">
+                    <div class="form-group-mini">
+                        <label>Bot Token</label>
+                        <input type="password" value="${c.botToken || ''}" oninput="App.state.notifyChannels[${index}].botToken = this.value.trim()">
+                    </div>
+                    <div class="form-group-mini">
+                        <label>Chat ID</label>
+                        <input type="text" value="${c.chatID || ''}" oninput="App.state.notifyChannels[${index}].chatID = this.value.trim()">
+                    </div>
+                </div>
+                <div class="form-grid-2" style="display: ${isTelegram ? 'none' : 'grid'};">
+                    <div class="form-group-mini">
+                        <label>Webhook 地址</label>
+                        <input type="text" value="${c.webhook || ''}" oninput="App.state.notifyChannels[${index}].webhook = this.value.trim()">
+                    </div>
+                    <div class="form-group-mini" style="visibility: ${c.type === 'dingtalk' ? 'visible' : 'hidden'};">
+                        <label>加签密钥 (可留空)</label>
+                        <input type="password" value="${c.secret || ''}" oninput="App.state.notifyChannels[${index}].secret = this.value.trim()">
+                    </div>
+                </div>
+            `;
+            container.appendChild(row);
+        });
+    },
+
+    renderPlugins() {
+        const container = document.getElementById('plugin-list');
+        if (!container) return;
+        const events = { connected: "已连接", disconnected: "已断开", "tunnel-down": "意外断开", "proxy-up": "规则上线", "proxy-down": "规则异常", "quota-exceeded": "内存超限" };
+        container.innerHTML = this.state.plugins.map((p, index) => {
+            const watched = p.events?.length ? p.events : Object.keys(events);
+            return `
+            <div class="notify-row">
+                <div class="notify-row-head">
+                    <input type="text" value="${this.escapeHTML(p.name || '')}" placeholder="名称" oninput="App.state.plugins[${index}].name = this.value.trim()">
+                    <label class="mini-switch">
+                        <input type="checkbox" ${p.enabled ? 'checked' : ''} onchange="App.state.plugins[${index}].enabled = this.checked">
+                        <span class="mini-switch-text">启用</span>
+                    </label>
+                    ${Object.entries(events).map(([k, label]) => `
+                        <label class="mini-switch">
+                            <input type="checkbox" ${watched.includes(k) ? 'checked' : ''} onchange="App.togglePluginEvent(${index}, '${k}', this.checked)">
+                            <span class="mini-switch-text">${label}</span>
+                        </label>`).join('')}
+                    <button class="btn btn-outline" onclick="App.testPlugin(${index})">测试运行</button>
+                    <button class="btn-delete-text" onclick="App.removePlugin(${index})">
+                        <span class="icon">🗑️</span> 删除
+                    </button>
+                </div>
+                <div class="form-grid-2">
+                    <div class="form-group-mini">
+                        <label>命令 (脚本或程序路径)</label>
+                        <input type="text" value="${this.escapeHTML(p.command || '')}" placeholder="/home/me/ddns-update.sh" oninput="App.state.plugins[${index}].command = this.value.trim()">
+                    </div>
+                    <div class="form-group-mini">
+                        <label>参数 (空格分隔) / 超时 (秒)</label>
+                        <div class="form-grid-2">
+                            <input type="text" value="${this.escapeHTML((p.args || []).join(' '))}" oninput="App.state.plugins[${index}].args = this.value.split(/\s+/).filter(Boolean)">
+                            <input type="number" min="0" value="${p.timeout || ''}" placeholder="30" oninput="App.state.plugins[${index}].timeout = parseInt(this.value) || 0">
+                        </div>
+                    </div>
+                </div>
+            </div>`;
+        }).join('');
+    },
+
+    collectHook(kind) {
+        return {
+            command: document.getElementById(`hook-${kind}-command`).value.trim(),
+            args: document.getElementById(`hook-${kind}-args`).value.split(/\s+/).filter(Boolean),
+            timeout: parseInt(document.getElementById(`hook-${kind}-timeout`).value) || 0
+        };
+    },
+
+    async testHook(kind) {
+        try {
+            await TestLifecycleHook(kind, this.collectHook(kind));
+            this.appendLogs(`${kind} 钩子运行完成`);
+        } catch (err) {
+            this.appendLogs(`${kind} 钩子测试失败: ` + (err?.message || err));
+        }
+    },
+
+    addPlugin() {
+        this.state.plugins.push({ name: "plugin_" + (this.state.plugins.length + 1), enabled: true, command: "", args: [], events: [] });
+        this.renderPlugins();
+    },
+
+    removePlugin(index) {
+        this.state.plugins.splice(index, 1);
+        this.renderPlugins();
+        this.appendLogs("插件已移除，请点击保存生效");
+    },
+
+    // 与通知渠道相同：全选时保存为空列表，表示订阅全部事件
+    togglePluginEvent(index, event, on) {
+        const all = ["connected", "disconnected", "tunnel-down", "proxy-up", "proxy-down", "quota-exceeded"];
+        const p = this.state.plugins[index];
+        const current = new Set(p.events?.length ? p.events : all);
+        on ? current.add(event) : current.delete(event);
+        p.events = current.size === all.length ? [] : all.filter(k => current.has(k));
+        if (current.size === 0) p.enabled = false;
+        this.renderPlugins();
+    },
+
+    async testPlugin(index) {
+        try {
+            await TestPlugin(this.state.plugins[index]);
+            this.appendLogs("插件测试运行完成");
+        } catch (err) {
+            this.appendLogs("插件测试失败: " + (err?.message || err));
+        }
+    },
+
+    addNotifyChannel() {
+        this.state.notifyChannels.push({ type: "telegram", enabled: true, events: [] });
+        this.renderNotifyChannels();
+    },
+
+    removeNotifyChannel(index) {
+        this.state.notifyChannels.splice(index, 1);
+        this.renderNotifyChannels();
+        this.appendLogs("通知渠道已移除，请点击保存生效");
+    },
+
+    updateNotifyType(index, type) {
+        this.state.notifyChannels[index].type = type;
+        this.renderNotifyChannels();
+    },
+
+    // 事件全选时保存为空列表，表示订阅全部 (包括以后新增的事件类型)
+    toggleNotifyEvent(index, kind, on) {
+        const all = ["down", "up", "reconnect"];
+        const c = this.state.notifyChannels[index];
+        const current = new Set(c.events?.length ? c.events : all);
+        on ? current.add(kind) : current.delete(kind);
+        c.events = current.size === all.length ? [] : all.filter(k => current.has(k));
+        if (current.size === 0) c.enabled = false;
+        this.renderNotifyChannels();
+    },
+
+    async testNotifyChannel(index) {
+        try {
+            await TestNotifyChannel(this.state.notifyChannels[index]);
+            this.appendLogs("测试消息已发送");
+        } catch (err) {
+            this.appendLogs("测试失败: " + err);
+        }
+    },
+
+    collectEmailConfig() {
+        return {
+            ...this.state.rawConfig?.email,
+            enabled: document.getElementById('email-enabled').checked,
+            host: document.getElementById('email-host').value.trim(),
+            port: parseInt(document.getElementById('email-port').value) || 0,
+            username: document.getElementById('email-username').value.trim(),
+            password: document.getElementById('email-password').value,
+            to: document.getElementById('email-to').value.split(/[,，;\s]+/).filter(Boolean),
+            downAfterMinutes: parseInt(document.getElementById('email-down-after').value) || 0,
+            minIntervalMinutes: parseInt(document.getElementById('email-min-interval').value) || 0
+        };
+    },
+
+    async testEmail() {
+        try {
+            await TestEmail(this.collectEmailConfig());
+            this.appendLogs("测试邮件已发送");
+        } catch (err) {
+            this.appendLogs("测试失败: " + err);
+        }
+    },
+
     // 保存配置
     async saveAllConfig() {
         if (this.state.isProcessing) return;
@@ -331,13 +1794,26 @@ window.App = {
                 statusMsg.style.color = "var(--danger)";
                 return;
             }
-            if (p.type === 'http' && !p.customDomains?.trim()) {
-                this.appendLogs(`保存失败：HTTP 规则 "${p.name}" 必须填写域名`);
-                statusMsg.innerText = `❌ 保存失败：HTTP 规则 "${p.name}" 必须填写域名`;
+            const byDomain = ['http', 'https', 'tcpmux'].includes(p.type);
+            if (byDomain && !p.customDomains?.trim()) {
+                this.appendLogs(`保存失败：${p.type.toUpperCase()} 规则 "${p.name}" 必须填写域名`);
+                statusMsg.innerText = `❌ 保存失败：${p.type.toUpperCase()} 规则 "${p.name}" 必须填写域名`;
                 statusMsg.style.color = "var(--danger)";
                 return;
             }
-            if (p.type !== 'http' && (!p.remotePort || p.remotePort <= 0)) {
+            if (p.type === 'https' && p.httpsTerminate && (!p.certFile || !p.keyFile)) {
+                this.appendLogs(`保存失败：HTTPS 规则 "${p.name}" 需要填写证书和私钥文件`);
+                statusMsg.innerText = `❌ 保存失败：HTTPS 规则 "${p.name}" 需要填写证书和私钥文件`;
+                statusMsg.style.color = "var(--danger)";
+                return;
+            }
+            if (p.type === 'stcp' && p.enabled && !p.secretKey && !p.secretKeySource) {
+                this.appendLogs(`保存失败：STCP 规则 "${p.name}" 必须填写密钥`);
+                statusMsg.innerText = `❌ 保存失败：STCP 规则 "${p.name}" 必须填写密钥`;
+                statusMsg.style.color = "var(--danger)";
+                return;
+            }
+            if (!byDomain && p.type !== 'stcp' && (!p.remotePort || p.remotePort <= 0)) {
                 this.appendLogs(`保存失败：${p.type.toUpperCase()} 规则 "${p.name}" 必须填写远程端口`);
                 statusMsg.innerText = `❌ 保存失败：${p.type.toUpperCase()} 规则 "${p.name}" 必须填写远程端口`;
                 statusMsg.style.color = "var(--danger)";
@@ -355,8 +1831,10 @@ window.App = {
             };
 
             // 处理域名：将字符串转回后端需要的数组格式
-            if (type === 'http') {
-                mapped.domains = [customDomains.trim()];
+            if (['http', 'https', 'tcpmux'].includes(type)) {
+                mapped.domains = customDomains.split(',').map(d => d.trim()).filter(Boolean);
+            } else if (type === 'stcp') {
+                mapped.remotePort = 0; // STCP 不占用服务器端口
             } else {
                 mapped.remotePort = parseInt(p.remotePort);
             }
@@ -369,29 +1847,82 @@ window.App = {
             port: parseInt(document.getElementById('server-port').value),
             token: document.getElementById('server-token').value,
             autoStart: document.getElementById('server-autostart').checked,
-            remark: document.getElementById('server-remark').value
+            remark: document.getElementById('server-remark').value,
+            transport: document.getElementById('server-transport').value,
+            tokenSource: document.getElementById('server-token-source').value.trim(),
+            extraArgs: document.getElementById('server-extra-args').value.split(/\s+/).filter(Boolean),
+            user: document.getElementById('server-user').value.trim(),
+            namePrefix: document.getElementById('server-name-prefix').value.trim(),
+            tlsServerName: document.getElementById('server-tls-name').value.trim(),
+            tlsDisableCustomFirstByte: { "": null, "true": true, "false": false }[document.getElementById('server-tls-first-byte').value],
+            tlsTrustedCAFile: document.getElementById('server-tls-ca').value.trim(),
+            tlsPinSHA256: document.getElementById('server-tls-pin').value.trim()
+        };
+
+        const sshConfig = {
+            port: parseInt(document.getElementById('ssh-port').value) || 0,
+            user: document.getElementById('ssh-user').value.trim(),
+            password: document.getElementById('ssh-password').value,
+            keyFile: document.getElementById('ssh-keyfile').value.trim(),
+            hostKeyFingerprint: document.getElementById('ssh-hostkey').value.trim()
         };
 
+        const mqttConfig = {
+            ...this.state.rawConfig?.mqtt,
+            enabled: document.getElementById('mqtt-enabled').checked,
+            homeAssistant: document.getElementById('mqtt-ha').checked,
+            broker: document.getElementById('mqtt-broker').value.trim(),
+            topicPrefix: document.getElementById('mqtt-prefix').value.trim(),
+            username: document.getElementById('mqtt-username').value.trim(),
+            password: document.getElementById('mqtt-password').value
+        };
+
+        // 以原始配置为底，保留页面上未展示的配置段 (如内置 frps 服务端)
         const finalConfig = {
+            ...this.state.rawConfig,
             server: serverConfig,
+            ssh: sshConfig,
+            mqtt: mqttConfig,
+            notify: { channels: this.state.notifyChannels },
+            plugins: this.state.plugins,
+            hooks: {
+                preConnect: this.collectHook('pre-connect'),
+                postConnect: this.collectHook('post-connect'),
+                postDisconnect: this.collectHook('post-disconnect')
+            },
+            // 不再包含任何规则的文件夹随保存删除
+            folders: this.state.folders.filter(f => this.state.proxyList.some(p => p.folder === f.path || (p.folder || '').startsWith(f.path + '/'))),
+            email: this.collectEmailConfig(),
+            retry: {
+                maxRetries: parseInt(document.getElementById('retry-max').value) || 0,
+                interval: parseInt(document.getElementById('retry-interval').value) || 0,
+                multiplier: parseFloat(document.getElementById('retry-multiplier').value) || 0
+            },
+            preferences: {
+                ...this.state.rawConfig?.preferences,
+                autoPauseDownTargets: document.getElementById('pref-autopause').checked,
+                restartAt: document.getElementById('pref-restart-at').value,
+                waitForLocal: document.getElementById('pref-wait-local').checked,
+                waitForLocalTimeout: parseInt(document.getElementById('pref-wait-local-timeout').value) || 0,
+                frpcLowPriority: document.getElementById('pref-frpc-low-priority').checked,
+                frpcCpuAffinity: document.getElementById('pref-frpc-affinity').value.split(/[,，\s]+/).filter(Boolean).map(Number),
+                frpcMemoryLimitMB: parseInt(document.getElementById('pref-frpc-memory-limit').value) || 0,
+                frpcMemoryLimitMinutes: parseInt(document.getElementById('pref-frpc-memory-minutes').value) || 0
+            },
             proxies: proxiesForBackend // 直接使用内存中的最新快照
         };
 
         try {
-            // 3. 调用后端 Wails 接口
-            const success = await SaveUserConfig(finalConfig);
+            // 3. 调用后端 Wails 接口（后端仅更新内存并立即返回，落盘结果通过 config-save 事件通知）
+            await SaveUserConfig(finalConfig);
 
-            if (success) {
-                // 4. 更新“原始数据”备份，标记当前内存数据为最新
-                this.state.rawConfig = JSON.parse(JSON.stringify(finalConfig));
-
-                if (statusMsg) {
-                    statusMsg.innerText = "✅ 配置已保存，需要重新连接";
-                    statusMsg.style.color = "var(--primary)";
-                }
+            // 新增或改动过远程端口的 TCP 规则，后台探测一下是否已被他人占用 (不阻塞保存)
+            this.warnTakenRemotePorts(serverConfig.addr, proxiesForBackend, this.state.rawConfig);
+            this.warnDomainOverlaps(proxiesForBackend);
 
-                this.appendLogs("配置保存成功并已应用到内存");
-            }
+            // 4. 更新“原始数据”备份，标记当前内存数据为最新
+            this.state.rawConfig = JSON.parse(JSON.stringify(finalConfig));
+            this.appendLogs("配置已应用到内存，正在后台写入");
         } catch (err) {
             if (statusMsg) {
                 statusMsg.innerText = "❌ 保存失败";
@@ -408,6 +1939,156 @@ window.App = {
         }
     },
 
+    // 渲染配置落盘状态
+    async warnTakenRemotePorts(addr, proxies, previous) {
+        const oldPorts = new Map((previous?.proxies || []).map(p => [p.id, p.remotePort]));
+        const changed = proxies.filter(p =>
+            p.enabled && p.proxyType === 'tcp' &&
+            (previous?.server?.addr !== addr || oldPorts.get(p.id) !== p.remotePort));
+
+        for (const p of changed) {
+            try {
+                const res = await CheckRemotePort(addr, p.proxyType, p.remotePort, p.id);
+                if (res.state === 'taken') {
+                    this.appendLogs(`⚠️ 规则 "${p.name}"：${res.message}，建议更换远程端口`);
+                }
+            } catch (e) {
+                console.warn('远程端口检测失败', e);
+            }
+        }
+    },
+
+    // 通配域名与其他规则的域名重叠时提示实际由哪条规则处理
+    async warnDomainOverlaps(proxies) {
+        try {
+            const warnings = await CheckDomainOverlaps(proxies);
+            warnings.forEach(w => this.appendLogs(`⚠️ ${w}`));
+        } catch (e) {
+            console.warn('域名重叠检测失败', e);
+        }
+    },
+
+    // 导入内容来自外部文件，插入 HTML 前必须转义
+    escapeHTML(str) {
+        return String(str ?? '').replace(/[&<>"']/g, c => ({
+            '&': '&amp;', '<': '&lt;', '>': '&gt;', '"': '&quot;', "'": '&#39;'
+        })[c]);
+    },
+
+    showImportPreview(preview) {
+        this.state.pendingImport = preview;
+        const esc = (v) => this.escapeHTML(v);
+        const prof = preview.profile || {};
+        const rules = (prof.proxies || []).map(p =>
+            `<li>${esc(p.name)} · ${esc(p.proxyType).toUpperCase()} · ${esc(p.localIP)}:${esc(p.localPort)} → ${esc(['http', 'https', 'tcpmux'].includes(p.proxyType) ? (p.domains || []).join(', ') : p.proxyType === 'stcp' ? 'visitor' : p.remotePort)}</li>`
+        ).join('');
+        const warnings = (preview.warnings || []).map(w => `<li>⚠️ ${esc(w)}</li>`).join('');
+        const compatText = {
+            translated: '已自动转换为',
+            ignored: 'Mole 不使用，已忽略，新版写法为',
+            manual: '含义已变化，请手动改为',
+            unmapped: '无法转换为新版配置，请手动处理'
+        };
+        const compat = (preview.compat || []).map(c =>
+            `<li>${esc(c.option)}：${compatText[c.action] || ''}${c.replacement ? ` <code>${esc(c.replacement)}</code>` : ''}</li>`
+        ).join('');
+
+        document.getElementById('import-summary').innerHTML = `
+            <p>来源：${esc(preview.source)} (${esc(preview.format)})</p>
+            <p>服务器：${esc(prof.server?.addr || '-')}:${esc(prof.server?.port || '-')}${prof.server?.token ? '' : ' (未包含 Token)'}</p>
+            <ul>${rules || '<li>没有可导入的规则</li>'}</ul>
+            ${warnings ? `<ul class="import-warnings">${warnings}</ul>` : ''}
+            ${compat ? `<p>旧版选项：</p><ul class="import-compat">${compat}</ul>` : ''}
+        `;
+        document.getElementById('import-save-toml').style.display = preview.toml ? '' : 'none';
+        document.getElementById('import-modal').style.display = 'flex';
+    },
+
+    async confirmImport(replace) {
+        const preview = this.state.pendingImport;
+        if (!preview) return;
+        try {
+            await ConfirmImport(preview.id, replace);
+            this.closeImportModal();
+            await this.refreshStatus();
+        } catch (err) {
+            this.appendLogs("导入失败: " + err);
+        }
+    },
+
+    // 导出完整配置，格式由保存时选择的扩展名决定 (.toml / .yaml / .json)
+    async exportConfig() {
+        try {
+            const path = await ExportConfig();
+            if (path) this.appendLogs("配置已导出: " + path);
+        } catch (err) {
+            this.appendLogs("导出配置失败: " + err);
+        }
+    },
+
+    // 二维码图片导入：图片内容以 base64 传给后端解码，识别成功后按 import-preview 事件弹出预览
+    async importQRImage(input) {
+        const file = input.files[0];
+        input.value = '';
+        if (!file) return;
+        try {
+            const dataURL = await new Promise((resolve, reject) => {
+                const reader = new FileReader();
+                reader.onload = () => resolve(reader.result);
+                reader.onerror = () => reject(reader.error);
+                reader.readAsDataURL(file);
+            });
+            await ImportFromQRImage(dataURL.slice(dataURL.indexOf(',') + 1));
+        } catch (err) {
+            this.appendLogs("二维码导入失败: " + err);
+        }
+    },
+
+    async saveConvertedToml() {
+        const preview = this.state.pendingImport;
+        if (!preview) return;
+        try {
+            const path = await SaveConvertedToml(preview.id);
+            if (path) this.appendLogs("已保存转换后的配置: " + path);
+        } catch (err) {
+            this.appendLogs("保存失败: " + err);
+        }
+    },
+
+    async cancelImport() {
+        const preview = this.state.pendingImport;
+        this.closeImportModal();
+        if (preview) await CancelImport(preview.id);
+    },
+
+    closeImportModal() {
+        this.state.pendingImport = null;
+        document.getElementById('import-modal').style.display = 'none';
+    },
+
+    renderSaveState(evt) {
+        const statusMsg = document.getElementById('save-status');
+        if (!statusMsg || !evt) return;
+
+        switch (evt.state) {
+            case 'pending':
+            case 'saving':
+                statusMsg.innerText = "⏳ 正在保存...";
+                statusMsg.style.color = "var(--text-main)";
+                break;
+            case 'saved':
+                statusMsg.innerText = "✅ 配置已保存，需要重新连接";
+                statusMsg.style.color = "var(--primary)";
+                this.appendLogs("配置保存成功");
+                break;
+            case 'failed':
+                statusMsg.innerText = "❌ 保存失败";
+                statusMsg.style.color = "var(--danger)";
+                this.appendLogs("保存失败: " + evt.message);
+                break;
+        }
+    },
+
     updateProxyType(index, newType) {
         // 1. 只修改类型，保留其他字段（如 localPort, remotePort 等）
         this.state.proxyList[index].type = newType;
@@ -417,6 +2098,149 @@ window.App = {
     },
 
 
+    // 在落盘的历史日志中搜索 (由后端扫描)，结果替换实时视图显示
+    async searchLogs() {
+        const levels = document.getElementById('log-search-level').value;
+        const hours = parseInt(document.getElementById('log-search-since').value);
+        const query = {
+            pattern: document.getElementById('log-search').value,
+            regex: document.getElementById('log-search-regex').checked,
+            caseSensitive: false,
+            levels: levels ? levels.split(',') : [],
+            limit: 500
+        };
+        if (hours > 0) query.since = new Date(Date.now() - hours * 3600 * 1000).toISOString();
+
+        const box = document.getElementById('log-search-results');
+        let result;
+        try {
+            result = await SearchLogs(query);
+        } catch (err) {
+            this.appendLogs("搜索失败: " + err);
+            return;
+        }
+
+        document.getElementById('log-list').style.display = 'none';
+        document.getElementById('log-search-exit').style.display = '';
+        box.style.display = '';
+
+        const levelClass = { error: 'error', warn: 'warning', info: 'success' };
+        const highlight = (line, positions) => {
+            if (!positions?.length) return this.escapeHTML(line);
+            let html = '', last = 0;
+            positions.forEach(([from, to]) => {
+                html += this.escapeHTML(line.slice(last, from)) + '<mark>' + this.escapeHTML(line.slice(from, to)) + '</mark>';
+                last = to;
+            });
+            return html + this.escapeHTML(line.slice(last));
+        };
+        const summary = `扫描 ${result.scanned} 行，匹配 ${result.matches.length} 条` + (result.truncated ? ' (仅显示最新部分)' : '');
+        box.innerHTML = `<div class="log-search-summary">${summary}</div>` + result.matches.map(m => `
+            <div class="log-item ${levelClass[m.level] || 'system'}">
+                <div class="log-meta">
+                    <span class="log-time">${new Date(m.time).toLocaleString('zh-CN', { hour12: false })}</span>
+                    <span class="log-tag">[${m.level.toUpperCase()}]</span>
+                </div>
+                <div class="log-content">${highlight(m.line, m.positions)}</div>
+            </div>`).join('');
+        box.scrollTop = box.scrollHeight;
+    },
+
+    renderHighlightRules() {
+        const container = document.getElementById('highlight-list');
+        if (!container) return;
+        const severities = { '': '不改变级别', info: '信息', warn: '警告', error: '错误' };
+        const colors = { '': '默认颜色', red: '红', orange: '橙', yellow: '黄', green: '绿', blue: '蓝', purple: '紫' };
+        const options = (map, value) => Object.entries(map)
+            .map(([v, label]) => `<option value="${v}" ${value === v ? 'selected' : ''}>${label}</option>`).join('');
+
+        container.innerHTML = this.state.highlightRules.map((r, index) => `
+            <div class="highlight-row">
+                <input type="text" value="${this.escapeHTML(r.pattern || '')}" placeholder="文本或正则"
+                       oninput="App.state.highlightRules[${index}].pattern = this.value">
+                <label class="mini-switch">
+                    <input type="checkbox" ${r.regex ? 'checked' : ''} onchange="App.state.highlightRules[${index}].regex = this.checked">
+                    <span class="mini-switch-text">正则</span>
+                </label>
+                <select onchange="App.state.highlightRules[${index}].severity = this.value">${options(severities, r.severity || '')}</select>
+                <select onchange="App.state.highlightRules[${index}].color = this.value">${options(colors, r.color || '')}</select>
+                <button class="btn-delete-text" onclick="App.removeHighlightRule(${index})">删除</button>
+            </div>`).join('');
+    },
+
+    async saveLogForwardLevel() {
+        try {
+            await SetLogForwardLevel(document.getElementById('log-forward-level').value);
+            await this.refreshStatus();
+        } catch (err) {
+            this.appendLogs("保存日志设置失败: " + (err?.message || err));
+        }
+    },
+
+    async saveLogTimeFormat() {
+        try {
+            await SetLogTimeFormat(
+                document.getElementById('log-time-zone').value,
+                document.getElementById('log-time-format').value,
+                document.getElementById('log-strip-frpc-time').checked
+            );
+            await this.refreshStatus();
+        } catch (err) {
+            this.appendLogs("保存日志设置失败: " + (err?.message || err));
+        }
+    },
+
+    addHighlightRule() {
+        this.state.highlightRules.push({ pattern: '', regex: false, severity: '', color: 'yellow' });
+        this.renderHighlightRules();
+    },
+
+    removeHighlightRule(index) {
+        this.state.highlightRules.splice(index, 1);
+        this.renderHighlightRules();
+    },
+
+    async saveHighlightRules() {
+        try {
+            await SetLogHighlights(this.state.highlightRules);
+            await this.refreshStatus();
+            this.appendLogs("日志高亮规则已保存");
+        } catch (err) {
+            this.appendLogs("保存高亮规则失败: " + (err?.message || err));
+        }
+    },
+
+    exitLogSearch() {
+        document.getElementById('log-search-results').style.display = 'none';
+        document.getElementById('log-search-exit').style.display = 'none';
+        const list = document.getElementById('log-list');
+        list.style.display = '';
+        list.scrollTop = list.scrollHeight;
+    },
+
+    // 暂停时后端缓存新日志，界面换成最近日志的快照，可以自由回翻和复制；继续时补发缓存的日志
+    async toggleLogPause() {
+        const btn = document.getElementById('log-pause');
+        try {
+            if (this.state.logPaused) {
+                this.state.logPaused = false;
+                btn.innerText = '暂停';
+                await ResumeLogStream();
+                return;
+            }
+            await PauseLogStream();
+            this.state.logPaused = true;
+            btn.innerText = '继续';
+            const snap = await GetLogSnapshot();
+            const entries = snap.entries.map(e => this.toLogEntry(e));
+            this.state.logs = entries.slice(-this.state.maxLogCount);
+            document.getElementById('log-list').innerHTML = '';
+            this.renderNewLogs(entries, entries.length);
+        } catch (err) {
+            this.appendLogs('切换日志暂停失败: ' + (err?.message || err));
+        }
+    },
+
     clearLogs() {
         // 1. 核心操作：清空内存中的日志数组
         this.state.logs = [];
@@ -433,7 +2257,7 @@ window.App = {
         console.log("Wails 2026: Logs cleared.");
     },
 
-    renderNewLogs(newLogs) {
+    renderNewLogs(newLogs, limit = this.state.maxLogCount) {
         const list = document.getElementById('log-list');
         if (!list) return;
 
@@ -441,7 +2265,7 @@ window.App = {
 
         newLogs.forEach(log => {
             const item = document.createElement('div');
-            item.className = `log-item ${log.level}`;
+            item.className = `log-item ${log.level}` + (log.color ? ` hl-${log.color}` : '');
             item.innerHTML = `
             <div class="log-meta">
                 <span class="log-time">${log.time}</span>
@@ -455,12 +2279,12 @@ window.App = {
         list.appendChild(fragment);
 
         // 4. 清理多余的旧 DOM 节点 (保持 DOM 树轻量)
-        while (list.children.length > this.state.maxLogCount) {
+        while (list.children.length > Math.max(limit, this.state.maxLogCount)) {
             list.removeChild(list.firstChild);
         }
 
-        // 5. 滚动到底部
-        list.scrollTop = list.scrollHeight;
+        // 5. 滚动到底部，暂停时保持用户当前的位置
+        if (!this.state.logPaused) list.scrollTop = list.scrollHeight;
     },
 
     /**
@@ -497,9 +2321,12 @@ window.App = {
 
         // 优化：直接从内存 state 中查找第一个 HTTP 代理
         const httpProxy = this.state.proxyList.find(p => p.type === 'http' && p.customDomains);
+        // 通配域名不能直接访问，优先展示精确域名
+        const domains = (httpProxy?.customDomains || '').split(',').map(d => d.trim()).filter(Boolean);
+        const domain = domains.find(d => !d.startsWith('*.')) || domains[0];
 
-        if (this.state.isRunning && httpProxy) {
-            document.getElementById('subdomain-url').innerText = "https://" + httpProxy.customDomains;
+        if (this.state.isRunning && domain) {
+            document.getElementById('subdomain-url').innerText = "https://" + domain;
             panel.style.display = 'block';
         } else {
             panel.style.display = 'none';
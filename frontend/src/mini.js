@@ -0,0 +1,67 @@
+/**
+ * 迷你状态窗：连接状态、在线规则数、平均延迟与连接开关
+ */
+
+import { Events } from "@wailsio/runtime";
+import { Connect, Disconnect, GetStatus, GetProxyStates, GetProxyLatency, ToggleMiniWindow, GetTemplateVars } from "../bindings/mole/moleservice";
+
+window.Mini = {
+    running: false,
+    busy: false,
+    vars: null,         // 模板变量，备注中的 {{hostname}} 等
+
+    init() {
+        Events.On('frp-status', () => this.refresh());
+        Events.On('tunnel-state', () => this.refresh());
+        Events.On('proxy-state', () => this.refresh());
+        Events.On('config-changed', () => this.refresh());
+        this.refresh();
+        // 延迟采样没有事件，定时刷新
+        setInterval(() => this.refresh(), 15000);
+    },
+
+    async refresh() {
+        const st = await GetStatus();
+        this.running = st.isRunning;
+        if (!this.vars) this.vars = await GetTemplateVars().catch(() => ({}));
+
+        const remark = (st.config?.server?.remark || '').replace(/\{\{\s*(\w+)\s*\}\}/g, (m, k) => this.vars[k.toLowerCase()] ?? m);
+        const server = remark || st.config?.server?.addr || '';
+        document.getElementById('mini-dot').className = 'mini-dot' + (st.isRunning ? ' on' : '');
+        const labels = { preparing: '准备中', connecting: '连接中', connected: '已连接', reconnecting: '重连中', stopping: '断开中', error: '连接失败' };
+        document.getElementById('mini-state').innerText = (labels[st.tunnelState] || '未连接') + (server ? ` · ${server}` : '');
+        document.getElementById('mini-toggle').innerText = st.isRunning ? '断开' : '连接';
+
+        let detail = '';
+        if (st.isRunning) {
+            const [states, latency] = await Promise.all([
+                GetProxyStates().catch(() => []),
+                GetProxyLatency().catch(() => [])
+            ]);
+            const online = states.filter(s => s.state === 'running').length;
+            const samples = latency.filter(l => l.last >= 0).map(l => l.last);
+            detail = `在线规则 ${online} / ${states.length}`;
+            if (samples.length) {
+                detail += ` · 延迟 ${Math.round(samples.reduce((a, b) => a + b, 0) / samples.length)} ms`;
+            }
+        }
+        document.getElementById('mini-detail').innerText = detail;
+    },
+
+    async toggle() {
+        if (this.busy) return;
+        this.busy = true;
+        try {
+            await (this.running ? Disconnect() : Connect());
+        } finally {
+            this.busy = false;
+            this.refresh();
+        }
+    },
+
+    hide() {
+        ToggleMiniWindow();
+    }
+};
+
+window.Mini.init();
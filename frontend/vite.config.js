@@ -4,4 +4,13 @@ import wails from "@wailsio/runtime/plugins/vite";
 // https://vitejs.dev/config/
 export default defineConfig({
   plugins: [wails("./bindings")],
+  // 主界面与迷你状态窗两个页面
+  build: {
+    rollupOptions: {
+      input: {
+        main: "index.html",
+        mini: "mini.html",
+      },
+    },
+  },
 });
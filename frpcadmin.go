@@ -0,0 +1,109 @@
+package main
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// frpc 自带的管理接口 (webServer)：只监听 127.0.0.1，用于不重启进程的热重载
+// 每次启动 frpc 都随机分配端口和密码，写进 frpc.toml
+
+// frpcAdmin 本次 frpc 进程的管理接口参数，Port 为 0 表示未启用
+type frpcAdmin struct {
+	Port     int
+	User     string
+	Password string
+}
+
+// newFrpcAdmin 选一个空闲端口并生成随机密码
+func newFrpcAdmin() (frpcAdmin, error) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return frpcAdmin{}, err
+	}
+	port := ln.Addr().(*net.TCPAddr).Port
+	_ = ln.Close()
+
+	buf := make([]byte, 16)
+	if _, err := rand.Read(buf); err != nil {
+		return frpcAdmin{}, err
+	}
+	return frpcAdmin{Port: port, User: "mole", Password: hex.EncodeToString(buf)}, nil
+}
+
+// tomlSection 生成 frpc.toml 的 webServer 段
+func (a frpcAdmin) tomlSection() map[string]any {
+	return map[string]any{
+		"addr":     "127.0.0.1",
+		"port":     a.Port,
+		"user":     a.User,
+		"password": a.Password,
+	}
+}
+
+// reload 让 frpc 重新读取 frpc.toml，增删改的代理即时生效
+func (a frpcAdmin) reload() error {
+	if err := a.get("/api/reload"); err != nil {
+		return fmt.Errorf("frpc 热重载失败: %v", err)
+	}
+	return nil
+}
+
+// ping 查询代理状态，用于确认 frpc 仍在正常工作，见 keepalive.go
+func (a frpcAdmin) ping() error {
+	return a.get("/api/status")
+}
+
+func (a frpcAdmin) get(path string) error {
+	if a.Port == 0 {
+		return fmt.Errorf("frpc 管理接口未启用")
+	}
+	req, err := http.NewRequest(http.MethodGet, "http://127.0.0.1:"+strconv.Itoa(a.Port)+path, nil)
+	if err != nil {
+		return err
+	}
+	req.SetBasicAuth(a.User, a.Password)
+
+	resp, err := (&http.Client{Timeout: 5 * time.Second}).Do(req)
+	if err != nil {
+		return fmt.Errorf("调用 frpc 管理接口失败: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("%s %s", resp.Status, strings.TrimSpace(string(msg)))
+	}
+	return nil
+}
+
+// hotReload 重新生成 frpc.toml 并通知运行中的 frpc 重载，未运行时只更新文件
+func (s *MoleService) hotReload() error {
+	s.mu.Lock()
+	err := s.generateFrpcToml()
+	admin := s.frpAdmin
+	running := s.frpCmd != nil
+	data, _ := os.ReadFile(filepath.Join(s.getFrpBinDir(), "frpc.toml"))
+	s.mu.Unlock()
+
+	if err != nil {
+		return fmt.Errorf("配置生成失败: %v", err)
+	}
+	if !running {
+		return nil
+	}
+	if err := admin.reload(); err != nil {
+		return err
+	}
+	s.noteRuntimeToml(data)
+	return nil
+}
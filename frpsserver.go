@@ -0,0 +1,290 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/BurntSushi/toml"
+)
+
+// 内置 frps 服务端模式：用户有一台公网机器时，用同一个软件同时跑服务端和客户端
+// 安装包只内置了 frpc，frps 需要放到 bin 目录下 (与 frpc 同一版本)，配置和生命周期由这里管理
+
+// FrpsConfig 内置 frps 的配置，生成 bin/frps.toml
+type FrpsConfig struct {
+	BindPort          int    `toml:"bind_port" json:"bindPort"` // 默认 7000
+	Token             string `toml:"token" json:"token"`
+	VhostHTTPPort     int    `toml:"vhost_http_port,omitempty" json:"vhostHTTPPort"`   // 0 表示不开启
+	VhostHTTPSPort    int    `toml:"vhost_https_port,omitempty" json:"vhostHTTPSPort"` // 0 表示不开启
+	DashboardPort     int    `toml:"dashboard_port,omitempty" json:"dashboardPort"`    // 0 表示不开启
+	DashboardUser     string `toml:"dashboard_user,omitempty" json:"dashboardUser"`
+	DashboardPassword string `toml:"dashboard_password,omitempty" json:"dashboardPassword"`
+	AutoStart         bool   `toml:"auto_start" json:"autoStart"` // 软件启动时是否自动运行服务端
+}
+
+// FrpsStatus 内置服务端的运行状态
+type FrpsStatus struct {
+	IsRunning bool       `json:"isRunning"`
+	Installed bool       `json:"installed"` // bin 目录下是否有 frps
+	BinPath   string     `json:"binPath"`
+	Config    FrpsConfig `json:"config"`
+	Message   string     `json:"message"`
+}
+
+// frpsServer 内置 frps 进程
+type frpsServer struct {
+	mu  sync.Mutex
+	cmd *exec.Cmd
+}
+
+func frpsTargetName() string {
+	if runtime.GOOS == "windows" {
+		return "frps.exe"
+	}
+	return "frps"
+}
+
+// GetFrpsStatus 查询内置服务端状态
+func (s *MoleService) GetFrpsStatus() (FrpsStatus, error) {
+	if s.remote != nil {
+		var st FrpsStatus
+		err := s.remote.call(http.MethodGet, "/api/frps/status", &st)
+		return st, err
+	}
+	return s.frpsStatus(), nil
+}
+
+// StartFrpsServer 生成 frps.toml 并启动内置服务端
+func (s *MoleService) StartFrpsServer() (FrpsStatus, error) {
+	if err := s.checkMutable(); err != nil {
+		return FrpsStatus{}, err
+	}
+	s.countFeature("frps_server")
+	if s.remote != nil {
+		var st FrpsStatus
+		err := s.remote.call(http.MethodPost, "/api/frps/start", &st)
+		return st, err
+	}
+	if err := s.startFrps(); err != nil {
+		return s.frpsStatus(), err
+	}
+	return s.frpsStatus(), nil
+}
+
+// StopFrpsServer 停止内置服务端
+func (s *MoleService) StopFrpsServer() (FrpsStatus, error) {
+	if err := s.checkMutable(); err != nil {
+		return FrpsStatus{}, err
+	}
+	if s.remote != nil {
+		var st FrpsStatus
+		err := s.remote.call(http.MethodPost, "/api/frps/stop", &st)
+		return st, err
+	}
+	s.stopFrps()
+	return s.frpsStatus(), nil
+}
+
+func (s *MoleService) frpsStatus() FrpsStatus {
+	binPath := filepath.Join(s.getFrpBinDir(), frpsTargetName())
+	_, statErr := os.Stat(binPath)
+
+	s.mu.RLock()
+	var cfg FrpsConfig
+	if s.config != nil {
+		cfg = s.config.Frps
+	}
+	s.mu.RUnlock()
+	cfg = withFrpsDefaults(cfg)
+
+	s.frps.mu.Lock()
+	running := s.frps.cmd != nil
+	s.frps.mu.Unlock()
+
+	st := FrpsStatus{
+		IsRunning: running,
+		Installed: statErr == nil,
+		BinPath:   binPath,
+		Config:    cfg,
+	}
+	switch {
+	case running:
+		st.Message = fmt.Sprintf("服务端运行中，客户端连接端口 %d", cfg.BindPort)
+	case !st.Installed:
+		st.Message = "未找到 frps，请将与 frpc 同版本的 frps 放到 " + s.getFrpBinDir()
+	default:
+		st.Message = "服务端未运行"
+	}
+	return st
+}
+
+func withFrpsDefaults(cfg FrpsConfig) FrpsConfig {
+	if cfg.BindPort <= 0 {
+		cfg.BindPort = 7000
+	}
+	return cfg
+}
+
+// generateFrpsToml 根据配置生成 frps.toml
+func generateFrpsToml(cfg FrpsConfig) ([]byte, error) {
+	cfg = withFrpsDefaults(cfg)
+
+	data := map[string]any{
+		"bindPort": cfg.BindPort,
+	}
+	if cfg.Token != "" {
+		data["auth"] = map[string]any{
+			"method": "token",
+			"token":  cfg.Token,
+		}
+	}
+	if cfg.VhostHTTPPort > 0 {
+		data["vhostHTTPPort"] = cfg.VhostHTTPPort
+	}
+	if cfg.VhostHTTPSPort > 0 {
+		data["vhostHTTPSPort"] = cfg.VhostHTTPSPort
+	}
+	if cfg.DashboardPort > 0 {
+		data["webServer"] = map[string]any{
+			"addr":     "0.0.0.0",
+			"port":     cfg.DashboardPort,
+			"user":     cfg.DashboardUser,
+			"password": cfg.DashboardPassword,
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := toml.NewEncoder(&buf).Encode(data); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func (s *MoleService) startFrps() error {
+	s.frps.mu.Lock()
+	defer s.frps.mu.Unlock()
+
+	if s.frps.cmd != nil {
+		return nil
+	}
+
+	binDir := s.getFrpBinDir()
+	frpsPath := filepath.Join(binDir, frpsTargetName())
+	if _, err := os.Stat(frpsPath); err != nil {
+		return fmt.Errorf("未找到 frps，请将与 frpc 同版本的 frps 放到 %s", binDir)
+	}
+
+	s.mu.RLock()
+	if s.config == nil {
+		s.mu.RUnlock()
+		return fmt.Errorf("未发现有效配置，请先保存服务端设置")
+	}
+	cfg := s.config.Frps
+	s.mu.RUnlock()
+	if cfg.DashboardPort > 0 && cfg.DashboardPassword == "" {
+		return fmt.Errorf("开启 Dashboard 时必须设置密码")
+	}
+
+	data, err := generateFrpsToml(cfg)
+	if err != nil {
+		return fmt.Errorf("生成 frps 配置失败: %v", err)
+	}
+	tomlPath := filepath.Join(binDir, "frps.toml")
+	if err := os.WriteFile(tomlPath, data, 0600); err != nil {
+		return fmt.Errorf("写入 frps 配置失败: %v", err)
+	}
+
+	cmd := exec.Command(frpsPath, "-c", tomlPath)
+	cmd.SysProcAttr = &syscall.SysProcAttr{}
+	setHideWindow(cmd.SysProcAttr)
+	stdout, _ := cmd.StdoutPipe()
+	stderr, _ := cmd.StderrPipe()
+	if err := cmd.Start(); err != nil {
+		return fmt.Errorf("启动 frps 失败: %v", err)
+	}
+	s.frps.cmd = cmd
+
+	// 只运行服务端时 frpc 的日志刷新协程不存在，这里单独起一个
+	ctx, cancel := context.WithCancel(s.ctx)
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go s.readFrpsLog(stdout, &wg)
+	go s.readFrpsLog(stderr, &wg)
+	go s.runLogFlusher(ctx)
+
+	s.emitLog("内置 frps 服务端已启动")
+	s.emitFrpsStatus("start")
+
+	go func() {
+		wg.Wait()
+		err := cmd.Wait()
+		cancel()
+
+		s.frps.mu.Lock()
+		if s.frps.cmd == cmd {
+			s.frps.cmd = nil
+		}
+		s.frps.mu.Unlock()
+
+		log.Printf("frps 进程退出: %v", err)
+		s.emitLog("内置 frps 服务端已停止")
+		s.emitFrpsStatus("stop")
+	}()
+	return nil
+}
+
+func (s *MoleService) stopFrps() {
+	s.frps.mu.Lock()
+	cmd := s.frps.cmd
+	s.frps.mu.Unlock()
+
+	if cmd == nil || cmd.Process == nil {
+		return
+	}
+	if err := cmd.Process.Kill(); err != nil {
+		log.Printf("停止 frps 失败: %v", err)
+	}
+}
+
+// readFrpsLog frps 日志与 frpc 日志共用缓冲区，加前缀区分
+func (s *MoleService) readFrpsLog(reader io.ReadCloser, wg *sync.WaitGroup) {
+	defer wg.Done()
+	defer reader.Close()
+
+	scanner := bufio.NewScanner(reader)
+	for scanner.Scan() {
+		s.logMu.Lock()
+		s.logBuffer = append(s.logBuffer, logLine{at: time.Now(), text: "[frps] " + scanner.Text()})
+		s.logMu.Unlock()
+	}
+}
+
+// autoStartFrps 软件启动时按配置自动运行服务端
+func (s *MoleService) autoStartFrps() {
+	s.mu.RLock()
+	auto := s.config != nil && s.config.Frps.AutoStart
+	s.mu.RUnlock()
+	if !auto {
+		return
+	}
+	if err := s.startFrps(); err != nil {
+		log.Printf("自动启动 frps 失败: %v", err)
+		s.emitLog("自动启动 frps 失败：", err.Error())
+	}
+}
+
+func (s *MoleService) emitFrpsStatus(status string) {
+	s.events.Emit("frps-status", status)
+}
@@ -0,0 +1,159 @@
+package main
+
+import (
+	"crypto/sha256"
+	"crypto/tls"
+	"encoding/hex"
+	"fmt"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// frpc 与 frps 之间的 TLS 设置 (frp 0.50 起默认开启 TLS)
+// serverName 与 disableCustomTLSFirstByte 用于穿过 CDN 或按 SNI 分流的反向代理：
+// frpc 默认直接发送标准 TLS 握手，旧版 frps 要求的自定义首字节 0x17 会让这类代理无法识别
+//
+// 证书固定有两种方式：
+//   - CA 文件：写入 transport.tls.trustedCaFile，由 frpc 在每次握手时校验
+//   - SHA-256 指纹：frpc 不支持，由 Mole 在启动 frpc 前自行握手比对，不一致时不启动，Token 不会发出
+//
+// frps 未配置 transport.tls.certFile 时每次启动都会生成新的自签名证书，这种情况只能使用 CA 方式或不固定
+
+const (
+	tlsProbeTimeout    = 5 * time.Second
+	frpTLSHeadByte     = 0x17 // frp 自定义首字节
+	sha256HexLength    = sha256.Size * 2
+	sha256PrefixString = "sha256:"
+)
+
+// tlsSection 生成 frpc.toml 的 transport.tls 段，没有需要设置的项时返回空
+func tlsSection(cfg *UserConfig) map[string]any {
+	tls := make(map[string]any)
+	if cfg.Server.TLSServerName != "" {
+		tls["serverName"] = cfg.Server.TLSServerName
+	}
+	if cfg.Server.TLSDisableCustomFirstByte != nil {
+		tls["disableCustomTLSFirstByte"] = *cfg.Server.TLSDisableCustomFirstByte
+	}
+	if cfg.Server.TLSTrustedCAFile != "" {
+		tls["trustedCaFile"] = cfg.Server.TLSTrustedCAFile
+	}
+	return tls
+}
+
+// normalizeFingerprint 接受带或不带冒号、大小写不限、可带 sha256: 前缀的十六进制指纹
+func normalizeFingerprint(fp string) (string, error) {
+	fp = strings.ToLower(strings.TrimSpace(fp))
+	fp = strings.TrimPrefix(fp, sha256PrefixString)
+	fp = strings.NewReplacer(":", "", " ", "").Replace(fp)
+	if len(fp) != sha256HexLength {
+		return "", fmt.Errorf("证书指纹应为 64 位十六进制 SHA-256")
+	}
+	if _, err := hex.DecodeString(fp); err != nil {
+		return "", fmt.Errorf("证书指纹格式错误: %v", err)
+	}
+	return fp, nil
+}
+
+// formatFingerprint 冒号分隔的大写十六进制，与常见工具显示的格式一致
+func formatFingerprint(sum []byte) string {
+	parts := make([]string, len(sum))
+	for i, b := range sum {
+		parts[i] = fmt.Sprintf("%02X", b)
+	}
+	return strings.Join(parts, ":")
+}
+
+// ServerCertificate frps 当前出示的证书
+type ServerCertificate struct {
+	Fingerprint string    `json:"fingerprint"` // SHA-256，冒号分隔的大写十六进制
+	Subject     string    `json:"subject"`
+	Issuer      string    `json:"issuer"`
+	NotAfter    time.Time `json:"notAfter"`
+}
+
+// probeServerCertificate 与 frps 完成一次 TLS 握手取得证书，不发送任何鉴权信息
+func probeServerCertificate(addr string, port int, serverName string, customFirstByte bool) (ServerCertificate, error) {
+	var info ServerCertificate
+	conn, err := net.DialTimeout("tcp", net.JoinHostPort(addr, strconv.Itoa(port)), tlsProbeTimeout)
+	if err != nil {
+		return info, fmt.Errorf("连接服务器失败: %v", err)
+	}
+	defer conn.Close()
+	_ = conn.SetDeadline(time.Now().Add(tlsProbeTimeout))
+
+	if customFirstByte {
+		if _, err := conn.Write([]byte{frpTLSHeadByte}); err != nil {
+			return info, fmt.Errorf("连接服务器失败: %v", err)
+		}
+	}
+	if serverName == "" {
+		serverName = addr
+	}
+	// 只取证书做比对，不依赖系统证书链 (frps 多为自签名证书)
+	tc := tls.Client(conn, &tls.Config{ServerName: serverName, InsecureSkipVerify: true})
+	if err := tc.Handshake(); err != nil {
+		return info, fmt.Errorf("TLS 握手失败 (服务器可能未开启 TLS): %v", err)
+	}
+	certs := tc.ConnectionState().PeerCertificates
+	if len(certs) == 0 {
+		return info, fmt.Errorf("服务器未提供证书")
+	}
+	sum := sha256.Sum256(certs[0].Raw)
+	info.Fingerprint = formatFingerprint(sum[:])
+	info.Subject = certs[0].Subject.String()
+	info.Issuer = certs[0].Issuer.String()
+	info.NotAfter = certs[0].NotAfter
+	return info, nil
+}
+
+// usesCustomFirstByte 与 frpc 的实际行为保持一致：未设置时按 frp 默认 (不发送)
+func usesCustomFirstByte(cfg *UserConfig) bool {
+	return cfg.Server.TLSDisableCustomFirstByte != nil && !*cfg.Server.TLSDisableCustomFirstByte
+}
+
+// verifyServerPin 配置了指纹时校验 frps 证书，不一致返回错误
+func verifyServerPin(cfg *UserConfig) error {
+	if cfg.Server.TLSPinSHA256 == "" {
+		return nil
+	}
+	want, err := normalizeFingerprint(cfg.Server.TLSPinSHA256)
+	if err != nil {
+		return err
+	}
+	cert, err := probeServerCertificate(envOr(cfg.Server.Addr), cfg.Server.Port, cfg.Server.TLSServerName, usesCustomFirstByte(cfg))
+	if err != nil {
+		return fmt.Errorf("无法校验服务器证书: %v", err)
+	}
+	got, _ := normalizeFingerprint(cert.Fingerprint)
+	if got != want {
+		return fmt.Errorf("服务器证书指纹不一致，可能遭到中间人攻击 (当前 %s)", cert.Fingerprint)
+	}
+	return nil
+}
+
+// validateServerTLS 保存配置时检查证书固定设置
+func validateServerTLS(cfg *UserConfig) error {
+	if cfg.Server.TLSPinSHA256 != "" {
+		if _, err := normalizeFingerprint(cfg.Server.TLSPinSHA256); err != nil {
+			return err
+		}
+	}
+	if f := cfg.Server.TLSTrustedCAFile; f != "" {
+		if _, err := os.Stat(f); err != nil {
+			return fmt.Errorf("CA 证书文件不可用: %v", err)
+		}
+	}
+	return nil
+}
+
+// FetchServerCertificate 获取 frps 当前的证书指纹，供用户核对后填入证书固定
+func (s *MoleService) FetchServerCertificate(addr string, port int, serverName string, customFirstByte bool) (ServerCertificate, error) {
+	if addr == "" || port <= 0 {
+		return ServerCertificate{}, fmt.Errorf("请先填写服务器地址和端口")
+	}
+	return probeServerCertificate(addr, port, serverName, customFirstByte)
+}
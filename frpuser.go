@@ -0,0 +1,38 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+)
+
+// frp 用户与代理名前缀：多台机器共用一台 frps 时，同名代理会互相顶替
+// User 对应 frpc 的 user 字段，frpc 会把它以 "user." 的形式加在每个代理名前面；
+// NamePrefix 由 Mole 直接加在代理名前面，可写模板变量 (如 {{hostname}}-)，frps 不支持 user 时也能区分
+// 两者都随命名配置保存与切换，界面与配置文件中的规则名称保持原样
+
+// 用户名与前缀在模板展开后只能包含字母、数字、点、下划线与连字符
+var reFrpUser = regexp.MustCompile(`^[A-Za-z0-9._-]*$`)
+
+func validateFrpUser(user, prefix string) error {
+	if len(user) > 64 || !reFrpUser.MatchString(user) {
+		return fmt.Errorf("frp 用户名只能包含字母、数字、点、下划线与连字符: %s", user)
+	}
+	if expanded := expandTemplate(prefix); len(expanded) > 64 || !reFrpUser.MatchString(expanded) {
+		return fmt.Errorf("代理名前缀只能包含字母、数字、点、下划线、连字符与模板变量: %s", prefix)
+	}
+	return nil
+}
+
+// proxyWireName 写入 frpc.toml 的代理名：前缀 + 模板展开后的代理名
+func proxyWireName(cfg *UserConfig, p ProxyRule) string {
+	return expandTemplate(cfg.Server.NamePrefix) + expandTemplate(proxyBaseName(p))
+}
+
+// proxyRuntimeNames frpc 日志与管理接口中可能出现的代理名
+func proxyRuntimeNames(cfg *UserConfig, p ProxyRule) []string {
+	name := proxyWireName(cfg, p)
+	if cfg.Server.User == "" {
+		return []string{name}
+	}
+	return []string{name, cfg.Server.User + "." + name}
+}
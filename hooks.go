@@ -0,0 +1,160 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// 生命周期钩子：在隧道启动前、连接成功后、断开后运行用户命令，如挂载共享目录、更新 DDNS 记录
+// 连接前钩子每次启动隧道时同步运行 (包括自动重连与定时重启)，失败时取消本次启动；
+// 连接后与断开后的钩子异步运行，不影响隧道。输出逐行记入运行日志，命令运行见 plugins.go 的 runUserCommand
+
+const (
+	hookPreConnect     = "pre-connect"
+	hookPostConnect    = "post-connect"
+	hookPostDisconnect = "post-disconnect"
+)
+
+// HookCommand 一个钩子命令，Command 为空时不运行
+type HookCommand struct {
+	Command string   `toml:"command,omitempty" json:"command"`
+	Args    []string `toml:"args,omitempty" json:"args"`
+	Timeout int      `toml:"timeout,omitempty" json:"timeout"` // 秒，0 为 30 秒
+}
+
+// LifecycleHooks 连接生命周期钩子
+type LifecycleHooks struct {
+	PreConnect     HookCommand `toml:"pre_connect,omitempty" json:"preConnect"`
+	PostConnect    HookCommand `toml:"post_connect,omitempty" json:"postConnect"`
+	PostDisconnect HookCommand `toml:"post_disconnect,omitempty" json:"postDisconnect"`
+}
+
+func (h LifecycleHooks) get(kind string) HookCommand {
+	switch kind {
+	case hookPreConnect:
+		return h.PreConnect
+	case hookPostConnect:
+		return h.PostConnect
+	case hookPostDisconnect:
+		return h.PostDisconnect
+	}
+	return HookCommand{}
+}
+
+func validateHooks(h LifecycleHooks) error {
+	for _, kind := range []string{hookPreConnect, hookPostConnect, hookPostDisconnect} {
+		c := h.get(kind)
+		if c.Command == "" && len(c.Args) > 0 {
+			return fmt.Errorf("%s 钩子填写了参数但缺少命令", kind)
+		}
+		if c.Timeout < 0 || c.Timeout > maxCommandTimeoutSecs {
+			return fmt.Errorf("%s 钩子的超时应在 0 ~ %d 秒之间", kind, maxCommandTimeoutSecs)
+		}
+	}
+	return nil
+}
+
+// runHook 运行钩子，未配置时直接返回
+func (s *MoleService) runHook(ctx context.Context, kind string, c HookCommand, cfg *UserConfig) error {
+	if c.Command == "" {
+		return nil
+	}
+	host, _ := os.Hostname()
+	env := []string{"MOLE_HOOK=" + kind, "MOLE_HOST=" + host}
+	if cfg != nil {
+		env = append(env, "MOLE_SERVER="+cfg.Server.Addr)
+		var names []string
+		for _, p := range cfg.Proxies {
+			if p.Enabled {
+				names = append(names, p.Name)
+			}
+		}
+		env = append(env, "MOLE_PROXIES="+strings.Join(names, ","))
+	}
+	return s.runUserCommand(ctx, kind, c.Command, c.Args, commandTimeout(c.Timeout), env, nil)
+}
+
+// startTunnel 启动隧道，先运行连接前钩子；调用方需持有 opMu
+// 钩子不能在 startFrp 中运行：startFrp 持有 s.mu，钩子运行期间状态查询都会被阻塞
+func (s *MoleService) startTunnel() {
+	// 上次断开留下的标记要在探测服务器之前清除，否则探测失败时 scheduleReconnect 会误以为用户已断开
+	s.stopRequested.Store(false)
+	cfg := s.status().Config
+	if cfg != nil && cfg.Hooks.PreConnect.Command != "" {
+		s.setTunnelState(tunnelPreparing, "")
+		if err := s.runHook(s.ctx, hookPreConnect, cfg.Hooks.PreConnect, cfg); err != nil {
+			msg := "连接前钩子运行失败: " + err.Error()
+			s.setTunnelState(tunnelError, msg)
+			s.emitLog(msg + "，已取消连接")
+			return
+		}
+	}
+	s.beginSelfTest()
+	s.startFrp()
+}
+
+// runLifecycleHooks 连接成功与断开后运行对应的钩子，事件判定与事件插件一致
+func (s *MoleService) runLifecycleHooks(ctx context.Context) {
+	events, cancel := s.bus.subscribeEvents(32)
+	defer cancel()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case ev := <-events:
+			var kind string
+			switch pluginEventName(ev.name, ev.data) {
+			case pluginEventConnected:
+				kind = hookPostConnect
+			case pluginEventDisconnected:
+				kind = hookPostDisconnect
+			default:
+				continue
+			}
+			cfg := s.status().Config
+			if cfg == nil {
+				continue
+			}
+			c := cfg.Hooks.get(kind)
+			if c.Command == "" {
+				continue
+			}
+			go func() {
+				if err := s.runHook(ctx, kind, c, cfg); err != nil {
+					s.emitLog(fmt.Sprintf("%s 钩子运行失败: %v", kind, err))
+				}
+			}()
+		}
+	}
+}
+
+// TestLifecycleHook 立即运行一次钩子 (kind 为 pre-connect / post-connect / post-disconnect)，输出记入运行日志
+func (s *MoleService) TestLifecycleHook(kind string, c HookCommand) error {
+	if err := s.checkMutable(); err != nil {
+		return err
+	}
+	var h LifecycleHooks
+	switch kind {
+	case hookPreConnect:
+		h.PreConnect = c
+	case hookPostConnect:
+		h.PostConnect = c
+	case hookPostDisconnect:
+		h.PostDisconnect = c
+	default:
+		return fmt.Errorf("未知的钩子: %s", kind)
+	}
+	if err := validateHooks(h); err != nil {
+		return err
+	}
+	if c.Command == "" {
+		return fmt.Errorf("请先填写命令")
+	}
+	if err := s.runHook(s.ctx, kind, c, s.status().Config); err != nil {
+		return fmt.Errorf("钩子运行失败: %v", err)
+	}
+	return nil
+}
@@ -0,0 +1,38 @@
+package main
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"strconv"
+)
+
+// HTTPS 规则：frps 需配置 vhostHTTPSPort，按 TLS 握手中的 SNI 把连接交给对应域名的规则
+// 证书可以由本地服务自己提供 (默认，frpc 原样转发)，也可以由 frpc 的 https2http 插件终止，本地只跑 HTTP
+
+// httpsPlugin 生成 https2http 插件配置，本地地址取规则的 localIP:localPort
+func httpsPlugin(p ProxyRule) map[string]any {
+	return map[string]any{
+		"type":              "https2http",
+		"localAddr":         net.JoinHostPort(p.LocalIP, strconv.Itoa(p.LocalPort)),
+		"crtPath":           p.CertFile,
+		"keyPath":           p.KeyFile,
+		"hostHeaderRewrite": p.LocalIP,
+	}
+}
+
+// validateHTTPS 开启证书终止时证书与私钥必须存在
+func validateHTTPS(p ProxyRule) error {
+	if !p.HTTPSTerminate {
+		return nil
+	}
+	if p.CertFile == "" || p.KeyFile == "" {
+		return fmt.Errorf("规则 \"%s\" 开启了证书终止，需要填写证书和私钥文件", p.Name)
+	}
+	for _, f := range []string{p.CertFile, p.KeyFile} {
+		if _, err := os.Stat(f); err != nil {
+			return fmt.Errorf("规则 \"%s\" 的证书文件不可用: %v", p.Name, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,87 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+)
+
+// 界面以外的文案 (托盘菜单等) 的多语言目录，按系统语言选择，缺失的条目回退到中文
+// 前端页面目前只有中文，这里只收录 Go 侧直接展示给用户的文字
+
+const (
+	langZH = "zh"
+	langEN = "en"
+)
+
+var catalog = map[string]map[string]string{
+	langZH: {
+		"tray.tooltip":         "Mole 内网穿透",
+		"tray.connected":       "● 已连接 · %s",
+		"tray.disconnected":    "○ 未连接 · %s",
+		"tray.not_configured":  "○ 尚未配置服务器",
+		"tray.last_status":     "最近：%s",
+		"tray.connect":         "建立连接",
+		"tray.disconnect":      "断开连接",
+		"tray.show":            "显示窗口",
+		"tray.mini":            "迷你状态窗",
+		"tray.profiles":        "切换配置并连接",
+		"tray.rules":           "复制访问地址",
+		"tray.quit":            "退出",
+		"tray.quit_disconnect": "退出并断开隧道",
+	},
+	langEN: {
+		"tray.tooltip":         "Mole tunnel",
+		"tray.connected":       "● Connected · %s",
+		"tray.disconnected":    "○ Disconnected · %s",
+		"tray.not_configured":  "○ No server configured",
+		"tray.last_status":     "Last: %s",
+		"tray.connect":         "Connect",
+		"tray.disconnect":      "Disconnect",
+		"tray.show":            "Show window",
+		"tray.mini":            "Mini status window",
+		"tray.profiles":        "Switch profile and connect",
+		"tray.rules":           "Copy public address",
+		"tray.quit":            "Quit",
+		"tray.quit_disconnect": "Quit and disconnect",
+	},
+}
+
+// uiLang 系统语言只在启动时读取一次
+var uiLang = sync.OnceValue(func() string {
+	return matchLang(systemLocale())
+})
+
+// matchLang 把 zh_CN.UTF-8、en-US 之类的区域设置归到目录中的语言，无法识别时用中文
+func matchLang(locale string) string {
+	locale = strings.ToLower(locale)
+	for lang := range catalog {
+		if strings.HasPrefix(locale, lang) {
+			return lang
+		}
+	}
+	return langZH
+}
+
+// tr 按当前语言取文案，带参数时按 fmt 格式化
+func tr(key string, args ...any) string {
+	msg, ok := catalog[uiLang()][key]
+	if !ok {
+		msg = catalog[langZH][key]
+	}
+	if len(args) > 0 {
+		return fmt.Sprintf(msg, args...)
+	}
+	return msg
+}
+
+// envLocale 按 POSIX 的优先级读取 LC_ALL / LC_MESSAGES / LANG
+func envLocale() string {
+	for _, k := range []string{"LC_ALL", "LC_MESSAGES", "LANG"} {
+		if v := os.Getenv(k); v != "" && v != "C" && v != "POSIX" {
+			return v
+		}
+	}
+	return ""
+}
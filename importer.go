@@ -0,0 +1,526 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/BurntSushi/toml"
+	"github.com/wailsapp/wails/v3/pkg/application"
+)
+
+// 配置导入流程：解析文件 -> 生成预览 (import-preview 事件) -> 用户确认后写入配置
+// 支持 frpc.toml (0.52+ 新格式)、frpc.ini (旧格式)、.moleprofile / 分享码，以及 Mole 导出的完整配置 (TOML / YAML / JSON)
+
+const (
+	importFormatToml    = "frpc.toml"
+	importFormatIni     = "frpc.ini"
+	importFormatProfile = "moleprofile"
+	importFormatConfig  = "mole-config"
+
+	maxImportFileSize = 1 << 20
+	maxPendingImports = 5
+)
+
+// ImportPreview 待确认的导入内容
+type ImportPreview struct {
+	ID       string        `json:"id"`
+	Source   string        `json:"source"` // 文件名或 "分享码"
+	Format   string        `json:"format"`
+	Profile  MoleProfile   `json:"profile"`
+	Warnings []string      `json:"warnings"`
+	Compat   []CompatIssue `json:"compat"`         // 旧版选项的转换情况，见 compat.go
+	Toml     string        `json:"toml,omitempty"` // frpc.ini 完整转换后的 frpc.toml，可另存
+
+	config *UserConfig // 完整配置导入时的全部内容，替换时一并恢复偏好、集成等设置
+}
+
+type importStore struct {
+	mu      sync.Mutex
+	pending []ImportPreview
+}
+
+func (st *importStore) put(p ImportPreview) {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	st.pending = append(st.pending, p)
+	if len(st.pending) > maxPendingImports {
+		st.pending = st.pending[len(st.pending)-maxPendingImports:]
+	}
+}
+
+func (st *importStore) get(id string) (ImportPreview, bool) {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	for _, p := range st.pending {
+		if p.ID == id {
+			return p, true
+		}
+	}
+	return ImportPreview{}, false
+}
+
+func (st *importStore) take(id string) (ImportPreview, bool) {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	for i, p := range st.pending {
+		if p.ID == id {
+			st.pending = append(st.pending[:i], st.pending[i+1:]...)
+			return p, true
+		}
+	}
+	return ImportPreview{}, false
+}
+
+// ImportFile 解析配置文件并生成预览，需调用 ConfirmImport 后才会生效
+func (s *MoleService) ImportFile(path string) (ImportPreview, error) {
+	s.countFeature("import_file")
+	info, err := os.Stat(path)
+	if err != nil {
+		return ImportPreview{}, fmt.Errorf("读取文件失败: %v", err)
+	}
+	if info.Size() > maxImportFileSize {
+		return ImportPreview{}, fmt.Errorf("文件过大，不像是配置文件")
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return ImportPreview{}, fmt.Errorf("读取文件失败: %v", err)
+	}
+	format := detectImportFormat(path, data)
+	if format == importFormatConfig {
+		return s.previewConfigImport(filepath.Base(path), configFormatByExt(path), data)
+	}
+	return s.previewImport(filepath.Base(path), format, data)
+}
+
+// ImportShareCode 解析分享码并生成预览
+func (s *MoleService) ImportShareCode(code string) (ImportPreview, error) {
+	s.countFeature("import_share_code")
+	return s.previewImport("分享码", importFormatProfile, []byte(code))
+}
+
+// GetPendingImports 返回尚未确认的导入预览
+// 双击文件启动时导入早于前端加载完成，前端初始化时通过它补上预览
+func (s *MoleService) GetPendingImports() []ImportPreview {
+	// 预览中可能带有 Token，锁定时不返回，解锁后前端会重新获取
+	if s.checkUnlocked() != nil {
+		return nil
+	}
+	s.imports.mu.Lock()
+	defer s.imports.mu.Unlock()
+	return append([]ImportPreview(nil), s.imports.pending...)
+}
+
+// importFiles 依次导入文件，失败写入日志
+func (s *MoleService) importFiles(paths []string) {
+	for _, path := range paths {
+		if _, err := s.ImportFile(path); err != nil {
+			s.emitLog("导入失败：", err.Error())
+		}
+	}
+}
+
+// ConfirmImport 应用预览内容；replace 为 true 时替换服务器与全部规则 (完整配置导入时替换全部设置)，否则把规则追加到现有配置
+func (s *MoleService) ConfirmImport(id string, replace bool) error {
+	if err := s.checkMutable(); err != nil {
+		return err
+	}
+	preview, ok := s.imports.take(id)
+	if !ok {
+		return fmt.Errorf("导入预览已失效，请重新导入")
+	}
+
+	var newCfg UserConfig
+	if cur := s.status().Config; cur != nil {
+		newCfg = *cur
+	}
+	if replace && preview.config != nil {
+		newCfg = *preview.config
+	}
+	newCfg.Proxies = append([]ProxyRule(nil), newCfg.Proxies...)
+
+	prof := preview.Profile
+	if replace || newCfg.Server.Addr == "" {
+		newCfg.Server.Addr = prof.Server.Addr
+		newCfg.Server.Port = prof.Server.Port
+		newCfg.Server.Remark = prof.Server.Remark
+		if prof.Server.Token != "" {
+			newCfg.Server.Token = prof.Server.Token
+		}
+	}
+	if replace {
+		newCfg.Proxies = nil
+	}
+	for _, p := range prof.Proxies {
+		p.ID = newRuleID()
+		p.Name = uniqueRuleName(newCfg.Proxies, p.Name)
+		newCfg.Proxies = append(newCfg.Proxies, p)
+	}
+	if len(newCfg.Proxies) > maxProxyRules {
+		return fmt.Errorf("导入后共 %d 条规则，超过上限 %d 条，请选择替换或先删除部分规则", len(newCfg.Proxies), maxProxyRules)
+	}
+
+	if err := s.saveUserConfig(newCfg); err != nil {
+		return err
+	}
+	s.emitLog(fmt.Sprintf("已从 %s 导入 %d 条规则", preview.Source, len(prof.Proxies)))
+	mode := "追加"
+	if replace {
+		mode = "替换"
+	}
+	s.audit(auditImport, fmt.Sprintf("%s %s 导入 %d 条规则", preview.Source, mode, len(prof.Proxies)))
+	return nil
+}
+
+// SaveConvertedToml 把导入预览中由 frpc.ini 转换得到的 frpc.toml 另存为文件，返回保存路径，用户取消时返回空字符串
+func (s *MoleService) SaveConvertedToml(id string) (string, error) {
+	if err := s.checkUnlocked(); err != nil {
+		return "", err
+	}
+	preview, ok := s.imports.get(id)
+	if !ok {
+		return "", fmt.Errorf("导入预览已失效，请重新导入")
+	}
+	if preview.Toml == "" {
+		return "", fmt.Errorf("只有 frpc.ini 可以转换")
+	}
+	app := application.Get()
+	if app == nil {
+		return "", fmt.Errorf("当前模式不支持文件对话框")
+	}
+	path, err := app.Dialog.SaveFile().
+		SetFilename("frpc.toml").
+		AddFilter("frpc.toml", "*.toml").
+		CanCreateDirectories(true).
+		PromptForSingleSelection()
+	if err != nil || path == "" {
+		return "", err
+	}
+	if err := os.WriteFile(path, []byte(preview.Toml), 0600); err != nil {
+		return "", fmt.Errorf("写入文件失败: %v", err)
+	}
+	return path, nil
+}
+
+// CancelImport 丢弃预览
+func (s *MoleService) CancelImport(id string) {
+	s.imports.take(id)
+}
+
+func (s *MoleService) previewImport(source, format string, data []byte) (ImportPreview, error) {
+	if err := s.checkMutable(); err != nil {
+		return ImportPreview{}, err
+	}
+
+	var (
+		prof     MoleProfile
+		warnings []string
+		compat   []CompatIssue
+		toml     string
+		err      error
+	)
+	switch format {
+	case importFormatIni:
+		prof, warnings, err = parseFrpcIni(data)
+		if err == nil {
+			sections, order, _ := splitIniSections(data)
+			compat = iniCompatIssues(sections, order)
+			if conv, err := convertFrpcIni(data); err == nil {
+				toml = string(conv.Toml)
+				compat = append(compat, conv.Unmapped...)
+			}
+		}
+	case importFormatProfile:
+		prof, err = parseMoleProfile(data)
+	default:
+		var normalized []byte
+		if normalized, compat, err = normalizeFrpcToml(data); err != nil {
+			return ImportPreview{}, fmt.Errorf("frpc.toml 格式错误: %v", err)
+		}
+		prof, warnings, err = parseFrpcToml(normalized)
+	}
+	if err != nil {
+		return ImportPreview{}, err
+	}
+
+	for i := range prof.Proxies {
+		prof.Proxies[i].Enabled = true
+	}
+	return s.putImportPreview(ImportPreview{
+		Source:   source,
+		Format:   format,
+		Profile:  prof,
+		Warnings: warnings,
+		Compat:   compat,
+		Toml:     toml,
+	}), nil
+}
+
+// previewConfigImport 导入 Mole 导出的完整配置，规则保留原有的启用状态
+func (s *MoleService) previewConfigImport(source, format string, data []byte) (ImportPreview, error) {
+	if err := s.checkMutable(); err != nil {
+		return ImportPreview{}, err
+	}
+	cfg, err := decodeUserConfig(data, format)
+	if err != nil {
+		return ImportPreview{}, err
+	}
+	return s.putImportPreview(ImportPreview{
+		Source:   source,
+		Format:   importFormatConfig,
+		Profile:  profileFromConfig(&cfg, true),
+		Warnings: []string{"选择“替换现有配置”时，偏好设置、通知等其他设置也会一并替换"},
+		config:   &cfg,
+	}), nil
+}
+
+// putImportPreview 规整规则后保存预览并通知前端
+func (s *MoleService) putImportPreview(preview ImportPreview) ImportPreview {
+	prof := &preview.Profile
+	for i := range prof.Proxies {
+		p := &prof.Proxies[i]
+		p.Name = sanitizeRuleName(p.Name)
+		if p.LocalIP == "" {
+			p.LocalIP = "127.0.0.1"
+		}
+	}
+	if len(prof.Proxies) > maxProxyRules {
+		preview.Warnings = append(preview.Warnings, fmt.Sprintf("共 %d 条规则，只导入前 %d 条", len(prof.Proxies), maxProxyRules))
+		prof.Proxies = prof.Proxies[:maxProxyRules]
+	}
+
+	preview.ID = newRuleID()
+	s.imports.put(preview)
+	s.events.Emit("import-preview", preview)
+	return preview
+}
+
+// detectImportFormat 优先按扩展名判断，其次按内容特征
+func detectImportFormat(path string, data []byte) string {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".ini":
+		return importFormatIni
+	case ".moleprofile":
+		return importFormatProfile
+	case ".yaml", ".yml", ".json":
+		return importFormatConfig
+	}
+
+	text := strings.TrimSpace(string(data))
+	// Mole 的 config.toml 以 [server] 段保存服务器，frpc.toml 使用顶层的 serverAddr
+	if strings.EqualFold(filepath.Ext(path), ".toml") {
+		if strings.Contains(text, "[server]") && !strings.Contains(text, "serverAddr") {
+			return importFormatConfig
+		}
+		return importFormatToml
+	}
+	switch {
+	case strings.HasPrefix(text, shareCodePrefix), strings.HasPrefix(text, "{"):
+		return importFormatProfile
+	case strings.Contains(text, "[common]"):
+		return importFormatIni
+	}
+	return importFormatToml
+}
+
+// parseMoleProfile .moleprofile 文件内容为分享码或 JSON
+func parseMoleProfile(data []byte) (MoleProfile, error) {
+	text := strings.TrimSpace(string(data))
+	if strings.HasPrefix(text, shareCodePrefix) {
+		return decodeShareCode(text)
+	}
+	var p MoleProfile
+	if err := json.Unmarshal([]byte(text), &p); err != nil {
+		return p, fmt.Errorf("配置档案格式错误: %v", err)
+	}
+	return p, nil
+}
+
+// frpcTomlFile frpc.toml 中 Mole 能识别的部分
+type frpcTomlFile struct {
+	ServerAddr string `toml:"serverAddr"`
+	ServerPort int    `toml:"serverPort"`
+	Auth       struct {
+		Token string `toml:"token"`
+	} `toml:"auth"`
+	Includes []string `toml:"includes"`
+	Proxies  []struct {
+		Name          string   `toml:"name"`
+		Type          string   `toml:"type"`
+		LocalIP       string   `toml:"localIP"`
+		LocalPort     int      `toml:"localPort"`
+		RemotePort    int      `toml:"remotePort"`
+		CustomDomains []string `toml:"customDomains"`
+		Subdomain     string   `toml:"subdomain"`
+	} `toml:"proxies"`
+}
+
+func parseFrpcToml(data []byte) (MoleProfile, []string, error) {
+	var (
+		f        frpcTomlFile
+		prof     MoleProfile
+		warnings []string
+	)
+	md, err := toml.Decode(string(data), &f)
+	if err != nil {
+		return prof, nil, fmt.Errorf("frpc.toml 格式错误: %v", err)
+	}
+	if undecoded := md.Undecoded(); len(undecoded) > 0 {
+		warnings = append(warnings, fmt.Sprintf("有 %d 项设置 Mole 暂不支持，已忽略", len(undecoded)))
+	}
+	if len(f.Includes) > 0 {
+		warnings = append(warnings, "不支持 includes 引用的外部文件，其中的规则未导入")
+	}
+
+	prof.Server.Addr = f.ServerAddr
+	prof.Server.Port = f.ServerPort
+	prof.Server.Token = f.Auth.Token
+	if prof.Server.Port == 0 {
+		prof.Server.Port = 7000
+	}
+
+	for _, p := range f.Proxies {
+		rule, warn := importRule(p.Name, p.Type, p.LocalIP, p.LocalPort, p.RemotePort, p.CustomDomains, p.Subdomain)
+		if warn != "" {
+			warnings = append(warnings, warn)
+			continue
+		}
+		prof.Proxies = append(prof.Proxies, rule)
+	}
+	return prof, warnings, nil
+}
+
+// splitIniSections 按小节拆分 ini 内容，order 为小节出现的顺序
+func splitIniSections(data []byte) (map[string]map[string]string, []string, error) {
+	sections := make(map[string]map[string]string)
+	var order []string
+	current := ""
+	scanner := bufio.NewScanner(strings.NewReader(string(data)))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") || strings.HasPrefix(line, ";") {
+			continue
+		}
+		if strings.HasPrefix(line, "[") && strings.HasSuffix(line, "]") {
+			current = strings.TrimSpace(line[1 : len(line)-1])
+			if _, ok := sections[current]; !ok {
+				sections[current] = make(map[string]string)
+				order = append(order, current)
+			}
+			continue
+		}
+		key, value, ok := strings.Cut(line, "=")
+		if !ok || current == "" {
+			continue
+		}
+		sections[current][strings.TrimSpace(key)] = strings.TrimSpace(value)
+	}
+	return sections, order, scanner.Err()
+}
+
+// parseFrpcIni 解析旧版 ini 格式：[common] 为全局设置，其余每个小节是一条代理
+func parseFrpcIni(data []byte) (MoleProfile, []string, error) {
+	var (
+		prof     MoleProfile
+		warnings []string
+	)
+
+	sections, order, err := splitIniSections(data)
+	if err != nil {
+		return prof, nil, err
+	}
+
+	common, ok := sections["common"]
+	if !ok {
+		return prof, nil, fmt.Errorf("frpc.ini 缺少 [common] 小节")
+	}
+	prof.Server.Addr = common["server_addr"]
+	prof.Server.Port, _ = strconv.Atoi(common["server_port"])
+	prof.Server.Token = common["token"]
+	if prof.Server.Port == 0 {
+		prof.Server.Port = 7000
+	}
+
+	for _, name := range order {
+		if name == "common" {
+			continue
+		}
+		sec := sections[name]
+		if strings.HasPrefix(name, "range:") {
+			warnings = append(warnings, fmt.Sprintf("[%s] 端口范围规则暂不支持，已跳过", name))
+			continue
+		}
+		localPort, _ := strconv.Atoi(sec["local_port"])
+		remotePort, _ := strconv.Atoi(sec["remote_port"])
+		var domains []string
+		for _, d := range strings.Split(sec["custom_domains"], ",") {
+			if d = strings.TrimSpace(d); d != "" {
+				domains = append(domains, d)
+			}
+		}
+		rule, warn := importRule(name, sec["type"], sec["local_ip"], localPort, remotePort, domains, sec["subdomain"])
+		if warn != "" {
+			warnings = append(warnings, warn)
+			continue
+		}
+		prof.Proxies = append(prof.Proxies, rule)
+	}
+	return prof, warnings, nil
+}
+
+// importRule 把一条外部代理定义转换为 Mole 规则，不支持时返回提示
+func importRule(name, typ, localIP string, localPort, remotePort int, domains []string, subdomain string) (ProxyRule, string) {
+	if typ == "" {
+		typ = "tcp"
+	}
+	switch typ {
+	case "tcp", "udp":
+		if remotePort <= 0 {
+			return ProxyRule{}, fmt.Sprintf("[%s] 缺少远程端口，已跳过", name)
+		}
+	case "http", "https", "tcpmux":
+		if len(domains) == 0 {
+			if subdomain != "" {
+				return ProxyRule{}, fmt.Sprintf("[%s] 暂不支持 subdomain，请改用完整域名，已跳过", name)
+			}
+			return ProxyRule{}, fmt.Sprintf("[%s] 缺少域名，已跳过", name)
+		}
+	default:
+		return ProxyRule{}, fmt.Sprintf("[%s] 暂不支持 %s 类型，已跳过", name, typ)
+	}
+	if localPort <= 0 {
+		return ProxyRule{}, fmt.Sprintf("[%s] 缺少本地端口，已跳过", name)
+	}
+
+	rule := ProxyRule{
+		Name:      name,
+		ProxyType: typ,
+		LocalIP:   localIP,
+		LocalPort: localPort,
+	}
+	if usesDomains(typ) {
+		rule.Domains = domains
+	} else {
+		rule.RemotePort = remotePort
+	}
+	return rule, ""
+}
+
+// sanitizeRuleName 外部文件中的规则名会显示在界面上，去掉可能破坏 HTML 的字符
+func sanitizeRuleName(name string) string {
+	name = strings.Map(func(r rune) rune {
+		if strings.ContainsRune(`<>"'&`+"`", r) {
+			return -1
+		}
+		return r
+	}, strings.TrimSpace(name))
+	if name == "" {
+		name = "imported"
+	}
+	return name
+}
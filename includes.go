@@ -0,0 +1,52 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+)
+
+// 附加配置目录：数据目录下的 conf.d 中手写的 *.toml (只能包含 [[proxies]] / [[visitors]])
+// 通过 frp 的 includes 引用到生成的 frpc.toml 中，Mole 不认识的高级代理可以与界面管理的规则共存
+// 目录中没有文件时不写 includes，与以前生成的内容一致
+
+const includeDirName = "conf.d"
+
+func includeDir() string {
+	return filepath.Join(getAppDataDir(), includeDirName)
+}
+
+// includeFiles 目录中会被引用的文件名，按名称排序
+func includeFiles() []string {
+	matches, _ := filepath.Glob(filepath.Join(includeDir(), "*.toml"))
+	names := make([]string, 0, len(matches))
+	for _, m := range matches {
+		names = append(names, filepath.Base(m))
+	}
+	sort.Strings(names)
+	return names
+}
+
+// includePatterns 写入 frpc.toml 的 includes，没有附加配置时为空
+func includePatterns() []string {
+	if len(includeFiles()) == 0 {
+		return nil
+	}
+	return []string{filepath.Join(includeDir(), "*.toml")}
+}
+
+// IncludeInfo 附加配置目录与其中的文件
+type IncludeInfo struct {
+	Dir   string   `json:"dir"`
+	Files []string `json:"files"`
+}
+
+// GetIncludeFiles 返回附加配置目录 (不存在时创建) 与其中会被引用的文件，修改后重新连接生效
+func (s *MoleService) GetIncludeFiles() (IncludeInfo, error) {
+	dir := includeDir()
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return IncludeInfo{}, fmt.Errorf("创建附加配置目录失败: %v", err)
+	}
+	return IncludeInfo{Dir: dir, Files: includeFiles()}, nil
+}
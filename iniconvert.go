@@ -0,0 +1,192 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+)
+
+// frpc.ini → frpc.toml 转换：把 0.52 之前的 ini 配置完整转换为新版 TOML，规则部分仍由 parseFrpcIni 导入为 ProxyRule
+// 导入 ini 时预览中附带转换结果，可另存为 frpc.toml；也可以在命令行使用 --convert-ini 单独转换
+// 无法转换的选项 (端口范围、未知选项等) 以 unmapped 列出，不会静默丢弃
+
+const compatUnmapped = "unmapped" // 无法转换，需手动处理
+
+// 按新写法确定值的类型，其余按字符串处理 (Token 等纯数字内容也要保持字符串)
+var (
+	iniIntOptions = map[string]bool{
+		"serverPort": true, "localPort": true, "remotePort": true, "bindPort": true, "webServer.port": true,
+		"transport.poolCount": true, "transport.tcpMuxKeepaliveInterval": true, "transport.dialServerTimeout": true,
+		"transport.dialServerKeepalive": true, "transport.heartbeatInterval": true, "transport.heartbeatTimeout": true,
+		"log.maxDays": true, "udpPacketSize": true,
+		"healthCheck.timeoutSeconds": true, "healthCheck.maxFailed": true, "healthCheck.intervalSeconds": true,
+	}
+	iniBoolOptions = map[string]bool{
+		"loginFailExit": true, "transport.tcpMux": true, "transport.tls.enable": true,
+		"transport.tls.disableCustomTLSFirstByte": true, "log.disablePrintColor": true,
+		"transport.useEncryption": true, "transport.useCompression": true,
+	}
+	iniListOptions = map[string]bool{
+		"customDomains": true, "locations": true, "allowUsers": true, "start": true, "includes": true,
+	}
+)
+
+// iniConversion 转换结果
+type iniConversion struct {
+	Toml     []byte
+	Unmapped []CompatIssue
+}
+
+// convertFrpcIni 把 frpc.ini 转换为新版 frpc.toml
+func convertFrpcIni(data []byte) (iniConversion, error) {
+	var conv iniConversion
+	sections, order, err := splitIniSections(data)
+	if err != nil {
+		return conv, err
+	}
+	common, ok := sections["common"]
+	if !ok {
+		return conv, fmt.Errorf("frpc.ini 缺少 [common] 小节")
+	}
+	unmapped := func(section, key string, proxy bool) {
+		conv.Unmapped = append(conv.Unmapped, CompatIssue{Option: compatOption(section, key, proxy), Action: compatUnmapped})
+	}
+
+	doc := make(map[string]any)
+	var scopes []string
+	for _, k := range sortedKeys(common) {
+		v := common[k]
+		switch k {
+		case "authenticate_heartbeats", "authenticate_new_work_conns":
+			if v == "true" {
+				scopes = append(scopes, map[string]string{
+					"authenticate_heartbeats":     "HeartBeats",
+					"authenticate_new_work_conns": "NewWorkConns",
+				}[k])
+			}
+			continue
+		}
+		repl, ok := legacyReplacement(k, false)
+		if !ok || !setNested(doc, repl, iniValue(repl, v)) {
+			unmapped("common", k, false)
+		}
+	}
+	if len(scopes) > 0 {
+		setNested(doc, "auth.additionalScopes", scopes)
+	}
+	if _, ok := common["token"]; ok {
+		setNested(doc, "auth.method", "token")
+	}
+
+	var proxies, visitors []map[string]any
+	for _, name := range order {
+		if name == "common" {
+			continue
+		}
+		sec := sections[name]
+		if strings.HasPrefix(name, "range:") {
+			unmapped(name, "端口范围", true)
+			continue
+		}
+		item := map[string]any{"name": name}
+		for _, k := range sortedKeys(sec) {
+			if k == "role" {
+				continue
+			}
+			repl, ok := legacyReplacement(k, true)
+			if !ok || !setNested(item, repl, iniValue(repl, sec[k])) {
+				unmapped(name, k, true)
+			}
+		}
+		if sec["role"] == "visitor" {
+			visitors = append(visitors, item)
+		} else {
+			proxies = append(proxies, item)
+		}
+	}
+	if len(proxies) > 0 {
+		doc["proxies"] = proxies
+	}
+	if len(visitors) > 0 {
+		doc["visitors"] = visitors
+	}
+
+	var buf bytes.Buffer
+	buf.WriteString("# 由 Mole 从 frpc.ini 转换生成\n\n")
+	if err := toml.NewEncoder(&buf).Encode(doc); err != nil {
+		return conv, err
+	}
+	conv.Toml = buf.Bytes()
+	return conv, nil
+}
+
+// iniValue 按新写法的类型转换 ini 中的字符串值，转换失败时保留原字符串
+func iniValue(key, v string) any {
+	switch {
+	case iniIntOptions[key]:
+		if n, err := strconv.Atoi(v); err == nil {
+			return n
+		}
+	case iniBoolOptions[key]:
+		if b, err := strconv.ParseBool(v); err == nil {
+			return b
+		}
+	case iniListOptions[key]:
+		var list []string
+		for _, item := range strings.Split(v, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				list = append(list, item)
+			}
+		}
+		return list
+	}
+	return v
+}
+
+func sortedKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// handleConvertCommand 处理 --convert-ini：转换结果写到同目录下的 .toml 文件，不覆盖已有文件
+func handleConvertCommand() bool {
+	src := appFlags.ConvertIni
+	if src == "" {
+		return false
+	}
+	data, err := os.ReadFile(src)
+	if err != nil {
+		log.Printf("读取文件失败: %v", err)
+		return true
+	}
+	conv, err := convertFrpcIni(data)
+	if err != nil {
+		log.Printf("转换失败: %v", err)
+		return true
+	}
+
+	dst := strings.TrimSuffix(src, filepath.Ext(src)) + ".toml"
+	if _, err := os.Stat(dst); err == nil {
+		dst = strings.TrimSuffix(src, filepath.Ext(src)) + ".converted.toml"
+	}
+	if err := os.WriteFile(dst, conv.Toml, 0644); err != nil {
+		log.Printf("写入文件失败: %v", err)
+		return true
+	}
+	log.Printf("已转换为 %s", dst)
+	for _, u := range conv.Unmapped {
+		log.Printf("无法转换，请手动处理: %s", u.Option)
+	}
+	return true
+}
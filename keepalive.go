@@ -0,0 +1,55 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os/exec"
+	"time"
+)
+
+// 管理接口保活：isRunning 只说明 frpc 进程还在，进程卡死时界面仍显示运行中
+// 隧道运行期间定期请求 frpc 管理接口，连续多次无响应即判定为僵死，上报断开并重启隧道
+
+const (
+	adminKeepaliveInterval = 15 * time.Second
+	adminKeepaliveFailures = 3 // 连续失败次数
+)
+
+// runAdminKeepalive 随本次会话 ctx 退出；cmd 用于确认重启前进程仍是本次启动的那个
+func (s *MoleService) runAdminKeepalive(ctx context.Context, cmd *exec.Cmd, admin frpcAdmin) {
+	ticker := time.NewTicker(adminKeepaliveInterval)
+	defer ticker.Stop()
+	failures := 0
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		err := admin.ping()
+		if err == nil {
+			failures = 0
+			continue
+		}
+		failures++
+		log.Printf("frpc 管理接口无响应 (%d/%d): %v", failures, adminKeepaliveFailures, err)
+		if failures < adminKeepaliveFailures {
+			continue
+		}
+
+		s.mu.RLock()
+		current := s.frpCmd == cmd
+		s.mu.RUnlock()
+		if !current || ctx.Err() != nil {
+			return
+		}
+		msg := fmt.Sprintf("frpc 管理接口连续 %d 次无响应，进程可能已僵死", failures)
+		s.emitLog(msg + "，正在重启隧道")
+		s.markTunnelDown(msg)
+		if err := s.restartFrpIfRunning(); err != nil {
+			log.Printf("重启僵死的 frpc 失败: %v", err)
+		}
+		return
+	}
+}
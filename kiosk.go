@@ -0,0 +1,58 @@
+package main
+
+import (
+	"errors"
+	"log"
+	"os"
+	"path/filepath"
+	"runtime"
+
+	"github.com/BurntSushi/toml"
+)
+
+// 只读 (Kiosk) 模式：IT 统一下发隧道配置后，用户只能查看配置、连接和断开，不能修改或导出
+// 通过 --kiosk 启动参数或系统级受管配置开启；受管配置位于普通用户无法写入的目录，
+// 守护进程同样读取，直接调用控制接口也无法绕过
+
+var errReadOnly = errors.New("当前为只读模式，配置由管理员统一管理，只能连接或断开")
+
+// ManagedPolicy 系统级受管配置 (managed.toml)
+type ManagedPolicy struct {
+	Kiosk bool `toml:"kiosk"`
+}
+
+// managedPolicyPath 各平台的系统级配置目录
+func managedPolicyPath() string {
+	switch runtime.GOOS {
+	case "windows":
+		return filepath.Join(os.Getenv("ProgramData"), "mole", "managed.toml")
+	case "darwin":
+		return "/Library/Application Support/mole/managed.toml"
+	default:
+		return "/etc/mole/managed.toml"
+	}
+}
+
+func loadManagedPolicy() ManagedPolicy {
+	var policy ManagedPolicy
+	if _, err := toml.DecodeFile(managedPolicyPath(), &policy); err != nil && !os.IsNotExist(err) {
+		log.Printf("读取受管配置失败: %v", err)
+	}
+	return policy
+}
+
+// readOnly 启动参数或受管配置任一开启即为只读
+func (s *MoleService) readOnly() bool {
+	return appFlags.Kiosk || s.managed.Kiosk
+}
+
+// checkMutable 修改配置、导出 Token 等操作入口调用：应用锁定或只读模式下拒绝
+func (s *MoleService) checkMutable() error {
+	if err := s.checkUnlocked(); err != nil {
+		return err
+	}
+	if s.readOnly() {
+		return errReadOnly
+	}
+	return nil
+}
@@ -0,0 +1,235 @@
+package main
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"fmt"
+	"log"
+	"math/big"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// 局域网接口：让同一网络中的手机 (或配套的移动端应用) 查看状态、连接与断开
+// 默认关闭；开启后在所有网卡上以 HTTPS 监听，只提供与设备管理相同的 /api/remote/ 接口，凭访问令牌调用
+// 证书为首次开启时生成的自签名证书，保存在配置目录，客户端按界面中显示的 SHA-256 指纹固定校验
+// 只在实际运行隧道的进程中监听 (守护进程，或未使用守护进程时的界面进程)
+
+const (
+	defaultLANAPIPort = 17601
+	lanAPICertFile    = "lanapi.crt"
+	lanAPIKeyFile     = "lanapi.key"
+	lanAPICertYears   = 10
+)
+
+// LANAPIInfo 局域网接口的设置与访问方式
+type LANAPIInfo struct {
+	Enabled     bool     `json:"enabled"`
+	Port        int      `json:"port"`
+	URLs        []string `json:"urls"`        // 本机各局域网地址对应的访问地址
+	Fingerprint string   `json:"fingerprint"` // 证书 SHA-256 指纹，冒号分隔的大写十六进制，尚未生成时为空
+}
+
+type lanAPIServer struct {
+	mu   sync.Mutex
+	srv  *http.Server
+	port int
+}
+
+func lanAPIPort(f FleetConfig) int {
+	if f.LANPort > 0 {
+		return f.LANPort
+	}
+	return defaultLANAPIPort
+}
+
+func validateLANAPI(f FleetConfig) error {
+	if f.LANPort < 0 || f.LANPort > 65535 {
+		return fmt.Errorf("局域网接口端口无效: %d", f.LANPort)
+	}
+	if f.LANEnabled && f.AccessToken == "" {
+		return fmt.Errorf("开启局域网接口前请先生成访问令牌")
+	}
+	return nil
+}
+
+func lanAPICertPaths() (string, string) {
+	dir := filepath.Join(getAppDataDir(), "config")
+	return filepath.Join(dir, lanAPICertFile), filepath.Join(dir, lanAPIKeyFile)
+}
+
+// loadOrCreateLANCert 读取证书，不存在时生成；证书长期不变，客户端固定的指纹才能一直有效
+func loadOrCreateLANCert() (tls.Certificate, error) {
+	certPath, keyPath := lanAPICertPaths()
+	if cert, err := tls.LoadX509KeyPair(certPath, keyPath); err == nil {
+		return cert, nil
+	}
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return tls.Certificate{}, fmt.Errorf("生成证书失败: %v", err)
+	}
+	serial, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 62))
+	if err != nil {
+		return tls.Certificate{}, fmt.Errorf("生成证书失败: %v", err)
+	}
+	host, _ := os.Hostname()
+	tmpl := &x509.Certificate{
+		SerialNumber: serial,
+		Subject:      pkix.Name{CommonName: "Mole LAN API", Organization: []string{host}},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().AddDate(lanAPICertYears, 0, 0),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil {
+		return tls.Certificate{}, fmt.Errorf("生成证书失败: %v", err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		return tls.Certificate{}, fmt.Errorf("生成证书失败: %v", err)
+	}
+	certPEM := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})
+	keyPEM := pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER})
+	if err := os.WriteFile(keyPath, keyPEM, 0600); err != nil {
+		return tls.Certificate{}, fmt.Errorf("保存证书失败: %v", err)
+	}
+	if err := os.WriteFile(certPath, certPEM, 0644); err != nil {
+		return tls.Certificate{}, fmt.Errorf("保存证书失败: %v", err)
+	}
+	return tls.X509KeyPair(certPEM, keyPEM)
+}
+
+// configure 随配置加载与保存启停监听，端口变化时重新监听
+func (l *lanAPIServer) configure(ms *MoleService, f FleetConfig) {
+	// 附着守护进程的界面进程不监听，由守护进程负责
+	if ms.remote != nil {
+		return
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	want := f.LANEnabled && f.AccessToken != ""
+	port := lanAPIPort(f)
+	if l.srv != nil && (!want || port != l.port) {
+		_ = l.srv.Close()
+		l.srv = nil
+		log.Printf("局域网接口已关闭")
+	}
+	if !want || l.srv != nil {
+		return
+	}
+
+	cert, err := loadOrCreateLANCert()
+	if err != nil {
+		log.Printf("局域网接口启动失败: %v", err)
+		return
+	}
+	ln, err := net.Listen("tcp", net.JoinHostPort("", strconv.Itoa(port)))
+	if err != nil {
+		log.Printf("局域网接口启动失败: %v", err)
+		return
+	}
+	mux := http.NewServeMux()
+	registerRemoteRoutes(mux, ms)
+	srv := &http.Server{
+		Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if !remoteAuthorized(r, ms.fleetAccessToken()) {
+				http.Error(w, "unauthorized", http.StatusUnauthorized)
+				return
+			}
+			mux.ServeHTTP(w, r)
+		}),
+		TLSConfig:         &tls.Config{Certificates: []tls.Certificate{cert}, MinVersion: tls.VersionTLS12},
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+	go func() {
+		if err := srv.ServeTLS(ln, "", ""); err != nil && err != http.ErrServerClosed {
+			log.Printf("局域网接口异常退出: %v", err)
+		}
+	}()
+	l.srv, l.port = srv, port
+	log.Printf("局域网接口已监听: %s", ln.Addr())
+}
+
+func (l *lanAPIServer) stop() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.srv != nil {
+		_ = l.srv.Close()
+		l.srv = nil
+	}
+}
+
+// lanIPv4s 本机的局域网 IPv4 地址
+func lanIPv4s() []string {
+	var ips []string
+	addrs, err := net.InterfaceAddrs()
+	if err != nil {
+		return nil
+	}
+	for _, a := range addrs {
+		ipnet, ok := a.(*net.IPNet)
+		if !ok || ipnet.IP.IsLoopback() || ipnet.IP.To4() == nil || !ipnet.IP.IsPrivate() {
+			continue
+		}
+		ips = append(ips, ipnet.IP.String())
+	}
+	return ips
+}
+
+// GetLANAPIInfo 局域网接口的设置、访问地址与证书指纹
+func (s *MoleService) GetLANAPIInfo() LANAPIInfo {
+	var info LANAPIInfo
+	if cfg := s.status().Config; cfg != nil {
+		info.Enabled = cfg.Fleet.LANEnabled
+		info.Port = lanAPIPort(cfg.Fleet)
+	}
+	if info.Port == 0 {
+		info.Port = defaultLANAPIPort
+	}
+	for _, ip := range lanIPv4s() {
+		info.URLs = append(info.URLs, "https://"+net.JoinHostPort(ip, strconv.Itoa(info.Port)))
+	}
+	certPath, _ := lanAPICertPaths()
+	if data, err := os.ReadFile(certPath); err == nil {
+		if block, _ := pem.Decode(data); block != nil {
+			sum := sha256.Sum256(block.Bytes)
+			info.Fingerprint = formatFingerprint(sum[:])
+		}
+	}
+	return info
+}
+
+// SetLANAPI 开启或关闭局域网接口，port 为 0 时使用默认端口
+func (s *MoleService) SetLANAPI(enabled bool, port int) error {
+	if err := s.checkMutable(); err != nil {
+		return err
+	}
+	cfg := s.status().Config
+	if cfg == nil {
+		return fmt.Errorf("未发现有效配置")
+	}
+	// 先生成证书，界面可以立即显示指纹
+	if enabled {
+		if _, err := loadOrCreateLANCert(); err != nil {
+			return err
+		}
+	}
+	newCfg := *cfg
+	newCfg.Fleet.LANEnabled = enabled
+	newCfg.Fleet.LANPort = port
+	return s.saveUserConfig(newCfg)
+}
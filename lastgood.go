@@ -0,0 +1,221 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/BurntSushi/toml"
+)
+
+// 最近一次可用的 frpc.toml：frpc 登录成功后把正在使用的 frpc.toml 另存一份；
+// 之后生成配置失败，或新配置让 frpc 启动后很快退出 (还没登录成功) 时，以 runtime-config 事件提示可以回滚，
+// RollbackRuntimeConfig 用这份文件重新连接。回滚后的启动与自动重连都固定使用它 (只替换本次的管理接口)，
+// 直到下一次保存配置或断开连接；期间的热重载 (自动暂停、快速分享等) 仍按当前配置生成
+
+const (
+	lastGoodTomlName = "frpc.last-good.toml"
+	quickCrashWindow = 15 * time.Second // 启动后多久内退出算“很快退出”
+)
+
+// RuntimeConfigStatus runtime-config 事件与查询接口
+type RuntimeConfigStatus struct {
+	HasLastGood bool      `json:"hasLastGood"`
+	LastGoodAt  time.Time `json:"lastGoodAt,omitempty"`
+	Failure     string    `json:"failure,omitempty"` // 非空时建议回滚
+	Pinned      bool      `json:"pinned"`            // 当前连接使用的是回滚的文件
+}
+
+type runtimeConfigState struct {
+	mu      sync.Mutex
+	current []byte // frpc 正在使用的 frpc.toml
+	failure string
+	pinned  bool
+}
+
+func (s *MoleService) lastGoodTomlPath() string {
+	return filepath.Join(s.getFrpBinDir(), lastGoodTomlName)
+}
+
+func (s *MoleService) runtimeConfigStatus() RuntimeConfigStatus {
+	s.runtimeCfg.mu.Lock()
+	st := RuntimeConfigStatus{Failure: s.runtimeCfg.failure, Pinned: s.runtimeCfg.pinned}
+	s.runtimeCfg.mu.Unlock()
+	if info, err := os.Stat(s.lastGoodTomlPath()); err == nil {
+		st.HasLastGood, st.LastGoodAt = true, info.ModTime()
+	}
+	return st
+}
+
+func (s *MoleService) emitRuntimeConfig() {
+	s.events.Emit("runtime-config", s.runtimeConfigStatus())
+}
+
+// comparableToml 去掉每次启动都不同的管理接口后重新编码，用于判断两份 frpc.toml 是否等价
+func comparableToml(data []byte) ([]byte, error) {
+	var m map[string]any
+	if _, err := toml.Decode(string(data), &m); err != nil {
+		return nil, err
+	}
+	delete(m, "webServer")
+	var buf bytes.Buffer
+	if err := toml.NewEncoder(&buf).Encode(m); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// noteRuntimeToml 记下 frpc 正在使用的 frpc.toml (需持有 s.mu)：启动时与热重载成功后调用，
+// 之后保存配置会改写磁盘上的文件，登录成功时另存的应是 frpc 实际加载的内容
+func (s *MoleService) noteRuntimeToml(data []byte) {
+	if data == nil {
+		var err error
+		if data, err = os.ReadFile(filepath.Join(s.getFrpBinDir(), "frpc.toml")); err != nil {
+			return
+		}
+	}
+	s.runtimeCfg.mu.Lock()
+	s.runtimeCfg.current = data
+	s.runtimeCfg.mu.Unlock()
+}
+
+// saveLastGoodToml frpc 登录成功后调用，把正在使用的配置另存为最近一次可用的配置
+func (s *MoleService) saveLastGoodToml() {
+	s.runtimeCfg.mu.Lock()
+	data := s.runtimeCfg.current
+	hadFailure := s.runtimeCfg.failure != ""
+	s.runtimeCfg.failure = ""
+	s.runtimeCfg.mu.Unlock()
+	if data != nil {
+		tmp := s.lastGoodTomlPath() + ".tmp"
+		err := os.WriteFile(tmp, data, 0600)
+		if err == nil {
+			err = os.Rename(tmp, s.lastGoodTomlPath())
+		}
+		if err != nil {
+			log.Printf("保存可用的 frpc.toml 失败: %v", err)
+		}
+	}
+	if hadFailure {
+		s.emitRuntimeConfig()
+	}
+}
+
+// offerRollback 记下失败原因并提示可以回滚，没有可用的旧配置时不提示
+func (s *MoleService) offerRollback(reason string) {
+	if _, err := os.Stat(s.lastGoodTomlPath()); err != nil {
+		return
+	}
+	s.runtimeCfg.mu.Lock()
+	s.runtimeCfg.failure = reason
+	s.runtimeCfg.mu.Unlock()
+	s.emitLog(reason + "；可以回滚到最近一次可用的 frpc.toml 后重新连接")
+	s.emitRuntimeConfig()
+}
+
+// frpcExitedEarly 进程退出时调用：启动后很快退出且从未登录成功，多半是新配置有问题；
+// 正在使用的 frpc.toml 与可用的旧配置等价时 (如服务器暂时连不上)，回滚也无济于事，不提示
+func (s *MoleService) frpcExitedEarly(startedAt time.Time) {
+	if s.stopRequested.Load() || time.Since(startedAt) > quickCrashWindow {
+		return
+	}
+	s.health.mu.Lock()
+	everUp := s.health.everUp
+	s.health.mu.Unlock()
+	if everUp {
+		return
+	}
+	good, err := os.ReadFile(s.lastGoodTomlPath())
+	if err != nil {
+		return
+	}
+	if cur, err := os.ReadFile(filepath.Join(s.getFrpBinDir(), "frpc.toml")); err == nil {
+		a, errA := comparableToml(good)
+		b, errB := comparableToml(cur)
+		if errA == nil && errB == nil && bytes.Equal(a, b) {
+			return
+		}
+	}
+	s.offerRollback("frpc 启动后很快退出")
+}
+
+func (s *MoleService) runtimePinned() bool {
+	s.runtimeCfg.mu.Lock()
+	defer s.runtimeCfg.mu.Unlock()
+	return s.runtimeCfg.pinned
+}
+
+// unpinRuntimeConfig 保存配置或断开连接后恢复按当前配置生成
+func (s *MoleService) unpinRuntimeConfig() {
+	s.runtimeCfg.mu.Lock()
+	changed := s.runtimeCfg.pinned
+	s.runtimeCfg.pinned = false
+	s.runtimeCfg.mu.Unlock()
+	if changed {
+		s.emitRuntimeConfig()
+	}
+}
+
+// restoreLastGoodToml 启动时 (需持有 s.mu) 用最近一次可用的配置覆盖 frpc.toml，管理接口换成本次分配的
+func (s *MoleService) restoreLastGoodToml() error {
+	data, err := os.ReadFile(s.lastGoodTomlPath())
+	if err != nil {
+		return fmt.Errorf("读取可用的 frpc.toml 失败: %v", err)
+	}
+	var m map[string]any
+	if _, err := toml.Decode(string(data), &m); err != nil {
+		return fmt.Errorf("解析可用的 frpc.toml 失败: %v", err)
+	}
+	delete(m, "webServer")
+	if s.frpAdmin.Port > 0 {
+		m["webServer"] = s.frpAdmin.tomlSection()
+	}
+	var buf bytes.Buffer
+	if err := toml.NewEncoder(&buf).Encode(m); err != nil {
+		return fmt.Errorf("生成 frpc.toml 失败: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(s.getFrpBinDir(), "frpc.toml"), buf.Bytes(), 0644); err != nil {
+		return fmt.Errorf("写入 frpc.toml 失败: %v", err)
+	}
+	return nil
+}
+
+// GetRuntimeConfigStatus 是否有可回滚的 frpc.toml，以及最近一次失败的原因
+func (s *MoleService) GetRuntimeConfigStatus() (RuntimeConfigStatus, error) {
+	if s.remote != nil {
+		var st RuntimeConfigStatus
+		err := s.remote.call(http.MethodGet, "/api/runtime-config", &st)
+		return st, err
+	}
+	return s.runtimeConfigStatus(), nil
+}
+
+// RollbackRuntimeConfig 回滚到最近一次可用的 frpc.toml 并重新连接
+func (s *MoleService) RollbackRuntimeConfig() error {
+	if err := s.checkMutable(); err != nil {
+		return err
+	}
+	s.audit(auditConnect, "回滚到最近一次可用的 frpc.toml")
+	if s.remote != nil {
+		return s.remote.rollbackRuntimeConfig()
+	}
+	return s.rollbackRuntimeConfig()
+}
+
+func (s *MoleService) rollbackRuntimeConfig() error {
+	if _, err := os.Stat(s.lastGoodTomlPath()); err != nil {
+		return fmt.Errorf("没有可回滚的 frpc.toml，需要先成功连接过一次")
+	}
+	s.runtimeCfg.mu.Lock()
+	s.runtimeCfg.pinned, s.runtimeCfg.failure = true, ""
+	s.runtimeCfg.mu.Unlock()
+	s.emitRuntimeConfig()
+	s.emitLog("已回滚到最近一次可用的 frpc.toml，正在重新连接")
+	s.reconnect.reset()
+	return s.restartFrp()
+}
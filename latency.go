@@ -0,0 +1,173 @@
+package main
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// 逐条规则的延迟采样：服务器 RTT 只反映本机到 frps 的链路，
+// 外部用户访问某个服务时实际经过 公网入口 -> frps -> 隧道 -> 本地服务，
+// 这里定期通过公网入口 (serverAddr:remotePort) 建立 TCP 连接，记录每条规则的连接耗时
+
+const (
+	latencySampleInterval = 30 * time.Second
+	latencyHistorySize    = 120 // 每条规则保留最近 1 小时的采样
+	latencyDialTimeout    = 5 * time.Second
+)
+
+// LatencySample 一次采样，失败时 Millis 为 -1
+type LatencySample struct {
+	Time   time.Time `json:"time"`
+	Millis float64   `json:"millis"`
+	Error  string    `json:"error,omitempty"`
+}
+
+// ProxyLatency 单条规则的延迟历史
+type ProxyLatency struct {
+	RuleID  string          `json:"ruleID"`
+	Name    string          `json:"name"`
+	Last    float64         `json:"last"`    // 最近一次成功采样，无则为 -1
+	Avg     float64         `json:"avg"`     // 成功采样的平均值，无则为 -1
+	Samples []LatencySample `json:"samples"` // 按时间先后排列
+}
+
+type latencyStore struct {
+	mu      sync.Mutex
+	history map[string][]LatencySample // key 为规则 ID
+}
+
+func (l *latencyStore) add(ruleID string, sample LatencySample) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.history == nil {
+		l.history = make(map[string][]LatencySample)
+	}
+	h := append(l.history[ruleID], sample)
+	if len(h) > latencyHistorySize {
+		h = h[len(h)-latencyHistorySize:]
+	}
+	l.history[ruleID] = h
+}
+
+func (l *latencyStore) snapshot(ruleID string) []LatencySample {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return append([]LatencySample(nil), l.history[ruleID]...)
+}
+
+// prune 删除已不存在的规则的历史
+func (l *latencyStore) prune(keep map[string]bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	for id := range l.history {
+		if !keep[id] {
+			delete(l.history, id)
+		}
+	}
+}
+
+// GetProxyLatency 返回每条 TCP 规则的延迟历史
+func (s *MoleService) GetProxyLatency() ([]ProxyLatency, error) {
+	if s.remote != nil {
+		var list []ProxyLatency
+		err := s.remote.call(http.MethodGet, "/api/latency", &list)
+		return list, err
+	}
+	return s.proxyLatency(), nil
+}
+
+func (s *MoleService) proxyLatency() []ProxyLatency {
+	s.mu.RLock()
+	var proxies []ProxyRule
+	if s.config != nil {
+		proxies = append(proxies, s.config.Proxies...)
+	}
+	s.mu.RUnlock()
+
+	list := make([]ProxyLatency, 0, len(proxies))
+	for _, p := range proxies {
+		if p.ProxyType != "tcp" {
+			continue
+		}
+		samples := s.latency.snapshot(p.ID)
+		item := ProxyLatency{RuleID: p.ID, Name: p.Name, Last: -1, Avg: -1, Samples: samples}
+
+		var sum float64
+		var ok int
+		for _, sm := range samples {
+			if sm.Millis >= 0 {
+				sum += sm.Millis
+				ok++
+				item.Last = sm.Millis
+			}
+		}
+		if ok > 0 {
+			item.Avg = sum / float64(ok)
+		}
+		list = append(list, item)
+	}
+	return list
+}
+
+// runLatencySampler 隧道运行期间定期采样，ctx 取消时退出
+func (s *MoleService) runLatencySampler(ctx context.Context) {
+	ticker := time.NewTicker(latencySampleInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if s.isRunning.Load() {
+				s.sampleLatency()
+			}
+		}
+	}
+}
+
+func (s *MoleService) sampleLatency() {
+	s.mu.RLock()
+	if s.config == nil {
+		s.mu.RUnlock()
+		return
+	}
+	addr := envOr(s.config.Server.Addr)
+	proxies := append([]ProxyRule(nil), s.config.Proxies...)
+	s.mu.RUnlock()
+
+	keep := make(map[string]bool)
+	var wg sync.WaitGroup
+	for _, p := range proxies {
+		if !p.Enabled || p.ProxyType != "tcp" || p.RemotePort <= 0 {
+			continue
+		}
+		keep[p.ID] = true
+
+		wg.Add(1)
+		go func(p ProxyRule) {
+			defer wg.Done()
+			s.latency.add(p.ID, probeLatency(net.JoinHostPort(addr, strconv.Itoa(p.RemotePort))))
+		}(p)
+	}
+	wg.Wait()
+
+	s.latency.prune(keep)
+	s.events.Emit("proxy-latency", s.proxyLatency())
+}
+
+func probeLatency(target string) LatencySample {
+	start := time.Now()
+	conn, err := net.DialTimeout("tcp", target, latencyDialTimeout)
+	if err != nil {
+		return LatencySample{Time: start, Millis: -1, Error: err.Error()}
+	}
+	elapsed := time.Since(start)
+	_ = conn.Close()
+	return LatencySample{Time: start, Millis: float64(elapsed.Microseconds()) / 1000}
+}
@@ -0,0 +1,67 @@
+package main
+
+import (
+	"fmt"
+	"time"
+)
+
+// 连接生命周期串行化：Connect、Disconnect、重启与自动重连都要先拿到 s.opMu，
+// 同一时间只有一个操作在启动或停止隧道，连续点击不会与异步的 startFrp、进程退出清理交错
+// 重复的请求按当前状态合并：启动中再次连接、已停止再次断开都直接返回
+// Disconnect 会等进程真正退出后才返回，之后的 Connect 不会遇到尚未清理的旧进程或过期的运行标记
+
+const tunnelExitTimeout = 10 * time.Second
+
+// waitTunnelExit 等待 frpc 进程与 SSH 隧道退出清理完毕
+func (s *MoleService) waitTunnelExit() error {
+	deadline := time.Now().Add(tunnelExitTimeout)
+	for {
+		s.mu.RLock()
+		exited := s.frpCmd == nil && s.sshTunnel == nil
+		s.mu.RUnlock()
+		if exited {
+			return nil
+		}
+		if time.Now().After(deadline) {
+			return fmt.Errorf("等待 frpc 退出超时")
+		}
+		time.Sleep(200 * time.Millisecond)
+	}
+}
+
+// startQueued 在 opMu 下启动隧道；排队期间已被 Disconnect 取消 (状态不再是 preparing) 时放弃
+func (s *MoleService) startQueued() {
+	s.opMu.Lock()
+	defer s.opMu.Unlock()
+	if s.tunnel.current() != tunnelPreparing {
+		return
+	}
+	s.startTunnel()
+}
+
+// restartFrp 停止隧道，等待进程退出后以最新配置重新启动；未运行时直接启动
+func (s *MoleService) restartFrp() error {
+	s.opMu.Lock()
+	defer s.opMu.Unlock()
+	return s.restartLocked()
+}
+
+// restartFrpIfRunning 定时重启、保活等后台任务使用：排队期间用户已断开时不再拉起
+func (s *MoleService) restartFrpIfRunning() error {
+	s.opMu.Lock()
+	defer s.opMu.Unlock()
+	if !s.isRunning.Load() && s.tunnel.current() != tunnelReconnecting {
+		return nil
+	}
+	return s.restartLocked()
+}
+
+// restartLocked 调用方需持有 opMu
+func (s *MoleService) restartLocked() error {
+	s.stopFrp()
+	if err := s.waitTunnelExit(); err != nil {
+		return err
+	}
+	s.startTunnel()
+	return nil
+}
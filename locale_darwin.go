@@ -0,0 +1,14 @@
+package main
+
+import (
+	"os/exec"
+	"strings"
+)
+
+// systemLocale 从 Finder 启动的应用没有 LANG 环境变量，优先读取系统偏好
+func systemLocale() string {
+	if out, err := exec.Command("defaults", "read", "-g", "AppleLocale").Output(); err == nil {
+		return strings.TrimSpace(string(out))
+	}
+	return envLocale()
+}
@@ -0,0 +1,7 @@
+//go:build !windows && !darwin
+
+package main
+
+func systemLocale() string {
+	return envLocale()
+}
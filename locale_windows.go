@@ -0,0 +1,12 @@
+package main
+
+import "golang.org/x/sys/windows"
+
+// systemLocale 用户界面语言的首选项，如 zh-CN
+func systemLocale() string {
+	langs, err := windows.GetUserPreferredUILanguages(windows.MUI_LANGUAGE_NAME)
+	if err != nil || len(langs) == 0 {
+		return ""
+	}
+	return langs[0]
+}
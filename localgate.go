@@ -0,0 +1,67 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// 启动前等待本地服务：开机自动连接时，本地服务 (数据库、NAS 管理页等) 往往比 Mole 启动得慢，
+// 直接连接会在 frpc 日志里刷出大量 "connect to local service error"。开启后先轮询各规则的本地端口，
+// 全部就绪或超时后再启动 frpc；超时不阻止连接，只记录仍未就绪的规则
+
+const (
+	defaultWaitForLocal  = 120 // 秒
+	maxWaitForLocal      = 1800
+	waitForLocalInterval = 2 * time.Second
+)
+
+// waitForLocalTargets 阻塞直到所有启用的 TCP 类规则的本地端口可连接、超时或 ctx 取消
+func (s *MoleService) waitForLocalTargets(ctx context.Context, rules []ProxyRule, timeout int) {
+	if timeout <= 0 {
+		timeout = defaultWaitForLocal
+	}
+	deadline := time.Now().Add(time.Duration(timeout) * time.Second)
+
+	var pending []ProxyRule
+	for _, p := range rules {
+		// UDP 没有握手，无法判断本地服务是否在监听
+		if p.Enabled && p.ProxyType != "udp" {
+			pending = append(pending, p)
+		}
+	}
+	announced := false
+	for {
+		var down []ProxyRule
+		for _, p := range pending {
+			if _, up := dialTarget(p); !up {
+				down = append(down, p)
+			}
+		}
+		pending = down
+		if len(pending) == 0 {
+			if announced {
+				s.emitLog("本地服务已全部就绪")
+			}
+			return
+		}
+		if time.Now().After(deadline) {
+			names := make([]string, len(pending))
+			for i, p := range pending {
+				names[i] = p.Name
+			}
+			s.emitLog(fmt.Sprintf("等待本地服务超时 (%d 秒)，以下规则的本地服务仍未就绪：%s", timeout, strings.Join(names, "、")))
+			return
+		}
+		if !announced {
+			s.emitLog(fmt.Sprintf("正在等待 %d 个本地服务就绪，最长 %d 秒", len(pending), timeout))
+			announced = true
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(waitForLocalInterval):
+		}
+	}
+}
@@ -0,0 +1,233 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf16"
+)
+
+// 日志历史：发往界面的每一行日志同时追加到 <数据目录>/logs/tunnel.log，按大小轮转
+// 搜索在 Go 中逐行扫描 (支持正则、级别、时间范围)，返回匹配位置，避免在 WebView 里遍历上万行
+// 每行格式为 “接收时间<TAB>原始内容”，接收时间保证没有时间戳的行 (mole 自身消息、SSH 隧道) 也能按时间过滤
+
+const (
+	logHistoryFile     = "tunnel.log"
+	logHistoryMaxSize  = 5 << 20 // 单个文件上限
+	logHistoryBackups  = 3       // 保留 tunnel.log.1 ~ tunnel.log.3
+	logHistoryTimeFmt  = "2006-01-02 15:04:05.000"
+	logSearchLimit     = 500
+	logSearchMaxLimit  = 5000
+	logSearchMaxLength = 4096 // 单行超过此长度时截断匹配，防止异常输出拖慢搜索
+)
+
+// 日志级别，frpc 以 [T] [D] [I] [W] [E] 标记，不带标记的行 (mole 自身输出等) 归为 system
+const (
+	logLevelTrace  = "trace"
+	logLevelDebug  = "debug"
+	logLevelInfo   = "info"
+	logLevelWarn   = "warn"
+	logLevelError  = "error"
+	logLevelSystem = "system"
+)
+
+var reLogLevel = regexp.MustCompile(`\[([TDIWE])\]`)
+
+// logLevel 识别一行日志的级别
+func logLevel(line string) string {
+	m := reLogLevel.FindStringSubmatch(line)
+	if m == nil {
+		return logLevelSystem
+	}
+	return map[string]string{
+		"T": logLevelTrace,
+		"D": logLevelDebug,
+		"I": logLevelInfo,
+		"W": logLevelWarn,
+		"E": logLevelError,
+	}[m[1]]
+}
+
+type logHistory struct {
+	mu   sync.Mutex
+	f    *os.File
+	size int64
+}
+
+func logHistoryPath(n int) string {
+	p := filepath.Join(getAppLogDir(), logHistoryFile)
+	if n > 0 {
+		p += fmt.Sprintf(".%d", n)
+	}
+	return p
+}
+
+// append 写入一批日志，失败时静默丢弃，不能影响日志推送
+func (h *logHistory) append(lines []logLine) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if h.f == nil {
+		f, err := os.OpenFile(logHistoryPath(0), os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+		if err != nil {
+			return
+		}
+		info, _ := f.Stat()
+		h.f, h.size = f, info.Size()
+	}
+
+	var b strings.Builder
+	for _, line := range lines {
+		b.WriteString(line.at.Format(logHistoryTimeFmt))
+		b.WriteByte('\t')
+		b.WriteString(strings.ReplaceAll(line.text, "\n", " "))
+		b.WriteByte('\n')
+	}
+	n, _ := h.f.WriteString(b.String())
+	h.size += int64(n)
+
+	if h.size >= logHistoryMaxSize {
+		h.rotate()
+	}
+}
+
+// rotate 调用方需持有 mu
+func (h *logHistory) rotate() {
+	h.f.Close()
+	h.f, h.size = nil, 0
+	for i := logHistoryBackups; i > 0; i-- {
+		_ = os.Rename(logHistoryPath(i-1), logHistoryPath(i))
+	}
+}
+
+// LogQuery 日志搜索条件
+type LogQuery struct {
+	Pattern       string    `json:"pattern"` // 为空时只按级别和时间过滤
+	Regex         bool      `json:"regex"`
+	CaseSensitive bool      `json:"caseSensitive"`
+	Levels        []string  `json:"levels"` // 为空表示全部级别
+	Since         time.Time `json:"since"`  // 零值表示不限
+	Until         time.Time `json:"until"`
+	Limit         int       `json:"limit"` // 最多返回条数，默认 500
+}
+
+// LogMatch 一条匹配的日志
+type LogMatch struct {
+	Time      time.Time `json:"time"`
+	Level     string    `json:"level"`
+	Line      string    `json:"line"`
+	Positions [][2]int  `json:"positions"` // 匹配区间 [起, 止)，按 UTF-16 计算，可直接用于 JS 字符串
+}
+
+// LogSearchResult 搜索结果，按时间升序，超过 Limit 时保留最新的部分
+type LogSearchResult struct {
+	Matches   []LogMatch `json:"matches"`
+	Scanned   int        `json:"scanned"`
+	Truncated bool       `json:"truncated"`
+}
+
+// SearchLogs 在历史日志中搜索
+func (s *MoleService) SearchLogs(q LogQuery) (LogSearchResult, error) {
+	if err := s.checkUnlocked(); err != nil {
+		return LogSearchResult{}, err
+	}
+	re, err := compileLogQuery(q)
+	if err != nil {
+		return LogSearchResult{}, err
+	}
+	limit := q.Limit
+	if limit <= 0 {
+		limit = logSearchLimit
+	}
+	limit = min(limit, logSearchMaxLimit)
+	levels := make(map[string]bool, len(q.Levels))
+	for _, l := range q.Levels {
+		levels[l] = true
+	}
+
+	res := LogSearchResult{Matches: []LogMatch{}}
+	// 从最旧的备份读到当前文件，结果自然按时间升序
+	for i := logHistoryBackups; i >= 0; i-- {
+		f, err := os.Open(logHistoryPath(i))
+		if err != nil {
+			continue
+		}
+		scanner := bufio.NewScanner(f)
+		scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+		for scanner.Scan() {
+			res.Scanned++
+			m, ok := matchLogLine(scanner.Text(), re, levels, q)
+			if !ok {
+				continue
+			}
+			if len(res.Matches) == limit {
+				res.Matches = res.Matches[1:]
+				res.Truncated = true
+			}
+			res.Matches = append(res.Matches, m)
+		}
+		f.Close()
+	}
+	return res, nil
+}
+
+func compileLogQuery(q LogQuery) (*regexp.Regexp, error) {
+	if q.Pattern == "" {
+		return nil, nil
+	}
+	expr := q.Pattern
+	if !q.Regex {
+		expr = regexp.QuoteMeta(expr)
+	}
+	if !q.CaseSensitive {
+		expr = "(?i)" + expr
+	}
+	re, err := regexp.Compile(expr)
+	if err != nil {
+		return nil, fmt.Errorf("正则表达式无效: %v", err)
+	}
+	return re, nil
+}
+
+func matchLogLine(raw string, re *regexp.Regexp, levels map[string]bool, q LogQuery) (LogMatch, bool) {
+	stamp, line, ok := strings.Cut(raw, "\t")
+	if !ok {
+		return LogMatch{}, false
+	}
+	t, err := time.ParseInLocation(logHistoryTimeFmt, stamp, time.Local)
+	if err != nil {
+		return LogMatch{}, false
+	}
+	if (!q.Since.IsZero() && t.Before(q.Since)) || (!q.Until.IsZero() && t.After(q.Until)) {
+		return LogMatch{}, false
+	}
+	level := logLevel(line)
+	if len(levels) > 0 && !levels[level] {
+		return LogMatch{}, false
+	}
+
+	m := LogMatch{Time: t, Level: level, Line: line}
+	if re != nil {
+		target := line
+		if len(target) > logSearchMaxLength {
+			target = target[:logSearchMaxLength]
+		}
+		locs := re.FindAllStringIndex(target, -1)
+		if len(locs) == 0 {
+			return LogMatch{}, false
+		}
+		for _, loc := range locs {
+			m.Positions = append(m.Positions, [2]int{utf16Len(line[:loc[0]]), utf16Len(line[:loc[1]])})
+		}
+	}
+	return m, true
+}
+
+func utf16Len(s string) int {
+	return len(utf16.Encode([]rune(s)))
+}
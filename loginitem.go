@@ -0,0 +1,31 @@
+package main
+
+import "fmt"
+
+// 登录时启动：注册系统登录自启动项，启动参数带 --autostart，
+// 据此识别由系统自启动，不显示主窗口，只在托盘中运行 (是否自动连接仍由“自动连接”开关决定)
+// 与后台服务不同，这里启动的是带界面的程序本身，不需要管理员权限
+
+const loginItemFlag = "--autostart"
+
+// GetLaunchAtLogin 是否已注册登录时启动
+func (s *MoleService) GetLaunchAtLogin() bool {
+	return loginItemInstalled()
+}
+
+// SetLaunchAtLogin 注册或移除登录时启动
+func (s *MoleService) SetLaunchAtLogin(enabled bool) error {
+	if err := s.checkMutable(); err != nil {
+		return err
+	}
+	var err error
+	if enabled {
+		err = installLoginItem()
+	} else {
+		err = removeLoginItem()
+	}
+	if err != nil {
+		return fmt.Errorf("设置登录时启动失败: %v", err)
+	}
+	return nil
+}
@@ -0,0 +1,55 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// macOS 使用单独的 LaunchAgent，与后台服务的 LaunchAgent 互不影响；只在登录时启动一次，退出后不拉起
+const loginItemLabel = launchAgentLabel + ".login"
+
+func loginItemPath() string {
+	home, _ := os.UserHomeDir()
+	return filepath.Join(home, "Library", "LaunchAgents", loginItemLabel+".plist")
+}
+
+func installLoginItem() error {
+	exePath, err := os.Executable()
+	if err != nil {
+		return err
+	}
+	plistPath := loginItemPath()
+	if err := os.MkdirAll(filepath.Dir(plistPath), 0755); err != nil {
+		return err
+	}
+	plist := fmt.Sprintf(`<?xml version="1.0" encoding="UTF-8"?>
+<!DOCTYPE plist PUBLIC "-//Apple//DTD PLIST 1.0//EN" "http://www.apple.com/DTDs/PropertyList-1.0.dtd">
+<plist version="1.0">
+<dict>
+	<key>Label</key>
+	<string>%s</string>
+	<key>ProgramArguments</key>
+	<array>
+		<string>%s</string>
+		<string>%s</string>
+	</array>
+	<key>RunAtLoad</key>
+	<true/>
+</dict>
+</plist>
+`, loginItemLabel, xmlEscape(exePath), loginItemFlag)
+	return os.WriteFile(plistPath, []byte(plist), 0644)
+}
+
+func removeLoginItem() error {
+	if err := os.Remove(loginItemPath()); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+func loginItemInstalled() bool {
+	_, err := os.Stat(loginItemPath())
+	return err == nil
+}
@@ -0,0 +1,45 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// Linux 使用 XDG 自启动目录 (~/.config/autostart)，主流桌面环境均支持
+func loginItemPath() string {
+	dir, _ := os.UserConfigDir()
+	return filepath.Join(dir, "autostart", serviceName+".desktop")
+}
+
+func installLoginItem() error {
+	exePath, err := os.Executable()
+	if err != nil {
+		return err
+	}
+	path := loginItemPath()
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	entry := fmt.Sprintf(`[Desktop Entry]
+Type=Application
+Name=%s
+Comment=%s
+Exec="%s" %s
+Terminal=false
+X-GNOME-Autostart-enabled=true
+`, serviceDisplayName, serviceDescription, exePath, loginItemFlag)
+	return os.WriteFile(path, []byte(entry), 0644)
+}
+
+func removeLoginItem() error {
+	if err := os.Remove(loginItemPath()); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+func loginItemInstalled() bool {
+	_, err := os.Stat(loginItemPath())
+	return err == nil
+}
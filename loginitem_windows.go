@@ -0,0 +1,45 @@
+package main
+
+import (
+	"os"
+
+	"golang.org/x/sys/windows/registry"
+)
+
+// Windows 使用当前用户的 Run 注册表项
+const loginItemRunKey = `Software\Microsoft\Windows\CurrentVersion\Run`
+
+func installLoginItem() error {
+	exePath, err := os.Executable()
+	if err != nil {
+		return err
+	}
+	k, _, err := registry.CreateKey(registry.CURRENT_USER, loginItemRunKey, registry.SET_VALUE)
+	if err != nil {
+		return err
+	}
+	defer k.Close()
+	return k.SetStringValue(serviceName, `"`+exePath+`" `+loginItemFlag)
+}
+
+func removeLoginItem() error {
+	k, err := registry.OpenKey(registry.CURRENT_USER, loginItemRunKey, registry.SET_VALUE)
+	if err != nil {
+		return err
+	}
+	defer k.Close()
+	if err := k.DeleteValue(serviceName); err != nil && err != registry.ErrNotExist {
+		return err
+	}
+	return nil
+}
+
+func loginItemInstalled() bool {
+	k, err := registry.OpenKey(registry.CURRENT_USER, loginItemRunKey, registry.QUERY_VALUE)
+	if err != nil {
+		return false
+	}
+	defer k.Close()
+	_, _, err = k.GetStringValue(serviceName)
+	return err == nil
+}
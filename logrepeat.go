@@ -0,0 +1,156 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// 重复错误节流：frpc 反复输出同一条警告或错误时 (如每 3 秒一次认证失败)，不再逐条推送到界面，
+// 而是按出现次数指数退避，只推送第 1、2、4、8... 次；重复达到一定次数后整理为一条“持续问题”，
+// 带出现次数与首次、最近出现时间，以 log-issues 事件推送。历史日志仍完整保存
+// 比较时去掉时间戳、运行 ID 与数字，端口、重试次数不同的同一条错误视为重复；一段时间不再出现即视为已恢复
+
+const (
+	logIssueThreshold = 3               // 同一条出现几次算持续问题
+	logIssueQuiet     = 2 * time.Minute // 多久不再出现视为已恢复
+	logIssueMaxKinds  = 50              // 同时跟踪的错误种类上限，超出时淘汰最久未出现的
+)
+
+var (
+	// 2026-01-02 15:04:05.000 [W] [client/service.go:301] [d3a1b2c4] login to the server failed: ...
+	reLogTimestamp = regexp.MustCompile(`^\d{4}[-/]\d{2}[-/]\d{2}[ T]\d{2}:\d{2}:\d{2}(\.\d+)?\s*`)
+	reLogRunID     = regexp.MustCompile(`\[[0-9a-f]{8,16}\]\s*`)
+	reLogDigits    = regexp.MustCompile(`\d+`)
+)
+
+// LogIssue 一条持续出现的警告或错误
+type LogIssue struct {
+	Message   string    `json:"message"` // 最近一次的原文 (不含时间戳)
+	Level     string    `json:"level"`
+	Count     int       `json:"count"`
+	FirstSeen time.Time `json:"firstSeen"`
+	LastSeen  time.Time `json:"lastSeen"`
+}
+
+type logRepeat struct {
+	LogIssue
+	next int // 下一次推送到界面的出现次数
+}
+
+type logRepeatTracker struct {
+	mu    sync.Mutex
+	byKey map[string]*logRepeat
+}
+
+// logRepeatKey 归一化后的日志内容
+func logRepeatKey(line string) string {
+	line = reLogTimestamp.ReplaceAllString(line, "")
+	line = reLogRunID.ReplaceAllString(line, "")
+	return reLogDigits.ReplaceAllString(strings.TrimSpace(line), "#")
+}
+
+// filter 返回应推送到界面的日志，changed 表示持续问题列表有变化
+func (t *logRepeatTracker) filter(entries []LogEntry) (kept []LogEntry, changed bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.byKey == nil {
+		t.byKey = make(map[string]*logRepeat)
+	}
+	kept = entries[:0:0]
+	for _, e := range entries {
+		if e.Level != logLevelWarn && e.Level != logLevelError {
+			kept = append(kept, e)
+			continue
+		}
+		key := logRepeatKey(e.Line)
+		r, ok := t.byKey[key]
+		if !ok {
+			t.evictLocked()
+			r = &logRepeat{LogIssue: LogIssue{Level: e.Level, FirstSeen: e.Time}, next: 1}
+			t.byKey[key] = r
+		}
+		r.Count++
+		r.LastSeen = e.Time
+		r.Message = strings.TrimSpace(reLogTimestamp.ReplaceAllString(e.Line, ""))
+		if r.Count >= logIssueThreshold {
+			changed = true
+		}
+		if r.Count >= r.next {
+			r.next *= 2
+			if r.Count > 1 {
+				e.Line = fmt.Sprintf("%s (第 %d 次出现)", e.Line, r.Count)
+			}
+			kept = append(kept, e)
+		}
+	}
+	return kept, changed
+}
+
+// evictLocked 种类达到上限时淘汰最久未出现的一条
+func (t *logRepeatTracker) evictLocked() {
+	if len(t.byKey) < logIssueMaxKinds {
+		return
+	}
+	var oldest string
+	for k, r := range t.byKey {
+		if oldest == "" || r.LastSeen.Before(t.byKey[oldest].LastSeen) {
+			oldest = k
+		}
+	}
+	delete(t.byKey, oldest)
+}
+
+// sweep 移除长时间未再出现的记录，持续问题有变化时返回 true
+func (t *logRepeatTracker) sweep(now time.Time) bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	changed := false
+	for k, r := range t.byKey {
+		if now.Sub(r.LastSeen) > logIssueQuiet {
+			if r.Count >= logIssueThreshold {
+				changed = true
+			}
+			delete(t.byKey, k)
+		}
+	}
+	return changed
+}
+
+// list 当前的持续问题，最近出现的在前
+func (t *logRepeatTracker) list() []LogIssue {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	out := []LogIssue{}
+	for _, r := range t.byKey {
+		if r.Count >= logIssueThreshold {
+			out = append(out, r.LogIssue)
+		}
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].LastSeen.After(out[j].LastSeen) })
+	return out
+}
+
+// sweepLogIssues 由日志刷新协程定期调用
+func (s *MoleService) sweepLogIssues() {
+	if s.logRepeat.sweep(time.Now()) {
+		s.events.Emit("log-issues", s.logRepeat.list())
+	}
+}
+
+// GetLogIssues 当前持续出现的警告与错误
+func (s *MoleService) GetLogIssues() ([]LogIssue, error) {
+	if err := s.checkUnlocked(); err != nil {
+		return nil, err
+	}
+	if s.remote != nil {
+		var list []LogIssue
+		err := s.remote.call(http.MethodGet, "/api/log-issues", &list)
+		return list, err
+	}
+	return s.logRepeat.list(), nil
+}
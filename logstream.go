@@ -0,0 +1,126 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// 日志暂停与快照：用户往回翻看、复制日志时，新到的批次会把视图拉回底部。
+// 暂停后推送到界面的日志先缓存在后端，继续时一次性补发；快照返回最近的日志和对应的序号，
+// 界面据此一次性渲染，不会和随后到达的批次错位。落盘的日志历史不受暂停影响
+// 附着守护进程时，守护进程推送的日志同样在界面进程中经过这里
+
+const (
+	logRecentMax = 1000 // 快照保留的最近日志条数
+	logPausedMax = 5000 // 暂停期间最多缓存的条数，超出时丢弃最旧的
+)
+
+type logStream struct {
+	mu      sync.Mutex
+	seq     uint64
+	recent  []LogEntry
+	paused  bool
+	held    []LogEntry
+	dropped int
+}
+
+// LogSnapshot 最近的日志，Seq 为最后一条的序号，之后推送的日志序号都更大
+type LogSnapshot struct {
+	Entries []LogEntry `json:"entries"`
+	Seq     uint64     `json:"seq"`
+	Paused  bool       `json:"paused"`
+	Held    int        `json:"held"` // 暂停期间缓存的条数
+}
+
+// publish 编号并记录最近日志，返回需要立即推送的部分，暂停时返回空
+func (l *logStream) publish(entries []LogEntry) []LogEntry {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := time.Now()
+	for i := range entries {
+		l.seq++
+		entries[i].Seq = l.seq
+		if entries[i].Time.IsZero() {
+			entries[i].Time = now
+		}
+	}
+	l.recent = append(l.recent, entries...)
+	if n := len(l.recent) - logRecentMax; n > 0 {
+		l.recent = append([]LogEntry(nil), l.recent[n:]...)
+	}
+
+	if !l.paused {
+		return entries
+	}
+	l.held = append(l.held, entries...)
+	if n := len(l.held) - logPausedMax; n > 0 {
+		l.held = l.held[n:]
+		l.dropped += n
+	}
+	return nil
+}
+
+// PauseLogStream 暂停向界面推送日志，期间的日志缓存在后端
+func (s *MoleService) PauseLogStream() {
+	s.logStream.mu.Lock()
+	s.logStream.paused = true
+	s.logStream.mu.Unlock()
+}
+
+// ResumeLogStream 继续推送，并把暂停期间缓存的日志作为一批补发，返回补发的条数
+func (s *MoleService) ResumeLogStream() int {
+	s.logStream.mu.Lock()
+	held, dropped := s.logStream.held, s.logStream.dropped
+	s.logStream.paused, s.logStream.held, s.logStream.dropped = false, nil, 0
+	s.logStream.mu.Unlock()
+
+	if dropped > 0 {
+		note := LogEntry{
+			Line:  fmt.Sprintf("暂停期间日志过多，已省略较早的 %d 行，完整内容可在日志搜索中查看", dropped),
+			Level: logLevelSystem,
+			Time:  time.Now(),
+		}
+		held = append(s.logClock.stamp([]LogEntry{note}), held...)
+	}
+	if len(held) > 0 {
+		s.events.Emit("frp-logs", held)
+	}
+	return len(held)
+}
+
+// GetLogSnapshot 返回最近的日志，用于暂停后一次性渲染可回翻的内容
+func (s *MoleService) GetLogSnapshot() (LogSnapshot, error) {
+	if err := s.checkUnlocked(); err != nil {
+		return LogSnapshot{}, err
+	}
+	s.logStream.mu.Lock()
+	defer s.logStream.mu.Unlock()
+	return LogSnapshot{
+		Entries: append([]LogEntry{}, s.logStream.recent...),
+		Seq:     s.logStream.seq,
+		Paused:  s.logStream.paused,
+		Held:    len(s.logStream.held),
+	}, nil
+}
+
+// forwardRemoteEvent 附着模式下转发守护进程的事件，日志批次经过暂停与快照处理
+func (s *MoleService) forwardRemoteEvent(name string, data any) {
+	if name != "frp-logs" {
+		s.events.Emit(name, data)
+		return
+	}
+	raw, err := json.Marshal(data)
+	if err != nil {
+		return
+	}
+	var entries []LogEntry
+	if json.Unmarshal(raw, &entries) != nil {
+		return
+	}
+	if entries = s.logStream.publish(entries); len(entries) > 0 {
+		s.events.Emit(name, entries)
+	}
+}
@@ -0,0 +1,170 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+)
+
+// 日志高亮：用户在偏好设置中定义“文本或正则 → 严重程度 / 颜色”，后端给命中的日志行打标签，
+// 前端据此着色，自己的域名、"error" 之类的重要消息不会淹没在大量输出里
+// 规则随配置加载与保存更新 (configure)，发送日志时只读取已编译的规则，不碰 s.mu
+
+const logHighlightMax = 20
+
+// 可选的颜色，与前端 CSS 类 hl-<color> 对应
+var logHighlightColors = map[string]bool{
+	"red": true, "orange": true, "yellow": true, "green": true, "blue": true, "purple": true,
+}
+
+var logHighlightSeverities = map[string]bool{
+	"": true, logLevelInfo: true, logLevelWarn: true, logLevelError: true,
+}
+
+// LogHighlightRule 一条高亮规则
+type LogHighlightRule struct {
+	Pattern  string `toml:"pattern" json:"pattern"`
+	Regex    bool   `toml:"regex" json:"regex"`
+	Severity string `toml:"severity,omitempty" json:"severity"` // info / warn / error，为空时不改变级别
+	Color    string `toml:"color,omitempty" json:"color"`
+}
+
+// LogEntry 推送给前端的一行日志
+type LogEntry struct {
+	Line     string `json:"line"`
+	Level    string `json:"level"`              // 由 [I] [W] [E] 等标记识别，见 logLevel
+	Severity string `json:"severity,omitempty"` // 命中高亮规则时的严重程度
+	Color    string `json:"color,omitempty"`
+
+	Seq   uint64    `json:"seq"`   // 界面进程内的递增序号，见 logstream.go
+	Time  time.Time `json:"time"`  // 接收时间
+	Stamp string    `json:"stamp"` // 按偏好设置格式化的接收时间，见 logtime.go
+}
+
+type compiledHighlight struct {
+	LogHighlightRule
+	re *regexp.Regexp
+}
+
+type logTagger struct {
+	mu       sync.RWMutex
+	rules    []compiledHighlight
+	warnOnly bool // 只向界面推送警告和错误
+}
+
+func compileHighlight(r LogHighlightRule) (*regexp.Regexp, error) {
+	expr := r.Pattern
+	if !r.Regex {
+		expr = regexp.QuoteMeta(expr)
+	}
+	return regexp.Compile("(?i)" + expr)
+}
+
+// configure 按偏好设置更新规则，无效的规则直接跳过 (保存时已校验)
+func (t *logTagger) configure(prefs Preferences) {
+	compiled := make([]compiledHighlight, 0, len(prefs.LogHighlights))
+	for _, r := range prefs.LogHighlights {
+		if r.Pattern == "" {
+			continue
+		}
+		re, err := compileHighlight(r)
+		if err != nil {
+			continue
+		}
+		compiled = append(compiled, compiledHighlight{LogHighlightRule: r, re: re})
+	}
+	t.mu.Lock()
+	t.rules = compiled
+	t.warnOnly = prefs.LogForwardLevel == logLevelWarn
+	t.mu.Unlock()
+}
+
+// tag 第一条命中的规则生效
+func (t *logTagger) tag(lines []logLine) []LogEntry {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+
+	entries := make([]LogEntry, len(lines))
+	for i, l := range lines {
+		line := l.text
+		entries[i].Line = line
+		entries[i].Time = l.at
+		entries[i].Level = logLevel(line)
+		for _, r := range t.rules {
+			if r.re.MatchString(line) {
+				entries[i].Severity, entries[i].Color = r.Severity, r.Color
+				break
+			}
+		}
+	}
+	return entries
+}
+
+// forward 过滤出需要推送到界面的日志
+// 只看警告和错误时，不带级别标记的行 (mole 自身的状态消息，数量很少) 仍然推送，命中规则的行按规则级别判断
+func (t *logTagger) forward(entries []LogEntry) []LogEntry {
+	t.mu.RLock()
+	warnOnly := t.warnOnly
+	t.mu.RUnlock()
+	if !warnOnly {
+		return entries
+	}
+	kept := entries[:0:0]
+	for _, e := range entries {
+		level := e.Level
+		if e.Severity != "" {
+			level = e.Severity
+		}
+		if level == logLevelWarn || level == logLevelError || level == logLevelSystem {
+			kept = append(kept, e)
+		}
+	}
+	return kept
+}
+
+// SetLogForwardLevel 设置推送到界面的日志级别：空字符串为全部，warn 为只看警告和错误 (历史日志仍完整保存)
+func (s *MoleService) SetLogForwardLevel(level string) error {
+	if level != "" && level != logLevelWarn {
+		return fmt.Errorf("无效的日志级别: %s", level)
+	}
+	cfg := s.status().Config
+	if cfg == nil {
+		return fmt.Errorf("未发现有效配置")
+	}
+	newCfg := *cfg
+	newCfg.Preferences.LogForwardLevel = level
+	return s.saveUserConfig(newCfg)
+}
+
+// SetLogHighlights 保存日志高亮规则
+func (s *MoleService) SetLogHighlights(rules []LogHighlightRule) error {
+	if len(rules) > logHighlightMax {
+		return fmt.Errorf("最多设置 %d 条高亮规则", logHighlightMax)
+	}
+	for i, r := range rules {
+		r.Pattern = strings.TrimSpace(r.Pattern)
+		if r.Pattern == "" {
+			return fmt.Errorf("第 %d 条规则的匹配内容为空", i+1)
+		}
+		if _, err := compileHighlight(r); err != nil {
+			return fmt.Errorf("第 %d 条规则的正则表达式无效: %v", i+1, err)
+		}
+		if r.Color != "" && !logHighlightColors[r.Color] {
+			return fmt.Errorf("第 %d 条规则的颜色无效: %s", i+1, r.Color)
+		}
+		if !logHighlightSeverities[r.Severity] {
+			return fmt.Errorf("第 %d 条规则的级别无效: %s", i+1, r.Severity)
+		}
+		rules[i] = r
+	}
+
+	cfg := s.status().Config
+	if cfg == nil {
+		return fmt.Errorf("未发现有效配置")
+	}
+	newCfg := *cfg
+	newCfg.Preferences.LogHighlights = rules
+	return s.saveUserConfig(newCfg)
+}
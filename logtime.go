@@ -0,0 +1,106 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"sync"
+	"time"
+)
+
+// 日志时间：每行日志在读到时记下接收时间，推送给界面时按偏好设置格式化为本地时间或 UTC
+// frpc 自带的时间戳是其所在机器的本地时间，跨时区排查时容易看错，可以选择在界面中去掉
+
+const (
+	logTimeZoneLocal = ""
+	logTimeZoneUTC   = "utc"
+)
+
+// 可选的时间格式，空字符串为默认的时:分:秒
+var logTimeLayouts = map[string]string{
+	"":         "15:04:05",
+	"datetime": "2006-01-02 15:04:05",
+	"ms":       "15:04:05.000",
+	"rfc3339":  time.RFC3339,
+}
+
+// frpc 日志行开头的时间戳，如 "2024-05-01 12:00:00.123 "
+var frpcTimePrefix = regexp.MustCompile(`^\d{4}[-/]\d{2}[-/]\d{2} \d{2}:\d{2}:\d{2}(\.\d+)? `)
+
+// logLine 缓冲区中的一行日志及其接收时间
+type logLine struct {
+	at   time.Time
+	text string
+}
+
+// stampNow 用当前时间标记一组日志，mole 自身产生的消息走这里
+func stampNow(lines []string) []logLine {
+	now := time.Now()
+	stamped := make([]logLine, len(lines))
+	for i, line := range lines {
+		stamped[i] = logLine{at: now, text: line}
+	}
+	return stamped
+}
+
+type logClock struct {
+	mu        sync.RWMutex
+	utc       bool
+	layout    string
+	stripFrpc bool // 去掉 frpc 自带的时间戳
+}
+
+func validateLogTime(prefs Preferences) error {
+	if prefs.LogTimeZone != logTimeZoneLocal && prefs.LogTimeZone != logTimeZoneUTC {
+		return fmt.Errorf("无效的日志时区: %s", prefs.LogTimeZone)
+	}
+	if _, ok := logTimeLayouts[prefs.LogTimeFormat]; !ok {
+		return fmt.Errorf("无效的日志时间格式: %s", prefs.LogTimeFormat)
+	}
+	return nil
+}
+
+// configure 随配置加载与保存更新，无效的值回落到默认 (保存时已校验)
+func (c *logClock) configure(prefs Preferences) {
+	layout, ok := logTimeLayouts[prefs.LogTimeFormat]
+	if !ok {
+		layout = logTimeLayouts[""]
+	}
+	c.mu.Lock()
+	c.utc = prefs.LogTimeZone == logTimeZoneUTC
+	c.layout = layout
+	c.stripFrpc = prefs.LogStripFrpcTime
+	c.mu.Unlock()
+}
+
+// stamp 为推送的日志填写格式化后的时间，历史日志不经过这里，始终保存原始内容
+func (c *logClock) stamp(entries []LogEntry) []LogEntry {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	for i := range entries {
+		t := entries[i].Time
+		if c.utc {
+			t = t.UTC()
+		} else {
+			t = t.Local()
+		}
+		entries[i].Stamp = t.Format(c.layout)
+		if c.stripFrpc {
+			entries[i].Line = frpcTimePrefix.ReplaceAllString(entries[i].Line, "")
+		}
+	}
+	return entries
+}
+
+// SetLogTimeFormat 设置界面中日志时间的时区 (空为本地，utc) 与格式，以及是否去掉 frpc 自带的时间戳
+func (s *MoleService) SetLogTimeFormat(zone, format string, stripFrpc bool) error {
+	cfg := s.status().Config
+	if cfg == nil {
+		return fmt.Errorf("未发现有效配置")
+	}
+	newCfg := *cfg
+	newCfg.Preferences.LogTimeZone = zone
+	newCfg.Preferences.LogTimeFormat = format
+	newCfg.Preferences.LogStripFrpcTime = stripFrpc
+	return s.saveUserConfig(newCfg)
+}
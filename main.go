@@ -5,12 +5,10 @@ import (
 	_ "embed"
 	"log"
 	"log/slog"
-	"runtime"
 	"time"
 
 	"github.com/wailsapp/wails/v3/pkg/application"
 	"github.com/wailsapp/wails/v3/pkg/events"
-	"github.com/wailsapp/wails/v3/pkg/icons"
 )
 
 // Wails uses Go's `embed` package to embed the frontend files into the binary.
@@ -32,6 +30,7 @@ func init() {
 type AppManager struct {
 	App        *application.App
 	MainWindow application.Window
+	MiniWindow application.Window // 迷你状态窗，首次打开时创建，见 miniwindow.go
 }
 
 var manager = &AppManager{}
@@ -40,8 +39,49 @@ var manager = &AppManager{}
 // and starts a goroutine that emits a time-based event every second. It subsequently runs the application and
 // logs any error that might occur.
 func main() {
+	parseFlags()
 
-	ms := NewMoleService()
+	// 模拟模式下由主程序拉起的假 frpc
+	if handleFakeFrpcCommand() {
+		return
+	}
+
+	// 安装/卸载后台服务，完成后直接退出
+	if handleServiceCommand() {
+		return
+	}
+	// 导出服务器部署包，完成后直接退出
+	if handleExportDeployCommand() {
+		return
+	}
+	// 安装/卸载全机共享的 frpc，完成后直接退出
+	if handleSharedFrpcCommand() {
+		return
+	}
+	// 转换 frpc.ini，完成后直接退出
+	if handleConvertCommand() {
+		return
+	}
+	// 无界面守护进程模式
+	if appFlags.Daemon {
+		runDaemon()
+		return
+	}
+
+	// 调试模式下接管日志输出并开启 pprof
+	debugLogger, closeDebug := setupDebug()
+	defer closeDebug()
+
+	// 事件发送器先于应用创建，待 App 创建后再绑定
+	emitter := &wailsEmitter{}
+	ms := NewMoleService(emitter)
+	// GUI 只做客户端：frpc 进程与配置由后台守护进程管理，关闭界面不影响隧道
+	// 拉起守护进程失败或指定 --standalone 时退回进程内模式
+	if !appFlags.Standalone {
+		if remote := ensureDaemon(); remote != nil {
+			ms.attachRemote(remote)
+		}
+	}
 	// Create a new Wails application by providing the necessary options.
 	// Variables 'Name' and 'Description' are for application metadata.
 	// 'Assets' configures the asset server with the 'FS' variable pointing to the frontend files.
@@ -51,12 +91,26 @@ func main() {
 		Name:        "FRP管理客户端",
 		Description: "一个实现自动内网穿透的管理工具",
 		LogLevel:    slog.LevelDebug,
+		Logger:      debugLogger, // 为 nil 时使用 Wails 默认日志
 		Services: []application.Service{
 			application.NewService(ms),
 		},
 		Assets: application.AssetOptions{
 			Handler: application.AssetFileServerFS(assets),
 		},
+		// 双击 .moleprofile 打开本程序并进入导入流程
+		FileAssociations: []string{".moleprofile"},
+		// 已在运行时再次启动 (如双击配置文件)，把文件交给正在运行的实例
+		SingleInstance: &application.SingleInstanceOptions{
+			UniqueID: "top.91demo.mole",
+			OnSecondInstanceLaunch: func(data application.SecondInstanceData) {
+				ms.importFiles(importableFiles(data.Args))
+				if manager.MainWindow != nil {
+					manager.MainWindow.Show()
+					manager.MainWindow.Focus()
+				}
+			},
+		},
 		Mac: application.MacOptions{
 			ApplicationShouldTerminateAfterLastWindowClosed: true,
 		},
@@ -69,6 +123,8 @@ func main() {
 		},
 	})
 
+	emitter.app = manager.App
+
 	// Create a new window with the necessary options.
 	// 'Title' is the title of the window.
 	// 'Mac' options tailor the window when running on macOS.
@@ -90,8 +146,26 @@ func main() {
 			TitleBar:                application.MacTitleBarHiddenInset,
 			// macOS 禁用缩放也会自动禁用全屏按钮
 		},
-		BackgroundColour: application.NewRGB(27, 38, 54),
-		URL:              "/",
+		BackgroundColour:  application.NewRGB(27, 38, 54),
+		URL:               "/",
+		EnableDragAndDrop: true,
+		// 登录时自启动不弹出窗口，只在托盘中运行
+		Hidden: appFlags.Autostart,
+	})
+
+	// 拖入配置文件 (frpc.toml / frpc.ini / .moleprofile) 时解析并弹出导入预览
+	manager.MainWindow.OnWindowEvent(events.Common.WindowFilesDropped, func(e *application.WindowEvent) {
+		ms.importFiles(e.Context().DroppedFiles())
+	})
+	// macOS 通过系统事件传入双击的文件，Windows / Linux 则作为启动参数传入
+	manager.App.Event.OnApplicationEvent(events.Common.ApplicationOpenedWithFile, func(e *application.ApplicationEvent) {
+		ms.importFiles([]string{e.Context().Filename()})
+		manager.MainWindow.Show()
+	})
+	ms.importFiles(appFlags.OpenFiles)
+	// 切回窗口时识别剪贴板中的分享码 (偏好设置中开启后生效)
+	manager.MainWindow.OnWindowEvent(events.Common.WindowFocus, func(e *application.WindowEvent) {
+		go ms.checkClipboardShareCode()
 	})
 
 	manager.MainWindow.RegisterHook(events.Common.WindowClosing, func(e *application.WindowEvent) {
@@ -111,33 +185,8 @@ func main() {
 		}
 	}()
 
-	systemTray := manager.App.SystemTray.New()
-
-	// Use the template icon on macOS so the clock respects light/dark modes.
-	if runtime.GOOS == "darwin" {
-		systemTray.SetTemplateIcon(icons.SystrayMacTemplate)
-	}
-
-	// 2. 定义左键点击逻辑：显示并聚焦窗口
-	systemTray.OnClick(func() {
-		if manager.MainWindow != nil {
-			manager.MainWindow.Show()
-			manager.MainWindow.Focus()
-		}
-	})
-
-	// 3. 定义右键菜单
-	menu := manager.App.NewMenu()
-	menu.Add("显示窗口").OnClick(func(ctx *application.Context) {
-		manager.MainWindow.Show()
-		manager.MainWindow.Focus()
-	})
-	menu.AddSeparator() // 分割线
-	menu.Add("退出").OnClick(func(ctx *application.Context) {
-		manager.App.Quit()
-	})
-
-	systemTray.SetMenu(menu)
+	// 托盘图标与菜单，菜单随隧道状态重建
+	newTrayMenu(manager.App, ms)
 
 	// Run the application. This blocks until the application has been exited.
 	err := manager.App.Run()
@@ -0,0 +1,60 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+)
+
+// 定时重启：部分网络环境下 frpc 长时间运行后连接质量下降，可设置每天固定时间重启一次隧道
+// 只在隧道运行中重启；重启属于主动操作，不触发断开告警
+
+const maintenanceCheckInterval = 30 * time.Second
+
+// parseDailyTime 解析 "HH:MM"，返回当天零点起的偏移
+func parseDailyTime(v string) (time.Duration, error) {
+	t, err := time.Parse("15:04", v)
+	if err != nil {
+		return 0, fmt.Errorf("定时重启时间格式应为 HH:MM: %s", v)
+	}
+	return time.Duration(t.Hour())*time.Hour + time.Duration(t.Minute())*time.Minute, nil
+}
+
+// runMaintenanceRestart 每 30 秒检查一次是否到了设定的重启时间，每天最多重启一次
+func (s *MoleService) runMaintenanceRestart(ctx context.Context) {
+	lastDay := ""
+	ticker := time.NewTicker(maintenanceCheckInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			s.mu.RLock()
+			at := ""
+			if s.config != nil {
+				at = s.config.Preferences.RestartAt
+			}
+			s.mu.RUnlock()
+			offset, err := parseDailyTime(at)
+			if at == "" || err != nil {
+				continue
+			}
+			day := now.Format(time.DateOnly)
+			midnight := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+			// 只在设定时间后的一个检查周期内触发，避免开机晚于设定时间时立即重启
+			if elapsed := now.Sub(midnight.Add(offset)); day == lastDay || elapsed < 0 || elapsed >= 2*maintenanceCheckInterval {
+				continue
+			}
+			lastDay = day
+			if !s.isRunning.Load() {
+				continue
+			}
+			s.emitLog("到达定时重启时间，正在重启隧道")
+			if err := s.restartFrpIfRunning(); err != nil {
+				log.Printf("定时重启失败: %v", err)
+			}
+		}
+	}
+}
@@ -0,0 +1,62 @@
+package main
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/wailsapp/wails/v3/pkg/application"
+	"github.com/wailsapp/wails/v3/pkg/events"
+)
+
+// 迷你状态窗：无边框、置顶的小窗口，只显示连接状态、在线规则、延迟和连接开关，
+// 适合玩游戏或直播时把隧道状态放在屏幕角落。页面为 frontend/mini.html，首次打开时创建，关闭时只隐藏
+
+const (
+	miniWindowName   = "mini"
+	miniWindowWidth  = 260
+	miniWindowHeight = 120
+)
+
+var miniWindowOnce sync.Once
+
+func miniWindow() application.Window {
+	miniWindowOnce.Do(func() {
+		w := manager.App.Window.NewWithOptions(application.WebviewWindowOptions{
+			Name:             miniWindowName,
+			Title:            "Mole",
+			Width:            miniWindowWidth,
+			Height:           miniWindowHeight,
+			DisableResize:    true,
+			Frameless:        true,
+			AlwaysOnTop:      true,
+			Hidden:           true,
+			BackgroundColour: application.NewRGB(27, 38, 54),
+			URL:              "/mini.html",
+		})
+		w.RegisterHook(events.Common.WindowClosing, func(e *application.WindowEvent) {
+			w.Hide()
+			e.Cancel()
+		})
+		manager.MiniWindow = w
+	})
+	return manager.MiniWindow
+}
+
+// toggleMiniWindow 显示或隐藏迷你状态窗
+func toggleMiniWindow() {
+	w := miniWindow()
+	if w.IsVisible() {
+		w.Hide()
+		return
+	}
+	w.Show()
+}
+
+// ToggleMiniWindow 供界面按钮调用
+func (s *MoleService) ToggleMiniWindow() error {
+	if manager.App == nil {
+		return fmt.Errorf("当前模式不支持迷你状态窗")
+	}
+	toggleMiniWindow()
+	return nil
+}
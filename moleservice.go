@@ -21,10 +21,35 @@ import (
 )
 
 type ServiceStatus struct {
-	Success   bool        `json:"success"`
-	IsRunning bool        `json:"isRunning"`
-	Config    *UserConfig `json:"config"` // 关键：记录是否已完成配置
-	Message   string      `json:"message"`
+	Success      bool        `json:"success"`
+	IsRunning    bool        `json:"isRunning"`
+	Config       *UserConfig `json:"config"` // 关键：记录是否已完成配置
+	Message      string      `json:"message"`
+	Locked       bool        `json:"locked"`       // 应用锁定中，此时不返回配置
+	TokenHidden  bool        `json:"tokenHidden"`  // 开启应用锁后 Token 不随配置下发，需通过 RevealToken 验证后查看
+	ReadOnly     bool        `json:"readOnly"`     // 只读模式，只允许连接 / 断开
+	Initializing bool        `json:"initializing"` // 仍在读取配置与数据库，其余字段尚无意义，前端收到 service-ready 后重新查询
+
+	// 连接状态与时间，见 tunnelstate.go
+	TunnelState     string     `json:"tunnelState"`     // idle / preparing / connecting / connected / reconnecting / stopping / error
+	LastError       string     `json:"lastError"`       // 最近一次失败原因，成功连接后保留以便排查
+	ConnectedSince  *time.Time `json:"connectedSince"`  // 本次连接成功的时间，未连接时为空
+	LastStateChange *time.Time `json:"lastStateChange"` // 最近一次状态变化的时间
+
+	FrpcResource *FrpcResource `json:"frpcResource"` // frpc 进程的 CPU 与内存，未运行时为空，见 resource.go
+}
+
+// 日志批量推送间隔
+const logFlushInterval = 500 * time.Millisecond
+
+// 配置落盘防抖间隔：表单输入时前端可能频繁调用保存
+const saveDebounceDelay = 500 * time.Millisecond
+
+// ConfigSaveEvent 配置落盘进度，State 取值 pending / saving / saved / failed
+type ConfigSaveEvent struct {
+	State   string `json:"state"`
+	Message string `json:"message"`
+	Time    string `json:"time"`
 }
 
 type MoleService struct {
@@ -33,18 +58,114 @@ type MoleService struct {
 	initWait chan struct{}
 	mu       sync.RWMutex
 
+	// --- 事件推送 (由外部注入，不直接依赖 Wails 应用实例) ---
+	events EventEmitter
+	bus    *eventBus // 与 events 为同一对象，供进程内集成订阅事件
+
+	// --- 守护进程 (GUI 作为客户端时非空，连接控制与配置读写均转发给它) ---
+	remote *controlClient
+
 	// --- 连接与配置 ---
 	config *UserConfig
 
 	// --- 状态标识 (使用原子操作减少锁竞争) ---
 	isRunning atomic.Bool // 仅记录 frp 进程是否在后台运行
 
+	// --- 隧道告警 (意外断开 / 恢复) ---
+	health        tunnelHealth
+	stopRequested atomic.Bool // 用户主动断开，进程退出时不算告警
+
+	// --- 连接 / 断开串行化，见 lifecycle.go ---
+	opMu sync.Mutex
+
+	// --- 剪贴板分享码识别 ---
+	clipWatch clipWatch
+
+	// --- 连接状态与时间 ---
+	tunnel tunnelTracker
+
+	// --- frpc 资源占用采样 ---
+	resource resourceMonitor
+
+	// --- 断线自动重连 ---
+	reconnect reconnector
+
 	// --- FRP 进程管理 ---
-	frpCmd *exec.Cmd
+	frpCmd   *exec.Cmd
+	frpAdmin frpcAdmin // 本次进程的管理接口，用于热重载
+
+	// --- SSH 反向隧道 (备用传输方式) ---
+	sshTunnel *sshTunnel
+
+	// --- 内置 frps 服务端 ---
+	frps frpsServer
+
+	// --- 逐条规则延迟采样 ---
+	latency latencyStore
+
+	// --- 最近一次可用的 frpc.toml，见 lastgood.go ---
+	runtimeCfg runtimeConfigState
+
+	// --- 启动自检，见 selftest.go ---
+	selfTest selfTestTracker
+
+	// --- 逐条规则运行状态 ---
+	proxyStates proxyStateTracker
+
+	// --- 本地目标离线时自动暂停规则 ---
+	autoPause autoPauser
+
+	// --- 规则启动顺序 (分阶段热重载) ---
+	startStage startStager
+
+	// --- 快速分享 (不保存的临时规则)，见 quickexpose.go ---
+	quick quickExposeSet
+
+	// --- HTTP 访问记录代理，见 requestlog.go ---
+	reqLog requestLogger
+
+	// --- 按来源 IP 的访问控制代理，见 accessfilter.go ---
+	access accessFilterSet
+
+	// --- 待确认的配置导入 ---
+	imports importStore
+
+	// --- 匿名使用统计 (默认关闭) ---
+	telemetry telemetryStore
+
+	// --- 本地数据库 (运行统计、会话记录、审计日志、配置历史) ---
+	store kvStore
+
+	// --- 应用锁 (PIN) ---
+	appLock appLock
+
+	// --- 系统级受管配置 (只读模式) ---
+	managed ManagedPolicy
+
+	// --- 局域网接口 (HTTPS + 访问令牌) ---
+	lanAPI lanAPIServer
+
+	// --- 配置变更通知与外部修改检测 ---
+	configWatch configWatch
+
+	// --- 配置异步落盘 ---
+	saveMu      sync.Mutex  // 保护 saveTimer、savePending
+	saveTimer   *time.Timer // 防抖定时器
+	savePending bool        // 有尚未开始写盘的保存
+	persistMu   sync.Mutex  // 串行化写盘
+
+	// --- 路由器端口映射 (UPnP / NAT-PMP) ---
+	portMapMu sync.Mutex
+	portMaps  map[string]*activePortMap // key 为规则 ID
 
 	// --- 日志缓冲区 ---
-	logMu     sync.Mutex
-	logBuffer []string // 建议在初始化时 make([]string, 0, 128)
+	logMu      sync.Mutex
+	logBuffer  []logLine        // 建议在初始化时 make([]logLine, 0, 128)，每行记下接收时间
+	logHistory logHistory       // 落盘的日志历史，供搜索
+	logTagger  logTagger        // 用户定义的日志高亮规则
+	logClock   logClock         // 推送日志的时区与时间格式
+	logStream  logStream        // 推送暂停与最近日志快照
+	logRepeat  logRepeatTracker // 重复错误节流与持续问题，见 logrepeat.go
 
 }
 
@@ -60,55 +181,243 @@ type UserConfig struct {
 		Token     string `toml:"token" json:"token"`
 		Remark    string `toml:"remark" json:"remark"`        // 用户给这台服务器起的别名
 		AutoStart bool   `toml:"auto_start" json:"autoStart"` // 软件启动时是否自动开启穿透
+		Transport string `toml:"transport" json:"transport"`  // 传输方式："frp" (默认)、"ssh" 或 "frp-ssh"
+
+		// Token 来源，如 env:FRP_TOKEN、cmd:/path/to/script，设置后忽略 Token，见 secrets.go
+		TokenSource string `toml:"token_source,omitempty" json:"tokenSource"`
+
+		// TLS 握手参数，见 frptls.go
+		TLSServerName             string `toml:"tls_server_name,omitempty" json:"tlsServerName"`                           // 握手时发送的 SNI，为空时使用服务器地址
+		TLSDisableCustomFirstByte *bool  `toml:"tls_disable_custom_first_byte,omitempty" json:"tlsDisableCustomFirstByte"` // 为空时使用 frp 默认值
+		TLSTrustedCAFile          string `toml:"tls_trusted_ca_file,omitempty" json:"tlsTrustedCAFile"`                    // 校验 frps 证书的 CA，由 frpc 校验
+		TLSPinSHA256              string `toml:"tls_pin_sha256,omitempty" json:"tlsPinSHA256"`                             // frps 证书的 SHA-256 指纹，连接前由 Mole 校验
+
+		// 追加到 frpc 启动命令的参数，如 --strict_config，见 extraargs.go
+		ExtraArgs []string `toml:"extra_args,omitempty" json:"extraArgs"`
+
+		// frp 用户与代理名前缀，多台机器共用 frps 时避免代理名冲突，见 frpuser.go
+		User       string `toml:"user,omitempty" json:"user"`
+		NamePrefix string `toml:"name_prefix,omitempty" json:"namePrefix"`
 	} `toml:"server" json:"server"`
 
+	// --- SSH 反向隧道参数 (Transport 为 "ssh" 时生效) ---
+	SSH SSHConfig `toml:"ssh" json:"ssh"`
+
+	// --- 内置 frps 服务端 (本机作为服务器时使用) ---
+	Frps FrpsConfig `toml:"frps" json:"frps"`
+
+	// --- MQTT 状态发布 / Home Assistant 自动发现 ---
+	MQTT MQTTConfig `toml:"mqtt" json:"mqtt"`
+
+	// --- 消息通知 (隧道告警推送到聊天工具) ---
+	Notify NotifyConfig `toml:"notify" json:"notify"`
+
+	// --- 邮件告警 (长时间断线) ---
+	Email EmailConfig `toml:"email" json:"email"`
+
+	// --- 命名配置 (托盘一键切换) ---
+	Profiles []ServerProfile `toml:"profiles,omitempty" json:"profiles"`
+
+	// --- 断线重试策略 ---
+	Retry RetryPolicy `toml:"retry" json:"retry"`
+
+	// --- 设备管理 (远程管理其他电脑上的 Mole)，见 fleet.go ---
+	Fleet FleetConfig `toml:"fleet" json:"fleet"`
+
+	// --- 事件插件 (用户脚本订阅事件)，见 plugins.go ---
+	Plugins []EventPlugin `toml:"plugins,omitempty" json:"plugins"`
+
+	// --- 生命周期钩子 (连接前 / 连接后 / 断开后运行命令)，见 hooks.go ---
+	Hooks LifecycleHooks `toml:"hooks" json:"hooks"`
+
+	// --- 偏好设置 ---
+	Preferences Preferences `toml:"preferences" json:"preferences"`
+
+	// --- 规则文件夹 (顺序与折叠状态)，见 folders.go ---
+	Folders []RuleFolder `toml:"folders,omitempty" json:"folders"`
+
 	// --- 代理规则详情 (限制最大3条) ---
 	// 使用 Slice 存储，方便前端循环渲染
 	Proxies []ProxyRule `toml:"proxies" json:"proxies"`
 }
 
+// Preferences 与具体服务器无关的行为开关
+type Preferences struct {
+	AutoPauseDownTargets bool `toml:"auto_pause_down_targets" json:"autoPauseDownTargets"` // 本地服务离线时自动暂停对应规则
+	TelemetryEnabled     bool `toml:"telemetry_enabled" json:"telemetryEnabled"`           // 匿名使用统计，默认关闭
+	AutoLockMinutes      int  `toml:"auto_lock_minutes" json:"autoLockMinutes"`            // 应用锁开启时无操作多少分钟后自动锁定，0 为不自动锁定
+	LockOnSessionLock    bool `toml:"lock_on_session_lock" json:"lockOnSessionLock"`       // 系统锁屏时一并锁定
+
+	LogHighlights   []LogHighlightRule `toml:"log_highlights,omitempty" json:"logHighlights"`      // 日志高亮规则
+	LogForwardLevel string             `toml:"log_forward_level,omitempty" json:"logForwardLevel"` // 推送到界面的日志级别，warn 为只看警告和错误
+
+	LogTimeZone      string `toml:"log_time_zone,omitempty" json:"logTimeZone"`            // 界面日志时间：空为本地时间，utc 为 UTC，见 logtime.go
+	LogTimeFormat    string `toml:"log_time_format,omitempty" json:"logTimeFormat"`        // 空 / datetime / ms / rfc3339
+	LogStripFrpcTime bool   `toml:"log_strip_frpc_time,omitempty" json:"logStripFrpcTime"` // 界面中去掉 frpc 自带的时间戳
+
+	RestartAt string `toml:"restart_at,omitempty" json:"restartAt"` // 每天定时重启隧道的时间 (HH:MM)，为空不重启，见 maintenance.go
+
+	TrayClickAction string `toml:"tray_click_action,omitempty" json:"trayClickAction"` // 托盘左键点击：show / toggle / status / mini，见 tray.go
+	TrayIconTheme   string `toml:"tray_icon_theme,omitempty" json:"trayIconTheme"`     // 托盘图标配色，空为跟随系统，见 traytheme.go
+
+	WaitForLocal        bool `toml:"wait_for_local,omitempty" json:"waitForLocal"`                // 自动连接前等待本地服务就绪，见 localgate.go
+	WaitForLocalTimeout int  `toml:"wait_for_local_timeout,omitempty" json:"waitForLocalTimeout"` // 最长等待秒数，0 使用默认值
+
+	DetectShareCode bool `toml:"detect_share_code,omitempty" json:"detectShareCode"` // 窗口获得焦点时识别剪贴板中的分享码，见 clipwatch.go
+
+	FrpcLowPriority bool  `toml:"frpc_low_priority,omitempty" json:"frpcLowPriority"` // 以低于正常的优先级运行 frpc，见 priority.go
+	FrpcCPUAffinity []int `toml:"frpc_cpu_affinity,omitempty" json:"frpcCpuAffinity"` // 限定 frpc 使用的 CPU 编号，为空不限制 (macOS 不支持)
+
+	FrpcMemoryLimitMB      int `toml:"frpc_memory_limit_mb,omitempty" json:"frpcMemoryLimitMB"`           // frpc 常驻内存上限，持续超出后自动重启，0 为不限制，见 resource.go
+	FrpcMemoryLimitMinutes int `toml:"frpc_memory_limit_minutes,omitempty" json:"frpcMemoryLimitMinutes"` // 持续超出多少分钟后重启，0 使用默认值
+
+	OutboundProxy string `toml:"outbound_proxy,omitempty" json:"outboundProxy"` // Mole 自身访问外网的代理：空为跟随系统，direct 为直连，见 outbound.go
+	UpdateChannel string `toml:"update_channel,omitempty" json:"updateChannel"` // 检查更新的渠道：空为正式版，beta 同时接收预发布版本，见 update.go
+
+	QuickExposeDomain string `toml:"quick_expose_domain,omitempty" json:"quickExposeDomain"` // HTTP 快速分享使用的主域名，其泛解析需指向服务器，见 quickexpose.go
+}
+
 type ProxyRule struct {
 	ID        string `toml:"id" json:"id"`                // 前端生成唯一ID (UUID或随机串)，删除修改定位用
 	Enabled   bool   `toml:"enabled" json:"enabled"`      // 是否启用当前代理
-	ProxyType string `toml:"proxy_type" json:"proxyType"` // "http", "tcp", "udp"
-	Name      string `toml:"name" json:"name"`            // 代理名称 (生成的frpc中的proxyName)
+	ProxyType string `toml:"proxy_type" json:"proxyType"` // "http", "https", "tcp", "udp", "tcpmux", "stcp"
+	Name      string `toml:"name" json:"name"`            // 规则名称，可以是中文等任意标题
+	// 写入 frpc.toml 的代理名，名称不能直接作为代理名时保存时自动生成，为空表示使用 Name，见 slug.go
+	WireName string `toml:"wire_name,omitempty" json:"wireName"`
+
+	// 说明：记录这条规则的用途，只在界面显示，不写入 frpc.toml
+	Description string `toml:"description,omitempty" json:"description"`
+	// 图标与颜色：界面与托盘中辨认规则用，可选值见 ruleicon.go
+	Icon  string `toml:"icon,omitempty" json:"icon"`
+	Color string `toml:"color,omitempty" json:"color"`
+	// 所在文件夹的路径，为空时不在文件夹中
+	Folder string `toml:"folder,omitempty" json:"folder"`
 
 	// 局域网内目标
 	LocalIP   string `toml:"local_ip" json:"localIP"` // 默认 127.0.0.1
 	LocalPort int    `toml:"local_port" json:"localPort"`
 
 	// 远程暴露参数
-	RemotePort int      `toml:"remote_port,omitempty" json:"remotePort"` // TCP/UDP 必填
-	Domains    []string `toml:"domains,omitempty" json:"domains"`        // HTTP 必填，使用数组方便以后扩展多域名
+	RemotePort int      `toml:"remote_port,omitempty" json:"remotePort"` // TCP/UDP 必填，STCP 不使用
+	Domains    []string `toml:"domains,omitempty" json:"domains"`        // HTTP / HTTPS 必填，可包含 *.example.com 形式的通配域名
+
+	// STCP 密钥与来源 (与 Token 来源格式相同)、允许访问的其他 frp 用户，见 stcp.go
+	// 密钥与 Token 一样在应用锁或只读模式下以占位符下发，原样保存时沿用原值，见 redact.go
+	SecretKey       string   `toml:"secret_key,omitempty" json:"secretKey"`
+	SecretKeySource string   `toml:"secret_key_source,omitempty" json:"secretKeySource"`
+	AllowUsers      []string `toml:"allow_users,omitempty" json:"allowUsers"`
+
+	// HTTPS 证书终止：开启时由 frpc 的 https2http 插件用本机证书解密，本地服务只需提供 HTTP；
+	// 关闭时 TLS 流量原样转发，由本地服务自己提供 HTTPS
+	HTTPSTerminate bool   `toml:"https_terminate,omitempty" json:"httpsTerminate"`
+	CertFile       string `toml:"cert_file,omitempty" json:"certFile"`
+	KeyFile        string `toml:"key_file,omitempty" json:"keyFile"`
+
+	// 访问记录：HTTP 规则经本机记录代理转发，可在界面查看最近的请求，见 requestlog.go
+	RequestLog bool `toml:"request_log,omitempty" json:"requestLog"`
+	// 访问控制：按来源 IP 或 CIDR 放行 / 拒绝，放行列表非空时只允许其中的地址，见 accessfilter.go
+	AllowIPs []string `toml:"allow_ips,omitempty" json:"allowIPs"`
+	DenyIPs  []string `toml:"deny_ips,omitempty" json:"denyIPs"`
+
+	// 限时分享：到期后自动停用，零值为不限时，见 shareexpiry.go
+	ExpiresAt time.Time `toml:"expires_at,omitempty" json:"expiresAt"`
+
+	// 通用客户端插件：类型与参数原样写入 frpc.toml，见 plugin.go
+	Plugin       string            `toml:"plugin,omitempty" json:"plugin"`
+	PluginParams map[string]string `toml:"plugin_params,omitempty" json:"pluginParams"`
+
+	// 启动顺序：在 StartAfter (规则 ID) 上线之后、再等待 StartDelay 秒才注册，见 startorder.go
+	StartAfter string `toml:"start_after,omitempty" json:"startAfter"`
+	StartDelay int    `toml:"start_delay,omitempty" json:"startDelay"`
 }
 
-func NewMoleService() *MoleService {
+func NewMoleService(events EventEmitter) *MoleService {
+	bus := newEventBus(events)
 	return &MoleService{
 		initWait: make(chan struct{}),
+		events:   bus,
+		bus:      bus,
 		// 预分配 128 条日志空间，避免启动时频繁内存分配
-		logBuffer: make([]string, 0, 128),
+		logBuffer: make([]logLine, 0, 128),
+		portMaps:  make(map[string]*activePortMap),
 	}
 }
 
+// attachRemote GUI 附着到后台守护进程：frpc 进程与配置均由守护进程管理，本进程只做界面
+func (s *MoleService) attachRemote(c *controlClient) {
+	s.remote = c
+}
+
 // 实现了wails服务接口，启动后调用
 func (s *MoleService) ServiceStartup(ctx context.Context, options application.ServiceOptions) error {
 	s.ctx = ctx
 
-	// 执行初始化任务
+	// 应用锁只保护界面，守护进程由界面进程调用，不加载
+	if !appFlags.Daemon {
+		s.appLock.load()
+	}
+	s.managed = loadManagedPolicy()
+
+	if s.remote != nil {
+		// 守护进程的事件原样转发到前端
+		go s.remote.subscribe(ctx, s.forwardRemoteEvent)
+	}
+
+	// 执行初始化任务：initWait 只等待配置与数据库就绪，数据库压缩、frps 与自动连接在之后进行，不拖慢界面首次显示
 	go func() {
-		defer close(s.initWait) // 无论加载成败，完成后必须关闭 channel
+		loadErr := s.loadState()
+		close(s.initWait) // 无论加载成败都必须关闭 channel
+		s.events.Emit("service-ready", nil)
+		if loadErr != nil {
+			log.Println("加载本地配置失败: " + loadErr.Error())
+			return
+		}
+		s.emitConfigChanged(configSourceLoad)
 
-		if err := s.loadConfigFromDisk(); err != nil {
-			log.Println("加载本地配置失败: " + err.Error())
+		go s.runTelemetryReporter(ctx)
+		go s.runAutoLock(ctx)
+
+		// 附着模式下由守护进程负责启动
+		if s.remote != nil {
 			return
 		}
 
+		go func() {
+			if err := s.store.compact(); err != nil {
+				log.Println(err)
+			}
+		}()
+		s.autoStartFrps()
+		go s.runLatencySampler(ctx)
+		go s.runTargetWatcher(ctx)
+		go s.runMQTT(ctx)
+		go s.runNotifier(ctx)
+		go s.runEmailAlerts(ctx)
+		go s.runPlugins(ctx)
+		go s.runLifecycleHooks(ctx)
+		go s.runUsageRecorder(ctx)
+		go s.runMaintenanceRestart(ctx)
+		go s.runShareExpiry(ctx)
+		go s.runConfigWatcher(ctx)
+
 		// 如果开启了自动启动，且配置存在，则启动
 		s.mu.RLock()
 		if s.config != nil && s.config.Server.AutoStart {
+			prefs, rules := s.config.Preferences, append([]ProxyRule(nil), s.config.Proxies...)
 			s.mu.RUnlock()
 			log.Println("检测到自动启动已开启，准备建立隧道...")
-			s.startFrp()
+			if prefs.WaitForLocal {
+				s.waitForLocalTargets(ctx, rules, prefs.WaitForLocalTimeout)
+				if ctx.Err() != nil {
+					return
+				}
+			}
+			// 初始化完成后守护进程的 --connect 或界面的连接可能已先行启动
+			s.opMu.Lock()
+			if !s.isRunning.Load() && s.tunnel.current() != tunnelPreparing {
+				s.startTunnel()
+			}
+			s.opMu.Unlock()
 		} else {
 			s.mu.RUnlock()
 		}
@@ -117,7 +426,29 @@ func (s *MoleService) ServiceStartup(ctx context.Context, options application.Se
 	return nil
 }
 
+// loadState 并行初始化数据库与读取配置，两者互不依赖；需要自动连接时同时在后台释放 frpc
+func (s *MoleService) loadState() error {
+	defer trackTime("服务初始化")()
+
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		if err := s.store.init(); err != nil {
+			log.Println("初始化本地数据库失败: " + err.Error())
+		}
+	}()
+	err := s.loadConfigFromDisk()
+	if err == nil && s.remote == nil && s.config.Server.AutoStart {
+		go warmFrpcBinary(s.getFrpBinDir())
+	}
+	wg.Wait()
+	return err
+}
+
 func (s *MoleService) loadConfigFromDisk() error {
+	defer trackTime("加载本地配置")()
+
 	configPath := filepath.Join(s.getAppConfigDir(), "config.toml")
 
 	// 1. 检查文件是否存在
@@ -140,21 +471,192 @@ func (s *MoleService) loadConfigFromDisk() error {
 	}
 
 	s.config = &loadedConfig
+	s.logTagger.configure(loadedConfig.Preferences)
+	s.logClock.configure(loadedConfig.Preferences)
+	configureOutbound(loadedConfig.Preferences)
+	s.lanAPI.configure(s, loadedConfig.Fleet)
+	s.configWatch.stamp()
+
+	return nil
+}
 
+// reloadConfig 重新读取磁盘配置，GUI 保存后通知守护进程时调用
+func (s *MoleService) reloadConfig() error {
+	s.mu.Lock()
+	err := s.loadConfigFromDisk()
+	s.mu.Unlock()
+	if err != nil {
+		return err
+	}
+	s.emitConfigChanged(configSourceReload)
 	return nil
 }
 
+// SaveUserConfig 前端与控制接口保存配置的入口：GetStatus 下发的凭据占位符先换回原值再校验 (如 STCP 规则要求填写密钥)
+// 程序内部构造完整配置的保存直接调用 saveUserConfig，清空的凭据 (如撤销访问令牌) 不会被恢复
 func (s *MoleService) SaveUserConfig(newCfg UserConfig) error {
+	if err := s.checkMutable(); err != nil {
+		return err
+	}
+	if cur := s.status().Config; cur != nil {
+		keepSecrets(&newCfg, cur)
+	}
+	return s.saveUserConfig(newCfg)
+}
+
+// saveUserConfig 更新内存配置后立即返回，落盘由 scheduleSave 异步完成
+// 结果通过 config-save 事件通知前端，避免阻塞绑定调用
+func (s *MoleService) saveUserConfig(newCfg UserConfig) error {
+	if err := s.checkMutable(); err != nil {
+		return err
+	}
+	proxies, err := normalizeProxyRules(newCfg.Proxies)
+	if err != nil {
+		return err
+	}
+	newCfg.Proxies = proxies
+	if newCfg.Folders, newCfg.Proxies, err = normalizeFolders(newCfg.Folders, newCfg.Proxies); err != nil {
+		return err
+	}
+	if err := validateServerTLS(&newCfg); err != nil {
+		return err
+	}
+	if newCfg.Server.ExtraArgs, err = normalizeExtraArgs(newCfg.Server.ExtraArgs); err != nil {
+		return err
+	}
+	if err := validateSecretSource(newCfg.Server.TokenSource); err != nil {
+		return err
+	}
+	if err := validateFrpUser(newCfg.Server.User, newCfg.Server.NamePrefix); err != nil {
+		return err
+	}
+	if err := validateStartOrder(newCfg.Proxies); err != nil {
+		return err
+	}
+	if t := newCfg.Preferences.WaitForLocalTimeout; t < 0 || t > maxWaitForLocal {
+		return fmt.Errorf("等待本地服务的时间应在 0 ~ %d 秒之间", maxWaitForLocal)
+	}
+	if err := validateLogTime(newCfg.Preferences); err != nil {
+		return err
+	}
+	if err := validateFrpcPriority(newCfg.Preferences); err != nil {
+		return err
+	}
+	if err := validateMemoryLimit(newCfg.Preferences); err != nil {
+		return err
+	}
+	if err := validateOutboundProxy(newCfg.Preferences.OutboundProxy); err != nil {
+		return err
+	}
+	if err := validateUpdateChannel(newCfg.Preferences.UpdateChannel); err != nil {
+		return err
+	}
+	if err := validateRetryPolicy(newCfg.Retry); err != nil {
+		return err
+	}
+	if err := validateFleet(newCfg.Fleet); err != nil {
+		return err
+	}
+	if err := validatePlugins(newCfg.Plugins); err != nil {
+		return err
+	}
+	if err := validateQuickExposeDomain(newCfg.Preferences.QuickExposeDomain); err != nil {
+		return err
+	}
+	if err := validateHooks(newCfg.Hooks); err != nil {
+		return err
+	}
+	if at := newCfg.Preferences.RestartAt; at != "" {
+		if _, err := parseDailyTime(at); err != nil {
+			return err
+		}
+	}
+	s.audit(auditConfigSave, fmt.Sprintf("服务器 %s:%d，%d 条规则", newCfg.Server.Addr, newCfg.Server.Port, len(newCfg.Proxies)))
+	// 客户端模式下配置由守护进程统一管理，落盘进度经事件流转发回来
+	if s.remote != nil {
+		return s.remote.saveConfig(newCfg)
+	}
+
 	// 加锁防止修改时读取
 	s.mu.Lock()
-	defer s.mu.Unlock()
-
 	// 1. 更新内存状态
 	s.config = &newCfg
 	s.config.ConfigVersion = "1.0.0" // 当前版本，不添加自动更新，这个版本仅用于配置变更时升级使用
 	s.config.LastUpdated = time.Now().Format(time.RFC3339)
+	s.logTagger.configure(s.config.Preferences)
+	s.logClock.configure(s.config.Preferences)
+	configureOutbound(s.config.Preferences)
+	s.lanAPI.configure(s, s.config.Fleet)
+	// 前端新建的规则没有 ID，这里补上，后续按 ID 定位规则
+	for i := range s.config.Proxies {
+		if s.config.Proxies[i].ID == "" {
+			s.config.Proxies[i].ID = newRuleID()
+		}
+	}
+	s.mu.Unlock()
+	s.emitConfigChanged(configSourceSave)
+	s.unpinRuntimeConfig()
+
+	// 2. 防抖落盘：表单连续输入时只写最后一次
+	s.scheduleSave()
+	return nil
+}
+
+// scheduleSave 重置防抖定时器，在最后一次保存请求之后 saveDebounceDelay 才真正写盘
+func (s *MoleService) scheduleSave() {
+	s.saveMu.Lock()
+	defer s.saveMu.Unlock()
+
+	if s.saveTimer != nil {
+		s.saveTimer.Stop()
+	}
+	s.saveTimer = time.AfterFunc(saveDebounceDelay, s.persistConfig)
+	s.savePending = true
+	s.emitConfigSave("pending", "等待写入")
+}
 
-	// 2. 序列化并保存到磁盘 (userConfig.toml)
+// flushPendingSave 退出前调用，如果还有未落盘的配置则同步写入
+func (s *MoleService) flushPendingSave() {
+	s.saveMu.Lock()
+	pending := s.saveTimer != nil && s.saveTimer.Stop()
+	s.saveTimer = nil
+	s.saveMu.Unlock()
+
+	if pending {
+		s.persistConfig()
+	}
+}
+
+// persistConfig 序列化并写入 config.toml 与 frpc.toml
+func (s *MoleService) persistConfig() {
+	// 串行化写盘，防止定时器触发时上一次写入尚未完成
+	s.persistMu.Lock()
+	defer s.persistMu.Unlock()
+	defer trackTime("保存配置")()
+
+	s.saveMu.Lock()
+	s.savePending = false
+	s.saveMu.Unlock()
+
+	s.emitConfigSave("saving", "正在写入配置")
+
+	// 只持有读锁，写盘期间不阻塞 GetStatus 等读取操作
+	s.mu.RLock()
+	err := s.writeConfigFiles()
+	s.mu.RUnlock()
+
+	if err != nil {
+		log.Printf("保存配置失败: %v", err)
+		s.emitConfigSave("failed", err.Error())
+		return
+	}
+
+	s.emitConfigSave("saved", "配置已保存")
+}
+
+// writeConfigFiles 调用方需持有 s.mu 读锁
+func (s *MoleService) writeConfigFiles() error {
+	// 1. 序列化并保存到磁盘 (userConfig.toml)
 	configPath := filepath.Join(s.getAppConfigDir(), "config.toml")
 	data, err := toml.Marshal(s.config)
 	if err != nil {
@@ -164,70 +666,152 @@ func (s *MoleService) SaveUserConfig(newCfg UserConfig) error {
 	if err := os.WriteFile(configPath, data, 0644); err != nil {
 		return fmt.Errorf("保存文件失败: %v", err)
 	}
+	s.configWatch.stamp()
+	s.recordRevision(s.config, data)
 
-	// 3. 同时触发生成运行所需的 frpc.toml
+	// 2. 同时触发生成运行所需的 frpc.toml
 	return s.generateFrpcToml()
 }
 
 func (s *MoleService) generateFrpcToml() error {
+	defer trackTime("生成 frpc.toml")()
+
 	if s.config == nil {
 		return fmt.Errorf("未发现有效配置")
 	}
 
+	cfg := withQuickExposes(s.config, s.quick.proxies())
+	cfg = redirectLocal(cfg, s.reqLog.sync(cfg.Proxies))
+	// 访问控制在访问记录之前，被拒绝的连接不会出现在访问记录中
+	filterPorts, err := s.access.sync(cfg.Proxies, s.emitLog)
+	if err != nil {
+		s.offerRollback("生成 frpc.toml 失败: " + err.Error())
+		return err
+	}
+	cfg = redirectLocal(cfg, filterPorts)
+	out, err := renderFrpcToml(cfg, s.frpAdmin, func(ruleID string) bool {
+		return s.autoPause.isPaused(ruleID) || s.startStage.isHeld(ruleID)
+	})
+	if err != nil {
+		s.offerRollback("生成 frpc.toml 失败: " + err.Error())
+		return err
+	}
+
+	// D. 写入 frpc.toml 文件
+	frpcPath := filepath.Join(s.getFrpBinDir(), "frpc.toml")
+	return os.WriteFile(frpcPath, out, 0644)
+}
+
+// renderFrpcToml 把用户配置转换为 frpc.toml 内容，skip 返回 true 的规则不写入
+func renderFrpcToml(cfg *UserConfig, admin frpcAdmin, skip func(ruleID string) bool) ([]byte, error) {
 	// 构建符合 frp 0.65 规范的结构
 	// 注意：根据 2026 年 frp 最佳实践，我们直接构建 map 以方便 Marshal 为 TOML
 	runCfg := make(map[string]any)
 
+	cfg, err := expandConfigEnv(cfg)
+	if err != nil {
+		return nil, err
+	}
+
 	// A. 服务端公共配置
-	runCfg["serverAddr"] = s.config.Server.Addr
-	runCfg["serverPort"] = s.config.Server.Port
+	runCfg["serverAddr"] = cfg.Server.Addr
+	runCfg["serverPort"] = cfg.Server.Port
 	// B. 构建嵌套的 auth 结构
+	token, err := serverToken(cfg)
+	if err != nil {
+		return nil, err
+	}
 	authCfg := make(map[string]string)
 	authCfg["method"] = "token"
-	authCfg["token"] = s.config.Server.Token
+	authCfg["token"] = token
 	runCfg["auth"] = authCfg // 将子 map 放入主 map
+	if cfg.Server.User != "" {
+		runCfg["user"] = cfg.Server.User
+	}
+	// 无限重试时由 frpc 自己重试首次登录，否则登录失败即退出，由 Mole 按重试策略重连
+	runCfg["loginFailExit"] = cfg.Retry.MaxRetries >= 0
+	if tls := tlsSection(cfg); len(tls) > 0 {
+		runCfg["transport"] = map[string]any{"tls": tls}
+	}
+	// 本机管理接口，供热重载使用
+	if admin.Port > 0 {
+		runCfg["webServer"] = admin.tomlSection()
+	}
+	// 数据目录 conf.d 中手写的代理，见 includes.go
+	if inc := includePatterns(); len(inc) > 0 {
+		runCfg["includes"] = inc
+	}
 	// C. 代理列表映射
 	var proxies []map[string]any
-	for _, p := range s.config.Proxies {
-		if !p.Enabled {
+	for _, p := range cfg.Proxies {
+		if !p.Enabled || (skip != nil && skip(p.ID)) {
 			continue
 		}
 
 		item := map[string]any{
-			"name":      p.Name,
+			"name":      proxyWireName(cfg, p),
 			"type":      p.ProxyType,
 			"localIP":   p.LocalIP,
 			"localPort": p.LocalPort,
 		}
 
 		// 根据类型按需添加字段
-		if p.ProxyType == "http" {
+		switch {
+		case usesDomains(p.ProxyType):
 			item["customDomains"] = p.Domains
-		} else {
+		case p.ProxyType == "stcp":
+			if err := stcpSection(item, p); err != nil {
+				return nil, err
+			}
+		default:
 			item["remotePort"] = p.RemotePort
 		}
+		if p.ProxyType == "https" && p.HTTPSTerminate {
+			item["plugin"] = httpsPlugin(p)
+		} else if p.Plugin != "" {
+			item["plugin"] = pluginSection(p)
+		}
+		if p.ProxyType == "tcpmux" {
+			item["multiplexer"] = tcpmuxHTTPConnect
+		}
+		// 访问控制代理需要从 PROXY protocol 中得到访问者地址
+		if hasAccessList(p) {
+			item["transport"] = map[string]any{"proxyProtocolVersion": "v2"}
+		}
 		proxies = append(proxies, item)
 	}
 	runCfg["proxies"] = proxies
 
-	// D. 写入 frpc.toml 文件
-	frpcPath := filepath.Join(s.getFrpBinDir(), "frpc.toml")
-	out, err := toml.Marshal(runCfg)
-	if err != nil {
-		return err
-	}
-
-	return os.WriteFile(frpcPath, out, 0644)
+	return toml.Marshal(runCfg)
 }
 
 func (s *MoleService) cleanup() {
 	log.Println("退出前清理资源")
 	// 1，关闭frp
 	s.stopFrp()
+	s.stopFrps()
+	// 2，写入尚未落盘的配置
+	s.flushPendingSave()
+	// 3，删除路由器上的端口映射
+	s.unmapAllPorts()
+	// 4，关闭局域网接口、访问记录与访问控制代理
+	s.lanAPI.stop()
+	s.reqLog.stopAll()
+	s.access.stopAll()
 }
 
 // Connect 供前端调用的主方法
 func (s *MoleService) Connect() ServiceStatus {
+	if err := s.checkUnlocked(); err != nil {
+		return ServiceStatus{Locked: true, Message: err.Error()}
+	}
+	s.countFeature("connect")
+	s.audit(auditConnect, "")
+	s.reconnect.reset()
+	if s.remote != nil {
+		return s.remoteStatus(s.remote.connect())
+	}
+
 	s.mu.RLock()
 	// 1. 检查配置状态
 	if s.config == nil {
@@ -241,7 +825,9 @@ func (s *MoleService) Connect() ServiceStatus {
 	}
 	s.mu.RUnlock()
 
-	// 2. 检查运行状态 (防止重复启动)
+	// 2. 检查运行状态 (防止重复启动)，与 Disconnect、重启互斥，见 lifecycle.go
+	s.opMu.Lock()
+	defer s.opMu.Unlock()
 	if s.isRunning.Load() {
 		return ServiceStatus{
 			Success:   true,
@@ -250,21 +836,48 @@ func (s *MoleService) Connect() ServiceStatus {
 			Message:   "提示：隧道已在运行中，无需重复连接。",
 		}
 	}
+	// 已有排队中的启动，合并为同一次
+	if s.tunnel.current() == tunnelPreparing {
+		return ServiceStatus{
+			Success:     true,
+			Config:      s.config,
+			Message:     "启动中...",
+			TunnelState: tunnelPreparing,
+		}
+	}
 
-	// 3. 尝试异步启动进程
-	go s.startFrp()
+	// 3. 尝试异步启动进程，先进入 preparing，返回的状态与随后的 tunnel-state 事件衔接
+	s.setTunnelState(tunnelPreparing, "")
+	go s.startQueued()
 
 	return ServiceStatus{
-		Success:   true,
-		IsRunning: false, // 此时还在启动中，由事件通知后续状态
-		Config:    s.config,
-		Message:   "启动中...",
+		Success:     true,
+		IsRunning:   false, // 此时还在启动中，由事件通知后续状态
+		Config:      s.config,
+		Message:     "启动中...",
+		TunnelState: tunnelPreparing,
 	}
 }
 
 func (s *MoleService) Disconnect() ServiceStatus {
-	// 1. 检查是否真的在运行
+	if err := s.checkUnlocked(); err != nil {
+		return ServiceStatus{Locked: true, Message: err.Error()}
+	}
+	s.audit(auditDisconnect, "")
+	s.reconnect.reset()
+	if s.remote != nil {
+		return s.remoteStatus(s.remote.disconnect())
+	}
+
+	s.opMu.Lock()
+	defer s.opMu.Unlock()
+
+	// 1. 检查是否真的在运行；排队中的启动或等待中的重连一并取消
 	if !s.isRunning.Load() {
+		s.stopRequested.Store(true)
+		s.setTunnelState(tunnelIdle, "")
+		s.clearQuickExposes()
+		s.unpinRuntimeConfig()
 		return ServiceStatus{
 			Success:   true,
 			IsRunning: false,
@@ -272,22 +885,21 @@ func (s *MoleService) Disconnect() ServiceStatus {
 		}
 	}
 
-	// 2. 停止进程逻辑
-	if s.frpCmd != nil && s.frpCmd.Process != nil {
-		// 在 Windows 下建议使用 TaskKill 或发送 Ctrl+C，这里使用跨平台最直接的 Kill
-		err := s.frpCmd.Process.Kill()
-		if err != nil {
-			return ServiceStatus{
-				Success:   false,
-				IsRunning: true,
-				Message:   "停止进程失败: " + err.Error(),
-			}
+	// 2. 停止进程并等待退出清理完成，之后的 Connect 不会遇到旧进程
+	s.setTunnelState(tunnelStopping, "")
+	s.stopFrp()
+	if err := s.waitTunnelExit(); err != nil {
+		return ServiceStatus{
+			Success:   false,
+			IsRunning: true,
+			Message:   "停止进程失败: " + err.Error(),
 		}
 	}
 
-	// 3. 更新状态
-	s.isRunning.Store(false)
+	// 3. 更新状态，快速分享随连接一起结束
 	s.emitLog("用户手动断开连接")
+	s.clearQuickExposes()
+	s.unpinRuntimeConfig()
 
 	return ServiceStatus{
 		Success:   true,
@@ -296,19 +908,99 @@ func (s *MoleService) Disconnect() ServiceStatus {
 	}
 }
 
+// initializingStatus 初始化未完成时返回占位状态，供查询接口立即返回而不是等待 initWait
+func (s *MoleService) initializingStatus() (ServiceStatus, bool) {
+	select {
+	case <-s.initWait:
+		return ServiceStatus{}, false
+	default:
+		return ServiceStatus{Success: true, Initializing: true, TunnelState: tunnelIdle, Message: "正在初始化..."}, true
+	}
+}
+
+// GetStatus 供前端查询状态，应用锁定时隐藏配置；初始化未完成时立即返回，不阻塞界面首次显示
 func (s *MoleService) GetStatus() ServiceStatus {
+	if st, ok := s.initializingStatus(); ok {
+		return st
+	}
+	st := s.status()
+	lock := s.GetLockStatus()
+	switch {
+	case lock.Locked:
+		st.Config = nil
+		st.Locked = true
+	case (lock.Enabled || s.readOnly()) && st.Config != nil:
+		// Token、密码、Webhook、STCP 密钥等凭据一律替换为占位符，见 redact.go
+		cfg, err := redactConfig(st.Config)
+		if err != nil {
+			log.Printf("隐藏配置中的凭据失败: %v", err)
+			cfg = nil
+		}
+		st.Config = cfg
+		st.TokenHidden = true
+	}
+	st.ReadOnly = s.readOnly()
+	return st
+}
+
+// status 内部使用的完整状态，不受应用锁影响
+func (s *MoleService) status() ServiceStatus {
 	// 等待初始化完成（如果已经关闭，会立即通过）
 	<-s.initWait
 
+	if s.remote != nil {
+		return s.remoteStatus(s.remote.status())
+	}
+
 	s.mu.RLock()
 	defer s.mu.RUnlock()
 
-	return ServiceStatus{
+	st := ServiceStatus{
 		Success:   true,
 		IsRunning: s.isRunning.Load(),
 		Config:    s.config,
 		Message:   s.getRunningSummary(), // 辅助方法返回简报
 	}
+	s.tunnel.fill(&st)
+	s.resource.fill(&st)
+	return st
+}
+
+// remoteStatus 把控制接口的调用错误转换成前端可展示的状态
+func (s *MoleService) remoteStatus(st ServiceStatus, err error) ServiceStatus {
+	if err != nil {
+		s.mu.RLock()
+		defer s.mu.RUnlock()
+		return ServiceStatus{
+			Success: false,
+			Config:  s.config,
+			Message: "无法连接后台服务: " + err.Error(),
+		}
+	}
+	return st
+}
+
+// stopDaemon 客户端模式下让守护进程断开隧道并退出，供托盘“退出并断开”使用
+func (s *MoleService) stopDaemon() {
+	if s.remote == nil {
+		return
+	}
+	if err := s.remote.shutdown(); err != nil {
+		log.Printf("停止后台服务失败: %v", err)
+	}
+}
+
+// reattachDaemon 守护进程被替换 (安装/卸载后台服务) 后重新附着，必要时重新拉起
+func (s *MoleService) reattachDaemon() {
+	if s.remote == nil {
+		return
+	}
+	if waitForDaemon(5*time.Second) == nil && ensureDaemon() == nil {
+		log.Println("重新连接后台服务失败")
+		return
+	}
+	// 沿用同一个客户端对象，事件订阅协程会在重连时读取新 token
+	_ = s.remote.refreshToken()
 }
 
 // 辅助方法：生成当前状态的文字描述
@@ -324,13 +1016,22 @@ func (s *MoleService) getRunningSummary() string {
 
 // =====================核心逻辑，启动FRP ===============================
 
-func (s *MoleService) getFrpBinDir() string {
+// getAppDataDir 应用数据根目录，默认位于系统标准配置目录下的 mole 子目录
+// 后台服务以其他账户 (如 SYSTEM) 运行时，通过 --config-dir 指回安装用户的目录
+func getAppDataDir() string {
+	if appFlags.ConfigDir != "" {
+		return appFlags.ConfigDir
+	}
 	// 获取系统标准的配置/数据目录
 	baseDir, _ := os.UserConfigDir()
+	return filepath.Join(baseDir, "mole")
+}
+
+func (s *MoleService) getFrpBinDir() string {
 	// 创建一个属于你应用的专用子目录
 	// Windows: AppData/Roaming/MoleApp/bin
 	// macOS: Library/Application Support/MoleApp/bin
-	appBinDir := filepath.Join(baseDir, "mole", "bin")
+	appBinDir := filepath.Join(getAppDataDir(), "bin")
 
 	// 确保目录一定存在
 	_ = os.MkdirAll(appBinDir, 0755)
@@ -338,12 +1039,10 @@ func (s *MoleService) getFrpBinDir() string {
 }
 
 func (s *MoleService) getAppConfigDir() string {
-	// 获取系统标准的配置/数据目录
-	baseDir, _ := os.UserConfigDir()
 	// 创建一个属于你应用的专用子目录
 	// Windows: AppData/Roaming/MoleApp/bin
 	// macOS: Library/Application Support/MoleApp/bin
-	appConfigDir := filepath.Join(baseDir, "mole", "config")
+	appConfigDir := filepath.Join(getAppDataDir(), "config")
 
 	log.Printf("配置目录: %v", appConfigDir)
 
@@ -352,28 +1051,22 @@ func (s *MoleService) getAppConfigDir() string {
 	return appConfigDir
 }
 
+// prepareFrpEnv 确定 frpc 与 frpc.toml 的路径，必要时释放 frpc；frpc.toml 由调用方事先生成，不需要持有 s.mu
 func (s *MoleService) prepareFrpEnv() (string, string, error) {
-	binDir := s.getFrpBinDir()
+	defer trackTime("准备 frpc 运行环境")()
 
-	// 1. 确定 frpc 路径
-	frpcPath := filepath.Join(binDir, frpcTargetName) // frpcTargetName 是在条件编译文件里定义的名称
+	binDir := s.getFrpBinDir()
 
-	// 如果文件不存在则从 embed 释放
-	if _, err := os.Stat(frpcPath); os.IsNotExist(err) {
-		arch := runtime.GOARCH
-		data, err := frpcBin.ReadFile(frpcMap[arch])
-		if err != nil {
-			return "", "", err
-		}
-		if err := os.WriteFile(frpcPath, data, 0755); err != nil {
-			return "", "", err
-		}
+	// 1. 确定 frpc 路径：用户数据目录中没有时优先使用全机共享的，否则从 embed 释放，见 sharedfrpc.go
+	frpcPath, err := resolveFrpcPath(binDir)
+	if err != nil {
+		return "", "", err
 	}
 
-	// 2. 确定 toml 路径并写入
+	// 2. 确定 toml 路径
 	tomlPath := filepath.Join(binDir, "frpc.toml")
-	if _, err := os.Stat(tomlPath); os.IsNotExist(err) {
-		s.generateFrpcToml()
+	if _, err := os.Stat(tomlPath); err != nil {
+		return "", "", fmt.Errorf("frpc.toml 不可用: %v", err)
 	}
 
 	return frpcPath, tomlPath, nil
@@ -386,102 +1079,248 @@ func (s *MoleService) startFrp() {
 	if s.isRunning.Load() {
 		return
 	}
-	frpcPath, tomlPath, err := s.prepareFrpEnv()
-	if err != nil {
-		log.Printf("准备 FRP 环境失败: %v", err)
+	s.setTunnelState(tunnelPreparing, "")
+	s.selfTestStep(selfTestConfig, selfTestRunning, "")
+	if t := s.config.Server.Transport; (t == transportSSH || t == transportFrpSSH) && !appFlags.Simulate {
+		s.selfTestStep(selfTestConfig, selfTestOK, "")
+		s.selfTestStep(selfTestBinary, selfTestSkipped, "SSH 传输不需要 frpc")
+		s.selfTestStep(selfTestServer, selfTestRunning, "")
+		s.resetTunnelHealth()
+		s.resetProxyStates()
+		// 拨号期间释放 s.mu，与 frpc 的服务器检查相同，见 checkServer
+		cfg := s.config
+		s.mu.Unlock()
+		s.startSSHTunnel(cfg)
+		s.mu.Lock()
 		return
 	}
-	// 启动前生成或覆盖最新的 frpc.toml
-	err = s.generateFrpcToml()
-	if err != nil {
+	// 自检 1：生成 frpc.toml。每次启动分配新的管理接口，并以完整配置启动
+	if admin, err := newFrpcAdmin(); err == nil {
+		s.frpAdmin = admin
+	} else {
+		log.Printf("分配 frpc 管理端口失败，热重载不可用: %v", err)
+		s.frpAdmin = frpcAdmin{}
+	}
+	s.autoPause.reset()
+	// 使用回滚的 frpc.toml 时不再分阶段放出规则，见 lastgood.go
+	pinned := s.runtimePinned()
+	// 需要等待的规则先不写入，frpc 上线后由 runStartStages 逐步放出 (依赖热重载)
+	held := s.startStage.begin(s.config.Proxies, s.frpAdmin.Port > 0 && !pinned)
+	if pinned {
+		if err := s.restoreLastGoodToml(); err != nil {
+			s.setTunnelState(tunnelError, err.Error())
+			log.Printf("恢复可用的 frpc.toml 失败: %v", err)
+			return
+		}
+		s.selfTestStep(selfTestConfig, selfTestOK, "使用最近一次可用的 frpc.toml")
+	} else if err := s.generateFrpcToml(); err != nil {
+		// 启动前生成或覆盖最新的 frpc.toml
+		s.setTunnelState(tunnelError, "配置生成失败: "+err.Error())
 		log.Printf("配置生成失败: %v", err)
 		return
+	} else {
+		s.selfTestStep(selfTestConfig, selfTestOK, "")
 	}
-	// 如果已经在运行，先停止
+
+	// 自检 2、3：释放 frpc (首次启动时可能要写入十几 MB) 与服务器检查都是慢速 I/O，期间释放 s.mu，
+	// 查询状态等读取方不被拖住。启动与停止都在 opMu 下串行，期间不会有另一次启动，
+	// 只可能有人保存了配置，重新加锁后按新配置重新生成
+	cfg := s.config
+	s.mu.Unlock()
+	frpcPath, tomlPath, ok := s.checkFrpcBinary()
+	reachable, retry := ok, false
+	if ok {
+		reachable, retry = s.checkServer(cfg)
+	}
+	s.mu.Lock()
+	if !reachable {
+		if retry {
+			s.scheduleReconnect()
+		}
+		return
+	}
+	if s.config != cfg && !s.runtimePinned() {
+		if err := s.generateFrpcToml(); err != nil {
+			s.setTunnelState(tunnelError, "配置生成失败: "+err.Error())
+			log.Printf("配置生成失败: %v", err)
+			return
+		}
+	}
+	s.selfTestStep(selfTestAuth, selfTestRunning, "")
+
+	// 上一个进程尚未清理 (正常情况下 opMu 保证不会出现)，直接结束它，退出协程发现句柄已替换后不再清理
 	if s.frpCmd != nil && s.frpCmd.Process != nil {
-		s.stopFrp()
+		_ = s.frpCmd.Process.Kill()
+		s.frpCmd = nil
 	}
 	s.isRunning.Store(false) // 重置标记
+	s.resetTunnelHealth()
+
+	s.noteRuntimeToml(nil)
 
 	// 1. 创建命令
-	s.frpCmd = exec.Command(frpcPath, "-c", tomlPath)
+	name, args := frpcCommand(frpcPath, tomlPath, s.config.Server.ExtraArgs)
+	cmd := exec.Command(name, args...)
 
-	s.frpCmd.SysProcAttr = &syscall.SysProcAttr{}
-	setHideWindow(s.frpCmd.SysProcAttr) // 直接调用，编译器会根据平台自动选择对应的实现
+	cmd.SysProcAttr = &syscall.SysProcAttr{}
+	setHideWindow(cmd.SysProcAttr) // 直接调用，编译器会根据平台自动选择对应的实现
 
 	// 创建管道获取输出
-	stdout, _ := s.frpCmd.StdoutPipe()
-	stderr, _ := s.frpCmd.StderrPipe()
-
-	// 启动一个定时器，每 500ms 检查一次缓存并发送
-	ticker := time.NewTicker(500 * time.Millisecond)
-	go func() {
-		for {
-			select {
-			case <-ticker.C:
-				s.flushLogs()
-			case <-s.ctx.Done():
-				ticker.Stop()
-				return
-			}
-		}
-	}()
-
-	// 2. 合并读取日志的函数
-	readLog := func(reader io.ReadCloser) {
-		// 关键点：函数结束时关闭 reader，确保系统资源释放
-		defer reader.Close()
-
-		scanner := bufio.NewScanner(reader)
-		// 当进程退出，管道关闭时，Scan() 会自动返回 false，循环结束
-		for scanner.Scan() {
-			line := scanner.Text()
-
-			s.logMu.Lock()
-			s.logBuffer = append(s.logBuffer, line) // 将日志存入切片
-			s.logMu.Unlock()
-		}
-
-		log.Println("日志协程正常退出")
-	}
+	stdout, _ := cmd.StdoutPipe()
+	stderr, _ := cmd.StderrPipe()
 
 	// 启动进程
-	if err := s.frpCmd.Start(); err != nil {
+	startDone := trackTime("启动 frpc 进程")
+	err := cmd.Start()
+	startDone()
+	if err != nil {
 		// 发送通知到前端
+		s.selfTestStep(selfTestBinary, selfTestFailed, err.Error())
+		s.setTunnelState(tunnelError, "frpc 进程启动失败: "+err.Error())
 		s.emitLog("frpc 进程启动失败：", err.Error())
 		log.Printf("启动 frpc 失败: %v", err)
 		return
 	}
+	s.frpCmd = cmd
+	startedAt := time.Now()
+	s.applyFrpcPriority(cmd.Process.Pid, s.config.Preferences)
 
+	s.resetProxyStates()
 	s.emitFrpStatus("start")
 	// 4. 关键：启动成功后立即设置 isStarted
 	s.isRunning.Store(true)
 
-	go readLog(stdout)
-	go readLog(stderr)
+	// 2. 本次进程专属的日志管道：读取协程随管道关闭退出，刷新协程随会话 ctx 取消退出
+	sessionCtx, cancel := context.WithCancel(s.ctx)
+	var readers sync.WaitGroup
+	readers.Add(2)
+	go s.readFrpLog(stdout, &readers)
+	go s.readFrpLog(stderr, &readers)
+	go s.runLogFlusher(sessionCtx)
+	if len(held) > 0 {
+		go s.runStartStages(sessionCtx, held)
+	}
+	if s.frpAdmin.Port > 0 {
+		go s.runAdminKeepalive(sessionCtx, cmd, s.frpAdmin)
+	}
+	go s.runResourceMonitor(sessionCtx, cmd.Process.Pid)
 
 	go func() {
-		// Wait 会阻塞直到进程结束
-		_ = s.frpCmd.Wait()
+		// 必须先读完管道再调用 Wait，否则 Wait 关闭管道会导致尾部日志丢失
+		readers.Wait()
+		_ = cmd.Wait()
+		// 结束本次会话的刷新协程，它会在退出前把剩余日志全部发出
+		cancel()
 
 		s.mu.Lock()
 		defer s.mu.Unlock()
 
+		// 期间可能已经启动了新进程，只清理属于自己的句柄
+		if s.frpCmd != cmd {
+			return
+		}
 		// 清理句柄并重置运行状态
 		s.frpCmd = nil
+		s.frpAdmin = frpcAdmin{}
 		s.isRunning.Store(false)
 
 		s.emitLog("警告：frpc 进程已退出")
+		s.frpcExitedEarly(startedAt)
+		s.markTunnelDown("frpc 进程已退出")
+		s.tunnelExited("frpc 进程已退出")
+		s.stopProxyStates()
 		// 这里可以触发 Wails 事件通知前端 UI 变更为“停止”状态
 		s.emitFrpStatus("stop")
-
+		s.scheduleReconnect()
 	}()
 
 	// 发送自定义事件，通知前端关闭弹窗
-	log.Printf("frpc 已启动，PID: %d，配置文件: %s", s.frpCmd.Process.Pid, tomlPath)
+	log.Printf("frpc 已启动，PID: %d，配置文件: %s", cmd.Process.Pid, tomlPath)
+}
+
+// checkFrpcBinary 启动前释放并检查 frpc，不持有 s.mu 调用
+func (s *MoleService) checkFrpcBinary() (frpcPath, tomlPath string, ok bool) {
+	s.selfTestStep(selfTestBinary, selfTestRunning, "")
+	frpcPath, tomlPath, err := s.prepareFrpEnv()
+	if err == nil {
+		// 模拟模式下实际运行的是 Mole 自身，检查将要执行的程序
+		name, _ := frpcCommand(frpcPath, tomlPath, nil)
+		err = checkFrpcExecutable(name)
+	}
+	if err != nil {
+		s.setTunnelState(tunnelError, "准备 FRP 环境失败: "+err.Error())
+		log.Printf("准备 FRP 环境失败: %v", err)
+		return "", "", false
+	}
+	s.selfTestStep(selfTestBinary, selfTestOK, "")
+	return frpcPath, tomlPath, true
+}
+
+// checkServer 启动前的服务器检查，不持有 s.mu 调用；retry 表示服务器连不上，应按重试策略稍后再试
+func (s *MoleService) checkServer(cfg *UserConfig) (ok, retry bool) {
+	s.selfTestStep(selfTestServer, selfTestRunning, "")
+	if appFlags.Simulate {
+		s.emitLog("模拟模式：使用内置的假 frpc，不会连接真实服务器")
+		s.selfTestStep(selfTestServer, selfTestSkipped, "模拟模式")
+		return true, false
+	}
+	if err := probeServer(cfg); err != nil {
+		s.setTunnelState(tunnelError, err.Error())
+		s.emitLog("连接失败：", err.Error())
+		return false, true
+	}
+	if err := verifyServerPin(cfg); err != nil {
+		s.setTunnelState(tunnelError, err.Error())
+		s.emitLog("已拒绝连接：", err.Error())
+		log.Printf("证书固定校验失败: %v", err)
+		return false, false
+	}
+	s.selfTestStep(selfTestServer, selfTestOK, "")
+	return true, false
+}
+
+// readFrpLog 逐行读取 frpc 输出写入缓冲区，进程退出管道关闭时返回
+func (s *MoleService) readFrpLog(reader io.ReadCloser, wg *sync.WaitGroup) {
+	defer wg.Done()
+	// 关键点：函数结束时关闭 reader，确保系统资源释放
+	defer reader.Close()
+
+	scanner := bufio.NewScanner(reader)
+	// 当进程退出，管道关闭时，Scan() 会自动返回 false，循环结束
+	for scanner.Scan() {
+		line := scanner.Text()
+		s.trackProxyLog(line)
+		s.trackConnLog(line)
+
+		s.logMu.Lock()
+		s.logBuffer = append(s.logBuffer, logLine{at: time.Now(), text: line}) // 将日志存入切片
+		s.logMu.Unlock()
+	}
+
+	log.Println("日志协程正常退出")
+}
+
+// runLogFlusher 每 500ms 检查一次缓存并发送，ctx 取消时做最后一次刷新后退出
+func (s *MoleService) runLogFlusher(ctx context.Context) {
+	ticker := time.NewTicker(logFlushInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			s.flushLogs()
+			s.sweepLogIssues()
+		case <-ctx.Done():
+			s.flushLogs()
+			return
+		}
+	}
 }
 
 func (s *MoleService) stopFrp() {
+	s.stopRequested.Store(true)
+	s.stopSSHTunnel()
+
 	s.mu.Lock()
 	if s.frpCmd == nil || s.frpCmd.Process == nil {
 		s.mu.Unlock()
@@ -489,6 +1328,7 @@ func (s *MoleService) stopFrp() {
 	}
 
 	pid := strconv.Itoa(s.frpCmd.Process.Pid)
+	s.setTunnelState(tunnelStopping, "")
 	s.mu.Unlock() // 先解锁，避免 taskkill 阻塞时占用锁
 
 	if runtime.GOOS == "windows" {
@@ -517,22 +1357,51 @@ func (s *MoleService) flushLogs() {
 	}
 
 	// 拷贝并清空缓存
-	logsToSend := make([]string, len(s.logBuffer))
+	logsToSend := make([]logLine, len(s.logBuffer))
 	copy(logsToSend, s.logBuffer)
 	s.logBuffer = s.logBuffer[:0]
 	s.logMu.Unlock()
 
-	s.emitLog(logsToSend...)
+	s.emitLogLines(logsToSend)
 }
 
 func (s *MoleService) emitLog(logs ...string) {
+	s.emitLogLines(stampNow(logs))
+}
+
+// emitLogLines 日志带着接收时间落盘与推送，刷新间隔不影响时间精度
+func (s *MoleService) emitLogLines(logs []logLine) {
 	if len(logs) == 0 {
 		return
 	}
+	// 实际运行隧道的进程负责落盘，附着模式下日志来自守护进程，不重复写
+	if s.remote == nil {
+		s.logHistory.append(logs)
+	}
+	// 历史完整保存，推送到界面的按级别过滤，反复出现的同一条错误按次数退避，见 logrepeat.go
+	tagged, issuesChanged := s.logRepeat.filter(s.logTagger.tag(logs))
+	if issuesChanged {
+		s.events.Emit("log-issues", s.logRepeat.list())
+	}
+	entries := s.logStream.publish(s.logClock.stamp(s.logTagger.forward(tagged)))
+	if len(entries) == 0 {
+		return
+	}
 	// 一次性发送数组，前端通过 v-for 循环渲染
-	manager.App.Event.Emit("frp-logs", logs)
+	s.events.Emit("frp-logs", entries)
 }
 
 func (s *MoleService) emitFrpStatus(status string) {
-	manager.App.Event.Emit("frp-status", status)
+	if status == "start" {
+		s.setTunnelState(tunnelConnecting, "")
+	}
+	s.events.Emit("frp-status", status)
+}
+
+func (s *MoleService) emitConfigSave(state, message string) {
+	s.events.Emit("config-save", ConfigSaveEvent{
+		State:   state,
+		Message: message,
+		Time:    time.Now().Format(time.RFC3339),
+	})
 }
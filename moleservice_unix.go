@@ -8,3 +8,11 @@ import "syscall"
 func setHideWindow(attr *syscall.SysProcAttr) {
 	// 留空
 }
+
+// setDetached 让子进程脱离当前会话，父进程退出后不受影响
+func setDetached(attr *syscall.SysProcAttr) {
+	if attr == nil {
+		return
+	}
+	attr.Setsid = true
+}
@@ -11,3 +11,12 @@ func setHideWindow(attr *syscall.SysProcAttr) {
 	}
 	attr.HideWindow = true
 }
+
+// setDetached 让子进程脱离当前控制台与进程组，父进程退出后不受影响
+func setDetached(attr *syscall.SysProcAttr) {
+	if attr == nil {
+		return
+	}
+	// DETACHED_PROCESS | CREATE_NEW_PROCESS_GROUP
+	attr.CreationFlags |= 0x00000008 | syscall.CREATE_NEW_PROCESS_GROUP
+}
@@ -0,0 +1,314 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	mqtt "github.com/eclipse/paho.mqtt.golang"
+)
+
+// MQTT 集成：把隧道与各条规则的状态发布到 MQTT，开启 Home Assistant 自动发现后
+// 隧道显示为一个连接状态 binary_sensor，每条规则各有一个状态 sensor 和一个延迟 sensor，无需手写 YAML
+// 只在实际运行隧道的进程 (守护进程或独立模式) 中运行
+
+const (
+	mqttDefaultPrefix    = "mole"
+	mqttDefaultDiscovery = "homeassistant"
+	mqttConnectTimeout   = 10 * time.Second
+	mqttQoS              = 1
+)
+
+// MQTTConfig MQTT 发布设置
+type MQTTConfig struct {
+	Enabled         bool   `toml:"enabled" json:"enabled"`
+	Broker          string `toml:"broker" json:"broker"` // tcp://host:1883、ssl://host:8883 或 ws://host:8083/mqtt
+	Username        string `toml:"username,omitempty" json:"username"`
+	Password        string `toml:"password,omitempty" json:"password"`
+	TopicPrefix     string `toml:"topic_prefix,omitempty" json:"topicPrefix"`         // 默认 mole
+	HomeAssistant   bool   `toml:"home_assistant" json:"homeAssistant"`               // 发布 Home Assistant 自动发现消息
+	DiscoveryPrefix string `toml:"discovery_prefix,omitempty" json:"discoveryPrefix"` // 默认 homeassistant
+}
+
+var reMQTTNode = regexp.MustCompile(`[^a-z0-9_-]+`)
+
+// mqttBridge 一次 MQTT 连接及其发布状态
+type mqttBridge struct {
+	s      *MoleService
+	cfg    MQTTConfig
+	node   string // 主题与实体 ID 中使用的本机标识
+	host   string
+	client mqtt.Client
+
+	mu         sync.Mutex
+	discovered map[string]bool // 已发布自动发现的规则 ID，规则删除时据此清除
+}
+
+func (s *MoleService) newMQTTBridge(cfg MQTTConfig) *mqttBridge {
+	host, _ := os.Hostname()
+	if host == "" {
+		host = "mole"
+	}
+	if cfg.TopicPrefix == "" {
+		cfg.TopicPrefix = mqttDefaultPrefix
+	}
+	if cfg.DiscoveryPrefix == "" {
+		cfg.DiscoveryPrefix = mqttDefaultDiscovery
+	}
+	b := &mqttBridge{
+		s:          s,
+		cfg:        cfg,
+		node:       reMQTTNode.ReplaceAllString(strings.ToLower(host), "_"),
+		host:       host,
+		discovered: make(map[string]bool),
+	}
+
+	opts := mqtt.NewClientOptions().
+		AddBroker(cfg.Broker).
+		SetClientID("mole-"+b.node).
+		SetUsername(cfg.Username).
+		SetPassword(cfg.Password).
+		SetAutoReconnect(true).
+		SetConnectRetry(true).
+		SetWill(b.topic("availability"), "offline", mqttQoS, true).
+		SetOnConnectHandler(func(mqtt.Client) { b.publishAll() }).
+		SetConnectionLostHandler(func(_ mqtt.Client, err error) {
+			log.Printf("MQTT 连接断开: %v", err)
+		})
+	b.client = mqtt.NewClient(opts)
+	return b
+}
+
+func (b *mqttBridge) topic(parts ...string) string {
+	return b.cfg.TopicPrefix + "/" + b.node + "/" + strings.Join(parts, "/")
+}
+
+func (b *mqttBridge) connect() {
+	tok := b.client.Connect()
+	if !tok.WaitTimeout(mqttConnectTimeout) {
+		b.s.emitLog("MQTT 连接超时，将在后台继续重试: " + b.cfg.Broker)
+		return
+	}
+	if err := tok.Error(); err != nil {
+		b.s.emitLog(fmt.Sprintf("MQTT 连接失败: %v", err))
+		return
+	}
+	b.s.emitLog("已连接 MQTT: " + b.cfg.Broker)
+}
+
+// close 主动下线，发布 offline 后断开
+func (b *mqttBridge) close() {
+	if b.client.IsConnected() {
+		b.publish(b.topic("availability"), "offline")
+	}
+	b.client.Disconnect(250)
+}
+
+func (b *mqttBridge) publish(topic string, payload any) {
+	if !b.client.IsConnected() {
+		return
+	}
+	var data []byte
+	switch v := payload.(type) {
+	case string:
+		data = []byte(v)
+	default:
+		data, _ = json.Marshal(v)
+	}
+	b.client.Publish(topic, mqttQoS, true, data)
+}
+
+// publishAll 连上 (含重连) 后发布自动发现与当前全部状态
+func (b *mqttBridge) publishAll() {
+	b.publish(b.topic("availability"), "online")
+	b.syncDiscovery()
+	b.publishTunnel()
+	if states, err := b.s.GetProxyStates(); err == nil {
+		for _, st := range states {
+			b.publishProxyState(st)
+		}
+	}
+	b.publishLatency(b.s.proxyLatency())
+}
+
+func (b *mqttBridge) publishTunnel() {
+	state := "OFF"
+	if b.s.isRunning.Load() {
+		state = "ON"
+	}
+	b.publish(b.topic("tunnel"), state)
+}
+
+func (b *mqttBridge) publishProxyState(st ProxyState) {
+	b.publish(b.topic("proxy", st.RuleID, "state"), st.State)
+}
+
+func (b *mqttBridge) publishLatency(list []ProxyLatency) {
+	for _, l := range list {
+		if l.Last < 0 {
+			continue
+		}
+		b.publish(b.topic("proxy", l.RuleID, "latency"), fmt.Sprintf("%.0f", l.Last))
+	}
+}
+
+// syncDiscovery 按当前规则发布 Home Assistant 自动发现，已删除的规则发布空消息让 HA 移除实体
+func (b *mqttBridge) syncDiscovery() {
+	if !b.cfg.HomeAssistant || !b.client.IsConnected() {
+		return
+	}
+	cfg := b.s.status().Config
+	if cfg == nil {
+		return
+	}
+
+	device := map[string]any{
+		"identifiers":  []string{"mole_" + b.node},
+		"name":         "Mole " + b.host,
+		"manufacturer": "Mole",
+		"sw_version":   appVersion,
+	}
+	avail := b.topic("availability")
+
+	b.publish(b.discoveryTopic("binary_sensor", "tunnel"), map[string]any{
+		"name":               "隧道",
+		"unique_id":          "mole_" + b.node + "_tunnel",
+		"state_topic":        b.topic("tunnel"),
+		"device_class":       "connectivity",
+		"availability_topic": avail,
+		"device":             device,
+	})
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	current := make(map[string]bool, len(cfg.Proxies))
+	for _, p := range cfg.Proxies {
+		current[p.ID] = true
+		b.discovered[p.ID] = true
+		b.publish(b.discoveryTopic("sensor", p.ID+"_state"), map[string]any{
+			"name":               p.Name + " 状态",
+			"unique_id":          "mole_" + b.node + "_" + p.ID + "_state",
+			"state_topic":        b.topic("proxy", p.ID, "state"),
+			"icon":               "mdi:lan-connect",
+			"availability_topic": avail,
+			"device":             device,
+		})
+		b.publish(b.discoveryTopic("sensor", p.ID+"_latency"), map[string]any{
+			"name":                p.Name + " 延迟",
+			"unique_id":           "mole_" + b.node + "_" + p.ID + "_latency",
+			"state_topic":         b.topic("proxy", p.ID, "latency"),
+			"unit_of_measurement": "ms",
+			"state_class":         "measurement",
+			"availability_topic":  avail,
+			"device":              device,
+		})
+	}
+	for id := range b.discovered {
+		if current[id] {
+			continue
+		}
+		b.publish(b.discoveryTopic("sensor", id+"_state"), "")
+		b.publish(b.discoveryTopic("sensor", id+"_latency"), "")
+		delete(b.discovered, id)
+	}
+}
+
+// clearDiscovery 关闭集成或自动发现时让 Home Assistant 移除全部实体
+func (b *mqttBridge) clearDiscovery() {
+	if !b.cfg.HomeAssistant {
+		return
+	}
+	b.publish(b.discoveryTopic("binary_sensor", "tunnel"), "")
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for id := range b.discovered {
+		b.publish(b.discoveryTopic("sensor", id+"_state"), "")
+		b.publish(b.discoveryTopic("sensor", id+"_latency"), "")
+		delete(b.discovered, id)
+	}
+}
+
+func (b *mqttBridge) discoveryTopic(component, object string) string {
+	return b.cfg.DiscoveryPrefix + "/" + component + "/mole_" + b.node + "/" + object + "/config"
+}
+
+// runMQTT 跟随配置启停 MQTT 连接，并把状态事件转发到 MQTT
+func (s *MoleService) runMQTT(ctx context.Context) {
+	events, cancel := s.bus.subscribeEvents(64)
+	defer cancel()
+
+	var bridge *mqttBridge
+	apply := func() {
+		var cfg MQTTConfig
+		if c := s.status().Config; c != nil {
+			cfg = c.MQTT
+		}
+		if bridge != nil && bridge.sameConfig(cfg) {
+			// 连接参数未变，规则可能增删，刷新自动发现
+			bridge.syncDiscovery()
+			return
+		}
+		if bridge != nil {
+			if !cfg.Enabled || !cfg.HomeAssistant {
+				bridge.clearDiscovery()
+			}
+			bridge.close()
+			bridge = nil
+		}
+		if cfg.Enabled && cfg.Broker != "" {
+			bridge = s.newMQTTBridge(cfg)
+			go bridge.connect()
+		}
+	}
+	apply()
+
+	for {
+		select {
+		case <-ctx.Done():
+			if bridge != nil {
+				bridge.close()
+			}
+			return
+		case ev := <-events:
+			if ev.name == "config-save" {
+				if e, ok := ev.data.(ConfigSaveEvent); ok && e.State == "saved" {
+					apply()
+				}
+				continue
+			}
+			if bridge == nil {
+				continue
+			}
+			switch ev.name {
+			case "frp-status":
+				bridge.publishTunnel()
+			case "proxy-state":
+				if st, ok := ev.data.(ProxyState); ok {
+					bridge.publishProxyState(st)
+				}
+			case "proxy-latency":
+				if list, ok := ev.data.([]ProxyLatency); ok {
+					bridge.publishLatency(list)
+				}
+			}
+		}
+	}
+}
+
+// sameConfig 比较时按填充默认值后的配置，避免空前缀与默认前缀被当成不同
+func (b *mqttBridge) sameConfig(cfg MQTTConfig) bool {
+	if cfg.TopicPrefix == "" {
+		cfg.TopicPrefix = mqttDefaultPrefix
+	}
+	if cfg.DiscoveryPrefix == "" {
+		cfg.DiscoveryPrefix = mqttDefaultDiscovery
+	}
+	return b.cfg == cfg
+}
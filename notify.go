@@ -0,0 +1,253 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// 消息通知：隧道告警推送到 Telegram、Slack、钉钉、企业微信等聊天工具，
+// 无界面的服务器上看不到桌面通知，这是最直接的提醒方式
+// 每个渠道单独配置凭据与关心的事件，后端按 Type 在 notifierBackends 中查找，新增渠道只需注册一个构造函数
+
+const notifyTimeout = 10 * time.Second
+
+// NotifyConfig 消息通知设置
+type NotifyConfig struct {
+	Channels []NotifyChannel `toml:"channels,omitempty" json:"channels"`
+}
+
+// NotifyChannel 一个通知渠道
+type NotifyChannel struct {
+	Type    string   `toml:"type" json:"type"` // telegram / slack / dingtalk / wecom
+	Name    string   `toml:"name,omitempty" json:"name"`
+	Enabled bool     `toml:"enabled" json:"enabled"`
+	Events  []string `toml:"events,omitempty" json:"events"` // down / up / reconnect / expiring，为空表示全部
+
+	// 凭据：Telegram 使用 BotToken + ChatID，其余使用 Webhook 地址，钉钉加签时填写 Secret
+	Webhook  string `toml:"webhook,omitempty" json:"webhook"`
+	Secret   string `toml:"secret,omitempty" json:"secret"`
+	BotToken string `toml:"bot_token,omitempty" json:"botToken"`
+	ChatID   string `toml:"chat_id,omitempty" json:"chatID"`
+}
+
+// wants 渠道是否订阅了该类事件
+func (c NotifyChannel) wants(kind string) bool {
+	if len(c.Events) == 0 {
+		return true
+	}
+	for _, e := range c.Events {
+		if e == kind {
+			return true
+		}
+	}
+	return false
+}
+
+// notifier 通知后端
+type notifier interface {
+	send(ctx context.Context, text string) error
+}
+
+var notifierBackends = map[string]func(NotifyChannel) (notifier, error){
+	"telegram": newTelegramNotifier,
+	"slack":    newWebhookNotifier(slackPayload, nil),
+	"dingtalk": newWebhookNotifier(robotPayload, signDingTalk),
+	"wecom":    newWebhookNotifier(robotPayload, nil),
+}
+
+func newNotifier(c NotifyChannel) (notifier, error) {
+	build, ok := notifierBackends[c.Type]
+	if !ok {
+		return nil, fmt.Errorf("不支持的通知渠道: %s", c.Type)
+	}
+	return build(c)
+}
+
+// --- Telegram ---
+
+type telegramNotifier struct {
+	token, chatID string
+}
+
+func newTelegramNotifier(c NotifyChannel) (notifier, error) {
+	if c.BotToken == "" || c.ChatID == "" {
+		return nil, fmt.Errorf("Telegram 需要填写 Bot Token 和 Chat ID")
+	}
+	return &telegramNotifier{token: c.BotToken, chatID: c.ChatID}, nil
+}
+
+func (n *telegramNotifier) send(ctx context.Context, text string) error {
+	endpoint := "https://api.telegram.org/bot" + n.token + "/sendMessage"
+	return postJSON(ctx, endpoint, map[string]string{"chat_id": n.chatID, "text": text})
+}
+
+// --- Webhook 类 (Slack / 钉钉 / 企业微信) ---
+
+type webhookNotifier struct {
+	url     string
+	payload func(text string) any
+}
+
+func newWebhookNotifier(payload func(string) any, sign func(hook, secret string) (string, error)) func(NotifyChannel) (notifier, error) {
+	return func(c NotifyChannel) (notifier, error) {
+		if c.Webhook == "" {
+			return nil, fmt.Errorf("请填写 Webhook 地址")
+		}
+		hook := c.Webhook
+		if sign != nil && c.Secret != "" {
+			signed, err := sign(hook, c.Secret)
+			if err != nil {
+				return nil, err
+			}
+			hook = signed
+		}
+		return &webhookNotifier{url: hook, payload: payload}, nil
+	}
+}
+
+func (n *webhookNotifier) send(ctx context.Context, text string) error {
+	return postJSON(ctx, n.url, n.payload(text))
+}
+
+func slackPayload(text string) any {
+	return map[string]string{"text": text}
+}
+
+// robotPayload 钉钉与企业微信群机器人的文本消息格式相同
+func robotPayload(text string) any {
+	return map[string]any{"msgtype": "text", "text": map[string]string{"content": text}}
+}
+
+// signDingTalk 钉钉机器人“加签”安全设置：timestamp + "\n" + secret 做 HmacSHA256
+func signDingTalk(hook, secret string) (string, error) {
+	u, err := url.Parse(hook)
+	if err != nil {
+		return "", fmt.Errorf("Webhook 地址无效: %v", err)
+	}
+	ts := strconv.FormatInt(time.Now().UnixMilli(), 10)
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(ts + "\n" + secret))
+
+	q := u.Query()
+	q.Set("timestamp", ts)
+	q.Set("sign", base64.StdEncoding.EncodeToString(mac.Sum(nil)))
+	u.RawQuery = q.Encode()
+	return u.String(), nil
+}
+
+// postJSON 发送通知请求；钉钉和企业微信失败时仍返回 200，需要检查 errcode
+func postJSON(ctx context.Context, endpoint string, body any) error {
+	data, err := json.Marshal(body)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := outboundClient(0).Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	respBody, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+	if resp.StatusCode/100 != 2 {
+		return fmt.Errorf("服务器返回 %s: %s", resp.Status, strings.TrimSpace(string(respBody)))
+	}
+
+	var result struct {
+		ErrCode *int   `json:"errcode"`
+		ErrMsg  string `json:"errmsg"`
+		OK      *bool  `json:"ok"`
+		Desc    string `json:"description"`
+	}
+	if json.Unmarshal(respBody, &result) == nil {
+		if result.ErrCode != nil && *result.ErrCode != 0 {
+			return fmt.Errorf("发送失败: %s", result.ErrMsg)
+		}
+		if result.OK != nil && !*result.OK {
+			return fmt.Errorf("发送失败: %s", result.Desc)
+		}
+	}
+	return nil
+}
+
+// alertText 通知文本
+func alertText(a TunnelAlert) string {
+	title := map[string]string{
+		alertDown:          "🔴 隧道已断开",
+		alertUp:            "🟢 隧道已连接",
+		alertReconnect:     "🟡 隧道已恢复",
+		alertShareExpiring: "⏰ 限时分享即将到期",
+	}[a.Kind]
+	return fmt.Sprintf("[Mole · %s] %s\n%s\n%s", a.Host, title, a.Message, a.Time.Format("2006-01-02 15:04:05"))
+}
+
+// TestNotifyChannel 发送一条测试消息，用于保存前确认凭据是否正确
+func (s *MoleService) TestNotifyChannel(c NotifyChannel) error {
+	n, err := newNotifier(c)
+	if err != nil {
+		return err
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), notifyTimeout)
+	defer cancel()
+	host, _ := os.Hostname()
+	if err := n.send(ctx, fmt.Sprintf("[Mole · %s] 这是一条测试消息", host)); err != nil {
+		return fmt.Errorf("发送测试消息失败: %v", err)
+	}
+	return nil
+}
+
+// runNotifier 订阅隧道告警并按渠道配置推送
+func (s *MoleService) runNotifier(ctx context.Context) {
+	events, cancel := s.bus.subscribeEvents(32)
+	defer cancel()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case ev := <-events:
+			alert, ok := ev.data.(TunnelAlert)
+			if ev.name != "tunnel-alert" || !ok {
+				continue
+			}
+			cfg := s.status().Config
+			if cfg == nil {
+				continue
+			}
+			for _, c := range cfg.Notify.Channels {
+				if !c.Enabled || !c.wants(alert.Kind) {
+					continue
+				}
+				go s.deliverAlert(ctx, c, alert)
+			}
+		}
+	}
+}
+
+func (s *MoleService) deliverAlert(ctx context.Context, c NotifyChannel, alert TunnelAlert) {
+	n, err := newNotifier(c)
+	if err == nil {
+		sendCtx, cancel := context.WithTimeout(ctx, notifyTimeout)
+		err = n.send(sendCtx, alertText(alert))
+		cancel()
+	}
+	if err != nil {
+		s.emitLog(fmt.Sprintf("通知渠道 %s 发送失败: %v", c.Type, err))
+	}
+}
@@ -0,0 +1,86 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+// 出站代理：Mole 自己发起的 HTTP 请求 (消息通知、匿名统计、Vault 等) 走的代理，与 frpc 的传输无关
+// 公司网络通常只能经代理访问外网；默认跟随系统设置 (HTTPS_PROXY 等环境变量，Windows 上还会读取 Internet 选项)
+// 访问本机与局域网的请求 (frpc 管理接口、守护进程、路由器) 不经过这里
+
+const outboundDirect = "direct"
+
+// 当前生效的 Transport，随配置加载与保存替换
+var outboundTransport atomic.Pointer[http.Transport]
+
+func validateOutboundProxy(v string) error {
+	if v == "" || v == outboundDirect {
+		return nil
+	}
+	u, err := url.Parse(v)
+	if err != nil || u.Host == "" {
+		return fmt.Errorf("代理地址无效: %s", v)
+	}
+	switch u.Scheme {
+	case "http", "https", "socks5":
+		return nil
+	}
+	return fmt.Errorf("不支持的代理协议: %s (可用 http、https、socks5)", u.Scheme)
+}
+
+// configureOutbound 无效的设置按跟随系统处理 (保存时已校验)
+func configureOutbound(prefs Preferences) {
+	t := http.DefaultTransport.(*http.Transport).Clone()
+	switch v := prefs.OutboundProxy; v {
+	case outboundDirect:
+		t.Proxy = nil
+	case "":
+		t.Proxy = systemProxy
+	default:
+		u, err := url.Parse(v)
+		if err != nil {
+			t.Proxy = systemProxy
+			break
+		}
+		t.Proxy = http.ProxyURL(u)
+	}
+	outboundTransport.Store(t)
+}
+
+// systemProxy 环境变量优先，未设置时使用系统的代理设置 (见 outbound_*.go)
+func systemProxy(req *http.Request) (*url.URL, error) {
+	if u, err := http.ProxyFromEnvironment(req); u != nil || err != nil {
+		return u, err
+	}
+	return platformProxy(req)
+}
+
+// outboundClient 访问外网使用的 client，timeout 为 0 时不限制
+func outboundClient(timeout time.Duration) *http.Client {
+	t := outboundTransport.Load()
+	if t == nil {
+		configureOutbound(Preferences{})
+		t = outboundTransport.Load()
+	}
+	return &http.Client{Timeout: timeout, Transport: t}
+}
+
+// SetOutboundProxy 设置出站代理：空字符串跟随系统，direct 为直连，或 http://host:port、socks5://host:port
+func (s *MoleService) SetOutboundProxy(proxy string) error {
+	proxy = strings.TrimSpace(proxy)
+	if err := validateOutboundProxy(proxy); err != nil {
+		return err
+	}
+	cfg := s.status().Config
+	if cfg == nil {
+		return fmt.Errorf("未发现有效配置")
+	}
+	newCfg := *cfg
+	newCfg.Preferences.OutboundProxy = proxy
+	return s.saveUserConfig(newCfg)
+}
@@ -0,0 +1,13 @@
+//go:build !windows
+
+package main
+
+import (
+	"net/http"
+	"net/url"
+)
+
+// platformProxy macOS / Linux 只使用环境变量中的代理设置
+func platformProxy(req *http.Request) (*url.URL, error) {
+	return nil, nil
+}
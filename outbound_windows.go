@@ -0,0 +1,69 @@
+//go:build windows
+
+package main
+
+import (
+	"net/http"
+	"net/url"
+	"strings"
+
+	"golang.org/x/sys/windows/registry"
+)
+
+// platformProxy 读取当前用户“Internet 选项”中的手动代理设置，不处理 PAC 自动配置脚本
+func platformProxy(req *http.Request) (*url.URL, error) {
+	k, err := registry.OpenKey(registry.CURRENT_USER, `Software\Microsoft\Windows\CurrentVersion\Internet Settings`, registry.QUERY_VALUE)
+	if err != nil {
+		return nil, nil
+	}
+	defer k.Close()
+	if enabled, _, err := k.GetIntegerValue("ProxyEnable"); err != nil || enabled == 0 {
+		return nil, nil
+	}
+	server, _, err := k.GetStringValue("ProxyServer")
+	if err != nil || server == "" {
+		return nil, nil
+	}
+	if bypass, _, err := k.GetStringValue("ProxyOverride"); err == nil && proxyBypassed(req.URL.Hostname(), bypass) {
+		return nil, nil
+	}
+	// 形如 "host:port" 或按协议分别设置 "http=host:port;https=host:port"
+	addr := server
+	if strings.Contains(server, "=") {
+		addr = ""
+		for _, part := range strings.Split(server, ";") {
+			scheme, hostport, ok := strings.Cut(strings.TrimSpace(part), "=")
+			if ok && strings.EqualFold(scheme, req.URL.Scheme) {
+				addr = hostport
+			}
+		}
+		if addr == "" {
+			return nil, nil
+		}
+	}
+	if !strings.Contains(addr, "://") {
+		addr = "http://" + addr
+	}
+	return url.Parse(addr)
+}
+
+// proxyBypassed ProxyOverride 以分号分隔，支持 * 通配与 <local> (不含点的主机名)
+func proxyBypassed(host, override string) bool {
+	for _, rule := range strings.Split(override, ";") {
+		rule = strings.ToLower(strings.TrimSpace(rule))
+		switch {
+		case rule == "":
+		case rule == "<local>":
+			if !strings.Contains(host, ".") {
+				return true
+			}
+		case strings.HasPrefix(rule, "*"):
+			if strings.HasSuffix(host, strings.TrimPrefix(rule, "*")) {
+				return true
+			}
+		case rule == strings.ToLower(host):
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,73 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// 通用客户端插件：frp 的 http_proxy、socks5、static_file、unix_domain_socket 等插件，
+// 以及以后新增的插件，在 Mole 提供专门界面之前可以直接填写插件类型与参数，原样写入 frpc.toml
+// 参数值一律为字符串，只有 true / false 转换为布尔值 (frp 的插件参数目前只有这两种类型)
+
+const maxPluginParams = 20
+
+var (
+	pluginTypePattern  = regexp.MustCompile(`^[a-z0-9_]{1,32}$`)
+	pluginParamPattern = regexp.MustCompile(`^[A-Za-z][A-Za-z0-9_]{0,63}$`)
+)
+
+// validatePlugin 插件类型与 HTTPS 证书终止 (同样通过插件实现) 不能同时使用
+func validatePlugin(p ProxyRule) error {
+	if p.Plugin == "" {
+		if len(p.PluginParams) > 0 {
+			return fmt.Errorf("规则 \"%s\" 填写了插件参数，但没有选择插件类型", p.Name)
+		}
+		return nil
+	}
+	if !pluginTypePattern.MatchString(p.Plugin) {
+		return fmt.Errorf("规则 \"%s\" 的插件类型无效: %s", p.Name, p.Plugin)
+	}
+	if p.ProxyType == "https" && p.HTTPSTerminate {
+		return fmt.Errorf("规则 \"%s\" 已开启证书终止，不能再指定插件", p.Name)
+	}
+	if len(p.PluginParams) > maxPluginParams {
+		return fmt.Errorf("规则 \"%s\" 最多填写 %d 个插件参数", p.Name, maxPluginParams)
+	}
+	for k := range p.PluginParams {
+		if !pluginParamPattern.MatchString(k) || k == "type" {
+			return fmt.Errorf("规则 \"%s\" 的插件参数名无效: %s", p.Name, k)
+		}
+	}
+	return nil
+}
+
+// normalizePluginParams 去掉参数名与值两端的空白，丢弃空参数名
+func normalizePluginParams(params map[string]string) map[string]string {
+	if len(params) == 0 {
+		return nil
+	}
+	out := make(map[string]string, len(params))
+	for k, v := range params {
+		if k = strings.TrimSpace(k); k != "" {
+			out[k] = strings.TrimSpace(v)
+		}
+	}
+	return out
+}
+
+// pluginSection 生成 frpc.toml 中代理的 plugin 段
+func pluginSection(p ProxyRule) map[string]any {
+	section := map[string]any{"type": p.Plugin}
+	for k, v := range p.PluginParams {
+		switch v {
+		case "true":
+			section[k] = true
+		case "false":
+			section[k] = false
+		default:
+			section[k] = v
+		}
+	}
+	return section
+}
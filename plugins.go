@@ -0,0 +1,259 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// 事件插件：用户提供的脚本或可执行程序订阅 Mole 的事件，实现 Mole 没有内置的集成 (如更新 DDNS、写入自己的监控)
+// 插件每次事件运行一次：事件名放在环境变量 MOLE_EVENT 中，事件内容以 JSON 写入标准输入，输出逐行记入运行日志
+// 对外的事件名与内部事件解耦，内部事件改名时插件不受影响。只支持可执行程序，不内置 WASM 运行时
+
+const (
+	pluginEventConnected     = "connected"      // 隧道已连接
+	pluginEventDisconnected  = "disconnected"   // 隧道已断开 (主动断开或出错)
+	pluginEventTunnelDown    = "tunnel-down"    // 隧道意外断开
+	pluginEventProxyUp       = "proxy-up"       // 规则上线
+	pluginEventProxyDown     = "proxy-down"     // 规则出错
+	pluginEventQuotaExceeded = "quota-exceeded" // frpc 持续超出内存上限，随后自动重启
+	pluginEventTest          = "test"           // 界面中的“测试运行”
+
+	maxPlugins             = 10
+	defaultCommandTimeout  = 30 * time.Second
+	maxCommandTimeoutSecs  = 600
+	maxConcurrentCommands  = 4
+	commandOutputLineLimit = 50 // 单次运行最多记入日志的输出行数
+)
+
+var pluginEvents = []string{
+	pluginEventConnected, pluginEventDisconnected, pluginEventTunnelDown,
+	pluginEventProxyUp, pluginEventProxyDown, pluginEventQuotaExceeded,
+}
+
+// EventPlugin 一个事件插件
+type EventPlugin struct {
+	Name    string   `toml:"name" json:"name"`
+	Enabled bool     `toml:"enabled" json:"enabled"`
+	Command string   `toml:"command" json:"command"`           // 可执行程序或脚本的路径
+	Args    []string `toml:"args,omitempty" json:"args"`       // 附加参数
+	Events  []string `toml:"events,omitempty" json:"events"`   // 订阅的事件，空为全部
+	Timeout int      `toml:"timeout,omitempty" json:"timeout"` // 单次运行的超时秒数，0 为 30 秒
+}
+
+// PluginEvent 写入插件标准输入的内容
+type PluginEvent struct {
+	Event string    `json:"event"`
+	Host  string    `json:"host"`
+	Time  time.Time `json:"time"`
+	Data  any       `json:"data"` // 原始事件内容，如 TunnelStateEvent、ProxyState
+}
+
+// MemoryLimitEvent frpc-memory-limit 事件：frpc 持续超出内存上限，即将重启
+type MemoryLimitEvent struct {
+	RSSBytes   uint64    `json:"rssBytes"`
+	LimitBytes uint64    `json:"limitBytes"`
+	Time       time.Time `json:"time"`
+}
+
+func (p EventPlugin) wants(event string) bool {
+	if len(p.Events) == 0 {
+		return true
+	}
+	for _, e := range p.Events {
+		if e == event {
+			return true
+		}
+	}
+	return false
+}
+
+func commandTimeout(secs int) time.Duration {
+	if secs <= 0 {
+		return defaultCommandTimeout
+	}
+	return time.Duration(secs) * time.Second
+}
+
+func validatePlugins(plugins []EventPlugin) error {
+	if len(plugins) > maxPlugins {
+		return fmt.Errorf("最多配置 %d 个插件", maxPlugins)
+	}
+	known := make(map[string]bool, len(pluginEvents))
+	for _, e := range pluginEvents {
+		known[e] = true
+	}
+	seen := make(map[string]bool, len(plugins))
+	for _, p := range plugins {
+		if p.Name == "" {
+			return fmt.Errorf("插件名称不能为空")
+		}
+		if seen[p.Name] {
+			return fmt.Errorf("插件名称重复: %s", p.Name)
+		}
+		seen[p.Name] = true
+		if p.Command == "" {
+			return fmt.Errorf("插件 %s 缺少命令", p.Name)
+		}
+		if p.Timeout < 0 || p.Timeout > maxCommandTimeoutSecs {
+			return fmt.Errorf("插件 %s 的超时应在 0 ~ %d 秒之间", p.Name, maxCommandTimeoutSecs)
+		}
+		for _, e := range p.Events {
+			if !known[e] {
+				return fmt.Errorf("插件 %s 订阅了未知事件: %s", p.Name, e)
+			}
+		}
+	}
+	return nil
+}
+
+// pluginEventName 把内部事件换成插件事件名，不关心的事件返回空
+func pluginEventName(name string, data any) string {
+	switch name {
+	case "tunnel-state":
+		ev, ok := data.(TunnelStateEvent)
+		if !ok {
+			return ""
+		}
+		switch {
+		case ev.State == tunnelConnected:
+			return pluginEventConnected
+		case (ev.State == tunnelIdle || ev.State == tunnelError) &&
+			(ev.Previous == tunnelConnected || ev.Previous == tunnelReconnecting || ev.Previous == tunnelStopping):
+			return pluginEventDisconnected
+		}
+	case "tunnel-alert":
+		if a, ok := data.(TunnelAlert); ok && a.Kind == alertDown {
+			return pluginEventTunnelDown
+		}
+	case "proxy-state":
+		st, ok := data.(ProxyState)
+		if !ok {
+			return ""
+		}
+		switch st.State {
+		case proxyStateRunning:
+			return pluginEventProxyUp
+		case proxyStateError:
+			return pluginEventProxyDown
+		}
+	case "frpc-memory-limit":
+		return pluginEventQuotaExceeded
+	}
+	return ""
+}
+
+// 插件与生命周期钩子共用的并发上限，避免事件密集时同时拉起大量进程
+var commandSlots = make(chan struct{}, maxConcurrentCommands)
+
+// runUserCommand 运行用户配置的命令，输出逐行以 [label] 为前缀记入运行日志
+func (s *MoleService) runUserCommand(ctx context.Context, label, command string, args []string, timeout time.Duration, env []string, stdin []byte) error {
+	select {
+	case commandSlots <- struct{}{}:
+		defer func() { <-commandSlots }()
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	cmd := exec.CommandContext(ctx, command, args...)
+	cmd.Env = append(os.Environ(), env...)
+	cmd.SysProcAttr = &syscall.SysProcAttr{}
+	setHideWindow(cmd.SysProcAttr)
+	if stdin != nil {
+		cmd.Stdin = bytes.NewReader(stdin)
+	}
+	pr, pw := io.Pipe()
+	cmd.Stdout, cmd.Stderr = pw, pw
+
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		scanner := bufio.NewScanner(pr)
+		n := 0
+		for scanner.Scan() {
+			if n++; n <= commandOutputLineLimit {
+				s.emitLog(fmt.Sprintf("[%s] %s", label, scanner.Text()))
+			}
+		}
+		if n > commandOutputLineLimit {
+			s.emitLog(fmt.Sprintf("[%s] ... 省略 %d 行输出", label, n-commandOutputLineLimit))
+		}
+		_, _ = io.Copy(io.Discard, pr)
+	}()
+
+	err := cmd.Run()
+	pw.Close()
+	wg.Wait()
+	if ctx.Err() == context.DeadlineExceeded {
+		return fmt.Errorf("运行超时 (%s)", timeout)
+	}
+	return err
+}
+
+// runPlugin 以一个事件运行插件
+func (s *MoleService) runPlugin(ctx context.Context, p EventPlugin, event string, data any) error {
+	host, _ := os.Hostname()
+	payload, err := json.Marshal(PluginEvent{Event: event, Host: host, Time: time.Now(), Data: data})
+	if err != nil {
+		return err
+	}
+	env := []string{"MOLE_EVENT=" + event, "MOLE_HOST=" + host}
+	return s.runUserCommand(ctx, "插件 "+p.Name, p.Command, p.Args, commandTimeout(p.Timeout), env, payload)
+}
+
+// runPlugins 订阅事件并交给匹配的插件，每次运行在独立协程中
+func (s *MoleService) runPlugins(ctx context.Context) {
+	events, cancel := s.bus.subscribeEvents(64)
+	defer cancel()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case ev := <-events:
+			event := pluginEventName(ev.name, ev.data)
+			if event == "" {
+				continue
+			}
+			cfg := s.status().Config
+			if cfg == nil {
+				continue
+			}
+			for _, p := range cfg.Plugins {
+				if !p.Enabled || !p.wants(event) {
+					continue
+				}
+				go func() {
+					if err := s.runPlugin(ctx, p, event, ev.data); err != nil {
+						s.emitLog(fmt.Sprintf("插件 %s 处理 %s 事件失败: %v", p.Name, event, err))
+					}
+				}()
+			}
+		}
+	}
+}
+
+// TestPlugin 以 test 事件运行一次插件，输出记入运行日志
+func (s *MoleService) TestPlugin(p EventPlugin) error {
+	if err := s.checkMutable(); err != nil {
+		return err
+	}
+	if err := validatePlugins([]EventPlugin{p}); err != nil {
+		return err
+	}
+	if err := s.runPlugin(s.ctx, p, pluginEventTest, map[string]string{"message": "这是一次测试运行"}); err != nil {
+		return fmt.Errorf("插件运行失败: %v", err)
+	}
+	return nil
+}
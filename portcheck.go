@@ -0,0 +1,73 @@
+package main
+
+import (
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const remotePortProbeTimeout = 3 * time.Second
+
+// RemotePortCheck 远程端口占用检测结果
+type RemotePortCheck struct {
+	Port    int    `json:"port"`
+	State   string `json:"state"` // free: 无人监听 / taken: 已被占用 / own: 本机隧道正在使用 / unknown: 无法判断
+	Message string `json:"message"`
+}
+
+// CheckRemotePort 保存规则前探测 serverAddr:remotePort 是否已被占用
+// 共享 frps 上端口先到先得，被别人占用时 frpc 只会在日志里报 "port already used"，这里提前提醒
+// addr 使用前端当前填写的服务器地址 (可能尚未保存)，ruleID 用于排除本机正在运行的同一条规则
+func (s *MoleService) CheckRemotePort(addr, proxyType string, port int, ruleID string) RemotePortCheck {
+	res := RemotePortCheck{Port: port}
+
+	addr = strings.TrimSpace(addr)
+	if addr == "" || port <= 0 || port > 65535 {
+		res.State = "unknown"
+		res.Message = "服务器地址或端口无效"
+		return res
+	}
+
+	// 隧道运行中时，这条规则自己就占着这个端口
+	if st := s.status(); st.IsRunning && st.Config != nil && st.Config.Server.Addr == addr {
+		for _, p := range st.Config.Proxies {
+			if p.ID == ruleID && p.Enabled && p.RemotePort == port {
+				res.State = "own"
+				res.Message = fmt.Sprintf("端口 %d 正由本机隧道使用", port)
+				return res
+			}
+		}
+	}
+
+	// UDP 没有握手，探测不到是否有人监听
+	if proxyType != "tcp" {
+		res.State = "unknown"
+		res.Message = "UDP 端口无法远程探测"
+		return res
+	}
+
+	conn, err := net.DialTimeout("tcp", net.JoinHostPort(addr, strconv.Itoa(port)), remotePortProbeTimeout)
+	if err == nil {
+		_ = conn.Close()
+		res.State = "taken"
+		res.Message = fmt.Sprintf("远程端口 %d 已有服务在监听，可能已被其他用户占用", port)
+		return res
+	}
+
+	// 连接被拒绝说明服务器可达但端口空闲；超时则可能是服务器防火墙拦截，无法判断
+	if isConnRefused(err) {
+		res.State = "free"
+		res.Message = fmt.Sprintf("远程端口 %d 当前空闲", port)
+		return res
+	}
+	res.State = "unknown"
+	res.Message = fmt.Sprintf("无法判断远程端口 %d 是否可用: %v", port, err)
+	return res
+}
+
+func isConnRefused(err error) bool {
+	msg := strings.ToLower(err.Error())
+	return strings.Contains(msg, "refused")
+}
@@ -0,0 +1,535 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// 路由器直连端口映射 (UPnP IGD / NAT-PMP)
+// 家用宽带有公网 IP 且路由器支持时，直接在路由器上开端口，不需要 frps 服务器
+
+const (
+	portMapLifetime = 2 * time.Hour // 映射租期，到期前一半时间自动续约
+	natpmpPort      = 5351
+	ssdpAddr        = "239.255.255.250:1900"
+)
+
+// PortMapping 一条路由器端口映射
+type PortMapping struct {
+	RuleID       string `json:"ruleID"`
+	Method       string `json:"method"` // upnp / natpmp
+	Protocol     string `json:"protocol"`
+	InternalIP   string `json:"internalIP"`
+	InternalPort int    `json:"internalPort"`
+	ExternalIP   string `json:"externalIP"`
+	ExternalPort int    `json:"externalPort"`
+	Endpoint     string `json:"endpoint"` // 公网访问地址 externalIP:externalPort
+	ExpiresAt    string `json:"expiresAt"`
+}
+
+// activePortMap 运行中的映射及其续约协程
+type activePortMap struct {
+	mapping PortMapping
+	mapper  portMapper
+	stop    chan struct{}
+}
+
+// portMapper UPnP 与 NAT-PMP 的共同抽象
+type portMapper interface {
+	name() string
+	externalIP() (string, error)
+	addMapping(protocol, internalIP string, internalPort, externalPort int, lifetime time.Duration) (int, error)
+	deleteMapping(protocol string, internalPort, externalPort int) error
+}
+
+// MapPortDirect 在路由器上为规则建立端口映射，返回公网访问地址
+// 外部端口优先使用规则的远程端口，未填写则与本地端口相同
+func (s *MoleService) MapPortDirect(ruleID string) (PortMapping, error) {
+	if err := s.checkMutable(); err != nil {
+		return PortMapping{}, err
+	}
+	s.countFeature("port_map")
+	p, ok := s.findProxy(ruleID)
+	if !ok {
+		return PortMapping{}, fmt.Errorf("未找到规则: %s", ruleID)
+	}
+	if p.ProxyType != "tcp" && p.ProxyType != "udp" {
+		return PortMapping{}, fmt.Errorf("路由器端口映射仅支持 TCP/UDP 规则")
+	}
+
+	s.portMapMu.Lock()
+	defer s.portMapMu.Unlock()
+	if m, ok := s.portMaps[ruleID]; ok {
+		return m.mapping, nil
+	}
+
+	internalIP, err := mappingInternalIP(envOr(p.LocalIP))
+	if err != nil {
+		return PortMapping{}, err
+	}
+	externalPort := p.RemotePort
+	if externalPort <= 0 {
+		externalPort = p.LocalPort
+	}
+	protocol := strings.ToUpper(p.ProxyType)
+
+	mapper, err := discoverPortMapper(internalIP)
+	if err != nil {
+		return PortMapping{}, err
+	}
+	// NAT-PMP 只能把端口映射到发起请求的主机本身
+	if mapper.name() == "natpmp" && !isLocalAddress(internalIP) {
+		return PortMapping{}, fmt.Errorf("路由器仅支持 NAT-PMP，无法映射到局域网其他主机 %s", internalIP)
+	}
+
+	mapped, err := mapper.addMapping(protocol, internalIP, p.LocalPort, externalPort, portMapLifetime)
+	if err != nil {
+		return PortMapping{}, fmt.Errorf("%s 映射失败: %v", mapper.name(), err)
+	}
+	extIP, err := mapper.externalIP()
+	if err != nil {
+		log.Printf("获取公网 IP 失败: %v", err)
+	}
+
+	m := &activePortMap{
+		mapping: PortMapping{
+			RuleID:       ruleID,
+			Method:       mapper.name(),
+			Protocol:     protocol,
+			InternalIP:   internalIP,
+			InternalPort: p.LocalPort,
+			ExternalIP:   extIP,
+			ExternalPort: mapped,
+			Endpoint:     net.JoinHostPort(extIP, strconv.Itoa(mapped)),
+			ExpiresAt:    time.Now().Add(portMapLifetime).Format(time.RFC3339),
+		},
+		mapper: mapper,
+		stop:   make(chan struct{}),
+	}
+	if isPrivateIPv4(extIP) {
+		s.emitLog("提示：路由器报告的外网地址 " + extIP + " 为内网地址，可能处于多层 NAT 之后，公网无法直接访问")
+	}
+
+	s.portMaps[ruleID] = m
+	go s.renewPortMap(m)
+
+	s.emitLog(fmt.Sprintf("已通过 %s 映射端口：%s -> %s:%d", mapper.name(), m.mapping.Endpoint, internalIP, p.LocalPort))
+	return m.mapping, nil
+}
+
+// UnmapPortDirect 删除规则对应的路由器端口映射
+func (s *MoleService) UnmapPortDirect(ruleID string) error {
+	if err := s.checkMutable(); err != nil {
+		return err
+	}
+	s.portMapMu.Lock()
+	m, ok := s.portMaps[ruleID]
+	delete(s.portMaps, ruleID)
+	s.portMapMu.Unlock()
+
+	if !ok {
+		return nil
+	}
+	close(m.stop)
+	return m.mapper.deleteMapping(m.mapping.Protocol, m.mapping.InternalPort, m.mapping.ExternalPort)
+}
+
+// GetPortMappings 当前生效的路由器端口映射
+func (s *MoleService) GetPortMappings() []PortMapping {
+	s.portMapMu.Lock()
+	defer s.portMapMu.Unlock()
+
+	list := make([]PortMapping, 0, len(s.portMaps))
+	for _, m := range s.portMaps {
+		list = append(list, m.mapping)
+	}
+	return list
+}
+
+// unmapAllPorts 退出前清理，避免路由器上残留映射
+func (s *MoleService) unmapAllPorts() {
+	s.portMapMu.Lock()
+	ids := make([]string, 0, len(s.portMaps))
+	for id := range s.portMaps {
+		ids = append(ids, id)
+	}
+	s.portMapMu.Unlock()
+
+	for _, id := range ids {
+		if err := s.UnmapPortDirect(id); err != nil {
+			log.Printf("删除端口映射失败: %v", err)
+		}
+	}
+}
+
+// renewPortMap 在租期过半时续约，直到映射被删除
+func (s *MoleService) renewPortMap(m *activePortMap) {
+	ticker := time.NewTicker(portMapLifetime / 2)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-m.stop:
+			return
+		case <-ticker.C:
+			mp := m.mapping
+			if _, err := m.mapper.addMapping(mp.Protocol, mp.InternalIP, mp.InternalPort, mp.ExternalPort, portMapLifetime); err != nil {
+				s.emitLog("端口映射续约失败：" + err.Error())
+				continue
+			}
+			s.portMapMu.Lock()
+			m.mapping.ExpiresAt = time.Now().Add(portMapLifetime).Format(time.RFC3339)
+			s.portMapMu.Unlock()
+		}
+	}
+}
+
+// discoverPortMapper 优先尝试 UPnP，失败再尝试 NAT-PMP
+func discoverPortMapper(localIP string) (portMapper, error) {
+	upnp, upnpErr := discoverUPnP(localIP)
+	if upnpErr == nil {
+		return upnp, nil
+	}
+
+	gw, err := defaultGateway(localIP)
+	if err != nil {
+		return nil, fmt.Errorf("未发现支持 UPnP 的路由器 (%v)，且无法确定网关: %v", upnpErr, err)
+	}
+	pmp := &natPMPMapper{gateway: gw}
+	if _, err := pmp.externalIP(); err != nil {
+		return nil, fmt.Errorf("路由器不支持 UPnP (%v) 与 NAT-PMP (%v)", upnpErr, err)
+	}
+	return pmp, nil
+}
+
+// mappingInternalIP 映射目标地址：回环地址替换为本机局域网地址
+func mappingInternalIP(localIP string) (string, error) {
+	ip := net.ParseIP(localIP)
+	if ip != nil && !ip.IsLoopback() && !ip.IsUnspecified() {
+		return ip.String(), nil
+	}
+	return outboundIP()
+}
+
+// outboundIP 本机访问外网时使用的局域网地址 (UDP 拨号不会真的发包)
+func outboundIP() (string, error) {
+	conn, err := net.Dial("udp4", "8.8.8.8:80")
+	if err != nil {
+		return "", fmt.Errorf("无法确定本机局域网地址: %v", err)
+	}
+	defer conn.Close()
+	return conn.LocalAddr().(*net.UDPAddr).IP.String(), nil
+}
+
+// defaultGateway Linux 读取路由表，其他平台按家用路由器惯例取本网段 .1
+func defaultGateway(localIP string) (net.IP, error) {
+	if f, err := os.Open("/proc/net/route"); err == nil {
+		defer f.Close()
+		scanner := bufio.NewScanner(f)
+		for scanner.Scan() {
+			fields := strings.Fields(scanner.Text())
+			// Destination 为 00000000 的是默认路由，Gateway 为小端序十六进制
+			if len(fields) > 2 && fields[1] == "00000000" {
+				raw, err := hex.DecodeString(fields[2])
+				if err == nil && len(raw) == 4 {
+					return net.IPv4(raw[3], raw[2], raw[1], raw[0]), nil
+				}
+			}
+		}
+	}
+
+	ip := net.ParseIP(localIP).To4()
+	if ip == nil {
+		return nil, fmt.Errorf("无效的本机地址: %s", localIP)
+	}
+	return net.IPv4(ip[0], ip[1], ip[2], 1), nil
+}
+
+// isLocalAddress 判断地址是否属于本机网卡
+func isLocalAddress(host string) bool {
+	ip := net.ParseIP(host)
+	return ip != nil && (ip.IsLoopback() || isLocalNonLoopback(host))
+}
+
+// isPrivateIPv4 判断是否为内网地址 (含运营商级 NAT 的 100.64.0.0/10)
+func isPrivateIPv4(s string) bool {
+	ip := net.ParseIP(s).To4()
+	if ip == nil {
+		return false
+	}
+	return ip.IsPrivate() || (ip[0] == 100 && ip[1]&0xC0 == 64)
+}
+
+// =====================NAT-PMP (RFC 6886) ===============================
+
+type natPMPMapper struct {
+	gateway net.IP
+}
+
+func (n *natPMPMapper) name() string { return "natpmp" }
+
+func (n *natPMPMapper) request(msg []byte, respLen int) ([]byte, error) {
+	conn, err := net.DialUDP("udp4", nil, &net.UDPAddr{IP: n.gateway, Port: natpmpPort})
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	buf := make([]byte, 16)
+	// 按协议建议从 250ms 起指数退避重发
+	timeout := 250 * time.Millisecond
+	for i := 0; i < 4; i++ {
+		if _, err := conn.Write(msg); err != nil {
+			return nil, err
+		}
+		_ = conn.SetReadDeadline(time.Now().Add(timeout))
+		nr, err := conn.Read(buf)
+		if err == nil {
+			if nr < respLen {
+				return nil, fmt.Errorf("NAT-PMP 响应长度错误")
+			}
+			if code := binary.BigEndian.Uint16(buf[2:4]); code != 0 {
+				return nil, fmt.Errorf("NAT-PMP 错误码 %d", code)
+			}
+			return buf[:nr], nil
+		}
+		timeout *= 2
+	}
+	return nil, fmt.Errorf("网关 %s 无 NAT-PMP 响应", n.gateway)
+}
+
+func (n *natPMPMapper) externalIP() (string, error) {
+	resp, err := n.request([]byte{0, 0}, 12)
+	if err != nil {
+		return "", err
+	}
+	return net.IP(resp[8:12]).String(), nil
+}
+
+func (n *natPMPMapper) addMapping(protocol, internalIP string, internalPort, externalPort int, lifetime time.Duration) (int, error) {
+	msg := make([]byte, 12)
+	msg[1] = natPMPOpcode(protocol)
+	binary.BigEndian.PutUint16(msg[4:6], uint16(internalPort))
+	binary.BigEndian.PutUint16(msg[6:8], uint16(externalPort))
+	binary.BigEndian.PutUint32(msg[8:12], uint32(lifetime.Seconds()))
+
+	resp, err := n.request(msg, 16)
+	if err != nil {
+		return 0, err
+	}
+	return int(binary.BigEndian.Uint16(resp[10:12])), nil
+}
+
+func (n *natPMPMapper) deleteMapping(protocol string, internalPort, externalPort int) error {
+	// 租期为 0 即删除
+	_, err := n.addMapping(protocol, "", internalPort, 0, 0)
+	return err
+}
+
+func natPMPOpcode(protocol string) byte {
+	if protocol == "UDP" {
+		return 1
+	}
+	return 2
+}
+
+// =====================UPnP IGD ===============================
+
+type upnpMapper struct {
+	controlURL  string
+	serviceType string
+}
+
+func (u *upnpMapper) name() string { return "upnp" }
+
+// discoverUPnP 通过 SSDP 组播发现网关，解析设备描述找到 WAN 连接服务的控制地址
+func discoverUPnP(localIP string) (*upnpMapper, error) {
+	conn, err := net.ListenUDP("udp4", &net.UDPAddr{IP: net.ParseIP(localIP)})
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	dst, _ := net.ResolveUDPAddr("udp4", ssdpAddr)
+	search := "M-SEARCH * HTTP/1.1\r\n" +
+		"HOST: " + ssdpAddr + "\r\n" +
+		"MAN: \"ssdp:discover\"\r\n" +
+		"MX: 2\r\n" +
+		"ST: urn:schemas-upnp-org:device:InternetGatewayDevice:1\r\n\r\n"
+	if _, err := conn.WriteTo([]byte(search), dst); err != nil {
+		return nil, err
+	}
+
+	_ = conn.SetReadDeadline(time.Now().Add(3 * time.Second))
+	buf := make([]byte, 2048)
+	for {
+		nr, _, err := conn.ReadFrom(buf)
+		if err != nil {
+			return nil, fmt.Errorf("未发现 UPnP 网关")
+		}
+		resp, err := http.ReadResponse(bufio.NewReader(bytes.NewReader(buf[:nr])), nil)
+		if err != nil {
+			continue
+		}
+		location := resp.Header.Get("Location")
+		resp.Body.Close()
+		if location == "" {
+			continue
+		}
+		if m, err := parseIGDDescription(location); err == nil {
+			return m, nil
+		}
+	}
+}
+
+type upnpService struct {
+	ServiceType string `xml:"serviceType"`
+	ControlURL  string `xml:"controlURL"`
+}
+
+type upnpDevice struct {
+	Services []upnpService `xml:"serviceList>service"`
+	Devices  []upnpDevice  `xml:"deviceList>device"`
+}
+
+type upnpRoot struct {
+	URLBase string     `xml:"URLBase"`
+	Device  upnpDevice `xml:"device"`
+}
+
+func parseIGDDescription(location string) (*upnpMapper, error) {
+	client := &http.Client{Timeout: 3 * time.Second}
+	resp, err := client.Get(location)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	var root upnpRoot
+	if err := xml.NewDecoder(resp.Body).Decode(&root); err != nil {
+		return nil, err
+	}
+
+	svc := findWANService(root.Device)
+	if svc == nil {
+		return nil, fmt.Errorf("网关未提供 WAN 连接服务")
+	}
+
+	base := location
+	if root.URLBase != "" {
+		base = root.URLBase
+	}
+	baseURL, err := url.Parse(base)
+	if err != nil {
+		return nil, err
+	}
+	ctrl, err := baseURL.Parse(svc.ControlURL)
+	if err != nil {
+		return nil, err
+	}
+	return &upnpMapper{controlURL: ctrl.String(), serviceType: svc.ServiceType}, nil
+}
+
+// findWANService 在设备树中递归查找 WANIPConnection / WANPPPConnection
+func findWANService(d upnpDevice) *upnpService {
+	for i, s := range d.Services {
+		if strings.Contains(s.ServiceType, "WANIPConnection") || strings.Contains(s.ServiceType, "WANPPPConnection") {
+			return &d.Services[i]
+		}
+	}
+	for _, child := range d.Devices {
+		if s := findWANService(child); s != nil {
+			return s
+		}
+	}
+	return nil
+}
+
+func (u *upnpMapper) soap(action, args string) ([]byte, error) {
+	body := `<?xml version="1.0"?>` +
+		`<s:Envelope xmlns:s="http://schemas.xmlsoap.org/soap/envelope/" s:encodingStyle="http://schemas.xmlsoap.org/soap/encoding/">` +
+		`<s:Body><u:` + action + ` xmlns:u="` + u.serviceType + `">` + args + `</u:` + action + `></s:Body></s:Envelope>`
+
+	req, err := http.NewRequest(http.MethodPost, u.controlURL, strings.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", `text/xml; charset="utf-8"`)
+	req.Header.Set("SOAPAction", `"`+u.serviceType+"#"+action+`"`)
+
+	client := &http.Client{Timeout: 5 * time.Second}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	data, _ := io.ReadAll(resp.Body)
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("UPnP %s 失败: %s %s", action, resp.Status, upnpErrorDesc(data))
+	}
+	return data, nil
+}
+
+func (u *upnpMapper) externalIP() (string, error) {
+	data, err := u.soap("GetExternalIPAddress", "")
+	if err != nil {
+		return "", err
+	}
+	var r struct {
+		IP string `xml:"Body>GetExternalIPAddressResponse>NewExternalIPAddress"`
+	}
+	if err := xml.Unmarshal(data, &r); err != nil {
+		return "", err
+	}
+	if r.IP == "" {
+		return "", errors.New("路由器未返回外网地址")
+	}
+	return r.IP, nil
+}
+
+func (u *upnpMapper) addMapping(protocol, internalIP string, internalPort, externalPort int, lifetime time.Duration) (int, error) {
+	args := fmt.Sprintf("<NewRemoteHost></NewRemoteHost>"+
+		"<NewExternalPort>%d</NewExternalPort>"+
+		"<NewProtocol>%s</NewProtocol>"+
+		"<NewInternalPort>%d</NewInternalPort>"+
+		"<NewInternalClient>%s</NewInternalClient>"+
+		"<NewEnabled>1</NewEnabled>"+
+		"<NewPortMappingDescription>mole</NewPortMappingDescription>"+
+		"<NewLeaseDuration>%d</NewLeaseDuration>",
+		externalPort, protocol, internalPort, internalIP, int(lifetime.Seconds()))
+
+	if _, err := u.soap("AddPortMapping", args); err != nil {
+		return 0, err
+	}
+	return externalPort, nil
+}
+
+func (u *upnpMapper) deleteMapping(protocol string, internalPort, externalPort int) error {
+	args := fmt.Sprintf("<NewRemoteHost></NewRemoteHost><NewExternalPort>%d</NewExternalPort><NewProtocol>%s</NewProtocol>",
+		externalPort, protocol)
+	_, err := u.soap("DeletePortMapping", args)
+	return err
+}
+
+func upnpErrorDesc(data []byte) string {
+	var r struct {
+		Desc string `xml:"Body>Fault>detail>UPnPError>errorDescription"`
+	}
+	_ = xml.Unmarshal(data, &r)
+	return r.Desc
+}
@@ -0,0 +1,125 @@
+package main
+
+import (
+	"fmt"
+	"net"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// 局域网端口扫描：帮用户找出摄像头 / NAS / Web 应用实际在哪个端口提供服务，
+// 只允许扫描本机和内网地址，避免被当作对外扫描工具
+
+const (
+	portScanTimeout     = 500 * time.Millisecond
+	portScanConcurrency = 200
+	portScanMaxPorts    = 10000
+)
+
+// OpenPort 扫描到的开放端口
+type OpenPort struct {
+	Port   int    `json:"port"`
+	Banner string `json:"banner,omitempty"` // 服务主动发送的首行 (如 SSH 版本)，没有则为空
+}
+
+// ScanLocalPorts 扫描内网主机的 TCP 端口，portRange 形如 "1-1024" 或 "22,80,443,8000-8100"
+func (s *MoleService) ScanLocalPorts(host, portRange string) ([]OpenPort, error) {
+	s.countFeature("port_scan")
+	host = strings.TrimSpace(host)
+	if host == "" {
+		host = "127.0.0.1"
+	}
+	ip := net.ParseIP(host)
+	if ip == nil || !(ip.IsLoopback() || isPrivateIPv4(host) || isLocalAddress(host)) {
+		return nil, fmt.Errorf("只能扫描本机或局域网地址: %s", host)
+	}
+
+	ports, err := parsePortRange(portRange)
+	if err != nil {
+		return nil, err
+	}
+
+	var (
+		mu     sync.Mutex
+		result []OpenPort
+		wg     sync.WaitGroup
+		sem    = make(chan struct{}, portScanConcurrency)
+	)
+	for _, port := range ports {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(port int) {
+			defer wg.Done()
+			defer func() { <-sem }()
+
+			if open, ok := probePort(host, port); ok {
+				mu.Lock()
+				result = append(result, open)
+				mu.Unlock()
+			}
+		}(port)
+	}
+	wg.Wait()
+
+	sort.Slice(result, func(i, j int) bool { return result[i].Port < result[j].Port })
+	return result, nil
+}
+
+func probePort(host string, port int) (OpenPort, bool) {
+	conn, err := net.DialTimeout("tcp", net.JoinHostPort(host, strconv.Itoa(port)), portScanTimeout)
+	if err != nil {
+		return OpenPort{}, false
+	}
+	defer conn.Close()
+
+	// 部分服务 (SSH、FTP、SMTP) 连上即发送欢迎语，顺手读一行帮助辨认
+	open := OpenPort{Port: port}
+	_ = conn.SetReadDeadline(time.Now().Add(portScanTimeout))
+	buf := make([]byte, 128)
+	if n, _ := conn.Read(buf); n > 0 {
+		line, _, _ := strings.Cut(string(buf[:n]), "\n")
+		open.Banner = strings.TrimSpace(line)
+	}
+	return open, true
+}
+
+// parsePortRange 解析端口列表，去重后按升序返回
+func parsePortRange(spec string) ([]int, error) {
+	spec = strings.TrimSpace(spec)
+	if spec == "" {
+		spec = "1-1024"
+	}
+
+	seen := make(map[int]bool)
+	for _, part := range strings.Split(spec, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		lo, hi := part, part
+		if a, b, ok := strings.Cut(part, "-"); ok {
+			lo, hi = strings.TrimSpace(a), strings.TrimSpace(b)
+		}
+		start, err1 := strconv.Atoi(lo)
+		end, err2 := strconv.Atoi(hi)
+		if err1 != nil || err2 != nil || start < 1 || end > 65535 || start > end {
+			return nil, fmt.Errorf("无效的端口范围: %s", part)
+		}
+		for p := start; p <= end; p++ {
+			seen[p] = true
+		}
+		if len(seen) > portScanMaxPorts {
+			return nil, fmt.Errorf("一次最多扫描 %d 个端口", portScanMaxPorts)
+		}
+	}
+
+	ports := make([]int, 0, len(seen))
+	for p := range seen {
+		ports = append(ports, p)
+	}
+	sort.Ints(ports)
+	return ports, nil
+}
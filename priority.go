@@ -0,0 +1,43 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"runtime"
+)
+
+// frpc 进程优先级：与游戏等对延迟敏感的程序共用一台机器时，让隧道流量不抢占 CPU
+// 启动后调整 frpc 进程的优先级与 CPU 亲和性，具体实现见 priority_*.go
+
+// Windows 的亲和性掩码为一个机器字长，超出的 CPU 无法指定
+const maxAffinityCPU = 64
+
+func validateFrpcPriority(prefs Preferences) error {
+	seen := make(map[int]bool, len(prefs.FrpcCPUAffinity))
+	for _, cpu := range prefs.FrpcCPUAffinity {
+		if cpu < 0 || cpu >= runtime.NumCPU() || cpu >= maxAffinityCPU {
+			return fmt.Errorf("无效的 CPU 编号: %d (本机共 %d 个逻辑 CPU，从 0 开始)", cpu, runtime.NumCPU())
+		}
+		if seen[cpu] {
+			return fmt.Errorf("CPU 编号重复: %d", cpu)
+		}
+		seen[cpu] = true
+	}
+	return nil
+}
+
+// applyFrpcPriority 进程已在运行，调整失败只记录，不影响隧道
+func (s *MoleService) applyFrpcPriority(pid int, prefs Preferences) {
+	if prefs.FrpcLowPriority {
+		if err := setLowPriority(pid); err != nil {
+			log.Printf("降低 frpc 优先级失败: %v", err)
+			s.emitLog("降低 frpc 优先级失败：", err.Error())
+		}
+	}
+	if len(prefs.FrpcCPUAffinity) > 0 {
+		if err := setCPUAffinity(pid, prefs.FrpcCPUAffinity); err != nil {
+			log.Printf("设置 frpc CPU 亲和性失败: %v", err)
+			s.emitLog("设置 frpc CPU 亲和性失败：", err.Error())
+		}
+	}
+}
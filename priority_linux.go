@@ -0,0 +1,51 @@
+//go:build linux
+
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strconv"
+
+	"golang.org/x/sys/unix"
+)
+
+// lowPriorityNice 对应 Windows 的“低于正常”
+const lowPriorityNice = 10
+
+// frpcThreads Linux 下优先级与亲和性按线程生效，frpc 启动时已创建了多个线程，需要逐个设置
+func frpcThreads(pid int) []int {
+	entries, err := os.ReadDir(filepath.Join("/proc", strconv.Itoa(pid), "task"))
+	if err != nil {
+		return []int{pid}
+	}
+	tids := make([]int, 0, len(entries))
+	for _, e := range entries {
+		if tid, err := strconv.Atoi(e.Name()); err == nil {
+			tids = append(tids, tid)
+		}
+	}
+	return tids
+}
+
+func setLowPriority(pid int) error {
+	for _, tid := range frpcThreads(pid) {
+		if err := unix.Setpriority(unix.PRIO_PROCESS, tid, lowPriorityNice); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func setCPUAffinity(pid int, cpus []int) error {
+	var set unix.CPUSet
+	for _, cpu := range cpus {
+		set.Set(cpu)
+	}
+	for _, tid := range frpcThreads(pid) {
+		if err := unix.SchedSetaffinity(tid, &set); err != nil {
+			return err
+		}
+	}
+	return nil
+}
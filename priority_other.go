@@ -0,0 +1,21 @@
+//go:build !linux && !windows
+
+package main
+
+import (
+	"fmt"
+
+	"golang.org/x/sys/unix"
+)
+
+// lowPriorityNice 对应 Windows 的“低于正常”
+const lowPriorityNice = 10
+
+func setLowPriority(pid int) error {
+	return unix.Setpriority(unix.PRIO_PROCESS, pid, lowPriorityNice)
+}
+
+// setCPUAffinity macOS 不支持把进程绑定到指定 CPU
+func setCPUAffinity(pid int, cpus []int) error {
+	return fmt.Errorf("当前系统不支持设置 CPU 亲和性")
+}
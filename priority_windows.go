@@ -0,0 +1,37 @@
+//go:build windows
+
+package main
+
+import "golang.org/x/sys/windows"
+
+var procSetProcessAffinityMask = windows.NewLazySystemDLL("kernel32.dll").NewProc("SetProcessAffinityMask")
+
+func openForPriority(pid int) (windows.Handle, error) {
+	return windows.OpenProcess(windows.PROCESS_SET_INFORMATION|windows.PROCESS_QUERY_LIMITED_INFORMATION, false, uint32(pid))
+}
+
+func setLowPriority(pid int) error {
+	h, err := openForPriority(pid)
+	if err != nil {
+		return err
+	}
+	defer windows.CloseHandle(h)
+	return windows.SetPriorityClass(h, windows.BELOW_NORMAL_PRIORITY_CLASS)
+}
+
+func setCPUAffinity(pid int, cpus []int) error {
+	h, err := openForPriority(pid)
+	if err != nil {
+		return err
+	}
+	defer windows.CloseHandle(h)
+
+	var mask uintptr
+	for _, cpu := range cpus {
+		mask |= 1 << uint(cpu)
+	}
+	if r, _, err := procSetProcessAffinityMask.Call(uintptr(h), mask); r == 0 {
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,154 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// 命名配置：把当前的服务器与规则保存为一个名字 (如 "Office VPS")，之后可从托盘一键切换并连接
+// 只保存与服务器相关的部分，偏好设置、通知等本机配置在切换时保持不变
+
+const maxProfiles = 10
+
+// ServerProfile 一份命名配置
+type ServerProfile struct {
+	Name        string      `toml:"name" json:"name"`
+	Addr        string      `toml:"addr" json:"addr"`
+	Port        int         `toml:"port" json:"port"`
+	Token       string      `toml:"token" json:"token"`
+	TokenSource string      `toml:"token_source,omitempty" json:"tokenSource"`
+	Remark      string      `toml:"remark" json:"remark"`
+	Transport   string      `toml:"transport" json:"transport"`
+	ExtraArgs   []string    `toml:"extra_args,omitempty" json:"extraArgs"`
+	User        string      `toml:"user,omitempty" json:"user"`
+	NamePrefix  string      `toml:"name_prefix,omitempty" json:"namePrefix"`
+	Proxies     []ProxyRule `toml:"proxies" json:"proxies"`
+
+	// 证书校验与 SSH 参数同样只对这台服务器有效，切换时一并替换，避免旧服务器的指纹拒绝新服务器
+	TLSServerName             string    `toml:"tls_server_name,omitempty" json:"tlsServerName"`
+	TLSDisableCustomFirstByte *bool     `toml:"tls_disable_custom_first_byte,omitempty" json:"tlsDisableCustomFirstByte"`
+	TLSTrustedCAFile          string    `toml:"tls_trusted_ca_file,omitempty" json:"tlsTrustedCAFile"`
+	TLSPinSHA256              string    `toml:"tls_pin_sha256,omitempty" json:"tlsPinSHA256"`
+	SSH                       SSHConfig `toml:"ssh" json:"ssh"`
+}
+
+func profileIndex(list []ServerProfile, name string) int {
+	for i, p := range list {
+		if p.Name == name {
+			return i
+		}
+	}
+	return -1
+}
+
+// SaveProfile 把当前服务器与规则保存为命名配置，同名时覆盖
+func (s *MoleService) SaveProfile(name string) error {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return fmt.Errorf("配置名称不能为空")
+	}
+	cfg := s.status().Config
+	if cfg == nil {
+		return fmt.Errorf("未发现有效配置")
+	}
+	newCfg := *cfg
+	p := ServerProfile{
+		Name:        name,
+		Addr:        cfg.Server.Addr,
+		Port:        cfg.Server.Port,
+		Token:       cfg.Server.Token,
+		TokenSource: cfg.Server.TokenSource,
+		Remark:      cfg.Server.Remark,
+		Transport:   cfg.Server.Transport,
+		ExtraArgs:   append([]string(nil), cfg.Server.ExtraArgs...),
+		User:        cfg.Server.User,
+		NamePrefix:  cfg.Server.NamePrefix,
+		Proxies:     append([]ProxyRule(nil), cfg.Proxies...),
+
+		TLSServerName:             cfg.Server.TLSServerName,
+		TLSDisableCustomFirstByte: cloneBool(cfg.Server.TLSDisableCustomFirstByte),
+		TLSTrustedCAFile:          cfg.Server.TLSTrustedCAFile,
+		TLSPinSHA256:              cfg.Server.TLSPinSHA256,
+		SSH:                       cfg.SSH,
+	}
+	newCfg.Profiles = append([]ServerProfile(nil), cfg.Profiles...)
+	if i := profileIndex(newCfg.Profiles, name); i >= 0 {
+		newCfg.Profiles[i] = p
+	} else {
+		if len(newCfg.Profiles) >= maxProfiles {
+			return fmt.Errorf("最多保存 %d 个命名配置", maxProfiles)
+		}
+		newCfg.Profiles = append(newCfg.Profiles, p)
+	}
+	return s.saveUserConfig(newCfg)
+}
+
+// DeleteProfile 删除命名配置，不影响当前正在使用的服务器与规则
+func (s *MoleService) DeleteProfile(name string) error {
+	cfg := s.status().Config
+	if cfg == nil {
+		return fmt.Errorf("未发现有效配置")
+	}
+	i := profileIndex(cfg.Profiles, name)
+	if i < 0 {
+		return fmt.Errorf("命名配置不存在: %s", name)
+	}
+	newCfg := *cfg
+	newCfg.Profiles = append(append([]ServerProfile(nil), cfg.Profiles[:i]...), cfg.Profiles[i+1:]...)
+	return s.saveUserConfig(newCfg)
+}
+
+// SwitchProfile 切换到命名配置，connect 为 true 时立即 (重新) 连接
+func (s *MoleService) SwitchProfile(name string, connect bool) error {
+	cfg := s.status().Config
+	if cfg == nil {
+		return fmt.Errorf("未发现有效配置")
+	}
+	i := profileIndex(cfg.Profiles, name)
+	if i < 0 {
+		return fmt.Errorf("命名配置不存在: %s", name)
+	}
+	p := cfg.Profiles[i]
+
+	newCfg := *cfg
+	newCfg.Server.Addr = p.Addr
+	newCfg.Server.Port = p.Port
+	newCfg.Server.Token = p.Token
+	newCfg.Server.TokenSource = p.TokenSource
+	newCfg.Server.Remark = p.Remark
+	newCfg.Server.Transport = p.Transport
+	newCfg.Server.ExtraArgs = append([]string(nil), p.ExtraArgs...)
+	newCfg.Server.User = p.User
+	newCfg.Server.NamePrefix = p.NamePrefix
+	newCfg.Proxies = append([]ProxyRule(nil), p.Proxies...)
+	newCfg.Server.TLSServerName = p.TLSServerName
+	newCfg.Server.TLSDisableCustomFirstByte = cloneBool(p.TLSDisableCustomFirstByte)
+	newCfg.Server.TLSTrustedCAFile = p.TLSTrustedCAFile
+	newCfg.Server.TLSPinSHA256 = p.TLSPinSHA256
+	newCfg.SSH = p.SSH
+	if err := s.saveUserConfig(newCfg); err != nil {
+		return err
+	}
+	s.emitLog(fmt.Sprintf("已切换到配置 \"%s\"", name))
+	if !connect {
+		return nil
+	}
+	return s.restartTunnel()
+}
+
+// restartTunnel 以最新配置重新连接，未运行时直接连接
+func (s *MoleService) restartTunnel() error {
+	if s.remote != nil {
+		return s.remote.restart()
+	}
+	s.reconnect.reset()
+	return s.restartFrp()
+}
+
+func cloneBool(b *bool) *bool {
+	if b == nil {
+		return nil
+	}
+	v := *b
+	return &v
+}
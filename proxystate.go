@@ -0,0 +1,175 @@
+package main
+
+import (
+	"net/http"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+)
+
+// 逐条规则的运行状态：从 frpc 日志中解析代理注册 / 出错 / 移除，
+// 以 proxy-state 事件按规则 ID 推送，前端无需再自己比对日志文本
+
+const (
+	proxyStatePending = "pending" // 已下发，等待 frps 响应
+	proxyStateRunning = "running"
+	proxyStateError   = "error"
+	proxyStateRemoved = "removed" // 热重载时被移除
+	proxyStateStopped = "stopped" // 隧道已停止
+	proxyStatePaused  = "paused"  // 本地服务离线，被自动暂停
+	proxyStateWaiting = "waiting" // 按启动顺序等待中，见 startorder.go
+)
+
+var (
+	// [I] [client/control.go:168] [d3a1b2c4] [web] start proxy success
+	reProxySuccess = regexp.MustCompile(`\[([^\[\]]+)\] start proxy success`)
+	// [W] [client/control.go:170] [d3a1b2c4] [web] start error: port already used
+	reProxyError = regexp.MustCompile(`\[([^\[\]]+)\] start error: (.*)$`)
+	// [I] [proxy/proxy_manager.go:150] [d3a1b2c4] proxy removed: [web ssh]
+	reProxyRemoved = regexp.MustCompile(`proxy removed: \[([^\]]*)\]`)
+	// [I] [proxy/proxy_manager.go:160] [d3a1b2c4] proxy added: [web ssh]
+	reProxyAdded = regexp.MustCompile(`proxy added: \[([^\]]*)\]`)
+)
+
+// ProxyState 单条规则的实时状态
+type ProxyState struct {
+	RuleID  string    `json:"ruleID"`
+	Name    string    `json:"name"`
+	State   string    `json:"state"`
+	Message string    `json:"message,omitempty"`
+	Time    time.Time `json:"time"`
+}
+
+type proxyStateTracker struct {
+	mu     sync.Mutex
+	byName map[string]string     // 代理名 (原文与模板展开后的名称) -> 规则 ID，启动隧道时快照
+	states map[string]ProxyState // 规则 ID -> 状态，Name 为规则名
+}
+
+// GetProxyStates 返回所有规则的当前状态
+func (s *MoleService) GetProxyStates() ([]ProxyState, error) {
+	if s.remote != nil {
+		var list []ProxyState
+		err := s.remote.call(http.MethodGet, "/api/proxy-states", &list)
+		return list, err
+	}
+	return s.proxyStates.list(), nil
+}
+
+func (t *proxyStateTracker) list() []ProxyState {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	list := make([]ProxyState, 0, len(t.states))
+	for _, st := range t.states {
+		list = append(list, st)
+	}
+	return list
+}
+
+func (t *proxyStateTracker) get(ruleID string) (ProxyState, bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	st, ok := t.states[ruleID]
+	return st, ok
+}
+
+// resetProxyStates 启动隧道时调用 (需持有 s.mu)：记录名称映射，启用的规则置为 pending
+func (s *MoleService) resetProxyStates() {
+	t := &s.proxyStates
+	t.mu.Lock()
+	t.byName = make(map[string]string)
+	t.states = make(map[string]ProxyState)
+	var pending []ProxyState
+	for _, p := range s.config.Proxies {
+		if !p.Enabled {
+			continue
+		}
+		t.byName[p.Name] = p.ID
+		// frpc 日志中是展开后、带前缀 (及 frp 用户名) 的名称
+		for _, name := range proxyRuntimeNames(s.config, p) {
+			t.byName[name] = p.ID
+		}
+		st := ProxyState{RuleID: p.ID, Name: p.Name, State: proxyStatePending, Time: time.Now()}
+		t.states[p.ID] = st
+		pending = append(pending, st)
+	}
+	t.mu.Unlock()
+
+	for _, st := range pending {
+		s.events.Emit("proxy-state", st)
+	}
+}
+
+// setProxyState 按代理名更新状态并推送事件，未知名称忽略
+func (s *MoleService) setProxyState(name, state, message string) {
+	t := &s.proxyStates
+	t.mu.Lock()
+	id, ok := t.byName[name]
+	if !ok {
+		t.mu.Unlock()
+		return
+	}
+	st, ok := t.update(id, state, message)
+	t.mu.Unlock()
+	if !ok {
+		return
+	}
+
+	s.events.Emit("proxy-state", st)
+	s.checkSelfTestProxies()
+}
+
+// update 更新一条规则的状态 (需持有 t.mu)，不需要变化时返回 false
+// 一条规则可能对应多个 frpc 代理名 (名称前缀、端口范围等)，显示名称固定用规则名，不随日志中的代理名变化
+func (t *proxyStateTracker) update(id, state, message string) (ProxyState, bool) {
+	prev := t.states[id]
+	// 自动暂停引起的移除日志晚于暂停状态到达，不覆盖；等待启动的规则同理
+	if state == proxyStateRemoved && (prev.State == proxyStatePaused || prev.State == proxyStateWaiting) {
+		return ProxyState{}, false
+	}
+	st := ProxyState{RuleID: id, Name: prev.Name, State: state, Message: message, Time: time.Now()}
+	t.states[id] = st
+	return st, true
+}
+
+// stopProxyStates 隧道停止时把所有规则置为 stopped
+func (s *MoleService) stopProxyStates() {
+	t := &s.proxyStates
+	t.mu.Lock()
+	var changed []ProxyState
+	for id := range t.states {
+		if st, ok := t.update(id, proxyStateStopped, ""); ok {
+			changed = append(changed, st)
+		}
+	}
+	t.mu.Unlock()
+
+	for _, st := range changed {
+		s.events.Emit("proxy-state", st)
+	}
+	s.checkSelfTestProxies()
+}
+
+// trackProxyLog 解析一行 frpc 日志，命中代理状态变化时更新
+func (s *MoleService) trackProxyLog(line string) {
+	if m := reProxySuccess.FindStringSubmatch(line); m != nil {
+		s.setProxyState(m[1], proxyStateRunning, "")
+		return
+	}
+	if m := reProxyError.FindStringSubmatch(line); m != nil {
+		s.setProxyState(m[1], proxyStateError, strings.TrimSpace(m[2]))
+		return
+	}
+	if m := reProxyRemoved.FindStringSubmatch(line); m != nil {
+		for _, name := range strings.Fields(m[1]) {
+			s.setProxyState(name, proxyStateRemoved, "")
+		}
+		return
+	}
+	if m := reProxyAdded.FindStringSubmatch(line); m != nil {
+		for _, name := range strings.Fields(m[1]) {
+			s.setProxyState(name, proxyStatePending, "")
+		}
+	}
+}
@@ -0,0 +1,54 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	_ "image/jpeg"
+	_ "image/png"
+
+	"github.com/makiuchi-d/gozxing"
+	"github.com/makiuchi-d/gozxing/qrcode"
+)
+
+// 二维码导入：手机上保存的分享码二维码截图、服务商提供的配置二维码，解码后按文本交给导入流程
+// 内容按文本识别格式：分享码 (MOLE1:) 或 JSON 为 Mole 配置片段，否则当作 frpc.toml / frpc.ini
+
+const maxQRImageSize = 10 << 20
+
+// ImportFromQRImage 解码 PNG / JPEG 图片中的二维码并生成导入预览，确认后才会写入配置
+func (s *MoleService) ImportFromQRImage(data []byte) (ImportPreview, error) {
+	if err := s.checkMutable(); err != nil {
+		return ImportPreview{}, err
+	}
+	if len(data) == 0 {
+		return ImportPreview{}, fmt.Errorf("图片内容为空")
+	}
+	if len(data) > maxQRImageSize {
+		return ImportPreview{}, fmt.Errorf("图片过大，最多 %d MB", maxQRImageSize>>20)
+	}
+	text, err := decodeQRImage(data)
+	if err != nil {
+		return ImportPreview{}, err
+	}
+	s.countFeature("import_qr")
+	return s.previewImport("二维码", detectImportFormat("", []byte(text)), []byte(text))
+}
+
+func decodeQRImage(data []byte) (string, error) {
+	img, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return "", fmt.Errorf("无法识别的图片格式: %v", err)
+	}
+	bmp, err := gozxing.NewBinaryBitmapFromImage(img)
+	if err != nil {
+		return "", fmt.Errorf("读取图片失败: %v", err)
+	}
+	// 截图中二维码往往只占一小部分，开启 TRY_HARDER 提高识别率
+	hints := map[gozxing.DecodeHintType]any{gozxing.DecodeHintType_TRY_HARDER: true}
+	res, err := qrcode.NewQRCodeReader().Decode(bmp, hints)
+	if err != nil {
+		return "", fmt.Errorf("图片中未找到二维码")
+	}
+	return res.GetText(), nil
+}
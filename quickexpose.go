@@ -0,0 +1,256 @@
+package main
+
+import (
+	"fmt"
+	"net"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// 快速分享：临时把本机一个端口暴露出去 (如给同事看一眼开发中的网站)，不写入配置文件
+// 规则名与远程端口自动分配，通过热重载加入运行中的 frpc，断开连接时全部撤销
+// TCP/UDP 在服务器的高位端口中随机挑选；HTTP 需要先设置一个泛解析到服务器的域名，以随机子域名访问
+
+const (
+	maxQuickExposes      = 5
+	quickExposePortMin   = 30000
+	quickExposePortMax   = 39999
+	quickExposePortTries = 5
+	quickExposeIDPrefix  = "quick-"
+)
+
+// QuickExpose 一条快速分享
+type QuickExpose struct {
+	ID        string    `json:"id"`
+	Name      string    `json:"name"`
+	ProxyType string    `json:"proxyType"`
+	LocalPort int       `json:"localPort"`
+	Endpoint  string    `json:"endpoint"` // 对外访问地址
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+type quickExposeSet struct {
+	mu    sync.Mutex
+	items []QuickExpose
+	rules []ProxyRule // 与 items 一一对应，写入 frpc.toml 的临时规则
+}
+
+func (q *quickExposeSet) proxies() []ProxyRule {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return append([]ProxyRule(nil), q.rules...)
+}
+
+func (q *quickExposeSet) list() []QuickExpose {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return append([]QuickExpose{}, q.items...)
+}
+
+func (q *quickExposeSet) add(item QuickExpose, rule ProxyRule) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if len(q.items) >= maxQuickExposes {
+		return fmt.Errorf("最多同时快速分享 %d 个端口", maxQuickExposes)
+	}
+	q.items = append(q.items, item)
+	q.rules = append(q.rules, rule)
+	return nil
+}
+
+func (q *quickExposeSet) remove(id string) bool {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	for i, it := range q.items {
+		if it.ID == id {
+			q.items = append(q.items[:i], q.items[i+1:]...)
+			q.rules = append(q.rules[:i], q.rules[i+1:]...)
+			return true
+		}
+	}
+	return false
+}
+
+// clear 清空并返回是否有分享被撤销
+func (q *quickExposeSet) clear() bool {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	had := len(q.items) > 0
+	q.items, q.rules = nil, nil
+	return had
+}
+
+// withQuickExposes 在配置副本中追加快速分享的临时规则，没有时原样返回
+func withQuickExposes(cfg *UserConfig, extra []ProxyRule) *UserConfig {
+	if len(extra) == 0 {
+		return cfg
+	}
+	c := *cfg
+	c.Proxies = append(append([]ProxyRule(nil), cfg.Proxies...), extra...)
+	return &c
+}
+
+func validateQuickExposeDomain(d string) error {
+	if d == "" {
+		return nil
+	}
+	if isWildcardDomain(d) {
+		return fmt.Errorf("快速分享域名请填写主域名，不带 *.，如 dev.example.com")
+	}
+	if err := validateDomain(d); err != nil {
+		return err
+	}
+	return nil
+}
+
+// pickQuickExposePort 随机挑选远程端口，TCP 会探测避开已被占用的端口，探测不出结果时直接使用
+func pickQuickExposePort(addr, proxyType string, used map[int]bool) int {
+	port := 0
+	for range quickExposePortTries {
+		port = randomBetween(quickExposePortMin, quickExposePortMax)
+		if used[port] {
+			continue
+		}
+		if proxyType != "tcp" {
+			return port
+		}
+		conn, err := net.DialTimeout("tcp", net.JoinHostPort(addr, strconv.Itoa(port)), remotePortProbeTimeout)
+		if err == nil {
+			_ = conn.Close()
+			continue
+		}
+		return port
+	}
+	return port
+}
+
+// QuickExpose 临时分享本机端口 (proxyType 为 tcp / udp / http)，隧道需已连接；返回对外访问地址
+func (s *MoleService) QuickExpose(localPort int, proxyType string) (QuickExpose, error) {
+	if err := s.checkMutable(); err != nil {
+		return QuickExpose{}, err
+	}
+	s.audit(auditConfigSave, fmt.Sprintf("快速分享本地端口 %d (%s)", localPort, proxyType))
+	if s.remote != nil {
+		return s.remote.quickExpose(localPort, proxyType)
+	}
+	if localPort <= 0 || localPort > 65535 {
+		return QuickExpose{}, fmt.Errorf("本地端口无效: %d", localPort)
+	}
+	st := s.status()
+	if !st.IsRunning || st.Config == nil {
+		return QuickExpose{}, fmt.Errorf("请先连接隧道")
+	}
+	s.mu.Lock()
+	hot := s.frpAdmin.Port > 0
+	s.mu.Unlock()
+	if !hot {
+		return QuickExpose{}, fmt.Errorf("当前连接不支持热重载，无法快速分享")
+	}
+	cfg := st.Config
+
+	suffix := randomHex(6)
+	item := QuickExpose{
+		ID:        quickExposeIDPrefix + newRuleID(),
+		Name:      fmt.Sprintf("quick_%d_%s", localPort, suffix),
+		ProxyType: proxyType,
+		LocalPort: localPort,
+		CreatedAt: time.Now(),
+	}
+	rule := ProxyRule{
+		ID:        item.ID,
+		Enabled:   true,
+		ProxyType: proxyType,
+		Name:      item.Name,
+		LocalIP:   "127.0.0.1",
+		LocalPort: localPort,
+	}
+	switch proxyType {
+	case "tcp", "udp":
+		used := make(map[int]bool)
+		for _, p := range cfg.Proxies {
+			used[p.RemotePort] = true
+		}
+		for _, p := range s.quick.proxies() {
+			used[p.RemotePort] = true
+		}
+		rule.RemotePort = pickQuickExposePort(cfg.Server.Addr, proxyType, used)
+		item.Endpoint = net.JoinHostPort(cfg.Server.Addr, strconv.Itoa(rule.RemotePort))
+	case "http":
+		base := cfg.Preferences.QuickExposeDomain
+		if base == "" {
+			return QuickExpose{}, fmt.Errorf("HTTP 快速分享需要先设置泛解析到服务器的域名")
+		}
+		domain := fmt.Sprintf("%d-%s.%s", localPort, suffix, base)
+		rule.Domains = []string{domain}
+		item.Endpoint = "http://" + domain
+	default:
+		return QuickExpose{}, fmt.Errorf("快速分享只支持 tcp、udp 与 http: %s", proxyType)
+	}
+
+	if err := s.quick.add(item, rule); err != nil {
+		return QuickExpose{}, err
+	}
+	if err := s.hotReload(); err != nil {
+		s.quick.remove(item.ID)
+		_ = s.hotReload()
+		return QuickExpose{}, fmt.Errorf("热重载失败: %v", err)
+	}
+	s.emitLog(fmt.Sprintf("已快速分享本地端口 %d: %s", localPort, item.Endpoint))
+	s.countFeature("quick_expose")
+	s.events.Emit("quick-expose", s.quick.list())
+	return item, nil
+}
+
+// StopQuickExpose 撤销一条快速分享
+func (s *MoleService) StopQuickExpose(id string) error {
+	if err := s.checkMutable(); err != nil {
+		return err
+	}
+	if s.remote != nil {
+		return s.remote.stopQuickExpose(id)
+	}
+	if !s.quick.remove(id) {
+		return fmt.Errorf("快速分享不存在或已结束")
+	}
+	s.events.Emit("quick-expose", s.quick.list())
+	if err := s.hotReload(); err != nil {
+		return fmt.Errorf("热重载失败: %v", err)
+	}
+	s.emitLog("已结束快速分享")
+	return nil
+}
+
+// ListQuickExposes 进行中的快速分享
+func (s *MoleService) ListQuickExposes() []QuickExpose {
+	if s.remote != nil {
+		list, err := s.remote.quickExposes()
+		if err != nil {
+			return []QuickExpose{}
+		}
+		return list
+	}
+	return s.quick.list()
+}
+
+// clearQuickExposes 断开连接时撤销全部快速分享
+func (s *MoleService) clearQuickExposes() {
+	if s.quick.clear() {
+		s.emitLog("连接已断开，快速分享已全部结束")
+		s.events.Emit("quick-expose", []QuickExpose{})
+	}
+}
+
+// SetQuickExposeDomain 设置 HTTP 快速分享使用的主域名，为空时只能分享 TCP/UDP
+func (s *MoleService) SetQuickExposeDomain(domain string) error {
+	if err := s.checkMutable(); err != nil {
+		return err
+	}
+	cfg := s.status().Config
+	if cfg == nil {
+		return fmt.Errorf("未发现有效配置")
+	}
+	newCfg := *cfg
+	newCfg.Preferences.QuickExposeDomain = normalizeDomain(domain)
+	return s.saveUserConfig(newCfg)
+}
@@ -0,0 +1,248 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+)
+
+// 脱敏导出：反馈问题时附上配置能省去很多来回询问，但配置里有 Token、密码、Webhook 等凭据
+// 这里按 toml 键名识别敏感字段 (新增的凭据字段沿用这些键名即可自动覆盖)，替换为占位符后
+// 输出 config.toml 与据此生成的 frpc.toml，可以直接贴到 GitHub Issue
+// 开启应用锁或只读模式时，GetStatus 用同样的规则替换凭据后再下发给前端；前端原样提交的占位符在保存时换回原值，
+// 清空的字段照常清空
+
+const redactedPlaceholder = "<redacted>"
+
+// 视为凭据的 toml 键名；Webhook 地址通常带有访问令牌，一并隐藏
+var redactKeys = map[string]bool{
+	"token":              true,
+	"access_token":       true,
+	"password":           true,
+	"secret":             true,
+	"secret_key":         true,
+	"bot_token":          true,
+	"dashboard_password": true,
+	"webhook":            true,
+}
+
+// redactParam 自由键名 (如插件的 httpPassword) 是否视为凭据
+func redactParam(key string) bool {
+	key = strings.ToLower(key)
+	for _, w := range []string{"password", "token", "secret"} {
+		if strings.Contains(key, w) {
+			return true
+		}
+	}
+	return false
+}
+
+// RedactedConfig 脱敏后的配置
+type RedactedConfig struct {
+	Config   string `json:"config"`   // config.toml
+	FrpcToml string `json:"frpcToml"` // 生成的 frpc.toml，生成失败时为错误说明
+}
+
+// ExportRedactedConfig 返回隐藏了凭据的用户配置与 frpc.toml
+func (s *MoleService) ExportRedactedConfig() (RedactedConfig, error) {
+	if err := s.checkUnlocked(); err != nil {
+		return RedactedConfig{}, err
+	}
+	cfg := s.status().Config
+	if cfg == nil {
+		return RedactedConfig{}, fmt.Errorf("未发现有效配置")
+	}
+	red, err := redactConfig(cfg)
+	if err != nil {
+		return RedactedConfig{}, fmt.Errorf("配置脱敏失败: %v", err)
+	}
+	data, err := toml.Marshal(red)
+	if err != nil {
+		return RedactedConfig{}, fmt.Errorf("配置文件格式化失败: %v", err)
+	}
+	out := RedactedConfig{Config: string(data)}
+
+	// Token 来源 (环境变量、钥匙串) 保留在配置中便于排查，生成 frpc.toml 时不去真正读取
+	render := *red
+	render.Server.TokenSource = ""
+	render.Proxies = append([]ProxyRule(nil), red.Proxies...)
+	for i := range render.Proxies {
+		render.Proxies[i].SecretKeySource = ""
+	}
+	s.mu.RLock()
+	admin := s.frpAdmin
+	s.mu.RUnlock()
+	if admin.Password != "" {
+		admin.Password = redactedPlaceholder
+	}
+	frpc, err := renderFrpcToml(&render, admin, nil)
+	if err != nil {
+		out.FrpcToml = "# 生成 frpc.toml 失败: " + err.Error()
+	} else {
+		out.FrpcToml = string(frpc)
+	}
+	s.countFeature("export_redacted")
+	return out, nil
+}
+
+// redactConfig 深拷贝配置并替换凭据字段，不修改原配置
+func redactConfig(cfg *UserConfig) (*UserConfig, error) {
+	data, err := json.Marshal(cfg)
+	if err != nil {
+		return nil, err
+	}
+	var red UserConfig
+	if err := json.Unmarshal(data, &red); err != nil {
+		return nil, err
+	}
+	// 不参与 JSON 的字段单独复制
+	red.ConfigVersion, red.LastUpdated = cfg.ConfigVersion, cfg.LastUpdated
+	redactValue(reflect.ValueOf(&red).Elem())
+	return &red, nil
+}
+
+// redactValue 递归替换非空的凭据字段，空值保持为空以便看出“未设置”
+func redactValue(v reflect.Value) {
+	switch v.Kind() {
+	case reflect.Pointer, reflect.Interface:
+		if !v.IsNil() {
+			redactValue(v.Elem())
+		}
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < v.Len(); i++ {
+			redactValue(v.Index(i))
+		}
+	case reflect.Map:
+		for _, k := range v.MapKeys() {
+			elem := reflect.New(v.Type().Elem()).Elem()
+			elem.Set(v.MapIndex(k))
+			// 插件参数等自由键名的字符串表，按键名中是否含有凭据字样判断
+			if k.Kind() == reflect.String && elem.Kind() == reflect.String && redactParam(k.String()) {
+				if elem.String() != "" {
+					elem.SetString(redactedPlaceholder)
+				}
+			} else {
+				redactValue(elem)
+			}
+			v.SetMapIndex(k, elem)
+		}
+	case reflect.Struct:
+		t := v.Type()
+		for i := 0; i < t.NumField(); i++ {
+			f := v.Field(i)
+			if !t.Field(i).IsExported() {
+				continue
+			}
+			name, _, _ := strings.Cut(t.Field(i).Tag.Get("toml"), ",")
+			if f.Kind() == reflect.String && redactKeys[name] {
+				if f.String() != "" {
+					f.SetString(redactedPlaceholder)
+				}
+				continue
+			}
+			redactValue(f)
+		}
+	}
+}
+
+// keepSecrets 把前端原样提交的占位符换回 old 中的原值，用户清空或改写的字段不动
+// 列表中的元素按 ID 或名称对应 (没有时按位置)，找不到原值的占位符 (如复制出的新规则) 清空
+func keepSecrets(cfg, old *UserConfig) {
+	keepSecretValue(reflect.ValueOf(cfg).Elem(), reflect.ValueOf(old).Elem())
+}
+
+// keepSecretValue src 无效时表示没有可沿用的原值
+func keepSecretValue(dst, src reflect.Value) {
+	switch dst.Kind() {
+	case reflect.Pointer:
+		if !dst.IsNil() {
+			if src.IsValid() && !src.IsNil() {
+				src = src.Elem()
+			} else {
+				src = reflect.Value{}
+			}
+			keepSecretValue(dst.Elem(), src)
+		}
+	case reflect.Slice:
+		for i := 0; i < dst.Len(); i++ {
+			var elem reflect.Value
+			if src.IsValid() {
+				if j := matchSecretElem(dst.Index(i), src, i); j >= 0 {
+					elem = src.Index(j)
+				}
+			}
+			keepSecretValue(dst.Index(i), elem)
+		}
+	case reflect.Map:
+		if dst.Type().Key().Kind() != reflect.String || dst.Type().Elem().Kind() != reflect.String {
+			return
+		}
+		for _, k := range dst.MapKeys() {
+			if !redactParam(k.String()) || dst.MapIndex(k).String() != redactedPlaceholder {
+				continue
+			}
+			v := reflect.ValueOf("").Convert(dst.Type().Elem())
+			if src.IsValid() && src.MapIndex(k).IsValid() {
+				v = src.MapIndex(k)
+			}
+			dst.SetMapIndex(k, v)
+		}
+	case reflect.Struct:
+		t := dst.Type()
+		for i := 0; i < t.NumField(); i++ {
+			f := dst.Field(i)
+			if !t.Field(i).IsExported() {
+				continue
+			}
+			var sf reflect.Value
+			if src.IsValid() {
+				sf = src.Field(i)
+			}
+			name, _, _ := strings.Cut(t.Field(i).Tag.Get("toml"), ",")
+			if f.Kind() == reflect.String && redactKeys[name] {
+				if f.String() == redactedPlaceholder {
+					if sf.IsValid() {
+						f.SetString(sf.String())
+					} else {
+						f.SetString("")
+					}
+				}
+				continue
+			}
+			keepSecretValue(f, sf)
+		}
+	}
+}
+
+// secretElemKey 列表元素的标识：ID 优先，其次名称
+func secretElemKey(v reflect.Value) string {
+	if v.Kind() != reflect.Struct {
+		return ""
+	}
+	for _, name := range []string{"ID", "Name"} {
+		if f := v.FieldByName(name); f.IsValid() && f.Kind() == reflect.String && f.String() != "" {
+			return name + ":" + f.String()
+		}
+	}
+	return ""
+}
+
+// matchSecretElem 在 src 中找与 dst 对应的元素，找不到返回 -1
+func matchSecretElem(dst, src reflect.Value, i int) int {
+	key := secretElemKey(dst)
+	if key == "" {
+		if i < src.Len() && secretElemKey(src.Index(i)) == "" {
+			return i
+		}
+		return -1
+	}
+	for j := 0; j < src.Len(); j++ {
+		if secretElemKey(src.Index(j)) == key {
+			return j
+		}
+	}
+	return -1
+}
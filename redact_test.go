@@ -0,0 +1,96 @@
+package main
+
+import "testing"
+
+func secretConfig() *UserConfig {
+	cfg := &UserConfig{
+		ConfigVersion: "1.0.0",
+		Proxies: []ProxyRule{
+			{ID: "a", Name: "A", ProxyType: "stcp", SecretKey: "stcp-key"},
+			{ID: "b", Name: "B", ProxyType: "tcp", PluginParams: map[string]string{"httpUser": "admin", "httpPassword": "plugin-pass"}},
+		},
+	}
+	cfg.Server.Addr = "frps.example.com"
+	cfg.Server.Token = "server-token"
+	cfg.SSH.Password = "ssh-pass"
+	return cfg
+}
+
+func TestRedactConfig(t *testing.T) {
+	cfg := secretConfig()
+	red, err := redactConfig(cfg)
+	if err != nil {
+		t.Fatalf("redactConfig: %v", err)
+	}
+
+	for name, got := range map[string]string{
+		"server.token": red.Server.Token,
+		"ssh.password": red.SSH.Password,
+		"secret_key":   red.Proxies[0].SecretKey,
+		"httpPassword": red.Proxies[1].PluginParams["httpPassword"],
+	} {
+		if got != redactedPlaceholder {
+			t.Errorf("%s = %q，应替换为占位符", name, got)
+		}
+	}
+	if red.Server.Addr != "frps.example.com" || red.Proxies[1].PluginParams["httpUser"] != "admin" {
+		t.Error("非凭据字段不应替换")
+	}
+	if red.ConfigVersion != "1.0.0" {
+		t.Errorf("ConfigVersion = %q", red.ConfigVersion)
+	}
+	if red.Proxies[0].ID != "a" {
+		t.Error("规则 ID 不应丢失")
+	}
+
+	// 原配置保持不变
+	if cfg.Server.Token != "server-token" || cfg.Proxies[1].PluginParams["httpPassword"] != "plugin-pass" {
+		t.Error("不应修改原配置")
+	}
+
+	// 未设置的凭据保持为空
+	empty, err := redactConfig(&UserConfig{})
+	if err != nil {
+		t.Fatalf("redactConfig: %v", err)
+	}
+	if empty.Server.Token != "" || empty.SSH.Password != "" {
+		t.Error("空凭据不应替换为占位符")
+	}
+}
+
+func TestKeepSecrets(t *testing.T) {
+	old := secretConfig()
+	old.Fleet.AccessToken = "fleet-token"
+	sent, err := redactConfig(old)
+	if err != nil {
+		t.Fatalf("redactConfig: %v", err)
+	}
+
+	// 前端调整顺序、修改一项凭据、清空一项凭据、复制出一条新规则后提交
+	sent.Proxies[0], sent.Proxies[1] = sent.Proxies[1], sent.Proxies[0]
+	sent.SSH.Password = "new-pass"
+	sent.Fleet.AccessToken = ""
+	dup := sent.Proxies[1]
+	dup.ID, dup.Name = "c", "C"
+	sent.Proxies = append(sent.Proxies, dup)
+	keepSecrets(sent, old)
+
+	if sent.Server.Token != "server-token" {
+		t.Errorf("server.token = %q", sent.Server.Token)
+	}
+	if sent.SSH.Password != "new-pass" {
+		t.Errorf("修改过的凭据被覆盖: %q", sent.SSH.Password)
+	}
+	if sent.Fleet.AccessToken != "" {
+		t.Errorf("清空的凭据被恢复: %q", sent.Fleet.AccessToken)
+	}
+	if sent.Proxies[0].PluginParams["httpPassword"] != "plugin-pass" {
+		t.Errorf("插件密码 = %q", sent.Proxies[0].PluginParams["httpPassword"])
+	}
+	if sent.Proxies[1].SecretKey != "stcp-key" {
+		t.Errorf("secret_key = %q", sent.Proxies[1].SecretKey)
+	}
+	if sent.Proxies[2].SecretKey != "" {
+		t.Errorf("新增规则不应沿用其他规则的凭据: %q", sent.Proxies[2].SecretKey)
+	}
+}
@@ -0,0 +1,93 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+// 月度报表：按月汇总数据库中的每日统计，按规则列出在线时长与在线天数，方便和 VPS 账单对照
+// frpc 不提供流量计数，报表只能给出时长；如需核对流量请结合服务商后台
+
+// UsageReport 一个月的使用报表
+type UsageReport struct {
+	Month         string       `json:"month"` // 2006-01
+	Days          int          `json:"days"`  // 有运行记录的天数
+	TunnelSeconds int64        `json:"tunnelSeconds"`
+	Sessions      int          `json:"sessions"`
+	Proxies       []ProxyUsage `json:"proxies"` // 按在线时长从高到低
+	Daily         []DailyStats `json:"daily"`   // 按日期升序
+}
+
+// ProxyUsage 单条规则在一个月内的汇总
+type ProxyUsage struct {
+	RuleID        string `json:"ruleID"`
+	Name          string `json:"name"`
+	OnlineSeconds int64  `json:"onlineSeconds"`
+	Days          int    `json:"days"`
+}
+
+// loadDailyStats 读取 [from, to] 日期范围内的每日统计 (日期格式 2006-01-02，含两端)
+func (s *MoleService) loadDailyStats(from, to string) ([]DailyStats, error) {
+	var list []DailyStats
+	err := s.store.view(func(tx *bolt.Tx) error {
+		c := tx.Bucket(bucketStats).Cursor()
+		for k, v := c.Seek([]byte(from)); k != nil && string(k) <= to; k, v = c.Next() {
+			var d DailyStats
+			if json.Unmarshal(v, &d) != nil {
+				continue
+			}
+			list = append(list, d)
+		}
+		return nil
+	})
+	return list, err
+}
+
+// GetUsageReport 生成指定月份 (2006-01) 的报表，传空字符串表示本月
+func (s *MoleService) GetUsageReport(month string) (UsageReport, error) {
+	if month == "" {
+		month = time.Now().Format("2006-01")
+	}
+	start, err := time.ParseInLocation("2006-01", month, time.Local)
+	if err != nil {
+		return UsageReport{}, fmt.Errorf("月份格式应为 YYYY-MM: %s", month)
+	}
+	end := start.AddDate(0, 1, -1)
+
+	daily, err := s.loadDailyStats(start.Format(time.DateOnly), end.Format(time.DateOnly))
+	if err != nil {
+		return UsageReport{}, fmt.Errorf("读取统计数据失败: %v", err)
+	}
+
+	report := UsageReport{Month: month, Proxies: []ProxyUsage{}, Daily: daily}
+	byRule := make(map[string]*ProxyUsage)
+	for _, d := range daily {
+		report.Days++
+		report.TunnelSeconds += d.TunnelSeconds
+		report.Sessions += d.Sessions
+		for id, p := range d.Proxies {
+			u := byRule[id]
+			if u == nil {
+				u = &ProxyUsage{RuleID: id}
+				byRule[id] = u
+			}
+			u.Name = p.Name // 日期升序，最终保留最近使用的名称
+			u.OnlineSeconds += p.OnlineSeconds
+			u.Days++
+		}
+	}
+	for _, u := range byRule {
+		report.Proxies = append(report.Proxies, *u)
+	}
+	sort.Slice(report.Proxies, func(i, j int) bool {
+		return report.Proxies[i].OnlineSeconds > report.Proxies[j].OnlineSeconds
+	})
+	if report.Daily == nil {
+		report.Daily = []DailyStats{}
+	}
+	return report, nil
+}
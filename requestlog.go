@@ -0,0 +1,279 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"net/http/httputil"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// 访问记录：HTTP 规则开启“记录访问请求”后，frpc 不再直连本地服务，而是连到 Mole 在 127.0.0.1 上开的记录代理，
+// 由它转发到本地服务并记下最近的请求 (方法、路径、状态码、来源 IP)，在界面中查看
+// frps 转发 HTTP 时会带上 X-Forwarded-For，来源 IP 取其中第一个地址；记录只保存在内存中
+// 监听端口随规则保持不变，隧道重连后 frpc.toml 依然有效，规则关闭记录或被删除时停止
+
+const requestLogLimit = 200 // 每条规则保留的最近请求数
+
+// RequestLogEntry 一次请求
+type RequestLogEntry struct {
+	RuleID   string    `json:"ruleId"`
+	Time     time.Time `json:"time"`
+	Method   string    `json:"method"`
+	Host     string    `json:"host"`
+	Path     string    `json:"path"`
+	Status   int       `json:"status"`
+	ClientIP string    `json:"clientIP"`
+	Duration int64     `json:"durationMs"`
+	Bytes    int64     `json:"bytes"`
+}
+
+// loggingProxy 一条规则的记录代理
+type loggingProxy struct {
+	srv    *http.Server
+	port   int
+	target string // 本地服务地址 host:port
+
+	mu      sync.Mutex
+	entries []RequestLogEntry
+}
+
+type requestLogger struct {
+	mu      sync.Mutex
+	proxies map[string]*loggingProxy // 规则 ID -> 记录代理
+}
+
+// usesRequestLog 规则是否经过记录代理
+func usesRequestLog(p ProxyRule) bool {
+	return p.RequestLog && p.Enabled && p.ProxyType == "http"
+}
+
+// statusRecorder 记下状态码与响应字节数，Unwrap 让 ReverseProxy 仍能完成 WebSocket 升级与刷新
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+	bytes  int64
+}
+
+func (r *statusRecorder) WriteHeader(code int) {
+	if r.status == 0 {
+		r.status = code
+	}
+	r.ResponseWriter.WriteHeader(code)
+}
+
+func (r *statusRecorder) Write(b []byte) (int, error) {
+	if r.status == 0 {
+		r.status = http.StatusOK
+	}
+	n, err := r.ResponseWriter.Write(b)
+	r.bytes += int64(n)
+	return n, err
+}
+
+func (r *statusRecorder) Unwrap() http.ResponseWriter {
+	return r.ResponseWriter
+}
+
+// requestClientIP 访问者地址：优先取 frps 添加的 X-Forwarded-For
+func requestClientIP(r *http.Request) string {
+	if xff := r.Header.Get("X-Forwarded-For"); xff != "" {
+		return strings.TrimSpace(strings.Split(xff, ",")[0])
+	}
+	if ip := r.Header.Get("X-Real-IP"); ip != "" {
+		return ip
+	}
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+func (lp *loggingProxy) record(e RequestLogEntry) {
+	lp.mu.Lock()
+	defer lp.mu.Unlock()
+	lp.entries = append(lp.entries, e)
+	if len(lp.entries) > requestLogLimit {
+		lp.entries = append([]RequestLogEntry(nil), lp.entries[len(lp.entries)-requestLogLimit:]...)
+	}
+}
+
+func startLoggingProxy(ruleID, target string) (*loggingProxy, error) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return nil, err
+	}
+	lp := &loggingProxy{port: ln.Addr().(*net.TCPAddr).Port, target: target}
+	upstream := &url.URL{Scheme: "http", Host: target}
+	rp := &httputil.ReverseProxy{
+		// 原样保留 Host 与 frps 添加的 X-Forwarded-* 头，对本地服务透明
+		Rewrite: func(pr *httputil.ProxyRequest) {
+			pr.SetURL(upstream)
+			pr.Out.Host = pr.In.Host
+			for _, h := range []string{"X-Forwarded-For", "X-Forwarded-Host", "X-Forwarded-Proto"} {
+				if v, ok := pr.In.Header[h]; ok {
+					pr.Out.Header[h] = v
+				}
+			}
+		},
+		ErrorHandler: func(w http.ResponseWriter, r *http.Request, err error) {
+			w.WriteHeader(http.StatusBadGateway)
+		},
+	}
+	lp.srv = &http.Server{
+		Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			start := time.Now()
+			rec := &statusRecorder{ResponseWriter: w}
+			rp.ServeHTTP(rec, r)
+			// WebSocket 等协议升级由 ReverseProxy 直接写入连接，不经过 WriteHeader
+			if rec.status == 0 && r.Header.Get("Upgrade") != "" {
+				rec.status = http.StatusSwitchingProtocols
+			}
+			lp.record(RequestLogEntry{
+				RuleID:   ruleID,
+				Time:     start,
+				Method:   r.Method,
+				Host:     r.Host,
+				Path:     r.URL.RequestURI(),
+				Status:   rec.status,
+				ClientIP: requestClientIP(r),
+				Duration: time.Since(start).Milliseconds(),
+				Bytes:    rec.bytes,
+			})
+		}),
+		ReadHeaderTimeout: 30 * time.Second,
+	}
+	go func() {
+		if err := lp.srv.Serve(ln); err != nil && err != http.ErrServerClosed {
+			log.Printf("访问记录代理异常退出: %v", err)
+		}
+	}()
+	return lp, nil
+}
+
+// sync 按规则启停记录代理，返回规则 ID 到代理端口的映射；本地地址变化时重新监听
+func (l *requestLogger) sync(proxies []ProxyRule) map[string]int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.proxies == nil {
+		l.proxies = make(map[string]*loggingProxy)
+	}
+
+	ports := make(map[string]int)
+	for _, p := range proxies {
+		if !usesRequestLog(p) {
+			continue
+		}
+		target := net.JoinHostPort(p.LocalIP, strconv.Itoa(p.LocalPort))
+		if lp, ok := l.proxies[p.ID]; ok {
+			if lp.target == target {
+				ports[p.ID] = lp.port
+				continue
+			}
+			_ = lp.srv.Close()
+			delete(l.proxies, p.ID)
+		}
+		lp, err := startLoggingProxy(p.ID, target)
+		if err != nil {
+			log.Printf("规则 %s 的访问记录代理启动失败，改为直连: %v", p.Name, err)
+			continue
+		}
+		l.proxies[p.ID] = lp
+		ports[p.ID] = lp.port
+	}
+	for id, lp := range l.proxies {
+		if _, ok := ports[id]; !ok {
+			_ = lp.srv.Close()
+			delete(l.proxies, id)
+		}
+	}
+	return ports
+}
+
+func (l *requestLogger) stopAll() {
+	l.sync(nil)
+}
+
+// entries 最近的请求，新的在前；ruleID 为空时返回全部规则
+func (l *requestLogger) entries(ruleID string) []RequestLogEntry {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	out := []RequestLogEntry{}
+	for id, lp := range l.proxies {
+		if ruleID != "" && id != ruleID {
+			continue
+		}
+		lp.mu.Lock()
+		out = append(out, lp.entries...)
+		lp.mu.Unlock()
+	}
+	// 多条规则的记录按时间倒序合并
+	sort.SliceStable(out, func(i, j int) bool { return out[i].Time.After(out[j].Time) })
+	return out
+}
+
+func (l *requestLogger) clear(ruleID string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	for id, lp := range l.proxies {
+		if ruleID == "" || id == ruleID {
+			lp.mu.Lock()
+			lp.entries = nil
+			lp.mu.Unlock()
+		}
+	}
+}
+
+// redirectLocal 把规则的本地地址换成本机上的代理 (访问记录、访问控制)，返回配置副本
+func redirectLocal(cfg *UserConfig, ports map[string]int) *UserConfig {
+	if len(ports) == 0 {
+		return cfg
+	}
+	c := *cfg
+	c.Proxies = make([]ProxyRule, len(cfg.Proxies))
+	for i, p := range cfg.Proxies {
+		if port, ok := ports[p.ID]; ok {
+			p.LocalIP, p.LocalPort = "127.0.0.1", port
+		}
+		c.Proxies[i] = p
+	}
+	return &c
+}
+
+// GetRequestLog 最近的访问记录，ruleID 为空时返回全部规则
+func (s *MoleService) GetRequestLog(ruleID string) ([]RequestLogEntry, error) {
+	if err := s.checkUnlocked(); err != nil {
+		return nil, err
+	}
+	if s.remote != nil {
+		return s.remote.requestLog(ruleID)
+	}
+	return s.reqLog.entries(ruleID), nil
+}
+
+// ClearRequestLog 清空访问记录
+func (s *MoleService) ClearRequestLog(ruleID string) error {
+	if err := s.checkMutable(); err != nil {
+		return err
+	}
+	if s.remote != nil {
+		return s.remote.clearRequestLog(ruleID)
+	}
+	s.reqLog.clear(ruleID)
+	return nil
+}
+
+// requestLogPath 控制接口中访问记录的路径
+func requestLogPath(ruleID string) string {
+	if ruleID == "" {
+		return "/api/request-log"
+	}
+	return fmt.Sprintf("/api/request-log?rule=%s", url.QueryEscape(ruleID))
+}
@@ -0,0 +1,126 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+)
+
+// frpc 资源占用：定期采样 frpc 进程的 CPU 与内存，随状态接口与 frpc-resource 事件下发，
+// 方便发现长时间运行后的异常占用，并与流量变化对照。采样实现见 resource_*.go
+
+const resourceSampleInterval = 5 * time.Second
+
+// 内存上限：小内存机器上长时间运行的 frpc 如有泄漏，持续超过上限一段时间后自动重启
+const (
+	defaultMemoryLimitMinutes = 5
+	maxMemoryLimitMB          = 64 * 1024
+	maxMemoryLimitMinutes     = 24 * 60
+)
+
+// FrpcResource 最近一次采样
+type FrpcResource struct {
+	CPUPercent float64   `json:"cpuPercent"` // 相对单个核心的占用，多核同时忙时可超过 100
+	RSSBytes   uint64    `json:"rssBytes"`   // 常驻内存
+	Time       time.Time `json:"time"`
+}
+
+type resourceMonitor struct {
+	mu   sync.Mutex
+	last *FrpcResource
+}
+
+func (m *resourceMonitor) set(r *FrpcResource) {
+	m.mu.Lock()
+	m.last = r
+	m.mu.Unlock()
+}
+
+func (m *resourceMonitor) fill(st *ServiceStatus) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.last != nil {
+		r := *m.last
+		st.FrpcResource = &r
+	}
+}
+
+func validateMemoryLimit(prefs Preferences) error {
+	if prefs.FrpcMemoryLimitMB < 0 || prefs.FrpcMemoryLimitMB > maxMemoryLimitMB {
+		return fmt.Errorf("frpc 内存上限应在 0 ~ %d MB 之间", maxMemoryLimitMB)
+	}
+	if prefs.FrpcMemoryLimitMinutes < 0 || prefs.FrpcMemoryLimitMinutes > maxMemoryLimitMinutes {
+		return fmt.Errorf("超出内存上限的持续时间应在 0 ~ %d 分钟之间", maxMemoryLimitMinutes)
+	}
+	return nil
+}
+
+// memoryLimit 当前配置的上限与持续时间，上限为 0 时不限制；每次采样时读取，修改后无需重启隧道
+func (s *MoleService) memoryLimit() (limit uint64, sustain time.Duration) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if s.config == nil || s.config.Preferences.FrpcMemoryLimitMB <= 0 {
+		return 0, 0
+	}
+	minutes := s.config.Preferences.FrpcMemoryLimitMinutes
+	if minutes == 0 {
+		minutes = defaultMemoryLimitMinutes
+	}
+	return uint64(s.config.Preferences.FrpcMemoryLimitMB) << 20, time.Duration(minutes) * time.Minute
+}
+
+// runResourceMonitor 随本次会话 ctx 退出，退出时清空采样，界面不再显示已结束进程的数据
+func (s *MoleService) runResourceMonitor(ctx context.Context, pid int) {
+	ticker := time.NewTicker(resourceSampleInterval)
+	defer ticker.Stop()
+	defer s.resource.set(nil)
+
+	prevCPU, _, err := processUsage(pid)
+	prevAt := time.Now()
+	failed := err != nil
+	var overSince time.Time // 本轮持续超出内存上限的起始时间
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		cpu, rss, err := processUsage(pid)
+		now := time.Now()
+		if err != nil {
+			// 进程刚退出或当前系统无法采样，等会话结束即可，不刷屏
+			failed = true
+			continue
+		}
+		r := &FrpcResource{RSSBytes: rss, Time: now}
+		if !failed && cpu >= prevCPU {
+			r.CPUPercent = float64(cpu-prevCPU) / float64(now.Sub(prevAt)) * 100
+		}
+		prevCPU, prevAt, failed = cpu, now, false
+
+		s.resource.set(r)
+		s.events.Emit("frpc-resource", *r)
+
+		limit, sustain := s.memoryLimit()
+		if limit == 0 || rss <= limit {
+			overSince = time.Time{}
+			continue
+		}
+		if overSince.IsZero() {
+			overSince = now
+		}
+		if now.Sub(overSince) < sustain {
+			continue
+		}
+		msg := fmt.Sprintf("frpc 内存占用 %.1f MB，已连续 %s 超过上限 %d MB", float64(rss)/(1<<20), sustain, limit>>20)
+		log.Print(msg)
+		s.emitLog(msg + "，正在重启隧道")
+		s.events.Emit("frpc-memory-limit", MemoryLimitEvent{RSSBytes: rss, LimitBytes: limit, Time: now})
+		if err := s.restartFrpIfRunning(); err != nil {
+			log.Printf("重启内存超限的 frpc 失败: %v", err)
+		}
+		return
+	}
+}
@@ -0,0 +1,38 @@
+//go:build linux
+
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// 内核按 USER_HZ 统计 CPU 时间，主流发行版均为 100
+const clockTicks = 100
+
+// processUsage 从 /proc/<pid>/stat 读取累计 CPU 时间与常驻内存
+func processUsage(pid int) (cpu time.Duration, rss uint64, err error) {
+	data, err := os.ReadFile(filepath.Join("/proc", strconv.Itoa(pid), "stat"))
+	if err != nil {
+		return 0, 0, err
+	}
+	// 进程名可能含空格，从最后一个 ')' 之后开始按空格拆分，第一个字段为 state (第 3 项)
+	s := string(data)
+	i := strings.LastIndexByte(s, ')')
+	if i < 0 {
+		return 0, 0, fmt.Errorf("无法解析 %s/stat", strconv.Itoa(pid))
+	}
+	fields := strings.Fields(s[i+1:])
+	if len(fields) < 22 {
+		return 0, 0, fmt.Errorf("无法解析 %s/stat", strconv.Itoa(pid))
+	}
+	utime, _ := strconv.ParseUint(fields[11], 10, 64) // 第 14 项
+	stime, _ := strconv.ParseUint(fields[12], 10, 64) // 第 15 项
+	pages, _ := strconv.ParseUint(fields[21], 10, 64) // 第 24 项
+	cpu = time.Duration(utime+stime) * time.Second / clockTicks
+	return cpu, pages * uint64(os.Getpagesize()), nil
+}
@@ -0,0 +1,53 @@
+//go:build !linux && !windows
+
+package main
+
+import (
+	"fmt"
+	"os/exec"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// processUsage macOS 没有 /proc，借助 ps 读取常驻内存 (KB) 与累计 CPU 时间
+func processUsage(pid int) (cpu time.Duration, rss uint64, err error) {
+	out, err := exec.Command("ps", "-o", "rss=,time=", "-p", strconv.Itoa(pid)).Output()
+	if err != nil {
+		return 0, 0, err
+	}
+	fields := strings.Fields(string(out))
+	if len(fields) != 2 {
+		return 0, 0, fmt.Errorf("无法解析 ps 输出: %q", out)
+	}
+	kb, err := strconv.ParseUint(fields[0], 10, 64)
+	if err != nil {
+		return 0, 0, fmt.Errorf("无法解析 ps 输出: %q", out)
+	}
+	cpu, err = parsePsTime(fields[1])
+	if err != nil {
+		return 0, 0, err
+	}
+	return cpu, kb * 1024, nil
+}
+
+// parsePsTime 解析 [[dd-]hh:]mm:ss[.cc] 格式的 CPU 时间
+func parsePsTime(s string) (time.Duration, error) {
+	var days int
+	if d, rest, ok := strings.Cut(s, "-"); ok {
+		n, err := strconv.Atoi(d)
+		if err != nil {
+			return 0, fmt.Errorf("无法解析 CPU 时间: %s", s)
+		}
+		days, s = n, rest
+	}
+	var secs float64
+	for _, part := range strings.Split(s, ":") {
+		v, err := strconv.ParseFloat(part, 64)
+		if err != nil {
+			return 0, fmt.Errorf("无法解析 CPU 时间: %s", s)
+		}
+		secs = secs*60 + v
+	}
+	return time.Duration((secs + float64(days)*86400) * float64(time.Second)), nil
+}
@@ -0,0 +1,111 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"math"
+	"sync"
+	"time"
+)
+
+// 断线重试：隧道意外断开 (frpc 退出、SSH 连接中断) 后按配置的次数、间隔和退避倍数自动重新连接
+// 连接成功后计数清零；用户主动连接 / 断开时取消尚未执行的重试
+// frpc 首次登录失败默认直接退出 (loginFailExit)，由这里按策略重试；设置为无限重试时交给 frpc 自己重试登录
+
+const (
+	defaultRetryInterval   = 5 // 秒
+	defaultRetryMultiplier = 2.0
+	maxRetryInterval       = 3600
+	maxRetryDelay          = 5 * time.Minute
+)
+
+// RetryPolicy 断线重试策略
+type RetryPolicy struct {
+	MaxRetries int     `toml:"max_retries" json:"maxRetries"` // 0 为不重试，-1 为无限重试
+	Interval   int     `toml:"interval" json:"interval"`      // 首次重试前等待的秒数，0 使用默认值
+	Multiplier float64 `toml:"multiplier" json:"multiplier"`  // 每次重试间隔的倍数，0 使用默认值，1 为固定间隔
+}
+
+func validateRetryPolicy(p RetryPolicy) error {
+	if p.MaxRetries < -1 {
+		return fmt.Errorf("重试次数无效: %d", p.MaxRetries)
+	}
+	if p.Interval < 0 || p.Interval > maxRetryInterval {
+		return fmt.Errorf("重试间隔应在 0 ~ %d 秒之间", maxRetryInterval)
+	}
+	if p.Multiplier != 0 && (p.Multiplier < 1 || p.Multiplier > 10) {
+		return fmt.Errorf("退避倍数应在 1 ~ 10 之间")
+	}
+	return nil
+}
+
+// delay 第 attempt 次 (从 1 开始) 重试前的等待时间
+func (p RetryPolicy) delay(attempt int) time.Duration {
+	interval, multiplier := p.Interval, p.Multiplier
+	if interval == 0 {
+		interval = defaultRetryInterval
+	}
+	if multiplier == 0 {
+		multiplier = defaultRetryMultiplier
+	}
+	d := time.Duration(float64(interval) * math.Pow(multiplier, float64(attempt-1)) * float64(time.Second))
+	if d <= 0 || d > maxRetryDelay {
+		d = maxRetryDelay
+	}
+	return d
+}
+
+type reconnector struct {
+	mu       sync.Mutex
+	attempts int
+	timer    *time.Timer
+}
+
+// reset 连接成功或用户主动操作时调用
+func (r *reconnector) reset() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.attempts = 0
+	if r.timer != nil {
+		r.timer.Stop()
+		r.timer = nil
+	}
+}
+
+// scheduleReconnect 隧道意外断开后调用，调用方可能持有 s.mu，这里只读取 s.config
+func (s *MoleService) scheduleReconnect() {
+	if s.stopRequested.Load() || s.config == nil {
+		return
+	}
+	policy := s.config.Retry
+	if policy.MaxRetries == 0 {
+		return
+	}
+
+	s.reconnect.mu.Lock()
+	defer s.reconnect.mu.Unlock()
+	if s.reconnect.timer != nil {
+		return
+	}
+	if policy.MaxRetries > 0 && s.reconnect.attempts >= policy.MaxRetries {
+		s.emitLog(fmt.Sprintf("已重试 %d 次仍未连上，停止自动重连", s.reconnect.attempts))
+		return
+	}
+	s.reconnect.attempts++
+	d := policy.delay(s.reconnect.attempts)
+	s.setTunnelState(tunnelReconnecting, "")
+	s.emitLog(fmt.Sprintf("%s 后第 %d 次尝试重新连接", d.Round(time.Second), s.reconnect.attempts))
+	s.reconnect.timer = time.AfterFunc(d, func() {
+		s.reconnect.mu.Lock()
+		s.reconnect.timer = nil
+		s.reconnect.mu.Unlock()
+		s.opMu.Lock()
+		defer s.opMu.Unlock()
+		// 等待期间用户可能已断开或手动连接
+		if s.stopRequested.Load() || s.isRunning.Load() {
+			return
+		}
+		log.Printf("自动重连，第 %d 次", s.reconnect.attempts)
+		s.startTunnel()
+	})
+}
@@ -0,0 +1,111 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/BurntSushi/toml"
+	bolt "go.etcd.io/bbolt"
+)
+
+// 配置历史：每次写盘成功后把 config.toml 的内容存一份，误改或导入出错时可以回退
+// 内容与上一份相同时不重复记录，最多保留 configRevisionLimit 份
+
+const (
+	configRevisionLimit        = 50
+	configRevisionDefaultLimit = 20
+)
+
+// ConfigRevision 一份历史配置的摘要，不包含配置内容 (其中有 Token)
+type ConfigRevision struct {
+	ID      uint64    `json:"id"`
+	Time    time.Time `json:"time"`
+	User    string    `json:"user"`
+	Summary string    `json:"summary"`
+}
+
+type revisionRecord struct {
+	ConfigRevision
+	TOML string `json:"toml"`
+}
+
+// recordRevision 在 writeConfigFiles 写盘成功后调用，失败只记日志
+func (s *MoleService) recordRevision(cfg *UserConfig, data []byte) {
+	err := s.store.update(func(tx *bolt.Tx) error {
+		b := tx.Bucket(bucketRevisions)
+		if _, last := b.Cursor().Last(); last != nil {
+			var prev revisionRecord
+			if json.Unmarshal(last, &prev) == nil && prev.TOML == string(data) {
+				return nil
+			}
+		}
+		seq, err := b.NextSequence()
+		if err != nil {
+			return err
+		}
+		rec := revisionRecord{
+			ConfigRevision: ConfigRevision{
+				ID:      seq,
+				Time:    time.Now(),
+				User:    auditUser(),
+				Summary: fmt.Sprintf("服务器 %s:%d，%d 条规则", cfg.Server.Addr, cfg.Server.Port, len(cfg.Proxies)),
+			},
+			TOML: string(data),
+		}
+		v, err := json.Marshal(rec)
+		if err != nil {
+			return err
+		}
+		if err := b.Put(itob(seq), v); err != nil {
+			return err
+		}
+		return trimBucket(tx, bucketRevisions, configRevisionLimit)
+	})
+	if err != nil {
+		log.Printf("记录配置历史失败: %v", err)
+	}
+}
+
+// GetConfigRevisions 返回最近 limit 份历史配置，新的在前
+func (s *MoleService) GetConfigRevisions(limit int) ([]ConfigRevision, error) {
+	if err := s.checkUnlocked(); err != nil {
+		return nil, err
+	}
+	if limit <= 0 {
+		limit = configRevisionDefaultLimit
+	}
+	recs, err := latestJSON[revisionRecord](&s.store, bucketRevisions, limit)
+	if err != nil {
+		return nil, fmt.Errorf("读取配置历史失败: %v", err)
+	}
+	list := make([]ConfigRevision, len(recs))
+	for i, r := range recs {
+		list[i] = r.ConfigRevision
+	}
+	return list, nil
+}
+
+// RestoreConfigRevision 回退到指定的历史配置，走正常的保存流程 (同样会记入审计与新的历史)
+func (s *MoleService) RestoreConfigRevision(id uint64) error {
+	if err := s.checkMutable(); err != nil {
+		return err
+	}
+	var rec revisionRecord
+	err := s.store.view(func(tx *bolt.Tx) error {
+		v := tx.Bucket(bucketRevisions).Get(itob(id))
+		if v == nil {
+			return fmt.Errorf("历史配置不存在或已被清理")
+		}
+		return json.Unmarshal(v, &rec)
+	})
+	if err != nil {
+		return err
+	}
+	var cfg UserConfig
+	if err := toml.Unmarshal([]byte(rec.TOML), &cfg); err != nil {
+		return fmt.Errorf("历史配置已损坏: %v", err)
+	}
+	return s.saveUserConfig(cfg)
+}
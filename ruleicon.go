@@ -0,0 +1,83 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/wailsapp/wails/v3/pkg/application"
+)
+
+// 规则图标与颜色：规则较多时在界面与托盘中更容易辨认
+// 只允许固定的集合，图标以名称保存，界面与托盘统一用这里的字符显示；颜色与日志高亮共用一套色板
+
+var ruleIcons = map[string]string{
+	"web":      "🌐",
+	"api":      "🔗",
+	"server":   "🖥️",
+	"database": "🗄️",
+	"terminal": "⌨️",
+	"desktop":  "🖱️",
+	"file":     "📁",
+	"media":    "🎬",
+	"camera":   "📷",
+	"game":     "🎮",
+	"home":     "🏠",
+	"mail":     "✉️",
+	"lock":     "🔒",
+	"test":     "🧪",
+}
+
+// 托盘菜单不能着色，没有图标时用对应颜色的圆点
+var ruleColorDots = map[string]string{
+	"red": "🔴", "orange": "🟠", "yellow": "🟡", "green": "🟢", "blue": "🔵", "purple": "🟣",
+}
+
+func validateRuleStyle(p ProxyRule) error {
+	if p.Icon != "" && ruleIcons[p.Icon] == "" {
+		return fmt.Errorf("规则 \"%s\" 的图标无效: %s", p.Name, p.Icon)
+	}
+	if p.Color != "" && !logHighlightColors[p.Color] {
+		return fmt.Errorf("规则 \"%s\" 的颜色无效: %s", p.Name, p.Color)
+	}
+	return nil
+}
+
+// ruleLabel 托盘中显示的规则名称，带图标或颜色圆点
+func ruleLabel(p ProxyRule) string {
+	name := expandTemplate(p.Name)
+	if icon := ruleIcons[p.Icon]; icon != "" {
+		return icon + " " + name
+	}
+	if dot := ruleColorDots[p.Color]; dot != "" {
+		return dot + " " + name
+	}
+	return name
+}
+
+// GetRuleIcons 返回可选的图标 (名称 -> 字符)
+func (s *MoleService) GetRuleIcons() map[string]string {
+	return ruleIcons
+}
+
+// addRulesMenu 隧道运行时列出启用的规则，点击复制公网访问地址
+func (t *trayMenu) addRulesMenu(menu *application.Menu, cfg *UserConfig) {
+	var rules []ProxyRule
+	for _, p := range cfg.Proxies {
+		if p.Enabled {
+			rules = append(rules, p)
+		}
+	}
+	if len(rules) == 0 {
+		return
+	}
+	sub := menu.AddSubmenu(tr("tray.rules"))
+	for _, p := range rules {
+		id := p.ID
+		sub.Add(ruleLabel(p)).OnClick(func(ctx *application.Context) {
+			go func() {
+				if err := t.ms.CopyProxyURL(id); err != nil {
+					t.ms.emitLog("复制访问地址失败：", err.Error())
+				}
+			}()
+		})
+	}
+}
@@ -0,0 +1,96 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+)
+
+// 密钥管理工具的解析实现，注册在 secretResolvers 中
+// Vault 直接调用 HTTP 接口，不依赖 vault 命令；1Password 与 Bitwarden 调用各自的命令行工具，
+// 解锁状态由工具自身管理 (会话环境变量)，Mole 不保存主密码
+
+const vaultMaxResponse = 1 << 20
+
+// resolveVaultSecret ref 形如 secret/data/frp#token，同时兼容 KV v1 与 v2 的返回格式
+func resolveVaultSecret(ref string) (string, error) {
+	path, field, ok := strings.Cut(ref, "#")
+	if !ok || path == "" || field == "" {
+		return "", fmt.Errorf("Vault 来源格式应为 vault:路径#字段")
+	}
+	addr := strings.TrimSuffix(os.Getenv("VAULT_ADDR"), "/")
+	token := os.Getenv("VAULT_TOKEN")
+	if addr == "" || token == "" {
+		return "", fmt.Errorf("需要设置 VAULT_ADDR 与 VAULT_TOKEN 环境变量")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), secretCommandTimeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, addr+"/v1/"+strings.TrimPrefix(path, "/"), nil)
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("X-Vault-Token", token)
+	if ns := os.Getenv("VAULT_NAMESPACE"); ns != "" {
+		req.Header.Set("X-Vault-Namespace", ns)
+	}
+	resp, err := outboundClient(0).Do(req)
+	if err != nil {
+		return "", fmt.Errorf("请求 Vault 失败: %v", err)
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(io.LimitReader(resp.Body, vaultMaxResponse))
+	if err != nil {
+		return "", fmt.Errorf("读取 Vault 响应失败: %v", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("Vault 返回 %s", resp.Status)
+	}
+
+	var out struct {
+		Data map[string]any `json:"data"`
+	}
+	if err := json.Unmarshal(body, &out); err != nil {
+		return "", fmt.Errorf("Vault 响应格式错误: %v", err)
+	}
+	data := out.Data
+	if inner, ok := data["data"].(map[string]any); ok { // KV v2
+		data = inner
+	}
+	v, ok := data[field].(string)
+	if !ok {
+		return "", fmt.Errorf("Vault 路径 %s 中没有字段 %s", path, field)
+	}
+	return v, nil
+}
+
+// resolve1PasswordSecret ref 为 op:// 之后的部分，整体交给 op read
+func resolve1PasswordSecret(ref string) (string, error) {
+	if !strings.HasPrefix(ref, "//") {
+		return "", fmt.Errorf("1Password 来源格式应为 op://保管库/条目/字段")
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), secretCommandTimeout)
+	defer cancel()
+	return runSecretCommand(ctx, "op", "read", "--no-newline", "op:"+ref)
+}
+
+// resolveBitwardenSecret ref 形如 条目名或ID#字段，字段为 bw get 支持的 password / username / notes / totp 等
+func resolveBitwardenSecret(ref string) (string, error) {
+	item, field, _ := strings.Cut(ref, "#")
+	if item == "" {
+		return "", fmt.Errorf("Bitwarden 来源格式应为 bw:条目#字段")
+	}
+	if field == "" {
+		field = "password"
+	}
+	if os.Getenv("BW_SESSION") == "" {
+		return "", fmt.Errorf("Bitwarden 未解锁，请先运行 bw unlock 并设置 BW_SESSION")
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), secretCommandTimeout)
+	defer cancel()
+	return runSecretCommand(ctx, "bw", "get", field, item, "--nointeraction")
+}
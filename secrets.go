@@ -0,0 +1,105 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"sort"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// 密钥来源：Token 等凭据可以不保存在 Mole 中，生成 frpc.toml (连接、热重载) 时再按来源解析
+//   env:FRP_TOKEN               读取环境变量 (作为后台服务运行时是服务进程的环境，而不是登录用户的)
+//   cmd:/path/to/script         运行脚本，取标准输出去掉首尾空白后的内容
+//   vault:secret/data/frp#token HashiCorp Vault，地址与令牌取自 VAULT_ADDR / VAULT_TOKEN 环境变量
+//   op://Private/frp/token      1Password CLI (op read)，需已登录或设置 OP_SERVICE_ACCOUNT_TOKEN
+//   bw:frp-server#password      Bitwarden CLI (bw get)，需设置 BW_SESSION；# 后为字段，默认 password
+// 解析结果只写入 frpc.toml，不回写 config.toml；新增来源只需在 secretResolvers 中注册
+
+const secretCommandTimeout = 10 * time.Second
+
+// secretResolvers 按前缀选择解析方式，ref 为冒号之后的部分
+var secretResolvers = map[string]func(ref string) (string, error){
+	"env":   resolveEnvSecret,
+	"cmd":   resolveCommandSecret,
+	"vault": resolveVaultSecret,
+	"op":    resolve1PasswordSecret,
+	"bw":    resolveBitwardenSecret,
+}
+
+// validateSecretSource 保存配置时只检查格式，不实际解析
+func validateSecretSource(source string) error {
+	if source == "" {
+		return nil
+	}
+	scheme, ref, ok := strings.Cut(source, ":")
+	if _, known := secretResolvers[scheme]; !ok || !known || strings.TrimSpace(ref) == "" {
+		schemes := make([]string, 0, len(secretResolvers))
+		for k := range secretResolvers {
+			schemes = append(schemes, k+":")
+		}
+		sort.Strings(schemes)
+		return fmt.Errorf("无效的密钥来源 %q，应以 %s 开头", source, strings.Join(schemes, " / "))
+	}
+	return nil
+}
+
+// resolveSecret 解析密钥来源，结果为空视为失败
+func resolveSecret(source string) (string, error) {
+	if err := validateSecretSource(source); err != nil {
+		return "", err
+	}
+	scheme, ref, _ := strings.Cut(source, ":")
+	value, err := secretResolvers[scheme](strings.TrimSpace(ref))
+	if err != nil {
+		return "", err
+	}
+	if value == "" {
+		return "", fmt.Errorf("%s 返回了空值", source)
+	}
+	return value, nil
+}
+
+func resolveEnvSecret(name string) (string, error) {
+	v, ok := os.LookupEnv(name)
+	if !ok {
+		return "", fmt.Errorf("环境变量 %s 未设置", name)
+	}
+	return strings.TrimSpace(v), nil
+}
+
+func resolveCommandSecret(path string) (string, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), secretCommandTimeout)
+	defer cancel()
+	return runSecretCommand(ctx, path)
+}
+
+// runSecretCommand 运行外部命令取标准输出，出错时附带标准错误的内容
+func runSecretCommand(ctx context.Context, name string, args ...string) (string, error) {
+	cmd := exec.CommandContext(ctx, name, args...)
+	cmd.SysProcAttr = &syscall.SysProcAttr{}
+	setHideWindow(cmd.SysProcAttr)
+	out, err := cmd.Output()
+	if err != nil {
+		if ee, ok := err.(*exec.ExitError); ok && len(ee.Stderr) > 0 {
+			return "", fmt.Errorf("运行 %s 失败: %s", name, strings.TrimSpace(string(ee.Stderr)))
+		}
+		return "", fmt.Errorf("运行 %s 失败: %v", name, err)
+	}
+	return strings.TrimSpace(string(out)), nil
+}
+
+// serverToken 生成 frpc.toml 时使用的 Token：配置了来源时以来源为准
+func serverToken(cfg *UserConfig) (string, error) {
+	if cfg.Server.TokenSource == "" {
+		return cfg.Server.Token, nil
+	}
+	token, err := resolveSecret(cfg.Server.TokenSource)
+	if err != nil {
+		return "", fmt.Errorf("获取 Token 失败: %v", err)
+	}
+	return token, nil
+}
@@ -0,0 +1,296 @@
+package main
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"regexp"
+	"runtime"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// 启动自检：每次启动隧道时按 配置有效 → frpc 可执行 → 服务器可达 → 登录认证 → 规则注册 逐步检查，
+// 每一步的进度以 self-test 事件推送，连接失败时界面能直接指出卡在哪一步，而不是笼统的“连接失败”
+// 前三步在启动 frpc 之前由 Mole 主动检查，任一步失败即停止启动；后两步根据 frpc 日志与规则状态判定
+// 隧道状态进入 error / idle 时尚未完成的步骤记为失败或跳过，本次自检随之结束
+
+const (
+	selfTestConfig  = "config"
+	selfTestBinary  = "binary"
+	selfTestServer  = "server"
+	selfTestAuth    = "auth"
+	selfTestProxies = "proxies"
+
+	selfTestPending = "pending"
+	selfTestRunning = "running"
+	selfTestOK      = "ok"
+	selfTestFailed  = "failed"
+	selfTestSkipped = "skipped"
+
+	selfTestDialTimeout = 5 * time.Second
+)
+
+var selfTestTitles = []struct{ id, title string }{
+	{selfTestConfig, "配置有效"},
+	{selfTestBinary, "frpc 程序可执行"},
+	{selfTestServer, "服务器可达"},
+	{selfTestAuth, "登录认证"},
+	{selfTestProxies, "规则注册"},
+}
+
+// [W] [client/service.go:301] login to the server failed: authorization failed. With loginFailExit enabled, no additional retries will be attempted
+var reLoginFailed = regexp.MustCompile(`login to the server failed: (.*)$`)
+
+// SelfTestStep 自检中的一步
+type SelfTestStep struct {
+	ID      string    `json:"id"`
+	Title   string    `json:"title"`
+	State   string    `json:"state"`
+	Message string    `json:"message,omitempty"`
+	Time    time.Time `json:"time"`
+}
+
+// SelfTestReport self-test 事件，每次都带上全部步骤
+type SelfTestReport struct {
+	Steps     []SelfTestStep `json:"steps"`
+	Done      bool           `json:"done"`
+	Failed    string         `json:"failed,omitempty"` // 失败的步骤 ID
+	StartedAt time.Time      `json:"startedAt"`
+}
+
+type selfTestTracker struct {
+	mu     sync.Mutex
+	report SelfTestReport
+}
+
+func finishedStep(state string) bool {
+	return state == selfTestOK || state == selfTestFailed || state == selfTestSkipped
+}
+
+// snapshot 返回副本，避免事件序列化时与更新并发
+func (t *selfTestTracker) snapshot() SelfTestReport {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	r := t.report
+	r.Steps = append([]SelfTestStep(nil), t.report.Steps...)
+	if r.Steps == nil {
+		r.Steps = []SelfTestStep{}
+	}
+	return r
+}
+
+// update 修改一步的状态，自检已结束时忽略；返回是否有变化
+func (t *selfTestTracker) update(id, state, message string) bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.report.Done {
+		return false
+	}
+	changed := false
+	for i := range t.report.Steps {
+		st := &t.report.Steps[i]
+		if st.ID != id {
+			continue
+		}
+		if st.State == state && st.Message == message {
+			return false
+		}
+		st.State, st.Message, st.Time = state, message, time.Now()
+		changed = true
+	}
+	if !changed {
+		return false
+	}
+	if state == selfTestFailed {
+		t.finishLocked(id, "前面的步骤未通过")
+	} else {
+		t.report.Done = true
+		for _, st := range t.report.Steps {
+			if !finishedStep(st.State) {
+				t.report.Done = false
+			}
+		}
+	}
+	return true
+}
+
+// finishLocked 结束本次自检，尚未完成的步骤记为跳过
+func (t *selfTestTracker) finishLocked(failed, skipReason string) {
+	t.report.Done = true
+	t.report.Failed = failed
+	now := time.Now()
+	for i := range t.report.Steps {
+		if st := &t.report.Steps[i]; !finishedStep(st.State) {
+			st.State, st.Message, st.Time = selfTestSkipped, skipReason, now
+		}
+	}
+}
+
+// abort 隧道进入 error / idle：reason 非空时第一个未完成的步骤记为失败，其余跳过
+func (t *selfTestTracker) abort(reason, skipReason string) bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.report.Done || len(t.report.Steps) == 0 {
+		return false
+	}
+	failed := ""
+	if reason != "" {
+		for i := range t.report.Steps {
+			if st := &t.report.Steps[i]; !finishedStep(st.State) {
+				st.State, st.Message, st.Time = selfTestFailed, reason, time.Now()
+				failed = st.ID
+				break
+			}
+		}
+	}
+	t.finishLocked(failed, skipReason)
+	return true
+}
+
+func (t *selfTestTracker) state(id string) string {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	for _, st := range t.report.Steps {
+		if st.ID == id {
+			return st.State
+		}
+	}
+	return ""
+}
+
+// beginSelfTest 开始新一次自检，所有步骤置为 pending
+func (s *MoleService) beginSelfTest() {
+	now := time.Now()
+	steps := make([]SelfTestStep, 0, len(selfTestTitles))
+	for _, t := range selfTestTitles {
+		steps = append(steps, SelfTestStep{ID: t.id, Title: t.title, State: selfTestPending, Time: now})
+	}
+	s.selfTest.mu.Lock()
+	s.selfTest.report = SelfTestReport{Steps: steps, StartedAt: now}
+	s.selfTest.mu.Unlock()
+	s.events.Emit("self-test", s.selfTest.snapshot())
+}
+
+func (s *MoleService) selfTestStep(id, state, message string) {
+	if s.selfTest.update(id, state, message) {
+		s.events.Emit("self-test", s.selfTest.snapshot())
+	}
+}
+
+// selfTestTunnelState 由 setTunnelState 调用，把隧道状态对应到自检步骤
+func (s *MoleService) selfTestTunnelState(state, reason string) {
+	switch state {
+	case tunnelConnected:
+		// SSH 隧道在建立时才连接服务器，登录成功即说明可达
+		if st := s.selfTest.state(selfTestServer); st == selfTestPending || st == selfTestRunning {
+			s.selfTestStep(selfTestServer, selfTestOK, "")
+		}
+		s.selfTestStep(selfTestAuth, selfTestOK, "")
+		s.selfTestStep(selfTestProxies, selfTestRunning, "")
+		s.checkSelfTestProxies()
+	case tunnelError:
+		if reason == "" {
+			reason = "启动失败"
+		}
+		if s.selfTest.abort(reason, "前面的步骤未通过") {
+			s.events.Emit("self-test", s.selfTest.snapshot())
+		}
+	case tunnelIdle:
+		if s.selfTest.abort("", "已断开") {
+			s.events.Emit("self-test", s.selfTest.snapshot())
+		}
+	}
+}
+
+// selfTestLoginLog 从 frpc 日志识别登录失败：连不上服务器算服务器不可达，其余算认证失败
+func (s *MoleService) selfTestLoginLog(line string) {
+	m := reLoginFailed.FindStringSubmatch(line)
+	if m == nil {
+		return
+	}
+	msg, _, _ := strings.Cut(m[1], ". With loginFailExit")
+	step := selfTestAuth
+	for _, k := range []string{"dial tcp", "i/o timeout", "connection refused", "no such host"} {
+		if strings.Contains(msg, k) {
+			step = selfTestServer
+			break
+		}
+	}
+	s.selfTestStep(step, selfTestFailed, msg)
+}
+
+// checkSelfTestProxies 登录成功后查看规则状态：有规则出错即失败，没有等待 frps 响应的规则即通过
+func (s *MoleService) checkSelfTestProxies() {
+	if s.selfTest.state(selfTestProxies) != selfTestRunning {
+		return
+	}
+	var running, pending, waiting int
+	for _, st := range s.proxyStates.list() {
+		switch st.State {
+		case proxyStateError:
+			s.selfTestStep(selfTestProxies, selfTestFailed, fmt.Sprintf("规则 %s: %s", st.Name, st.Message))
+			return
+		case proxyStatePending:
+			pending++
+		case proxyStateRunning:
+			running++
+		case proxyStateWaiting:
+			waiting++
+		}
+	}
+	if pending > 0 {
+		return
+	}
+	msg := fmt.Sprintf("%d 条规则已注册", running)
+	switch {
+	case waiting > 0:
+		msg += fmt.Sprintf("，%d 条按启动顺序等待中", waiting)
+	case running == 0:
+		msg = "没有需要注册的规则"
+	}
+	s.selfTestStep(selfTestProxies, selfTestOK, msg)
+}
+
+// checkFrpcExecutable frpc 存在、是普通文件且有执行权限
+func checkFrpcExecutable(path string) error {
+	info, err := os.Stat(path)
+	if err != nil {
+		return fmt.Errorf("找不到 frpc: %v", err)
+	}
+	if !info.Mode().IsRegular() || info.Size() == 0 {
+		return fmt.Errorf("frpc 文件无效: %s", path)
+	}
+	if runtime.GOOS != "windows" && info.Mode().Perm()&0111 == 0 {
+		return fmt.Errorf("frpc 没有执行权限: %s", path)
+	}
+	return nil
+}
+
+// probeServer 与服务器端口建立一次 TCP 连接，地址中的 ${VAR} 先按环境变量展开
+func probeServer(cfg *UserConfig) error {
+	host, err := expandEnvRefs(cfg.Server.Addr)
+	if err != nil {
+		return fmt.Errorf("服务器地址: %v", err)
+	}
+	addr := net.JoinHostPort(host, strconv.Itoa(cfg.Server.Port))
+	conn, err := net.DialTimeout("tcp", addr, selfTestDialTimeout)
+	if err != nil {
+		return fmt.Errorf("无法连接服务器 %s: %v", addr, err)
+	}
+	_ = conn.Close()
+	return nil
+}
+
+// GetSelfTest 最近一次启动自检的结果
+func (s *MoleService) GetSelfTest() (SelfTestReport, error) {
+	if s.remote != nil {
+		var r SelfTestReport
+		err := s.remote.call(http.MethodGet, "/api/self-test", &r)
+		return r, err
+	}
+	return s.selfTest.snapshot(), nil
+}
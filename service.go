@@ -0,0 +1,72 @@
+package main
+
+import "fmt"
+
+// 后台服务注册名称
+const (
+	serviceName        = "mole"
+	serviceDisplayName = "Mole FRP Tunnel"
+	serviceDescription = "Mole 内网穿透后台服务，开机自动建立 frp 隧道"
+)
+
+// systemdUnit 生成以守护进程方式运行 mole 的 systemd 单元内容
+func systemdUnit(exePath, configDir, user string) string {
+	return fmt.Sprintf(`[Unit]
+Description=%s
+After=network-online.target
+Wants=network-online.target
+
+[Service]
+Type=simple
+User=%s
+ExecStart="%s" --daemon --connect --config-dir "%s"
+Restart=on-failure
+RestartSec=5
+
+[Install]
+WantedBy=multi-user.target
+`, serviceDescription, user, exePath, configDir)
+}
+
+// BackgroundServiceInfo 后台服务状态，Status 取值 not-installed / active / inactive / pending / unsupported
+type BackgroundServiceInfo struct {
+	Status  string `json:"status"`
+	Message string `json:"message"`
+}
+
+// GetBackgroundServiceStatus 查询后台服务 (macOS LaunchAgent / systemd / Windows 服务) 状态
+func (s *MoleService) GetBackgroundServiceStatus() BackgroundServiceInfo {
+	status, err := backgroundServiceStatus()
+	if err != nil {
+		return BackgroundServiceInfo{Status: "unknown", Message: err.Error()}
+	}
+	return BackgroundServiceInfo{Status: status}
+}
+
+// InstallBackgroundService 从界面安装后台服务
+// macOS 的 LaunchAgent 无需提权；Linux / Windows 需要以 root / 管理员身份运行
+func (s *MoleService) InstallBackgroundService() error {
+	if err := s.checkMutable(); err != nil {
+		return err
+	}
+	// 先停掉 GUI 拉起的守护进程，避免与服务争抢控制端口
+	s.stopDaemon()
+
+	if err := installBackgroundService(); err != nil {
+		return err
+	}
+	s.reattachDaemon()
+	return nil
+}
+
+// UninstallBackgroundService 卸载后台服务，隧道随服务一起停止
+func (s *MoleService) UninstallBackgroundService() error {
+	if err := s.checkMutable(); err != nil {
+		return err
+	}
+	if err := uninstallBackgroundService(); err != nil {
+		return err
+	}
+	s.reattachDaemon()
+	return nil
+}
@@ -0,0 +1,107 @@
+//go:build darwin
+
+package main
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// macOS 使用当前用户的 LaunchAgent 托管守护进程：登录后自动启动，崩溃后由 launchd 拉起
+const launchAgentLabel = "top.91demo.mole"
+
+func launchAgentPath() string {
+	home, _ := os.UserHomeDir()
+	return filepath.Join(home, "Library", "LaunchAgents", launchAgentLabel+".plist")
+}
+
+// installBackgroundService 写入 LaunchAgent plist 并通过 launchctl 加载，无需管理员权限
+func installBackgroundService() error {
+	exePath, err := os.Executable()
+	if err != nil {
+		return err
+	}
+
+	plistPath := launchAgentPath()
+	if err := os.MkdirAll(filepath.Dir(plistPath), 0755); err != nil {
+		return err
+	}
+
+	logPath := filepath.Join(getAppLogDir(), "daemon.log")
+	plist := fmt.Sprintf(`<?xml version="1.0" encoding="UTF-8"?>
+<!DOCTYPE plist PUBLIC "-//Apple//DTD PLIST 1.0//EN" "http://www.apple.com/DTDs/PropertyList-1.0.dtd">
+<plist version="1.0">
+<dict>
+	<key>Label</key>
+	<string>%s</string>
+	<key>ProgramArguments</key>
+	<array>
+		<string>%s</string>
+		<string>--daemon</string>
+		<string>--connect</string>
+		<string>--config-dir</string>
+		<string>%s</string>
+	</array>
+	<key>RunAtLoad</key>
+	<true/>
+	<key>KeepAlive</key>
+	<true/>
+	<key>ThrottleInterval</key>
+	<integer>5</integer>
+	<key>StandardOutPath</key>
+	<string>%s</string>
+	<key>StandardErrorPath</key>
+	<string>%s</string>
+</dict>
+</plist>
+`, launchAgentLabel, xmlEscape(exePath), xmlEscape(getAppDataDir()), xmlEscape(logPath), xmlEscape(logPath))
+
+	if err := os.WriteFile(plistPath, []byte(plist), 0644); err != nil {
+		return fmt.Errorf("写入 LaunchAgent 失败: %v", err)
+	}
+
+	// 重复安装时先卸载旧的定义
+	_ = exec.Command("launchctl", "unload", plistPath).Run()
+	if out, err := exec.Command("launchctl", "load", "-w", plistPath).CombinedOutput(); err != nil {
+		return fmt.Errorf("launchctl load 失败: %v %s", err, out)
+	}
+	return nil
+}
+
+func uninstallBackgroundService() error {
+	plistPath := launchAgentPath()
+	if _, err := os.Stat(plistPath); os.IsNotExist(err) {
+		return fmt.Errorf("LaunchAgent 未安装")
+	}
+
+	_ = exec.Command("launchctl", "unload", "-w", plistPath).Run()
+	return os.Remove(plistPath)
+}
+
+// backgroundServiceStatus 通过 launchctl list 判断是否已加载以及进程是否存活
+func backgroundServiceStatus() (string, error) {
+	if _, err := os.Stat(launchAgentPath()); os.IsNotExist(err) {
+		return "not-installed", nil
+	}
+
+	out, err := exec.Command("launchctl", "list", launchAgentLabel).Output()
+	if err != nil {
+		// plist 存在但未加载
+		return "inactive", nil
+	}
+	if strings.Contains(string(out), `"PID" =`) {
+		return "active", nil
+	}
+	return "inactive", nil
+}
+
+func xmlEscape(s string) string {
+	var buf bytes.Buffer
+	_ = xml.EscapeText(&buf, []byte(s))
+	return buf.String()
+}
@@ -0,0 +1,88 @@
+//go:build linux
+
+package main
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"os/user"
+	"path/filepath"
+	"strings"
+)
+
+const systemdUnitPath = "/etc/systemd/system/mole.service"
+
+// installBackgroundService 写入 systemd 单元并立即启用，需要 root 权限 (sudo)
+// 服务以发起 sudo 的普通用户身份运行，与 GUI 共用同一份配置目录
+func installBackgroundService() error {
+	if os.Geteuid() != 0 {
+		return fmt.Errorf("需要 root 权限，请使用 sudo 运行")
+	}
+
+	exePath, err := os.Executable()
+	if err != nil {
+		return err
+	}
+
+	runUser, configDir, err := serviceOwner()
+	if err != nil {
+		return err
+	}
+
+	unit := systemdUnit(exePath, configDir, runUser)
+	if err := os.WriteFile(systemdUnitPath, []byte(unit), 0644); err != nil {
+		return fmt.Errorf("写入 systemd 单元失败: %v", err)
+	}
+
+	if out, err := exec.Command("systemctl", "daemon-reload").CombinedOutput(); err != nil {
+		return fmt.Errorf("systemctl daemon-reload 失败: %v %s", err, out)
+	}
+	if out, err := exec.Command("systemctl", "enable", "--now", serviceName).CombinedOutput(); err != nil {
+		return fmt.Errorf("启用服务失败: %v %s", err, out)
+	}
+	return nil
+}
+
+func uninstallBackgroundService() error {
+	if os.Geteuid() != 0 {
+		return fmt.Errorf("需要 root 权限，请使用 sudo 运行")
+	}
+
+	_ = exec.Command("systemctl", "disable", "--now", serviceName).Run()
+	if err := os.Remove(systemdUnitPath); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return exec.Command("systemctl", "daemon-reload").Run()
+}
+
+// backgroundServiceStatus 返回 systemctl is-active 的结果，如 active / inactive / failed
+func backgroundServiceStatus() (string, error) {
+	if _, err := os.Stat(systemdUnitPath); os.IsNotExist(err) {
+		return "not-installed", nil
+	}
+	out, _ := exec.Command("systemctl", "is-active", serviceName).Output()
+	return strings.TrimSpace(string(out)), nil
+}
+
+// serviceOwner 确定服务运行用户及其配置目录：优先使用 SUDO_USER，而不是 root
+func serviceOwner() (string, string, error) {
+	if appFlags.ConfigDir != "" {
+		u, err := user.Current()
+		if err != nil {
+			return "", "", err
+		}
+		return u.Username, appFlags.ConfigDir, nil
+	}
+
+	name := os.Getenv("SUDO_USER")
+	if name == "" {
+		return "root", getAppDataDir(), nil
+	}
+
+	u, err := user.Lookup(name)
+	if err != nil {
+		return "", "", fmt.Errorf("查找用户 %s 失败: %v", name, err)
+	}
+	return u.Username, filepath.Join(u.HomeDir, ".config", "mole"), nil
+}
@@ -0,0 +1,94 @@
+//go:build windows
+
+package main
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"golang.org/x/sys/windows/svc"
+	"golang.org/x/sys/windows/svc/mgr"
+)
+
+// installBackgroundService 注册为自动启动的 Windows 服务，需要管理员权限
+// 服务以 LocalSystem 运行，通过 --config-dir 指回当前用户的配置目录
+func installBackgroundService() error {
+	exePath, err := os.Executable()
+	if err != nil {
+		return err
+	}
+
+	m, err := mgr.Connect()
+	if err != nil {
+		return fmt.Errorf("连接服务管理器失败 (需要管理员权限): %v", err)
+	}
+	defer m.Disconnect()
+
+	if s, err := m.OpenService(serviceName); err == nil {
+		s.Close()
+		return fmt.Errorf("服务 %s 已存在", serviceName)
+	}
+
+	s, err := m.CreateService(serviceName, exePath, mgr.Config{
+		DisplayName: serviceDisplayName,
+		Description: serviceDescription,
+		StartType:   mgr.StartAutomatic,
+	}, "--daemon", "--connect", "--config-dir", getAppDataDir())
+	if err != nil {
+		return fmt.Errorf("创建服务失败: %v", err)
+	}
+	defer s.Close()
+
+	// 进程异常退出时 5 秒后自动重启
+	_ = s.SetRecoveryActions([]mgr.RecoveryAction{
+		{Type: mgr.ServiceRestart, Delay: 5 * time.Second},
+	}, 60)
+
+	return s.Start()
+}
+
+func uninstallBackgroundService() error {
+	m, err := mgr.Connect()
+	if err != nil {
+		return fmt.Errorf("连接服务管理器失败 (需要管理员权限): %v", err)
+	}
+	defer m.Disconnect()
+
+	s, err := m.OpenService(serviceName)
+	if err != nil {
+		return fmt.Errorf("服务 %s 未安装", serviceName)
+	}
+	defer s.Close()
+
+	_, _ = s.Control(svc.Stop)
+	return s.Delete()
+}
+
+// backgroundServiceStatus 返回服务当前状态
+func backgroundServiceStatus() (string, error) {
+	m, err := mgr.Connect()
+	if err != nil {
+		return "", err
+	}
+	defer m.Disconnect()
+
+	s, err := m.OpenService(serviceName)
+	if err != nil {
+		return "not-installed", nil
+	}
+	defer s.Close()
+
+	st, err := s.Query()
+	if err != nil {
+		return "", err
+	}
+	switch st.State {
+	case svc.Running:
+		return "active", nil
+	case svc.Stopped:
+		return "inactive", nil
+	default:
+		return "pending", nil
+	}
+}
@@ -0,0 +1,18 @@
+//go:build darwin
+
+package main
+
+import (
+	"bytes"
+	"os/exec"
+)
+
+// sessionLocked 锁屏时 IORegistry 根节点的控制台会话信息会带上 CGSSessionScreenIsLocked
+func sessionLocked() bool {
+	out, err := exec.Command("ioreg", "-n", "Root", "-d1").Output()
+	if err != nil {
+		return false
+	}
+	return bytes.Contains(out, []byte(`"CGSSessionScreenIsLocked"=Yes`)) ||
+		bytes.Contains(out, []byte(`"IOConsoleLocked" = Yes`))
+}
@@ -0,0 +1,23 @@
+//go:build linux
+
+package main
+
+import (
+	"os"
+	"os/exec"
+	"strings"
+)
+
+// sessionLocked 通过 systemd-logind 的 LockedHint 判断桌面会话是否已锁屏
+// 没有 loginctl 或桌面环境不上报 LockedHint 时视为未锁定
+func sessionLocked() bool {
+	session := os.Getenv("XDG_SESSION_ID")
+	if session == "" {
+		session = "self"
+	}
+	out, err := exec.Command("loginctl", "show-session", session, "-p", "LockedHint", "--value").Output()
+	if err != nil {
+		return false
+	}
+	return strings.TrimSpace(string(out)) == "yes"
+}
@@ -0,0 +1,22 @@
+//go:build windows
+
+package main
+
+import "golang.org/x/sys/windows"
+
+var (
+	user32               = windows.NewLazySystemDLL("user32.dll")
+	procOpenInputDesktop = user32.NewProc("OpenInputDesktop")
+	procCloseDesktop     = user32.NewProc("CloseDesktop")
+)
+
+// sessionLocked 锁屏后输入桌面切换到 Winlogon 安全桌面，普通进程打不开
+func sessionLocked() bool {
+	const desktopSwitchDesktop = 0x0100
+	h, _, _ := procOpenInputDesktop.Call(0, 0, desktopSwitchDesktop)
+	if h == 0 {
+		return true
+	}
+	_, _, _ = procCloseDesktop.Call(h)
+	return false
+}
@@ -0,0 +1,84 @@
+package main
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// 分享码：把服务器与规则打包成一串文本，方便在设备之间复制粘贴或生成二维码
+// 格式为 "MOLE1:" + base64url(gzip(JSON))，前缀中的数字是格式版本
+
+const shareCodePrefix = "MOLE1:"
+
+// MoleProfile 可分享 / 导入导出的配置子集，不含本机相关的偏好和状态
+type MoleProfile struct {
+	Server struct {
+		Addr   string `json:"addr"`
+		Port   int    `json:"port"`
+		Token  string `json:"token,omitempty"`
+		Remark string `json:"remark,omitempty"`
+	} `json:"server"`
+	Proxies []ProxyRule `json:"proxies"`
+}
+
+// profileFromConfig 从用户配置中提取可分享的部分，includeToken 为 false 时不带鉴权 Token
+func profileFromConfig(cfg *UserConfig, includeToken bool) MoleProfile {
+	var p MoleProfile
+	p.Server.Addr = cfg.Server.Addr
+	p.Server.Port = cfg.Server.Port
+	p.Server.Remark = cfg.Server.Remark
+	if includeToken {
+		p.Server.Token = cfg.Server.Token
+	}
+	p.Proxies = append([]ProxyRule(nil), cfg.Proxies...)
+	return p
+}
+
+func encodeShareCode(p MoleProfile) (string, error) {
+	data, err := json.Marshal(p)
+	if err != nil {
+		return "", err
+	}
+
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	if _, err := zw.Write(data); err != nil {
+		return "", err
+	}
+	if err := zw.Close(); err != nil {
+		return "", err
+	}
+	return shareCodePrefix + base64.RawURLEncoding.EncodeToString(buf.Bytes()), nil
+}
+
+// decodeShareCode 解析分享码，允许前后带空白
+func decodeShareCode(code string) (MoleProfile, error) {
+	var p MoleProfile
+
+	code = strings.TrimSpace(code)
+	if !strings.HasPrefix(code, shareCodePrefix) {
+		return p, fmt.Errorf("不是有效的分享码")
+	}
+	raw, err := base64.RawURLEncoding.DecodeString(strings.TrimPrefix(code, shareCodePrefix))
+	if err != nil {
+		return p, fmt.Errorf("分享码格式错误: %v", err)
+	}
+
+	zr, err := gzip.NewReader(bytes.NewReader(raw))
+	if err != nil {
+		return p, fmt.Errorf("分享码格式错误: %v", err)
+	}
+	data, err := io.ReadAll(io.LimitReader(zr, 1<<20))
+	if err != nil {
+		return p, fmt.Errorf("分享码格式错误: %v", err)
+	}
+	if err := json.Unmarshal(data, &p); err != nil {
+		return p, fmt.Errorf("分享码内容错误: %v", err)
+	}
+	return p, nil
+}
@@ -0,0 +1,167 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sync"
+	"time"
+)
+
+// 全机共享的 frpc：多人共用的电脑上，以管理员身份运行一次 --install-shared-frpc，
+// 把内置的 frpc 写到所有用户都能读取的程序数据目录，之后各账户直接使用这一份，不再各自释放
+// 配置、日志与 frpc.toml 仍在每个用户自己的数据目录中
+// 用户自己替换过数据目录中的 frpc 时以用户的为准；共享的 frpc 与当前版本内置的不一致 (升级后未重新安装) 时不使用
+
+// sharedFrpcDir 各平台的程序数据目录
+func sharedFrpcDir() string {
+	switch runtime.GOOS {
+	case "windows":
+		base := os.Getenv("ProgramData")
+		if base == "" {
+			base = `C:\ProgramData`
+		}
+		return filepath.Join(base, "MoleApp", "bin")
+	case "darwin":
+		return "/Library/Application Support/MoleApp/bin"
+	default:
+		return "/usr/local/lib/mole"
+	}
+}
+
+func sharedFrpcPath() string {
+	return filepath.Join(sharedFrpcDir(), frpcTargetName)
+}
+
+// frpcExtractMu 启动时的预先释放与连接时的释放互斥，后到的一方直接使用已释放的文件
+// 同时保护 sharedFrpcChecked
+var frpcExtractMu sync.Mutex
+
+// sharedFrpcChecked 上次完整比对时共享 frpc 的大小与修改时间，文件未变时沿用比对结果
+var sharedFrpcChecked struct {
+	size    int64
+	modTime time.Time
+	usable  bool
+}
+
+// sharedFrpcUsable 共享目录中的 frpc 与本版本内置的完全一致 (需持有 frpcExtractMu)
+// 逐字节比对只在文件大小或修改时间变化后进行，不必每次连接都读一遍整个文件
+func sharedFrpcUsable(embedded []byte) bool {
+	path := sharedFrpcPath()
+	st, err := os.Stat(path)
+	if err != nil || st.Size() != int64(len(embedded)) {
+		return false
+	}
+	c := &sharedFrpcChecked
+	if c.size == st.Size() && c.modTime.Equal(st.ModTime()) {
+		return c.usable
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return false
+	}
+	c.size, c.modTime, c.usable = st.Size(), st.ModTime(), bytes.Equal(data, embedded)
+	return c.usable
+}
+
+// warmFrpcBinary 开启自动连接时在初始化阶段后台释放 frpc，与等待本地服务、启动 frps 等并行
+func warmFrpcBinary(binDir string) {
+	defer trackTime("预先释放 frpc")()
+	if _, err := resolveFrpcPath(binDir); err != nil {
+		log.Printf("预先释放 frpc 失败: %v", err)
+	}
+}
+
+// resolveFrpcPath 选择要运行的 frpc：用户数据目录中已有的 > 共享的 > 释放到用户数据目录
+func resolveFrpcPath(binDir string) (string, error) {
+	frpcExtractMu.Lock()
+	defer frpcExtractMu.Unlock()
+	userPath := filepath.Join(binDir, frpcTargetName)
+	if _, err := os.Stat(userPath); err == nil {
+		return userPath, nil
+	}
+	data, err := frpcBin.ReadFile(frpcMap[runtime.GOARCH])
+	if err != nil {
+		return "", err
+	}
+	if sharedFrpcUsable(data) {
+		return sharedFrpcPath(), nil
+	}
+	// 先写临时文件再改名，释放中途退出不会留下残缺的 frpc
+	tmp := userPath + ".new"
+	if err := os.WriteFile(tmp, data, 0755); err != nil {
+		return "", err
+	}
+	if err := os.Rename(tmp, userPath); err != nil {
+		os.Remove(tmp)
+		return "", err
+	}
+	return userPath, nil
+}
+
+// effectiveFrpcPath 当前会运行的 frpc 路径，只查看不释放，用于版本与环境信息
+func effectiveFrpcPath(binDir string) string {
+	userPath := filepath.Join(binDir, frpcTargetName)
+	if _, err := os.Stat(userPath); err == nil {
+		return userPath
+	}
+	if _, err := os.Stat(sharedFrpcPath()); err == nil {
+		return sharedFrpcPath()
+	}
+	return userPath
+}
+
+// installSharedFrpc 写入共享目录，需要管理员权限；先写临时文件再改名，正在运行的 frpc 不受影响 (Windows 除外)
+func installSharedFrpc() error {
+	data, err := frpcBin.ReadFile(frpcMap[runtime.GOARCH])
+	if err != nil {
+		return err
+	}
+	dir := sharedFrpcDir()
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return fmt.Errorf("创建目录失败 (需要管理员权限): %v", err)
+	}
+	tmp := sharedFrpcPath() + ".new"
+	if err := os.WriteFile(tmp, data, 0755); err != nil {
+		return fmt.Errorf("写入 frpc 失败 (需要管理员权限): %v", err)
+	}
+	// WriteFile 受 umask 影响，显式放开读取与执行权限
+	_ = os.Chmod(tmp, 0755)
+	if err := os.Rename(tmp, sharedFrpcPath()); err != nil {
+		os.Remove(tmp)
+		return fmt.Errorf("替换 frpc 失败，请先断开所有用户的隧道: %v", err)
+	}
+	return nil
+}
+
+func uninstallSharedFrpc() error {
+	if err := os.Remove(sharedFrpcPath()); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("删除 frpc 失败 (需要管理员权限): %v", err)
+	}
+	_ = os.Remove(sharedFrpcDir()) // 目录非空时保留
+	return nil
+}
+
+// handleSharedFrpcCommand 安装 / 卸载共享 frpc 后退出
+func handleSharedFrpcCommand() bool {
+	switch {
+	case appFlags.InstallSharedFrpc:
+		if err := installSharedFrpc(); err != nil {
+			log.Printf("安装共享 frpc 失败: %v", err)
+		} else {
+			log.Printf("已安装共享 frpc: %s", sharedFrpcPath())
+		}
+		return true
+	case appFlags.UninstallSharedFrpc:
+		if err := uninstallSharedFrpc(); err != nil {
+			log.Printf("卸载共享 frpc 失败: %v", err)
+		} else {
+			log.Println("已卸载共享 frpc")
+		}
+		return true
+	}
+	return false
+}
@@ -0,0 +1,118 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// 限时分享：“分享 N 小时”会启用规则并记下到期时间，到期后自动停用
+// 到期时间保存在配置中，Mole 或守护进程重启后依然有效；到期前 10 分钟发出一次 expiring 告警，
+// 通过消息通知渠道提醒 (渠道可在关心的事件中单独勾选)
+// 规则被手动停用时到期时间一并清除，之后再启用不会被意外停用
+
+const (
+	maxShareHours        = 7 * 24
+	shareReminderBefore  = 10 * time.Minute
+	shareExpiryInterval  = 30 * time.Second
+	alertShareExpiring   = "expiring" // 限时分享即将到期
+	shareExpiryLogFormat = "2006-01-02 15:04"
+)
+
+// ShareRuleFor 启用规则并在 hours 小时后自动停用；hours 为 0 时取消到期时间，规则保持启用
+func (s *MoleService) ShareRuleFor(ruleID string, hours int) error {
+	if err := s.checkMutable(); err != nil {
+		return err
+	}
+	if hours < 0 || hours > maxShareHours {
+		return fmt.Errorf("分享时长应在 1 ~ %d 小时之间", maxShareHours)
+	}
+	cfg := s.status().Config
+	if cfg == nil {
+		return fmt.Errorf("未发现有效配置")
+	}
+	newCfg := *cfg
+	newCfg.Proxies = append([]ProxyRule(nil), cfg.Proxies...)
+	for i := range newCfg.Proxies {
+		p := &newCfg.Proxies[i]
+		if p.ID != ruleID {
+			continue
+		}
+		p.Enabled = true
+		msg := fmt.Sprintf("规则 %s 已取消限时分享", p.Name)
+		if hours == 0 {
+			p.ExpiresAt = time.Time{}
+		} else {
+			p.ExpiresAt = time.Now().Add(time.Duration(hours) * time.Hour).Truncate(time.Second)
+			msg = fmt.Sprintf("规则 %s 限时分享 %d 小时，将于 %s 自动停用", p.Name, hours, p.ExpiresAt.Format(shareExpiryLogFormat))
+		}
+		if err := s.saveUserConfig(newCfg); err != nil {
+			return err
+		}
+		s.emitLog(msg)
+		return nil
+	}
+	return fmt.Errorf("规则不存在")
+}
+
+// runShareExpiry 定期检查限时分享：到期前提醒一次，到期后停用规则
+func (s *MoleService) runShareExpiry(ctx context.Context) {
+	reminded := make(map[string]time.Time) // 规则 ID -> 已提醒的到期时间
+	ticker := time.NewTicker(shareExpiryInterval)
+	defer ticker.Stop()
+	for {
+		s.checkShareExpiry(time.Now(), reminded)
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (s *MoleService) checkShareExpiry(now time.Time, reminded map[string]time.Time) {
+	cfg := s.status().Config
+	if cfg == nil {
+		return
+	}
+	var expired []string
+	for _, p := range cfg.Proxies {
+		if !p.Enabled || p.ExpiresAt.IsZero() {
+			continue
+		}
+		left := p.ExpiresAt.Sub(now)
+		switch {
+		case left <= 0:
+			expired = append(expired, p.ID)
+		case left <= shareReminderBefore && !reminded[p.ID].Equal(p.ExpiresAt):
+			reminded[p.ID] = p.ExpiresAt
+			msg := fmt.Sprintf("规则 %s 的限时分享将于 %s 到期，届时自动停用", p.Name, p.ExpiresAt.Format("15:04"))
+			s.emitLog(msg)
+			s.raiseAlert(alertShareExpiring, msg)
+		}
+	}
+	if len(expired) == 0 {
+		return
+	}
+
+	newCfg := *cfg
+	newCfg.Proxies = append([]ProxyRule(nil), cfg.Proxies...)
+	var names []string
+	for i := range newCfg.Proxies {
+		p := &newCfg.Proxies[i]
+		for _, id := range expired {
+			if p.ID == id {
+				p.Enabled = false
+				p.ExpiresAt = time.Time{}
+				names = append(names, p.Name)
+				delete(reminded, id)
+			}
+		}
+	}
+	if err := s.saveUserConfig(newCfg); err != nil {
+		s.emitLog(fmt.Sprintf("限时分享到期，停用规则失败: %v", err))
+		return
+	}
+	s.emitLog("限时分享已到期，已停用规则: " + strings.Join(names, ", "))
+}
@@ -0,0 +1,198 @@
+package main
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"log"
+	"math/big"
+	"net"
+	"net/http"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/BurntSushi/toml"
+)
+
+// 模拟模式：--simulate 启动时不运行真实的 frpc，而是以 --fake-frpc 参数再启动一份自身作为假进程，
+// 按 frpc 的格式输出登录、代理上线、偶发断线重连等日志，并提供管理接口 (状态与热重载)。
+// 进程管理、日志解析、状态机与界面走的都是真实流程，开发和演示时不需要 frps 服务器或网络
+
+const (
+	fakeLoginDelay    = 800 * time.Millisecond
+	fakeProxyDelay    = 300 * time.Millisecond
+	fakeHiccupMin     = 45 // 两次模拟断线之间的秒数范围
+	fakeHiccupMax     = 120
+	fakeReconnectTime = 3 * time.Second
+)
+
+// fakeFrpcConfig 假进程需要的 frpc.toml 字段
+type fakeFrpcConfig struct {
+	ServerAddr string `toml:"serverAddr"`
+	ServerPort int    `toml:"serverPort"`
+	WebServer  struct {
+		Port     int    `toml:"port"`
+		User     string `toml:"user"`
+		Password string `toml:"password"`
+	} `toml:"webServer"`
+	Proxies []struct {
+		Name string `toml:"name"`
+		Type string `toml:"type"`
+	} `toml:"proxies"`
+}
+
+// frpcCommand 启动 frpc 的程序与参数，模拟模式下换成自身
+func frpcCommand(frpcPath, tomlPath string, extra []string) (string, []string) {
+	if !appFlags.Simulate {
+		return frpcPath, frpcArgs(tomlPath, extra)
+	}
+	exe, err := os.Executable()
+	if err != nil {
+		log.Printf("获取程序路径失败，模拟模式不可用: %v", err)
+		return frpcPath, frpcArgs(tomlPath, extra)
+	}
+	return exe, []string{"--fake-frpc", tomlPath}
+}
+
+// handleFakeFrpcCommand 以假 frpc 运行，直到被 Mole 结束
+func handleFakeFrpcCommand() bool {
+	if appFlags.FakeFrpc == "" {
+		return false
+	}
+	f := &fakeFrpc{path: appFlags.FakeFrpc, runID: randomHex(8), proxies: map[string]bool{}}
+	f.run()
+	return true
+}
+
+type fakeFrpc struct {
+	path  string
+	runID string
+
+	mu      sync.Mutex
+	proxies map[string]bool // 当前已注册的代理
+}
+
+func randomHex(n int) string {
+	buf := make([]byte, n)
+	_, _ = rand.Read(buf)
+	return hex.EncodeToString(buf)[:n]
+}
+
+func randomBetween(min, max int) int {
+	n, err := rand.Int(rand.Reader, big.NewInt(int64(max-min+1)))
+	if err != nil {
+		return min
+	}
+	return min + int(n.Int64())
+}
+
+// logf 按 frpc 的格式输出一行日志
+func (f *fakeFrpc) logf(level, source, format string, args ...any) {
+	fmt.Printf("%s [%s] [%s] [%s] %s\n", time.Now().Format("2006-01-02 15:04:05.000"), level, source, f.runID, fmt.Sprintf(format, args...))
+}
+
+func (f *fakeFrpc) load() (fakeFrpcConfig, error) {
+	var cfg fakeFrpcConfig
+	_, err := toml.DecodeFile(f.path, &cfg)
+	return cfg, err
+}
+
+func (f *fakeFrpc) run() {
+	cfg, err := f.load()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%s [E] [sub/root.go:150] load config file error: %v\n", time.Now().Format("2006-01-02 15:04:05.000"), err)
+		os.Exit(1)
+	}
+	fmt.Printf("%s [I] [sub/root.go:142] start frpc service for config file [%s] (simulated)\n", time.Now().Format("2006-01-02 15:04:05.000"), f.path)
+	if cfg.WebServer.Port > 0 {
+		go f.serveAdmin(cfg)
+	}
+	server := net.JoinHostPort(cfg.ServerAddr, strconv.Itoa(cfg.ServerPort))
+	f.logf("I", "client/service.go:295", "try to connect to server [%s]...", server)
+	time.Sleep(fakeLoginDelay)
+	f.logf("I", "client/service.go:287", "login to server success, get run id [%s]", f.runID)
+	f.apply(cfg)
+
+	for {
+		time.Sleep(time.Duration(randomBetween(fakeHiccupMin, fakeHiccupMax)) * time.Second)
+		f.logf("W", "client/control.go:211", "connect to server error: read tcp %s: i/o timeout (simulated)", server)
+		f.logf("I", "client/service.go:196", "try to reconnect to server...")
+		time.Sleep(fakeReconnectTime)
+		f.logf("I", "client/service.go:287", "login to server success, get run id [%s]", f.runID)
+		f.mu.Lock()
+		names := make([]string, 0, len(f.proxies))
+		for name := range f.proxies {
+			names = append(names, name)
+		}
+		f.mu.Unlock()
+		for _, name := range names {
+			f.logf("I", "client/control.go:168", "[%s] start proxy success", name)
+		}
+	}
+}
+
+// apply 按配置增删代理并输出对应日志，启动与热重载共用
+func (f *fakeFrpc) apply(cfg fakeFrpcConfig) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	wanted := make(map[string]bool, len(cfg.Proxies))
+	var added []string
+	for _, p := range cfg.Proxies {
+		wanted[p.Name] = true
+		if !f.proxies[p.Name] {
+			added = append(added, p.Name)
+		}
+	}
+	var removed []string
+	for name := range f.proxies {
+		if !wanted[name] {
+			removed = append(removed, name)
+		}
+	}
+	if len(removed) > 0 {
+		f.logf("I", "proxy/proxy_manager.go:145", "proxy removed: %v", removed)
+	}
+	if len(added) > 0 {
+		f.logf("I", "proxy/proxy_manager.go:173", "proxy added: %v", added)
+	}
+	f.proxies = wanted
+	for _, name := range added {
+		time.Sleep(fakeProxyDelay)
+		f.logf("I", "client/control.go:168", "[%s] start proxy success", name)
+	}
+}
+
+// serveAdmin 只实现 Mole 用到的 /api/status 与 /api/reload
+func (f *fakeFrpc) serveAdmin(cfg fakeFrpcConfig) {
+	mux := http.NewServeMux()
+	auth := func(h http.HandlerFunc) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			user, pass, ok := r.BasicAuth()
+			if !ok || user != cfg.WebServer.User || pass != cfg.WebServer.Password {
+				http.Error(w, "unauthorized", http.StatusUnauthorized)
+				return
+			}
+			h(w, r)
+		}
+	}
+	mux.HandleFunc("/api/status", auth(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte("{}"))
+	}))
+	mux.HandleFunc("/api/reload", auth(func(w http.ResponseWriter, r *http.Request) {
+		next, err := f.load()
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		f.logf("I", "client/admin_api.go:65", "success reload conf")
+		go f.apply(next)
+	}))
+	addr := net.JoinHostPort("127.0.0.1", strconv.Itoa(cfg.WebServer.Port))
+	if err := http.ListenAndServe(addr, mux); err != nil {
+		f.logf("W", "client/admin.go:52", "start admin server error: %v", err)
+	}
+}
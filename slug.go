@@ -0,0 +1,74 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// 代理名自动生成：规则名称是给人看的标题，可以写中文、空格与表情 (如 "我的网站")；
+// 写入 frpc.toml 的代理名 (WireName) 只能使用字母、数字、点、下划线与连字符
+// 名称本身合法时 WireName 留空，直接使用名称，旧配置不受影响；否则保存时生成一次并写入配置，
+// 之后修改标题不会改变代理名，frps 上的代理不会因为改名而重建
+
+const maxWireName = 64
+
+var (
+	reWireNameSafe   = regexp.MustCompile(`^[A-Za-z0-9._-]+$`)
+	reWireNameUnsafe = regexp.MustCompile(`[^a-z0-9]+`)
+)
+
+// validWireName 名称能否直接作为代理名，模板变量展开后一定合法，不参与判断
+func validWireName(name string) bool {
+	rest := reTemplateVar.ReplaceAllString(name, "x")
+	return len(rest) <= maxWireName && reWireNameSafe.MatchString(rest)
+}
+
+// slugifyName 从标题生成代理名：保留其中的英文与数字，没有时以规则 ID (或标题的摘要) 生成
+func slugifyName(name, ruleID string) string {
+	slug := strings.Trim(reWireNameUnsafe.ReplaceAllString(strings.ToLower(name), "-"), "-")
+	if len(slug) > maxWireName-8 {
+		slug = strings.TrimRight(slug[:maxWireName-8], "-")
+	}
+	if slug != "" {
+		return slug
+	}
+	if len(ruleID) >= 6 {
+		return "rule-" + strings.ToLower(ruleID[:6])
+	}
+	sum := sha256.Sum256([]byte(name))
+	return "rule-" + hex.EncodeToString(sum[:3])
+}
+
+// proxyBaseName 规则的代理名 (未加前缀、未展开模板)
+func proxyBaseName(p ProxyRule) string {
+	if p.WireName != "" {
+		return p.WireName
+	}
+	return p.Name
+}
+
+// assignWireNames 为名称不能直接作为代理名的规则生成 WireName，并保证各规则的代理名互不相同
+func assignWireNames(rules []ProxyRule) error {
+	used := make(map[string]bool, len(rules))
+	for i := range rules {
+		p := &rules[i]
+		p.WireName = strings.TrimSpace(p.WireName)
+		switch {
+		case p.WireName != "":
+			if !validWireName(p.WireName) {
+				return fmt.Errorf("规则 \"%s\" 的代理名只能包含字母、数字、点、下划线与连字符: %s", p.Name, p.WireName)
+			}
+		case !validWireName(p.Name):
+			base := slugifyName(p.Name, p.ID)
+			p.WireName = base
+			for n := 2; used[p.WireName]; n++ {
+				p.WireName = fmt.Sprintf("%s-%d", base, n)
+			}
+		}
+		used[proxyBaseName(*p)] = true
+	}
+	return nil
+}
@@ -0,0 +1,117 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"fmt"
+	"log"
+	"net"
+	"strconv"
+	"strings"
+
+	"golang.org/x/crypto/ssh"
+)
+
+// frps (>= 0.53) 的 SSH 隧道网关：服务端开启 sshTunnelGateway 后，客户端无需 frpc 和 token，
+// 用标准 SSH 协议即可注册代理，等同于
+//   ssh -R :80:127.0.0.1:8080 v0@frps -p 2200 tcp --proxy_name web --remote_port 9090
+// 网关一条连接只对应一条代理，因此每条规则单独建立连接
+
+const (
+	frpGatewayDefaultPort = 2200
+	frpGatewayDefaultUser = "v0"
+)
+
+// openGatewayForwards 为每条启用的规则建立一条到 frps 网关的 SSH 连接
+func (s *MoleService) openGatewayForwards(ctx context.Context, t *sshTunnel, cfg *UserConfig) int {
+	forwarded := 0
+	for _, p := range cfg.Proxies {
+		if !p.Enabled {
+			continue
+		}
+		args, err := gatewayCommand(p)
+		if err != nil {
+			s.emitLog(fmt.Sprintf("[%s] %v，已跳过", p.Name, err))
+			s.setProxyState(p.Name, proxyStateError, err.Error())
+			continue
+		}
+
+		client, err := dialSSH(envOr(cfg.Server.Addr), cfg.SSH, frpGatewayDefaultPort, frpGatewayDefaultUser)
+		if err != nil {
+			s.emitLog(fmt.Sprintf("[%s] 连接 frps SSH 网关失败：%v", p.Name, err))
+			log.Printf("frps SSH 网关连接失败: %v", err)
+			s.setProxyState(p.Name, proxyStateError, err.Error())
+			continue
+		}
+
+		// 网关忽略这里的监听地址，真正的远程端口 / 域名由命令参数决定；端口不能为 0，否则需要服务端回填
+		ln, err := client.Listen("tcp", net.JoinHostPort("0.0.0.0", strconv.Itoa(p.LocalPort)))
+		if err != nil {
+			_ = client.Close()
+			s.emitLog(fmt.Sprintf("[%s] 请求转发失败：%v", p.Name, err))
+			continue
+		}
+
+		session, err := client.NewSession()
+		if err != nil {
+			_ = client.Close()
+			s.emitLog(fmt.Sprintf("[%s] 打开会话失败：%v", p.Name, err))
+			continue
+		}
+		stdout, err := session.StdoutPipe()
+		if err != nil {
+			_ = client.Close()
+			s.emitLog(fmt.Sprintf("[%s] 打开会话失败：%v", p.Name, err))
+			continue
+		}
+		if err := session.Start(args); err != nil {
+			_ = client.Close()
+			s.emitLog(fmt.Sprintf("[%s] 注册代理失败：%v", p.Name, err))
+			continue
+		}
+
+		// frps 通过会话输出代理创建结果与错误信息，转发到日志面板
+		go func(name string) {
+			scanner := bufio.NewScanner(stdout)
+			for scanner.Scan() {
+				if line := strings.TrimSpace(scanner.Text()); line != "" {
+					s.emitLog(fmt.Sprintf("[%s] %s", name, line))
+				}
+			}
+		}(p.Name)
+
+		// 注册结果由 frps 写回会话输出，这里只能确认连接与命令已送达
+		t.clients = append(t.clients, client)
+		forwarded++
+		s.setProxyState(p.Name, proxyStateRunning, "")
+		go serveReverseForward(ctx, ln, net.JoinHostPort(envOr(p.LocalIP), strconv.Itoa(p.LocalPort)))
+	}
+	return forwarded
+}
+
+// gatewayCommand 生成 frps 网关识别的代理参数
+func gatewayCommand(p ProxyRule) (string, error) {
+	name := strconv.Quote(p.Name)
+	switch p.ProxyType {
+	case "tcp":
+		return fmt.Sprintf("tcp --proxy_name %s --remote_port %d", name, p.RemotePort), nil
+	case "http":
+		if len(p.Domains) == 0 || p.Domains[0] == "" {
+			return "", fmt.Errorf("HTTP 规则缺少域名")
+		}
+		return fmt.Sprintf("http --proxy_name %s --custom_domain %s", name, strconv.Quote(p.Domains[0])), nil
+	default:
+		return "", fmt.Errorf("frps SSH 网关不支持 %s 规则", strings.ToUpper(p.ProxyType))
+	}
+}
+
+// ephemeralSigner 生成仅在本次连接中使用的 ed25519 密钥
+func ephemeralSigner() (ssh.Signer, error) {
+	_, key, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		return nil, fmt.Errorf("生成临时密钥失败: %v", err)
+	}
+	return ssh.NewSignerFromKey(key)
+}
@@ -0,0 +1,335 @@
+package main
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/knownhosts"
+)
+
+// SSH 反向隧道：没有部署 frps、只有一台普通 VPS 时的备用传输方式
+// 效果等同于 ssh -R remotePort:localIP:localPort，仅支持 TCP 规则
+// 注意：要让远程端口对公网开放，服务器 sshd 需要开启 GatewayPorts yes (或 clientspecified)
+
+const (
+	transportFrp    = "frp"
+	transportSSH    = "ssh"
+	transportFrpSSH = "frp-ssh" // frps 的 SSH 隧道网关 (sshTunnelGateway)
+
+	sshDialTimeout       = 10 * time.Second
+	sshKeepaliveInterval = 30 * time.Second
+)
+
+// SSHConfig SSH 传输方式的连接参数，服务器地址沿用 Server.Addr
+type SSHConfig struct {
+	Port     int    `toml:"port" json:"port"` // 默认 22，frps 网关模式默认 2200
+	User     string `toml:"user" json:"user"` // frps 网关模式默认 v0
+	Password string `toml:"password,omitempty" json:"password"`
+	KeyFile  string `toml:"key_file,omitempty" json:"keyFile"` // 私钥路径，优先于密码
+	// 服务器主机密钥指纹 (形如 SHA256:xxxx)，留空则首次连接时记入 config/ssh_known_hosts，之后密钥变化即拒绝连接
+	HostKeyFingerprint string `toml:"host_key_fingerprint,omitempty" json:"hostKeyFingerprint"`
+}
+
+// sshTunnel 一次 SSH 隧道会话：普通 SSH 模式只有一条连接，frps 网关模式每条规则一条连接
+type sshTunnel struct {
+	clients []*ssh.Client
+	cancel  context.CancelFunc
+	done    chan struct{}
+	once    sync.Once
+}
+
+// startSSHTunnel 建立 SSH 连接并为每条启用的规则开启反向转发；拨号最长要等 sshDialTimeout，
+// 调用方不持有 s.mu (cfg 为加锁时取得的配置)，连接建立后再加锁登记
+func (s *MoleService) startSSHTunnel(cfg *UserConfig) {
+	ctx, cancel := context.WithCancel(s.ctx)
+	t := &sshTunnel{cancel: cancel, done: make(chan struct{})}
+
+	var forwarded int
+	if cfg.Server.Transport == transportFrpSSH {
+		forwarded = s.openGatewayForwards(ctx, t, cfg)
+	} else {
+		forwarded = s.openReverseForwards(ctx, t, cfg)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if forwarded == 0 {
+		t.close()
+		s.stopProxyStates()
+		s.emitLog("SSH 隧道未建立任何转发，已断开")
+		s.setTunnelState(tunnelError, "SSH 隧道未建立任何转发")
+		s.scheduleReconnect()
+		return
+	}
+
+	s.sshTunnel = t
+	s.emitFrpStatus("start")
+	s.isRunning.Store(true)
+	s.markTunnelUp("SSH 隧道已建立")
+
+	for _, c := range t.clients {
+		go t.keepalive(ctx, c)
+		go func(c *ssh.Client) {
+			// 任意一条连接断开 (服务器重启、网络中断或主动关闭) 即整体断开
+			_ = c.Wait()
+			t.close()
+		}(c)
+	}
+	go func() {
+		<-t.done
+
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		if s.sshTunnel != t {
+			return
+		}
+		s.sshTunnel = nil
+		s.isRunning.Store(false)
+
+		s.emitLog("警告：SSH 隧道已断开")
+		s.markTunnelDown("SSH 隧道已断开")
+		s.tunnelExited("SSH 隧道已断开")
+		s.stopProxyStates()
+		s.emitFrpStatus("stop")
+		s.scheduleReconnect()
+	}()
+}
+
+// openReverseForwards 普通 SSH 服务器：一条连接上为每条 TCP 规则请求远程端口监听
+func (s *MoleService) openReverseForwards(ctx context.Context, t *sshTunnel, cfg *UserConfig) int {
+	client, err := dialSSH(envOr(cfg.Server.Addr), cfg.SSH, 22, "")
+	if err != nil {
+		s.emitLog("SSH 隧道连接失败：", err.Error())
+		log.Printf("SSH 连接失败: %v", err)
+		return 0
+	}
+	t.clients = append(t.clients, client)
+
+	forwarded := 0
+	for _, p := range cfg.Proxies {
+		if !p.Enabled {
+			continue
+		}
+		if p.ProxyType != "tcp" {
+			s.emitLog(fmt.Sprintf("SSH 传输仅支持 TCP 规则，已跳过 [%s]", p.Name))
+			s.setProxyState(p.Name, proxyStateError, "SSH 传输仅支持 TCP 规则")
+			continue
+		}
+		ln, err := client.Listen("tcp", net.JoinHostPort("0.0.0.0", strconv.Itoa(p.RemotePort)))
+		if err != nil {
+			s.emitLog(fmt.Sprintf("[%s] 远程端口 %d 监听失败：%v", p.Name, p.RemotePort, err))
+			s.setProxyState(p.Name, proxyStateError, err.Error())
+			continue
+		}
+		forwarded++
+		s.setProxyState(p.Name, proxyStateRunning, "")
+		s.emitLog(fmt.Sprintf("[%s] 反向转发已建立：%s:%d -> %s:%d", p.Name, cfg.Server.Addr, p.RemotePort, p.LocalIP, p.LocalPort))
+		go serveReverseForward(ctx, ln, net.JoinHostPort(envOr(p.LocalIP), strconv.Itoa(p.LocalPort)))
+	}
+	return forwarded
+}
+
+// stopSSHTunnel 主动断开 SSH 隧道，状态由 Wait 协程统一清理
+func (s *MoleService) stopSSHTunnel() {
+	s.mu.RLock()
+	t := s.sshTunnel
+	s.mu.RUnlock()
+
+	if t != nil {
+		t.close()
+	}
+}
+
+func (t *sshTunnel) close() {
+	t.once.Do(func() {
+		t.cancel()
+		for _, c := range t.clients {
+			_ = c.Close()
+		}
+		close(t.done)
+	})
+}
+
+// keepalive 定期发送 keepalive 请求，及时发现半开连接
+func (t *sshTunnel) keepalive(ctx context.Context, c *ssh.Client) {
+	ticker := time.NewTicker(sshKeepaliveInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if _, _, err := c.SendRequest("keepalive@openssh.com", true, nil); err != nil {
+				log.Printf("SSH keepalive 失败: %v", err)
+				t.close()
+				return
+			}
+		}
+	}
+}
+
+// serveReverseForward 接受远程端口上的连接，逐个转发到本地目标
+func serveReverseForward(ctx context.Context, ln net.Listener, target string) {
+	go func() {
+		<-ctx.Done()
+		_ = ln.Close()
+	}()
+
+	for {
+		remote, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		go func() {
+			defer remote.Close()
+
+			local, err := net.DialTimeout("tcp", target, 5*time.Second)
+			if err != nil {
+				log.Printf("连接本地服务 %s 失败: %v", target, err)
+				return
+			}
+			defer local.Close()
+			pipeConns(remote, local)
+		}()
+	}
+}
+
+// pipeConns 双向拷贝，任一方向结束即关闭两端
+func pipeConns(a, b net.Conn) {
+	done := make(chan struct{}, 2)
+	go func() {
+		_, _ = io.Copy(a, b)
+		done <- struct{}{}
+	}()
+	go func() {
+		_, _ = io.Copy(b, a)
+		done <- struct{}{}
+	}()
+	<-done
+}
+
+// dialSSH 按配置建立 SSH 连接，defaultPort / defaultUser 用于配置留空时的回退
+func dialSSH(host string, cfg SSHConfig, defaultPort int, defaultUser string) (*ssh.Client, error) {
+	user := cfg.User
+	if user == "" {
+		user = defaultUser
+	}
+	if user == "" {
+		return nil, fmt.Errorf("未填写 SSH 用户名")
+	}
+
+	var auths []ssh.AuthMethod
+	if cfg.KeyFile != "" {
+		keyFile := cfg.KeyFile
+		if rest, ok := strings.CutPrefix(keyFile, "~/"); ok {
+			if home, err := os.UserHomeDir(); err == nil {
+				keyFile = filepath.Join(home, rest)
+			}
+		}
+		key, err := os.ReadFile(keyFile)
+		if err != nil {
+			return nil, fmt.Errorf("读取私钥失败: %v", err)
+		}
+		signer, err := ssh.ParsePrivateKey(key)
+		if err != nil {
+			return nil, fmt.Errorf("解析私钥失败 (暂不支持带密码的私钥): %v", err)
+		}
+		auths = append(auths, ssh.PublicKeys(signer))
+	}
+	if cfg.Password != "" {
+		auths = append(auths, ssh.Password(cfg.Password))
+	}
+	if len(auths) == 0 {
+		if defaultUser == "" {
+			return nil, fmt.Errorf("请填写 SSH 密码或私钥路径")
+		}
+		// frps 网关未配置 authorizedKeysFile 时接受任意公钥，用临时密钥即可完成握手
+		signer, err := ephemeralSigner()
+		if err != nil {
+			return nil, err
+		}
+		auths = append(auths, ssh.PublicKeys(signer))
+	}
+
+	port := cfg.Port
+	if port <= 0 {
+		port = defaultPort
+	}
+
+	return ssh.Dial("tcp", net.JoinHostPort(host, strconv.Itoa(port)), &ssh.ClientConfig{
+		User:            user,
+		Auth:            auths,
+		HostKeyCallback: hostKeyCallback(cfg.HostKeyFingerprint),
+		Timeout:         sshDialTimeout,
+	})
+}
+
+// hostKeyCallback 配置了指纹则严格校验；未配置时按首次信任 (TOFU) 校验，见 trustOnFirstUse
+func hostKeyCallback(fingerprint string) ssh.HostKeyCallback {
+	if fingerprint == "" {
+		return trustOnFirstUse
+	}
+	return func(hostname string, remote net.Addr, key ssh.PublicKey) error {
+		actual := ssh.FingerprintSHA256(key)
+		// 兼容只填写了 base64 部分的情况
+		expected := strings.TrimPrefix(strings.TrimSpace(fingerprint), "SHA256:")
+		if _, err := base64.RawStdEncoding.DecodeString(expected); err != nil || "SHA256:"+expected != actual {
+			return fmt.Errorf("主机密钥指纹不匹配，期望 %s，实际 %s", fingerprint, actual)
+		}
+		return nil
+	}
+}
+
+func sshKnownHostsPath() string {
+	return filepath.Join(getAppDataDir(), "config", "ssh_known_hosts")
+}
+
+// knownHostsMu 串行化 ssh_known_hosts 的读写，frps 网关模式会为每条规则各拨一次
+var knownHostsMu sync.Mutex
+
+// trustOnFirstUse 未填写指纹时的主机密钥校验：首次连接把主机密钥记入 ssh_known_hosts (OpenSSH 格式)，
+// 之后密钥与记录不一致即拒绝，避免每次都接受任意密钥
+func trustOnFirstUse(hostname string, remote net.Addr, key ssh.PublicKey) error {
+	knownHostsMu.Lock()
+	defer knownHostsMu.Unlock()
+
+	path := sshKnownHostsPath()
+	_ = os.MkdirAll(filepath.Dir(path), 0700)
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0600)
+	if err != nil {
+		return fmt.Errorf("打开 SSH 已知主机文件失败: %v", err)
+	}
+	defer f.Close()
+
+	check, err := knownhosts.New(path)
+	if err != nil {
+		return fmt.Errorf("读取 SSH 已知主机文件失败: %v", err)
+	}
+	err = check(hostname, remote, key)
+	var keyErr *knownhosts.KeyError
+	if !errors.As(err, &keyErr) {
+		return err
+	}
+	if len(keyErr.Want) > 0 {
+		return fmt.Errorf("SSH 服务器 %s 的主机密钥与首次连接时记录的不一致，可能遭到中间人攻击 (当前 %s)；确认服务器重装过后可删除 %s 中对应的行",
+			hostname, ssh.FingerprintSHA256(key), path)
+	}
+	if _, err := f.WriteString(knownhosts.Line([]string{knownhosts.Normalize(hostname)}, key) + "\n"); err != nil {
+		return fmt.Errorf("记录 SSH 主机密钥失败: %v", err)
+	}
+	log.Printf("首次连接 SSH 服务器 %s，已记录主机密钥指纹 %s", hostname, ssh.FingerprintSHA256(key))
+	return nil
+}
@@ -0,0 +1,145 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+)
+
+// 规则启动顺序：规则可以声明“在某条规则上线之后”和/或“延迟若干秒”再注册
+// 例如数据库规则先上线，应用规则再对外开放。实现方式为分阶段热重载：
+// frpc 启动时先不写入需要等待的规则，条件满足后从 frpc.toml 中放出并热重载
+// 依赖的规则停用或不存在时视为已满足；没有管理接口 (无法热重载) 时不做分阶段
+
+const (
+	startStageInterval = time.Second
+	maxStartDelay      = 600 // 秒
+)
+
+// startStager 记录本次隧道中尚在等待的规则
+type startStager struct {
+	mu    sync.Mutex
+	held  map[string]bool // 规则 ID
+	since time.Time       // 隧道启动时间，没有依赖的规则从这里开始计算延迟
+}
+
+func (st *startStager) isHeld(ruleID string) bool {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	return st.held[ruleID]
+}
+
+// begin 隧道启动时调用，返回需要等待的规则
+func (st *startStager) begin(rules []ProxyRule, enabled bool) []ProxyRule {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	st.held = make(map[string]bool)
+	st.since = time.Now()
+	if !enabled {
+		return nil
+	}
+	var held []ProxyRule
+	for _, p := range rules {
+		if p.Enabled && (p.StartAfter != "" || p.StartDelay > 0) {
+			st.held[p.ID] = true
+			held = append(held, p)
+		}
+	}
+	return held
+}
+
+func (st *startStager) release(ruleID string) {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	delete(st.held, ruleID)
+}
+
+// validateStartOrder 依赖的规则必须存在且不能形成循环
+func validateStartOrder(rules []ProxyRule) error {
+	byID := make(map[string]ProxyRule, len(rules))
+	for _, p := range rules {
+		if p.ID != "" {
+			byID[p.ID] = p
+		}
+	}
+	for _, p := range rules {
+		if p.StartDelay < 0 || p.StartDelay > maxStartDelay {
+			return fmt.Errorf("规则 \"%s\" 的启动延迟应在 0 ~ %d 秒之间", p.Name, maxStartDelay)
+		}
+		seen := map[string]bool{p.ID: true}
+		for dep := p.StartAfter; dep != ""; dep = byID[dep].StartAfter {
+			if _, ok := byID[dep]; !ok {
+				return fmt.Errorf("规则 \"%s\" 依赖的规则不存在", p.Name)
+			}
+			if seen[dep] {
+				return fmt.Errorf("规则 \"%s\" 的启动顺序存在循环依赖", p.Name)
+			}
+			seen[dep] = true
+		}
+	}
+	return nil
+}
+
+// runStartStages 本次隧道期间逐秒检查等待中的规则，满足条件的放出并热重载，全部放出或 ctx 取消时退出
+func (s *MoleService) runStartStages(ctx context.Context, held []ProxyRule) {
+	for _, p := range held {
+		msg := fmt.Sprintf("延迟 %d 秒后启动", p.StartDelay)
+		if p.StartAfter != "" {
+			msg = "等待依赖的规则上线"
+		}
+		s.setProxyState(p.Name, proxyStateWaiting, msg)
+	}
+
+	ticker := time.NewTicker(startStageInterval)
+	defer ticker.Stop()
+	for len(held) > 0 {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		s.mu.RLock()
+		rules := append([]ProxyRule(nil), s.config.Proxies...)
+		s.mu.RUnlock()
+
+		var waiting []ProxyRule
+		released := false
+		for _, p := range held {
+			if !s.startReady(p, rules) {
+				waiting = append(waiting, p)
+				continue
+			}
+			s.startStage.release(p.ID)
+			s.setProxyState(p.Name, proxyStatePending, "")
+			s.emitLog(fmt.Sprintf("[%s] 启动条件已满足，开始注册", p.Name))
+			released = true
+		}
+		held = waiting
+		if released {
+			if err := s.hotReload(); err != nil {
+				log.Printf("分阶段启动热重载失败: %v", err)
+			}
+		}
+	}
+}
+
+// startReady 依赖的规则已上线 (或已停用、已删除) 且延迟已过
+func (s *MoleService) startReady(p ProxyRule, rules []ProxyRule) bool {
+	from := s.startStage.since
+	if p.StartAfter != "" {
+		for _, dep := range rules {
+			if dep.ID != p.StartAfter || !dep.Enabled {
+				continue
+			}
+			st, ok := s.proxyStates.get(dep.ID)
+			if !ok || st.State != proxyStateRunning {
+				return false
+			}
+			from = st.Time
+		}
+	}
+	return time.Since(from) >= time.Duration(p.StartDelay)*time.Second
+}
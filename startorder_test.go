@@ -0,0 +1,48 @@
+package main
+
+import "testing"
+
+func TestValidateStartOrder(t *testing.T) {
+	tests := []struct {
+		name  string
+		rules []ProxyRule
+		ok    bool
+	}{
+		{"无依赖", []ProxyRule{{ID: "a", Name: "A"}, {ID: "b", Name: "B", StartDelay: 5}}, true},
+		{"依赖链", []ProxyRule{{ID: "a", Name: "A"}, {ID: "b", Name: "B", StartAfter: "a"}, {ID: "c", Name: "C", StartAfter: "b"}}, true},
+		{"依赖停用的规则", []ProxyRule{{ID: "a", Name: "A", Enabled: false}, {ID: "b", Name: "B", StartAfter: "a", Enabled: true}}, true},
+		{"依赖不存在", []ProxyRule{{ID: "a", Name: "A", StartAfter: "x"}}, false},
+		{"依赖自身", []ProxyRule{{ID: "a", Name: "A", StartAfter: "a"}}, false},
+		{"循环依赖", []ProxyRule{{ID: "a", Name: "A", StartAfter: "c"}, {ID: "b", Name: "B", StartAfter: "a"}, {ID: "c", Name: "C", StartAfter: "b"}}, false},
+		{"延迟为负", []ProxyRule{{ID: "a", Name: "A", StartDelay: -1}}, false},
+		{"延迟过长", []ProxyRule{{ID: "a", Name: "A", StartDelay: maxStartDelay + 1}}, false},
+		{"延迟上限", []ProxyRule{{ID: "a", Name: "A", StartDelay: maxStartDelay}}, true},
+	}
+	for _, tt := range tests {
+		err := validateStartOrder(tt.rules)
+		if (err == nil) != tt.ok {
+			t.Errorf("%s: validateStartOrder = %v，期望通过: %v", tt.name, err, tt.ok)
+		}
+	}
+}
+
+func TestStartStagerBegin(t *testing.T) {
+	rules := []ProxyRule{
+		{ID: "a", Name: "A", Enabled: true},
+		{ID: "b", Name: "B", Enabled: true, StartAfter: "a"},
+		{ID: "c", Name: "C", Enabled: true, StartDelay: 3},
+		{ID: "d", Name: "D", Enabled: false, StartDelay: 3},
+	}
+	var st startStager
+	if held := st.begin(rules, false); len(held) != 0 || st.isHeld("b") {
+		t.Errorf("不分阶段时不应等待: %v", held)
+	}
+	held := st.begin(rules, true)
+	if len(held) != 2 || !st.isHeld("b") || !st.isHeld("c") || st.isHeld("a") || st.isHeld("d") {
+		t.Errorf("等待的规则 = %v", held)
+	}
+	st.release("b")
+	if st.isHeld("b") {
+		t.Error("放出后仍在等待")
+	}
+}
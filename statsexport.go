@@ -0,0 +1,132 @@
+package main
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/wailsapp/wails/v3/pkg/application"
+	bolt "go.etcd.io/bbolt"
+)
+
+// 统计导出：把每日在线时长和会话记录导出为 CSV 或 JSON，方便用表格或其他工具分析
+// CSV 每天一行隧道汇总 (kind=tunnel) 加每条规则一行 (kind=rule)，带 BOM 以便 Excel 正确识别中文
+
+const (
+	statsFormatCSV  = "csv"
+	statsFormatJSON = "json"
+)
+
+// StatsRange 导出的日期范围 (2006-01-02，含两端)，为空表示不限
+type StatsRange struct {
+	From string `json:"from"`
+	To   string `json:"to"`
+}
+
+// statsExport JSON 导出的内容
+type statsExport struct {
+	Range    StatsRange       `json:"range"`
+	Exported time.Time        `json:"exported"`
+	Daily    []DailyStats     `json:"daily"`
+	Sessions []SessionSummary `json:"sessions"`
+}
+
+// ExportStats 弹出保存对话框导出统计数据，返回保存的路径，用户取消时返回空字符串
+func (s *MoleService) ExportStats(format string, r StatsRange) (string, error) {
+	if err := s.checkUnlocked(); err != nil {
+		return "", err
+	}
+	if format != statsFormatCSV && format != statsFormatJSON {
+		return "", fmt.Errorf("不支持的导出格式: %s", format)
+	}
+	for _, d := range []string{r.From, r.To} {
+		if _, err := time.Parse(time.DateOnly, d); d != "" && err != nil {
+			return "", fmt.Errorf("日期格式应为 YYYY-MM-DD: %s", d)
+		}
+	}
+	to := r.To
+	if to == "" {
+		to = "9999-12-31"
+	}
+
+	daily, err := s.loadDailyStats(r.From, to)
+	if err != nil {
+		return "", fmt.Errorf("读取统计数据失败: %v", err)
+	}
+	var data []byte
+	if format == statsFormatCSV {
+		data, err = statsCSV(daily)
+	} else {
+		var sessions []SessionSummary
+		sessions, err = s.sessionsBetween(r.From, to)
+		if err == nil {
+			data, err = json.MarshalIndent(statsExport{Range: r, Exported: time.Now(), Daily: daily, Sessions: sessions}, "", "  ")
+		}
+	}
+	if err != nil {
+		return "", fmt.Errorf("生成导出内容失败: %v", err)
+	}
+
+	app := application.Get()
+	if app == nil {
+		return "", fmt.Errorf("当前模式不支持文件对话框")
+	}
+	path, err := app.Dialog.SaveFile().
+		SetFilename("mole-stats-"+time.Now().Format("20060102")+"."+format).
+		AddFilter(map[string]string{statsFormatCSV: "CSV 表格", statsFormatJSON: "JSON"}[format], "*."+format).
+		CanCreateDirectories(true).
+		PromptForSingleSelection()
+	if err != nil || path == "" {
+		return "", err
+	}
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		return "", fmt.Errorf("写入文件失败: %v", err)
+	}
+	s.countFeature("export_stats")
+	return path, nil
+}
+
+func statsCSV(daily []DailyStats) ([]byte, error) {
+	var buf bytes.Buffer
+	buf.WriteString("\ufeff")
+	w := csv.NewWriter(&buf)
+	_ = w.Write([]string{"date", "kind", "rule_id", "name", "online_seconds", "sessions"})
+	for _, d := range daily {
+		_ = w.Write([]string{d.Date, "tunnel", "", "", strconv.FormatInt(d.TunnelSeconds, 10), strconv.Itoa(d.Sessions)})
+
+		ids := make([]string, 0, len(d.Proxies))
+		for id := range d.Proxies {
+			ids = append(ids, id)
+		}
+		sort.Strings(ids)
+		for _, id := range ids {
+			p := d.Proxies[id]
+			_ = w.Write([]string{d.Date, "rule", id, p.Name, strconv.FormatInt(p.OnlineSeconds, 10), ""})
+		}
+	}
+	w.Flush()
+	return buf.Bytes(), w.Error()
+}
+
+// sessionsBetween 开始日期落在 [from, to] 内的会话，按时间升序
+func (s *MoleService) sessionsBetween(from, to string) ([]SessionSummary, error) {
+	list := []SessionSummary{}
+	err := s.store.view(func(tx *bolt.Tx) error {
+		return tx.Bucket(bucketSessions).ForEach(func(_, v []byte) error {
+			var sess SessionSummary
+			if json.Unmarshal(v, &sess) != nil {
+				return nil
+			}
+			if day := sess.Start.Local().Format(time.DateOnly); day >= from && day <= to {
+				list = append(list, sess)
+			}
+			return nil
+		})
+	})
+	return list, err
+}
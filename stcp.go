@@ -0,0 +1,61 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+// STCP (secret TCP)：frps 上不开放公网端口，访问方在自己的 frpc 中配置同名的 stcp visitor 与相同的密钥才能连接，
+// 流量仍经 frps 中转，适合只给少数人使用的 SSH、远程桌面等服务
+// 密钥可以直接填写，也可以像 Token 一样填写来源 (env:、cmd: 等，见 secrets.go)，生成 frpc.toml 时再解析
+// frps 默认只允许同一 frp 用户的 visitor 访问，AllowUsers 可放开给其他用户，"*" 为所有用户
+
+const maxSTCPSecretKey = 256
+
+// validateSTCP 保存配置时检查 STCP 规则：启用时必须有密钥，密钥与来源格式正确
+func validateSTCP(p ProxyRule) error {
+	if p.ProxyType != "stcp" {
+		return nil
+	}
+	if len(p.SecretKey) > maxSTCPSecretKey || strings.IndexFunc(p.SecretKey, unicode.IsSpace) >= 0 {
+		return fmt.Errorf("规则 \"%s\" 的密钥不能包含空白字符，最长 %d 个字符", p.Name, maxSTCPSecretKey)
+	}
+	if err := validateSecretSource(p.SecretKeySource); err != nil {
+		return fmt.Errorf("规则 \"%s\": %v", p.Name, err)
+	}
+	if p.Enabled && p.SecretKey == "" && p.SecretKeySource == "" {
+		return fmt.Errorf("STCP 规则 \"%s\" 必须填写密钥", p.Name)
+	}
+	for _, u := range p.AllowUsers {
+		if u != "*" && (u == "" || !reFrpUser.MatchString(u)) {
+			return fmt.Errorf("规则 \"%s\" 允许的用户名无效: %s", p.Name, u)
+		}
+	}
+	return nil
+}
+
+// stcpSecretKey 规则的密钥，设置了来源时按来源解析
+func stcpSecretKey(p ProxyRule) (string, error) {
+	if p.SecretKeySource == "" {
+		return p.SecretKey, nil
+	}
+	key, err := resolveSecret(p.SecretKeySource)
+	if err != nil {
+		return "", fmt.Errorf("获取规则 %s 的密钥失败: %v", p.Name, err)
+	}
+	return key, nil
+}
+
+// stcpSection 写入 frpc.toml 的 STCP 字段
+func stcpSection(item map[string]any, p ProxyRule) error {
+	key, err := stcpSecretKey(p)
+	if err != nil {
+		return err
+	}
+	item["secretKey"] = key
+	if len(p.AllowUsers) > 0 {
+		item["allowUsers"] = p.AllowUsers
+	}
+	return nil
+}
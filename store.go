@@ -0,0 +1,242 @@
+package main
+
+import (
+	"bufio"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+// 本地数据库：运行统计、会话记录、审计日志、配置历史统一存放在 <数据目录>/data/mole.db (bbolt)
+// 界面进程与守护进程都会读写，bbolt 同一时间只允许一个进程打开，所以每次操作都是“打开 → 事务 → 关闭”，
+// 写入频率很低 (分钟级)，这点开销可以忽略；另一进程正在使用时最多等待 storeOpenTimeout
+//
+// 表结构变更通过 storeMigrations 逐版本升级，版本号记在 meta 桶中
+
+const (
+	storeOpenTimeout    = 3 * time.Second
+	storeCompactMinSize = 8 << 20 // 文件超过 8MB 且空闲页过半时启动压缩
+)
+
+var (
+	bucketMeta      = []byte("meta")
+	bucketAudit     = []byte("audit")
+	bucketSessions  = []byte("sessions")
+	bucketRevisions = []byte("revisions")
+	bucketStats     = []byte("stats")
+
+	keySchemaVersion = []byte("schema_version")
+)
+
+// storeMigrations 第 i 项把库从版本 i 升级到 i+1，只能追加不能修改
+var storeMigrations = []func(tx *bolt.Tx) error{
+	// v1：建桶，并导入旧版 audit.log
+	func(tx *bolt.Tx) error {
+		for _, name := range [][]byte{bucketAudit, bucketSessions, bucketRevisions, bucketStats} {
+			if _, err := tx.CreateBucketIfNotExists(name); err != nil {
+				return err
+			}
+		}
+		return importLegacyAudit(tx)
+	},
+}
+
+type kvStore struct {
+	mu sync.Mutex // 进程内串行化，跨进程由 bbolt 的文件锁保证
+}
+
+func storePath() string {
+	return filepath.Join(getAppDataDir(), "data", "mole.db")
+}
+
+func (st *kvStore) open() (*bolt.DB, error) {
+	_ = os.MkdirAll(filepath.Dir(storePath()), 0755)
+	db, err := bolt.Open(storePath(), 0600, &bolt.Options{Timeout: storeOpenTimeout})
+	if err != nil {
+		return nil, fmt.Errorf("打开数据库失败: %v", err)
+	}
+	return db, nil
+}
+
+func (st *kvStore) update(fn func(tx *bolt.Tx) error) error {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	db, err := st.open()
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+	return db.Update(fn)
+}
+
+func (st *kvStore) view(fn func(tx *bolt.Tx) error) error {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	db, err := st.open()
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+	return db.View(fn)
+}
+
+// init 启动时调用：执行未完成的表结构升级
+func (st *kvStore) init() error {
+	from := 0
+	err := st.update(func(tx *bolt.Tx) error {
+		meta, err := tx.CreateBucketIfNotExists(bucketMeta)
+		if err != nil {
+			return err
+		}
+		version := 0
+		if v := meta.Get(keySchemaVersion); len(v) == 8 {
+			version = int(binary.BigEndian.Uint64(v))
+		}
+		from = version
+		if version > len(storeMigrations) {
+			return fmt.Errorf("数据库版本 %d 高于当前程序支持的 %d，请升级 Mole", version, len(storeMigrations))
+		}
+		for ; version < len(storeMigrations); version++ {
+			if err := storeMigrations[version](tx); err != nil {
+				return fmt.Errorf("数据库升级到 v%d 失败: %v", version+1, err)
+			}
+		}
+		return meta.Put(keySchemaVersion, itob(uint64(version)))
+	})
+	if err != nil {
+		return err
+	}
+	// 旧审计文件已在 v1 中导入，改名保留一份
+	if from == 0 {
+		_ = os.Rename(auditPath(), auditPath()+".migrated")
+	}
+	return nil
+}
+
+// compact 文件明显膨胀时重写一份紧凑的副本替换原文件，只在实际运行隧道的进程启动时调用
+func (st *kvStore) compact() error {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+
+	info, err := os.Stat(storePath())
+	if err != nil || info.Size() < storeCompactMinSize {
+		return nil
+	}
+	src, err := st.open()
+	if err != nil {
+		return err
+	}
+	free := int64(src.Stats().FreePageN) * int64(src.Info().PageSize)
+	if free < info.Size()/2 {
+		return src.Close()
+	}
+
+	tmpPath := storePath() + ".compact"
+	_ = os.Remove(tmpPath)
+	dst, err := bolt.Open(tmpPath, 0600, &bolt.Options{Timeout: storeOpenTimeout})
+	if err != nil {
+		src.Close()
+		return fmt.Errorf("压缩数据库失败: %v", err)
+	}
+	err = bolt.Compact(dst, src, 1<<20)
+	dst.Close()
+	src.Close()
+	if err != nil {
+		os.Remove(tmpPath)
+		return fmt.Errorf("压缩数据库失败: %v", err)
+	}
+	if err := os.Rename(tmpPath, storePath()); err != nil {
+		os.Remove(tmpPath)
+		return fmt.Errorf("压缩数据库失败: %v", err)
+	}
+	if after, err := os.Stat(storePath()); err == nil {
+		log.Printf("数据库已压缩: %d KB -> %d KB", info.Size()>>10, after.Size()>>10)
+	}
+	return nil
+}
+
+func itob(v uint64) []byte {
+	b := make([]byte, 8)
+	binary.BigEndian.PutUint64(b, v)
+	return b
+}
+
+// appendJSON 以自增序号为键追加一条记录
+func appendJSON(tx *bolt.Tx, bucket []byte, v any) error {
+	b := tx.Bucket(bucket)
+	seq, err := b.NextSequence()
+	if err != nil {
+		return err
+	}
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	return b.Put(itob(seq), data)
+}
+
+// trimBucket 只保留最新的 keep 条记录
+func trimBucket(tx *bolt.Tx, bucket []byte, keep int) error {
+	b := tx.Bucket(bucket)
+	extra := b.Stats().KeyN - keep
+	// 先收集再删除，边遍历边删会跳过元素
+	var stale [][]byte
+	c := b.Cursor()
+	for k, _ := c.First(); k != nil && len(stale) < extra; k, _ = c.Next() {
+		stale = append(stale, k)
+	}
+	for _, k := range stale {
+		if err := b.Delete(k); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// latestJSON 按插入顺序倒序读取最多 limit 条记录，新的在前
+func latestJSON[T any](st *kvStore, bucket []byte, limit int) ([]T, error) {
+	list := make([]T, 0, limit)
+	err := st.view(func(tx *bolt.Tx) error {
+		c := tx.Bucket(bucket).Cursor()
+		for k, v := c.Last(); k != nil && len(list) < limit; k, v = c.Prev() {
+			var item T
+			if json.Unmarshal(v, &item) != nil {
+				continue
+			}
+			list = append(list, item)
+		}
+		return nil
+	})
+	return list, err
+}
+
+// importLegacyAudit 把旧版按行追加的 audit.log 导入 audit 桶
+func importLegacyAudit(tx *bolt.Tx) error {
+	f, err := os.Open(auditPath())
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		var e AuditEntry
+		if json.Unmarshal(scanner.Bytes(), &e) != nil {
+			continue
+		}
+		if err := appendJSON(tx, bucketAudit, e); err != nil {
+			return err
+		}
+	}
+	return scanner.Err()
+}
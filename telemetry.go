@@ -0,0 +1,202 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sync"
+	"time"
+)
+
+// 匿名使用统计 (默认关闭，需用户在设置中主动开启)
+// 只记录聚合计数：操作系统、架构、版本号、各功能的使用次数，不含地址、Token、规则名等任何可识别信息
+// 统计只在界面进程中记录，守护进程不参与；上报地址为空时 (默认) 只生成本地预览，不会发出任何请求
+
+// telemetryEndpoint 上报地址，构建时注入；为空则从不上报
+var telemetryEndpoint = ""
+
+const telemetryInterval = 24 * time.Hour
+
+// TelemetryReport 将要上报的全部内容，前端预览与实际上报使用同一结构
+type TelemetryReport struct {
+	OS       string         `json:"os"`
+	Arch     string         `json:"arch"`
+	Version  string         `json:"version"`
+	Since    time.Time      `json:"since"` // 本统计周期开始时间
+	Features map[string]int `json:"features"`
+}
+
+// TelemetryInfo 设置页展示的统计状态
+type TelemetryInfo struct {
+	Enabled  bool            `json:"enabled"`
+	Endpoint string          `json:"endpoint"` // 为空表示本版本不会上报
+	Preview  TelemetryReport `json:"preview"`
+}
+
+type telemetryStore struct {
+	mu       sync.Mutex
+	loaded   bool
+	since    time.Time
+	features map[string]int
+}
+
+func telemetryPath() string {
+	return filepath.Join(getAppDataDir(), "config", "telemetry.json")
+}
+
+// load 读取本地计数，调用方需持有 mu
+func (t *telemetryStore) load() {
+	if t.loaded {
+		return
+	}
+	t.loaded = true
+	t.features = make(map[string]int)
+	t.since = time.Now()
+
+	data, err := os.ReadFile(telemetryPath())
+	if err != nil {
+		return
+	}
+	var saved TelemetryReport
+	if json.Unmarshal(data, &saved) == nil && saved.Features != nil {
+		t.features = saved.Features
+		t.since = saved.Since
+	}
+}
+
+// save 写回本地计数，调用方需持有 mu
+func (t *telemetryStore) save() {
+	data, err := json.MarshalIndent(t.report(), "", "  ")
+	if err != nil {
+		return
+	}
+	_ = os.MkdirAll(filepath.Dir(telemetryPath()), 0755)
+	_ = os.WriteFile(telemetryPath(), data, 0644)
+}
+
+// report 生成当前报告，调用方需持有 mu
+func (t *telemetryStore) report() TelemetryReport {
+	features := make(map[string]int, len(t.features))
+	for k, v := range t.features {
+		features[k] = v
+	}
+	return TelemetryReport{
+		OS:       runtime.GOOS,
+		Arch:     runtime.GOARCH,
+		Version:  appVersion,
+		Since:    t.since,
+		Features: features,
+	}
+}
+
+// GetTelemetryInfo 返回统计开关状态与将要上报内容的预览
+func (s *MoleService) GetTelemetryInfo() TelemetryInfo {
+	s.telemetry.mu.Lock()
+	s.telemetry.load()
+	preview := s.telemetry.report()
+	s.telemetry.mu.Unlock()
+
+	return TelemetryInfo{
+		Enabled:  s.telemetryEnabled(),
+		Endpoint: telemetryEndpoint,
+		Preview:  preview,
+	}
+}
+
+// SetTelemetryEnabled 开启或关闭匿名统计，关闭时清空已记录的计数
+func (s *MoleService) SetTelemetryEnabled(enabled bool) error {
+	cfg := s.status().Config
+	if cfg == nil {
+		return fmt.Errorf("未发现有效配置")
+	}
+	newCfg := *cfg
+	newCfg.Preferences.TelemetryEnabled = enabled
+	if err := s.saveUserConfig(newCfg); err != nil {
+		return err
+	}
+
+	if !enabled {
+		s.telemetry.mu.Lock()
+		s.telemetry.loaded = false
+		_ = os.Remove(telemetryPath())
+		s.telemetry.mu.Unlock()
+	}
+	return nil
+}
+
+func (s *MoleService) telemetryEnabled() bool {
+	cfg := s.status().Config
+	return cfg != nil && cfg.Preferences.TelemetryEnabled
+}
+
+// countFeature 记录一次功能使用，未开启统计或在守护进程中时什么都不做
+func (s *MoleService) countFeature(name string) {
+	if appFlags.Daemon || !s.telemetryEnabled() {
+		return
+	}
+	s.telemetry.mu.Lock()
+	defer s.telemetry.mu.Unlock()
+	s.telemetry.load()
+	s.telemetry.features[name]++
+	s.telemetry.save()
+}
+
+// runTelemetryReporter 每天尝试上报一次，成功后开始新的统计周期
+func (s *MoleService) runTelemetryReporter(ctx context.Context) {
+	if telemetryEndpoint == "" || appFlags.Daemon {
+		return
+	}
+
+	ticker := time.NewTicker(telemetryInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if !s.telemetryEnabled() {
+				continue
+			}
+			if err := s.sendTelemetry(); err != nil {
+				log.Printf("匿名统计上报失败: %v", err)
+			}
+		}
+	}
+}
+
+func (s *MoleService) sendTelemetry() error {
+	s.telemetry.mu.Lock()
+	s.telemetry.load()
+	report := s.telemetry.report()
+	s.telemetry.mu.Unlock()
+
+	if len(report.Features) == 0 {
+		return nil
+	}
+	data, err := json.Marshal(report)
+	if err != nil {
+		return err
+	}
+
+	client := outboundClient(10 * time.Second)
+	resp, err := client.Post(telemetryEndpoint, "application/json", bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		return fmt.Errorf("服务器返回 %s", resp.Status)
+	}
+
+	s.telemetry.mu.Lock()
+	s.telemetry.features = make(map[string]int)
+	s.telemetry.since = time.Now()
+	s.telemetry.save()
+	s.telemetry.mu.Unlock()
+	return nil
+}
@@ -0,0 +1,66 @@
+package main
+
+import (
+	"os"
+	"os/user"
+	"regexp"
+	"runtime"
+	"strings"
+	"sync"
+)
+
+// 模板变量：规则名称与服务器备注中可以写 {{hostname}}、{{username}}、{{os}}，生成 frpc.toml 时展开
+// 同一份配置分发到多台机器时，代理名称各不相同，不会在 frps 上互相顶替
+// 配置文件与界面中保留原文，只有生成的 frpc.toml、日志匹配与托盘显示使用展开后的值
+
+var reTemplateVar = regexp.MustCompile(`\{\{\s*(\w+)\s*\}\}`)
+
+// 变量值只保留字母、数字、点、下划线与连字符，其余替换为连字符，保证可以作为代理名称
+var reTemplateUnsafe = regexp.MustCompile(`[^A-Za-z0-9._-]+`)
+
+var templateVars = sync.OnceValue(func() map[string]string {
+	host, _ := os.Hostname()
+	// 去掉域名后缀：mbp.local -> mbp
+	host, _, _ = strings.Cut(host, ".")
+	name := ""
+	if u, err := user.Current(); err == nil {
+		name = u.Username
+		// Windows 返回 DOMAIN\user
+		if i := strings.LastIndex(name, `\`); i >= 0 {
+			name = name[i+1:]
+		}
+	}
+	vars := map[string]string{
+		"hostname": host,
+		"username": name,
+		"os":       runtime.GOOS,
+	}
+	for k, v := range vars {
+		v = strings.Trim(reTemplateUnsafe.ReplaceAllString(strings.ToLower(v), "-"), "-")
+		if v == "" {
+			v = "unknown"
+		}
+		vars[k] = v
+	}
+	return vars
+})
+
+// expandTemplate 展开模板变量，未知的变量原样保留
+func expandTemplate(s string) string {
+	if !strings.Contains(s, "{{") {
+		return s
+	}
+	vars := templateVars()
+	return reTemplateVar.ReplaceAllStringFunc(s, func(m string) string {
+		name := reTemplateVar.FindStringSubmatch(m)[1]
+		if v, ok := vars[strings.ToLower(name)]; ok {
+			return v
+		}
+		return m
+	})
+}
+
+// GetTemplateVars 返回本机的模板变量值，供界面提示与预览
+func (s *MoleService) GetTemplateVars() map[string]string {
+	return templateVars()
+}
@@ -0,0 +1,221 @@
+package main
+
+import (
+	"fmt"
+	"runtime"
+	"sync"
+
+	"github.com/wailsapp/wails/v3/pkg/application"
+	"github.com/wailsapp/wails/v3/pkg/icons"
+)
+
+// 托盘菜单：随隧道状态重建，显示当前服务器与最近一条状态，连接 / 断开按当前状态切换
+// 文案来自 i18n.go 的目录；状态变化通过事件总线得知，附着守护进程时事件同样会转发过来
+
+// 菜单中状态行的最大长度，断线告警可能带有整行 frpc 日志
+const trayStatusMaxLen = 40
+
+type trayMenu struct {
+	app  *application.App
+	ms   *MoleService
+	tray *application.SystemTray
+
+	mu    sync.Mutex // 串行化重建
+	last  string     // 最近一条状态，来自隧道告警
+	theme string     // 当前图标对应的任务栏深浅，见 traytheme.go
+}
+
+func newTrayMenu(app *application.App, ms *MoleService) *trayMenu {
+	t := &trayMenu{app: app, ms: ms, tray: app.SystemTray.New()}
+
+	// Use the template icon on macOS so the clock respects light/dark modes.
+	// Windows / Linux 按任务栏深浅选择图标
+	if runtime.GOOS == "darwin" {
+		t.tray.SetTemplateIcon(icons.SystrayMacTemplate)
+	} else {
+		t.applyIcon()
+		go t.watchTheme()
+	}
+	t.tray.SetTooltip(tr("tray.tooltip"))
+
+	// 左键点击的行为由偏好设置决定，默认显示窗口
+	t.tray.OnClick(t.onClick)
+
+	ms.bus.listen(func(name string, data any) {
+		switch name {
+		case "tunnel-alert":
+			if a, ok := data.(TunnelAlert); ok {
+				msg := a.Message
+				if r := []rune(msg); len(r) > trayStatusMaxLen {
+					msg = string(r[:trayStatusMaxLen]) + "…"
+				}
+				t.mu.Lock()
+				t.last = msg
+				t.mu.Unlock()
+			}
+		case "config-save":
+			go t.applyIcon()
+		case "frp-status", "tunnel-state":
+		default:
+			return
+		}
+		go t.rebuild()
+	})
+	t.rebuild()
+	return t
+}
+
+// 托盘左键点击的行为
+const (
+	trayClickShow   = "show"   // 显示窗口 (默认)
+	trayClickToggle = "toggle" // 连接 / 断开隧道
+	trayClickStatus = "status" // 弹出状态菜单
+	trayClickMini   = "mini"   // 显示 / 隐藏迷你状态窗
+)
+
+var trayClickActions = map[string]bool{"": true, trayClickShow: true, trayClickToggle: true, trayClickStatus: true, trayClickMini: true}
+
+func (t *trayMenu) onClick() {
+	action := ""
+	select {
+	case <-t.ms.initWait:
+		if cfg := t.ms.status().Config; cfg != nil {
+			action = cfg.Preferences.TrayClickAction
+		}
+	default:
+		// 初始化尚未完成，不阻塞托盘回调
+	}
+
+	switch action {
+	case trayClickToggle:
+		go func() {
+			if t.ms.status().IsRunning {
+				t.ms.Disconnect()
+			} else {
+				t.ms.Connect()
+			}
+			t.rebuild()
+		}()
+	case trayClickStatus:
+		t.tray.OpenMenu()
+	case trayClickMini:
+		toggleMiniWindow()
+	default:
+		showMainWindow()
+	}
+}
+
+// SetTrayClickAction 设置托盘左键点击的行为：show 显示窗口、toggle 连接 / 断开、status 弹出状态菜单、mini 迷你状态窗
+func (s *MoleService) SetTrayClickAction(action string) error {
+	if !trayClickActions[action] {
+		return fmt.Errorf("无效的托盘点击行为: %s", action)
+	}
+	cfg := s.status().Config
+	if cfg == nil {
+		return fmt.Errorf("未发现有效配置")
+	}
+	newCfg := *cfg
+	newCfg.Preferences.TrayClickAction = action
+	return s.saveUserConfig(newCfg)
+}
+
+func showMainWindow() {
+	if manager.MainWindow != nil {
+		manager.MainWindow.Show()
+		manager.MainWindow.Focus()
+	}
+}
+
+// rebuild 按当前状态重新生成菜单；首次调用时服务可能仍在初始化，先给出不依赖状态的菜单
+func (t *trayMenu) rebuild() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	menu := t.app.NewMenu()
+	select {
+	case <-t.ms.initWait:
+		t.addStatusItems(menu)
+	default:
+		go func() {
+			<-t.ms.initWait
+			t.rebuild()
+			t.applyIcon()
+		}()
+	}
+
+	menu.Add(tr("tray.show")).OnClick(func(ctx *application.Context) {
+		showMainWindow()
+	})
+	menu.Add(tr("tray.mini")).OnClick(func(ctx *application.Context) {
+		toggleMiniWindow()
+	})
+	menu.AddSeparator()
+	menu.Add(tr("tray.quit")).OnClick(func(ctx *application.Context) {
+		t.app.Quit()
+	})
+	menu.Add(tr("tray.quit_disconnect")).OnClick(func(ctx *application.Context) {
+		// 客户端模式下“退出”只关闭界面，隧道仍在后台运行
+		t.ms.stopDaemon()
+		t.app.Quit()
+	})
+	t.tray.SetMenu(menu)
+}
+
+// addStatusItems 状态行与连接开关，调用方需持有 t.mu
+func (t *trayMenu) addStatusItems(menu *application.Menu) {
+	st := t.ms.status()
+	if st.Config == nil {
+		menu.Add(tr("tray.not_configured")).SetEnabled(false)
+		menu.AddSeparator()
+		return
+	}
+
+	server := expandTemplate(st.Config.Server.Remark)
+	if server == "" {
+		server = st.Config.Server.Addr
+	}
+	if st.IsRunning {
+		menu.Add(tr("tray.connected", server)).SetEnabled(false)
+	} else {
+		menu.Add(tr("tray.disconnected", server)).SetEnabled(false)
+	}
+	if t.last != "" {
+		menu.Add(tr("tray.last_status", t.last)).SetEnabled(false)
+	}
+	if st.IsRunning {
+		t.addRulesMenu(menu, st.Config)
+	}
+	menu.AddSeparator()
+
+	if len(st.Config.Profiles) > 1 {
+		t.addProfileMenu(menu, st.Config)
+	}
+	if st.IsRunning {
+		menu.Add(tr("tray.disconnect")).OnClick(func(ctx *application.Context) {
+			t.ms.Disconnect()
+			go t.rebuild()
+		})
+	} else {
+		menu.Add(tr("tray.connect")).OnClick(func(ctx *application.Context) {
+			t.ms.Connect()
+		})
+	}
+}
+
+// addProfileMenu 有多个命名配置时列出，点击即切换并连接
+func (t *trayMenu) addProfileMenu(menu *application.Menu, cfg *UserConfig) {
+	sub := menu.AddSubmenu(tr("tray.profiles"))
+	for _, p := range cfg.Profiles {
+		name := p.Name
+		current := p.Addr == cfg.Server.Addr && p.Port == cfg.Server.Port && p.Transport == cfg.Server.Transport
+		sub.AddCheckbox(name, current).OnClick(func(ctx *application.Context) {
+			go func() {
+				if err := t.ms.SwitchProfile(name, true); err != nil {
+					t.ms.emitLog("切换配置失败：", err.Error())
+				}
+				t.rebuild()
+			}()
+		})
+	}
+	menu.AddSeparator()
+}
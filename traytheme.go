@@ -0,0 +1,85 @@
+package main
+
+import (
+	"fmt"
+	"runtime"
+	"time"
+
+	"github.com/wailsapp/wails/v3/pkg/icons"
+)
+
+// 托盘图标配色：macOS 使用模板图标由系统着色；Windows / Linux 需要自己按任务栏深浅选择图标，
+// 否则深色任务栏上的深色图标几乎看不见。自动模式下定期检测系统主题，也可在偏好设置中手动指定
+
+const (
+	trayThemeAuto  = ""
+	trayThemeLight = "light" // 浅色任务栏，使用深色图标
+	trayThemeDark  = "dark"  // 深色任务栏，使用浅色图标
+
+	trayThemeCheckInterval = 30 * time.Second
+)
+
+var trayThemes = map[string]bool{trayThemeAuto: true, trayThemeLight: true, trayThemeDark: true}
+
+// resolveTrayTheme 手动设置优先，自动模式下检测失败时按浅色处理
+func resolveTrayTheme(pref string) string {
+	if pref != trayThemeAuto {
+		return pref
+	}
+	if dark, ok := taskbarDark(); ok && dark {
+		return trayThemeDark
+	}
+	return trayThemeLight
+}
+
+// applyIcon 按当前主题设置图标，主题未变化时不重复设置
+func (t *trayMenu) applyIcon() {
+	if runtime.GOOS == "darwin" {
+		return
+	}
+	pref := trayThemeAuto
+	select {
+	case <-t.ms.initWait:
+		if cfg := t.ms.status().Config; cfg != nil {
+			pref = cfg.Preferences.TrayIconTheme
+		}
+	default:
+	}
+	theme := resolveTrayTheme(pref)
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if theme == t.theme {
+		return
+	}
+	t.theme = theme
+	if theme == trayThemeDark {
+		t.tray.SetIcon(icons.SystrayDark)
+	} else {
+		t.tray.SetIcon(icons.SystrayLight)
+	}
+}
+
+// watchTheme 定期检测系统主题，切换深浅色后及时更换图标
+func (t *trayMenu) watchTheme() {
+	if runtime.GOOS == "darwin" {
+		return
+	}
+	for range time.Tick(trayThemeCheckInterval) {
+		t.applyIcon()
+	}
+}
+
+// SetTrayIconTheme 设置托盘图标配色：空字符串为跟随系统，light / dark 为手动指定任务栏深浅
+func (s *MoleService) SetTrayIconTheme(theme string) error {
+	if !trayThemes[theme] {
+		return fmt.Errorf("无效的托盘图标配色: %s", theme)
+	}
+	cfg := s.status().Config
+	if cfg == nil {
+		return fmt.Errorf("未发现有效配置")
+	}
+	newCfg := *cfg
+	newCfg.Preferences.TrayIconTheme = theme
+	return s.saveUserConfig(newCfg)
+}
@@ -0,0 +1,37 @@
+//go:build !windows
+
+package main
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// taskbarDark Linux 桌面环境没有统一的接口：GNOME 系读 gsettings 的 color-scheme / gtk-theme，
+// KDE 读 kdeglobals 的配色方案名称，都取不到时返回 ok=false
+func taskbarDark() (dark, ok bool) {
+	if out, err := exec.Command("gsettings", "get", "org.gnome.desktop.interface", "color-scheme").Output(); err == nil {
+		if v := strings.Trim(strings.TrimSpace(string(out)), "'"); v != "default" && v != "" {
+			return v == "prefer-dark", true
+		}
+	}
+	if out, err := exec.Command("gsettings", "get", "org.gnome.desktop.interface", "gtk-theme").Output(); err == nil {
+		return strings.Contains(strings.ToLower(string(out)), "dark"), true
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return false, false
+	}
+	data, err := os.ReadFile(filepath.Join(home, ".config", "kdeglobals"))
+	if err != nil {
+		return false, false
+	}
+	for _, line := range strings.Split(string(data), "\n") {
+		if v, found := strings.CutPrefix(strings.TrimSpace(line), "ColorScheme="); found {
+			return strings.Contains(strings.ToLower(v), "dark"), true
+		}
+	}
+	return false, false
+}
@@ -0,0 +1,18 @@
+package main
+
+import "golang.org/x/sys/windows/registry"
+
+// taskbarDark 任务栏的深浅由 SystemUsesLightTheme 决定 (应用窗口的深浅是另一个值 AppsUseLightTheme)
+func taskbarDark() (dark, ok bool) {
+	k, err := registry.OpenKey(registry.CURRENT_USER, `Software\Microsoft\Windows\CurrentVersion\Themes\Personalize`, registry.QUERY_VALUE)
+	if err != nil {
+		return false, false
+	}
+	defer k.Close()
+	v, _, err := k.GetIntegerValue("SystemUsesLightTheme")
+	if err != nil {
+		// Windows 10 1903 之前没有浅色任务栏
+		return true, true
+	}
+	return v == 0, true
+}
@@ -0,0 +1,103 @@
+package main
+
+import (
+	"os"
+	"regexp"
+	"sync"
+	"time"
+)
+
+// 隧道告警：把“意外断开 / 建立 / 断线后恢复”整理成 tunnel-alert 事件，供消息通知、邮件等集成订阅
+// 用户主动断开不算告警；frpc 自身的断线重连从日志识别，进程退出与 SSH 隧道断开在各自的等待协程中上报
+
+const (
+	alertDown      = "down"      // 隧道意外断开
+	alertUp        = "up"        // 启动后首次登录成功
+	alertReconnect = "reconnect" // 断开后重新连上
+)
+
+var (
+	// [I] [client/service.go:295] [d3a1b2c4] login to server success, get run id [d3a1b2c4]
+	reLoginSuccess = regexp.MustCompile(`login to (the )?server success`)
+	// [W] [client/service.go:301] connect to server error: dial tcp ...: connection refused
+	// [I] [client/service.go:156] [d3a1b2c4] try to reconnect to server...
+	reServerLost = regexp.MustCompile(`(connect to server error|login to the server failed|try to reconnect to server)`)
+)
+
+// TunnelAlert 隧道告警事件
+type TunnelAlert struct {
+	Kind    string    `json:"kind"`
+	Host    string    `json:"host"` // 本机名，方便多台机器共用一个通知渠道时区分
+	Message string    `json:"message"`
+	Time    time.Time `json:"time"`
+}
+
+type tunnelHealth struct {
+	mu     sync.Mutex
+	up     bool // 当前是否已登录到服务器
+	everUp bool // 本次启动后是否登录成功过
+	down   bool // 已上报断开，尚未恢复
+}
+
+// resetTunnelHealth 每次启动隧道时调用
+func (s *MoleService) resetTunnelHealth() {
+	s.health.mu.Lock()
+	s.health.up, s.health.everUp, s.health.down = false, false, false
+	s.health.mu.Unlock()
+	s.stopRequested.Store(false)
+}
+
+// markTunnelUp 登录成功：首次为 up，断开后恢复为 reconnect
+func (s *MoleService) markTunnelUp(message string) {
+	s.health.mu.Lock()
+	kind := ""
+	switch {
+	case s.health.down:
+		kind = alertReconnect
+	case !s.health.everUp:
+		kind = alertUp
+	}
+	s.health.up, s.health.everUp, s.health.down = true, true, false
+	s.health.mu.Unlock()
+	s.reconnect.reset()
+	s.setTunnelState(tunnelConnected, "")
+
+	if kind != "" {
+		s.raiseAlert(kind, message)
+	}
+}
+
+// markTunnelDown 连接丢失，同一次断开只上报一次；用户主动断开时不上报
+func (s *MoleService) markTunnelDown(message string) {
+	if s.stopRequested.Load() {
+		return
+	}
+	// 进程退出时随后由 tunnelExited 记为 error，这里先记为正在重连
+	s.setTunnelState(tunnelReconnecting, message)
+	s.health.mu.Lock()
+	if s.health.down {
+		s.health.mu.Unlock()
+		return
+	}
+	s.health.up, s.health.down = false, true
+	s.health.mu.Unlock()
+
+	s.raiseAlert(alertDown, message)
+}
+
+// trackConnLog 从 frpc 日志识别与服务器的连接状态
+func (s *MoleService) trackConnLog(line string) {
+	s.selfTestLoginLog(line)
+	switch {
+	case reLoginSuccess.MatchString(line):
+		s.markTunnelUp("已连接到服务器")
+		s.saveLastGoodToml()
+	case reServerLost.MatchString(line):
+		s.markTunnelDown("与服务器的连接中断: " + line)
+	}
+}
+
+func (s *MoleService) raiseAlert(kind, message string) {
+	host, _ := os.Hostname()
+	s.events.Emit("tunnel-alert", TunnelAlert{Kind: kind, Host: host, Message: message, Time: time.Now()})
+}
@@ -0,0 +1,136 @@
+package main
+
+import (
+	"log"
+	"sync"
+	"time"
+)
+
+// 隧道状态机：isRunning 只说明 frpc / SSH 隧道进程在不在，这里把一次连接的完整过程整理为明确的状态，
+// 随状态接口与 tunnel-state 事件下发，界面不必再根据“启动中...”之类的提示去猜
+//
+//	idle ─Connect→ preparing ─进程启动→ connecting ─登录成功→ connected
+//	connected ─连接中断→ reconnecting ─恢复→ connected
+//	任意运行状态 ─Disconnect→ stopping ─进程退出→ idle
+//	preparing / 运行状态 ─启动失败或进程意外退出→ error ─按重试策略重连→ reconnecting / preparing
+//
+// 状态由进程启停与 frpc 日志 (tunnelalert.go) 驱动；不在转换表中的变化视为过期事件，记录日志后忽略
+
+const (
+	tunnelIdle         = "idle"
+	tunnelPreparing    = "preparing"    // 校验证书、释放 frpc、生成配置
+	tunnelConnecting   = "connecting"   // 进程已启动，尚未登录成功
+	tunnelConnected    = "connected"    // 已登录服务器
+	tunnelReconnecting = "reconnecting" // 连接中断，frpc 正在重连或等待按重试策略重新启动
+	tunnelStopping     = "stopping"     // 用户断开，等待进程退出
+	tunnelError        = "error"        // 启动失败或进程意外退出，原因见 LastError
+)
+
+var tunnelTransitions = map[string][]string{
+	tunnelIdle:         {tunnelPreparing},
+	tunnelPreparing:    {tunnelConnecting, tunnelConnected, tunnelStopping, tunnelError, tunnelIdle},
+	tunnelConnecting:   {tunnelConnected, tunnelReconnecting, tunnelStopping, tunnelError, tunnelIdle},
+	tunnelConnected:    {tunnelReconnecting, tunnelStopping, tunnelError, tunnelIdle},
+	tunnelReconnecting: {tunnelConnected, tunnelPreparing, tunnelStopping, tunnelError, tunnelIdle},
+	tunnelStopping:     {tunnelIdle, tunnelError},
+	tunnelError:        {tunnelPreparing, tunnelReconnecting, tunnelStopping, tunnelIdle},
+}
+
+// TunnelStateEvent tunnel-state 事件
+type TunnelStateEvent struct {
+	State    string    `json:"state"`
+	Previous string    `json:"previous"`
+	Error    string    `json:"error,omitempty"` // 进入 error / reconnecting 时的原因
+	Time     time.Time `json:"time"`
+}
+
+type tunnelTracker struct {
+	mu             sync.Mutex
+	state          string
+	lastError      string
+	connectedSince time.Time
+	lastChange     time.Time
+}
+
+func canTransition(from, to string) bool {
+	for _, s := range tunnelTransitions[from] {
+		if s == to {
+			return true
+		}
+	}
+	return false
+}
+
+// transition 尝试转换状态，状态未变或转换不合法时返回 false
+func (t *tunnelTracker) transition(to, reason string) (TunnelStateEvent, bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	from := t.state
+	if from == "" {
+		from = tunnelIdle
+	}
+	if from == to {
+		return TunnelStateEvent{}, false
+	}
+	if !canTransition(from, to) {
+		log.Printf("忽略隧道状态变化 %s -> %s", from, to)
+		return TunnelStateEvent{}, false
+	}
+	t.state = to
+	t.lastChange = time.Now()
+	// 连接时长只统计本次登录成功以来，重连恢复后重新计算
+	if to == tunnelConnected {
+		t.connectedSince = t.lastChange
+	} else {
+		t.connectedSince = time.Time{}
+	}
+	if reason != "" && (to == tunnelError || to == tunnelReconnecting) {
+		t.lastError = reason
+	}
+	return TunnelStateEvent{State: to, Previous: from, Error: reason, Time: t.lastChange}, true
+}
+
+func (t *tunnelTracker) current() string {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.state == "" {
+		return tunnelIdle
+	}
+	return t.state
+}
+
+// fill 把状态写入 ServiceStatus
+func (t *tunnelTracker) fill(st *ServiceStatus) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	st.TunnelState = t.state
+	if st.TunnelState == "" {
+		st.TunnelState = tunnelIdle
+	}
+	st.LastError = t.lastError
+	if !t.connectedSince.IsZero() {
+		since := t.connectedSince
+		st.ConnectedSince = &since
+	}
+	if !t.lastChange.IsZero() {
+		change := t.lastChange
+		st.LastStateChange = &change
+	}
+}
+
+// setTunnelState 转换状态并推送 tunnel-state 事件
+func (s *MoleService) setTunnelState(state, reason string) {
+	if ev, ok := s.tunnel.transition(state, reason); ok {
+		s.events.Emit("tunnel-state", ev)
+		s.selfTestTunnelState(state, reason)
+	}
+}
+
+// tunnelExited 进程退出：用户主动断开时回到 idle，否则记为 error，随后按重试策略重连
+func (s *MoleService) tunnelExited(reason string) {
+	if s.stopRequested.Load() {
+		s.setTunnelState(tunnelIdle, "")
+		return
+	}
+	s.setTunnelState(tunnelError, reason)
+}
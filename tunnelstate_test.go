@@ -0,0 +1,79 @@
+package main
+
+import "testing"
+
+func TestTunnelTransitions(t *testing.T) {
+	states := []string{tunnelIdle, tunnelPreparing, tunnelConnecting, tunnelConnected, tunnelReconnecting, tunnelStopping, tunnelError}
+	for _, from := range states {
+		if _, ok := tunnelTransitions[from]; !ok {
+			t.Errorf("转换表缺少状态 %s", from)
+		}
+	}
+	for from, tos := range tunnelTransitions {
+		for _, to := range tos {
+			if to == from {
+				t.Errorf("%s 不应转换到自身", from)
+			}
+			if _, ok := tunnelTransitions[to]; !ok {
+				t.Errorf("%s -> %s 的目标状态不在转换表中", from, to)
+			}
+		}
+	}
+
+	tests := []struct {
+		from, to string
+		want     bool
+	}{
+		{tunnelIdle, tunnelPreparing, true},
+		{tunnelIdle, tunnelConnected, false},
+		{tunnelIdle, tunnelError, false},
+		{tunnelPreparing, tunnelConnecting, true},
+		{tunnelConnecting, tunnelConnected, true},
+		{tunnelConnected, tunnelReconnecting, true},
+		{tunnelConnected, tunnelPreparing, false},
+		{tunnelReconnecting, tunnelConnected, true},
+		{tunnelStopping, tunnelIdle, true},
+		{tunnelStopping, tunnelConnected, false},
+		{tunnelError, tunnelPreparing, true},
+		{tunnelError, tunnelConnected, false},
+	}
+	for _, tt := range tests {
+		if got := canTransition(tt.from, tt.to); got != tt.want {
+			t.Errorf("canTransition(%s, %s) = %v，期望 %v", tt.from, tt.to, got, tt.want)
+		}
+	}
+}
+
+func TestTunnelTrackerTransition(t *testing.T) {
+	var tr tunnelTracker
+	if tr.current() != tunnelIdle {
+		t.Fatalf("初始状态 = %s", tr.current())
+	}
+	if _, ok := tr.transition(tunnelIdle, ""); ok {
+		t.Error("状态未变时不应转换")
+	}
+	if _, ok := tr.transition(tunnelConnected, ""); ok {
+		t.Error("idle 不能直接转换到 connected")
+	}
+
+	for _, to := range []string{tunnelPreparing, tunnelConnecting, tunnelConnected} {
+		if _, ok := tr.transition(to, ""); !ok {
+			t.Fatalf("转换到 %s 失败", to)
+		}
+	}
+	var st ServiceStatus
+	tr.fill(&st)
+	if st.ConnectedSince == nil {
+		t.Error("connected 状态应记录连接开始时间")
+	}
+
+	ev, ok := tr.transition(tunnelError, "登录失败")
+	if !ok || ev.Previous != tunnelConnected || ev.Error != "登录失败" {
+		t.Fatalf("转换到 error = %+v, %v", ev, ok)
+	}
+	st = ServiceStatus{}
+	tr.fill(&st)
+	if st.ConnectedSince != nil || st.LastError != "登录失败" {
+		t.Errorf("error 状态 = %v / %q", st.ConnectedSince, st.LastError)
+	}
+}
@@ -0,0 +1,212 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"runtime"
+	"strings"
+	"time"
+)
+
+// 检查更新：从 GitHub Releases 查询新版本，只提示与下载，不自动替换程序
+// frpc 随安装包内置，Mole 的版本即包含了对应的 frpc 版本，两者一起走同一个发布渠道
+// stable 只看正式版；beta 同时接收预发布版本。从 beta 切回 stable 时，如果当前是预发布版本，
+// 提示安装最新的正式版 (可能比当前版本号低)，作为回退路径
+
+const (
+	updateChannelStable = ""
+	updateChannelBeta   = "beta"
+
+	updateReleasesURL = "https://api.github.com/repos/littletow/mole-go/releases?per_page=30"
+	updateTimeout     = 15 * time.Second
+	updateMaxResponse = 2 << 20
+)
+
+// UpdateAsset 适用于本机系统与架构的安装包
+type UpdateAsset struct {
+	Name   string `json:"name"`
+	URL    string `json:"url"`
+	Size   int64  `json:"size"`
+	SHA256 string `json:"sha256"` // 发布页公布的校验值，没有时从校验文件中查找
+
+	checksumsURL string // 同一发布中的 SHA256SUMS / checksums.txt
+}
+
+// UpdateInfo 检查结果
+type UpdateInfo struct {
+	CurrentVersion string       `json:"currentVersion"`
+	Channel        string       `json:"channel"` // 空为正式版，beta 为测试版
+	LatestVersion  string       `json:"latestVersion"`
+	Available      bool         `json:"available"`  // 有可安装的版本
+	Downgrade      bool         `json:"downgrade"`  // 当前为预发布版本、渠道为正式版，提示回退到最新正式版
+	Prerelease     bool         `json:"prerelease"` // 找到的版本是预发布版本
+	PageURL        string       `json:"pageURL"`
+	Notes          string       `json:"notes"`
+	PublishedAt    time.Time    `json:"publishedAt"`
+	Asset          *UpdateAsset `json:"asset"` // 没有匹配本机的安装包时为空，只能打开发布页
+}
+
+// githubRelease GitHub Releases API 中用到的字段
+type githubRelease struct {
+	TagName     string    `json:"tag_name"`
+	Name        string    `json:"name"`
+	Body        string    `json:"body"`
+	HTMLURL     string    `json:"html_url"`
+	Draft       bool      `json:"draft"`
+	Prerelease  bool      `json:"prerelease"`
+	PublishedAt time.Time `json:"published_at"`
+	Assets      []struct {
+		Name   string `json:"name"`
+		URL    string `json:"browser_download_url"`
+		Size   int64  `json:"size"`
+		Digest string `json:"digest"` // 形如 sha256:xxxx
+	} `json:"assets"`
+}
+
+func validateUpdateChannel(channel string) error {
+	if channel != updateChannelStable && channel != updateChannelBeta {
+		return fmt.Errorf("无效的更新渠道: %s", channel)
+	}
+	return nil
+}
+
+// isPrerelease 版本号带 -beta.1 之类的后缀
+func isPrerelease(v string) bool {
+	return strings.Contains(strings.TrimPrefix(v, "v"), "-")
+}
+
+// compareReleaseVersions 在 compareVersions 的基础上区分预发布版本：1.2.0-beta.1 < 1.2.0
+func compareReleaseVersions(a, b string) int {
+	if c := compareVersions(a, b); c != 0 {
+		return c
+	}
+	pa, pb := isPrerelease(a), isPrerelease(b)
+	switch {
+	case pa && !pb:
+		return -1
+	case !pa && pb:
+		return 1
+	case pa && pb:
+		return strings.Compare(a, b)
+	}
+	return 0
+}
+
+// releaseAsset 按文件名中的系统与架构挑选安装包
+func releaseAsset(r githubRelease) *UpdateAsset {
+	osNames := map[string][]string{
+		"windows": {"windows", "win"},
+		"darwin":  {"darwin", "macos", "mac"},
+		"linux":   {"linux"},
+	}[runtime.GOOS]
+	var checksums string
+	for _, a := range r.Assets {
+		if isChecksumsAsset(a.Name) {
+			checksums = a.URL
+		}
+	}
+	for _, a := range r.Assets {
+		name := strings.ToLower(a.Name)
+		if !strings.Contains(name, runtime.GOARCH) || isChecksumsAsset(a.Name) {
+			continue
+		}
+		for _, o := range osNames {
+			if strings.Contains(name, o) {
+				sum, _ := strings.CutPrefix(a.Digest, "sha256:")
+				return &UpdateAsset{Name: a.Name, URL: a.URL, Size: a.Size, SHA256: strings.ToLower(sum), checksumsURL: checksums}
+			}
+		}
+	}
+	return nil
+}
+
+// isChecksumsAsset 发布中附带的校验文件
+func isChecksumsAsset(name string) bool {
+	name = strings.ToLower(name)
+	return strings.Contains(name, "checksums") || strings.Contains(name, "sha256sums")
+}
+
+func fetchReleases(ctx context.Context) ([]githubRelease, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, updateReleasesURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "application/vnd.github+json")
+	req.Header.Set("User-Agent", "mole/"+appVersion)
+	resp, err := outboundClient(updateTimeout).Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("查询新版本失败: %v", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("查询新版本失败: 服务器返回 %s", resp.Status)
+	}
+	var releases []githubRelease
+	if err := json.NewDecoder(io.LimitReader(resp.Body, updateMaxResponse)).Decode(&releases); err != nil {
+		return nil, fmt.Errorf("解析版本列表失败: %v", err)
+	}
+	return releases, nil
+}
+
+// latestRelease 渠道内版本号最高的发布
+func latestRelease(releases []githubRelease, channel string) *githubRelease {
+	var best *githubRelease
+	for i := range releases {
+		r := &releases[i]
+		if r.Draft || (r.Prerelease && channel != updateChannelBeta) {
+			continue
+		}
+		if best == nil || compareReleaseVersions(r.TagName, best.TagName) > 0 {
+			best = r
+		}
+	}
+	return best
+}
+
+// CheckForUpdates 按偏好设置中的渠道检查新版本
+func (s *MoleService) CheckForUpdates() (UpdateInfo, error) {
+	info := UpdateInfo{CurrentVersion: appVersion}
+	if cfg := s.status().Config; cfg != nil {
+		info.Channel = cfg.Preferences.UpdateChannel
+	}
+	releases, err := fetchReleases(s.ctx)
+	if err != nil {
+		return info, err
+	}
+	r := latestRelease(releases, info.Channel)
+	if r == nil {
+		return info, nil
+	}
+	info.LatestVersion = strings.TrimPrefix(r.TagName, "v")
+	info.Prerelease = r.Prerelease
+	info.PageURL = r.HTMLURL
+	info.Notes = r.Body
+	info.PublishedAt = r.PublishedAt
+	info.Asset = releaseAsset(*r)
+
+	switch c := compareReleaseVersions(r.TagName, appVersion); {
+	case c > 0:
+		info.Available = true
+	case c < 0 && info.Channel == updateChannelStable && isPrerelease(appVersion):
+		info.Available, info.Downgrade = true, true
+	}
+	s.countFeature("check_update")
+	return info, nil
+}
+
+// SetUpdateChannel 切换更新渠道，空字符串为正式版，beta 为测试版
+func (s *MoleService) SetUpdateChannel(channel string) error {
+	if err := validateUpdateChannel(channel); err != nil {
+		return err
+	}
+	cfg := s.status().Config
+	if cfg == nil {
+		return fmt.Errorf("未发现有效配置")
+	}
+	newCfg := *cfg
+	newCfg.Preferences.UpdateChannel = channel
+	return s.saveUserConfig(newCfg)
+}
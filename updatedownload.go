@@ -0,0 +1,251 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// 下载更新：慢速或按流量计费的网络上，安装包下载中断后从已下载的位置继续，而不是整个重来
+// 未完成的文件以 .part 结尾保存在数据目录的 updates 中，下次下载同一个安装包时通过 HTTP Range 续传，
+// 并带上首次下载时记录的 ETag (If-Range)，发布页换了文件时服务器返回完整内容，从头下载而不是把新旧内容拼在一起
+// 下载完成后按发布页公布的 SHA-256 校验，没有公布校验值或校验不符时不交给用户安装
+// frpc 内置在安装包中，发布页没有单独的 frpc 差分包，这里只做断点续传，不做二进制差分
+
+const (
+	updateDownloadDirName  = "updates"
+	updateDownloadTimeout  = 30 * time.Minute
+	updateProgressInterval = 500 * time.Millisecond
+)
+
+// UpdateDownloadEvent update-download 事件
+type UpdateDownloadEvent struct {
+	Name     string `json:"name"`
+	Received int64  `json:"received"` // 已下载的字节数，包含续传前已有的部分
+	Total    int64  `json:"total"`    // 未知时为 0
+	Resumed  bool   `json:"resumed"`  // 本次从上次中断的位置继续
+	Done     bool   `json:"done"`
+	Path     string `json:"path,omitempty"` // 完成后的文件路径
+	Error    string `json:"error,omitempty"`
+}
+
+// 同一时间只下载一个安装包
+var updateDownloadMu sync.Mutex
+
+func updateDownloadDir() string {
+	return filepath.Join(getAppDataDir(), updateDownloadDirName)
+}
+
+// DownloadUpdate 下载检查更新找到的安装包，返回下载完成的文件路径；进度通过 update-download 事件推送
+// 安装包地址由后端重新检查得到，不接受前端传入的 URL
+func (s *MoleService) DownloadUpdate() (string, error) {
+	if !updateDownloadMu.TryLock() {
+		return "", fmt.Errorf("正在下载中")
+	}
+	defer updateDownloadMu.Unlock()
+
+	info, err := s.CheckForUpdates()
+	if err != nil {
+		return "", err
+	}
+	if !info.Available || info.Asset == nil {
+		return "", fmt.Errorf("没有适用于本机的安装包，请前往发布页下载")
+	}
+	asset := *info.Asset
+	if asset.SHA256 == "" {
+		if asset.SHA256, err = fetchAssetChecksum(s.ctx, asset); err != nil {
+			return "", err
+		}
+	}
+	dir := updateDownloadDir()
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return "", fmt.Errorf("创建下载目录失败: %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(s.ctx, updateDownloadTimeout)
+	defer cancel()
+	path := filepath.Join(dir, filepath.Base(asset.Name))
+	err = downloadResumable(ctx, asset, path, func(ev UpdateDownloadEvent) {
+		s.events.Emit("update-download", ev)
+	})
+	if err != nil {
+		s.events.Emit("update-download", UpdateDownloadEvent{Name: asset.Name, Error: err.Error()})
+		return "", err
+	}
+	s.emitLog("安装包已下载：", path)
+	return path, nil
+}
+
+// fetchAssetChecksum 发布页没有直接给出校验值时，从同一发布的校验文件 (sha256sum 格式) 中查找
+func fetchAssetChecksum(ctx context.Context, asset UpdateAsset) (string, error) {
+	if asset.checksumsURL == "" {
+		return "", fmt.Errorf("发布页没有提供安装包的校验值，请前往发布页手动下载并核对")
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, asset.checksumsURL, nil)
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("User-Agent", "mole/"+appVersion)
+	resp, err := outboundClient(updateTimeout).Do(req)
+	if err != nil {
+		return "", fmt.Errorf("下载校验文件失败: %v", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("下载校验文件失败: 服务器返回 %s", resp.Status)
+	}
+	sc := bufio.NewScanner(io.LimitReader(resp.Body, updateMaxResponse))
+	for sc.Scan() {
+		// <sha256>  <文件名>，二进制模式的文件名前带 *
+		fields := strings.Fields(sc.Text())
+		if len(fields) == 2 && strings.TrimPrefix(fields[1], "*") == asset.Name {
+			return strings.ToLower(fields[0]), nil
+		}
+	}
+	return "", fmt.Errorf("校验文件中没有 %s 的校验值，请前往发布页手动下载并核对", asset.Name)
+}
+
+// downloadResumable 下载到 path.part，完成并核对大小与校验值后改名为 path；已经下载完成且校验通过的文件直接返回
+func downloadResumable(ctx context.Context, asset UpdateAsset, path string, progress func(UpdateDownloadEvent)) error {
+	if st, err := os.Stat(path); err == nil && asset.Size > 0 && st.Size() == asset.Size {
+		if verifyChecksum(path, asset.SHA256) == nil {
+			progress(UpdateDownloadEvent{Name: asset.Name, Received: asset.Size, Total: asset.Size, Done: true, Path: path})
+			return nil
+		}
+		_ = os.Remove(path)
+	}
+	part := path + ".part"
+	etagPath := part + ".etag"
+	var offset int64
+	if st, err := os.Stat(part); err == nil {
+		offset = st.Size()
+	}
+	etag, _ := os.ReadFile(etagPath)
+	// 没有记录 ETag 时无法确认服务器上仍是同一个文件，不续传
+	if (asset.Size > 0 && offset > asset.Size) || len(etag) == 0 {
+		offset = 0
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, asset.URL, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("User-Agent", "mole/"+appVersion)
+	if offset > 0 {
+		req.Header.Set("Range", "bytes="+strconv.FormatInt(offset, 10)+"-")
+		req.Header.Set("If-Range", string(etag))
+	}
+	resp, err := outboundClient(0).Do(req)
+	if err != nil {
+		return fmt.Errorf("下载安装包失败: %v", err)
+	}
+	defer resp.Body.Close()
+
+	flags := os.O_CREATE | os.O_WRONLY
+	switch {
+	case resp.StatusCode == http.StatusPartialContent && offset > 0:
+		flags |= os.O_APPEND
+	case resp.StatusCode == http.StatusRequestedRangeNotSatisfiable && offset > 0 && offset == asset.Size:
+		// 上次已经下完，只差改名
+		return finishDownload(asset, part, path, progress)
+	case resp.StatusCode == http.StatusOK:
+		// 服务器不支持续传或文件已经变化 (If-Range 不匹配)，从头开始
+		flags |= os.O_TRUNC
+		offset = 0
+		if tag := resp.Header.Get("ETag"); tag != "" && !strings.HasPrefix(tag, "W/") {
+			if err := os.WriteFile(etagPath, []byte(tag), 0644); err != nil {
+				return fmt.Errorf("写入安装包失败: %v", err)
+			}
+		} else {
+			_ = os.Remove(etagPath)
+		}
+	default:
+		return fmt.Errorf("下载安装包失败: 服务器返回 %s", resp.Status)
+	}
+
+	f, err := os.OpenFile(part, flags, 0644)
+	if err != nil {
+		return fmt.Errorf("写入安装包失败: %v", err)
+	}
+	ev := UpdateDownloadEvent{Name: asset.Name, Received: offset, Total: asset.Size, Resumed: offset > 0}
+	progress(ev)
+	last := time.Now()
+	buf := make([]byte, 64<<10)
+	for {
+		n, rerr := resp.Body.Read(buf)
+		if n > 0 {
+			if _, err := f.Write(buf[:n]); err != nil {
+				f.Close()
+				return fmt.Errorf("写入安装包失败: %v", err)
+			}
+			ev.Received += int64(n)
+			if time.Since(last) >= updateProgressInterval {
+				progress(ev)
+				last = time.Now()
+			}
+		}
+		if rerr == io.EOF {
+			break
+		}
+		if rerr != nil {
+			f.Close()
+			// 已写入的部分保留，下次继续
+			return fmt.Errorf("下载中断 (已保存 %d 字节，可稍后继续): %v", ev.Received, rerr)
+		}
+	}
+	if err := f.Close(); err != nil {
+		return fmt.Errorf("写入安装包失败: %v", err)
+	}
+	return finishDownload(asset, part, path, progress)
+}
+
+func finishDownload(asset UpdateAsset, part, path string, progress func(UpdateDownloadEvent)) error {
+	st, err := os.Stat(part)
+	if err != nil {
+		return err
+	}
+	if asset.Size > 0 && st.Size() != asset.Size {
+		// 大小不符说明续传拼接出错，删掉重新下载
+		_ = os.Remove(part)
+		_ = os.Remove(part + ".etag")
+		return fmt.Errorf("安装包大小不符 (%d / %d 字节)，请重新下载", st.Size(), asset.Size)
+	}
+	if err := verifyChecksum(part, asset.SHA256); err != nil {
+		_ = os.Remove(part)
+		_ = os.Remove(part + ".etag")
+		return err
+	}
+	if err := os.Rename(part, path); err != nil {
+		return fmt.Errorf("保存安装包失败: %v", err)
+	}
+	_ = os.Remove(part + ".etag")
+	progress(UpdateDownloadEvent{Name: asset.Name, Received: st.Size(), Total: asset.Size, Done: true, Path: path})
+	return nil
+}
+
+// verifyChecksum 核对文件的 SHA-256
+func verifyChecksum(path, want string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return fmt.Errorf("读取安装包失败: %v", err)
+	}
+	if got := hex.EncodeToString(h.Sum(nil)); got != want {
+		return fmt.Errorf("安装包校验失败 (SHA-256 %s，发布页为 %s)，已删除，请重新下载", got, want)
+	}
+	return nil
+}
@@ -0,0 +1,67 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestDownloadResumable(t *testing.T) {
+	content := []byte("0123456789abcdefghij")
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("ETag", `"v2"`)
+		http.ServeContent(w, r, "mole.zip", time.Unix(0, 0), bytes.NewReader(content))
+	}))
+	defer srv.Close()
+
+	sum := sha256.Sum256(content)
+	asset := UpdateAsset{Name: "mole.zip", URL: srv.URL, Size: int64(len(content)), SHA256: hex.EncodeToString(sum[:])}
+	path := filepath.Join(t.TempDir(), asset.Name)
+	download := func() (bool, error) {
+		resumed := false
+		err := downloadResumable(context.Background(), asset, path, func(ev UpdateDownloadEvent) {
+			resumed = resumed || ev.Resumed
+		})
+		return resumed, err
+	}
+	check := func() {
+		t.Helper()
+		if got, _ := os.ReadFile(path); !bytes.Equal(got, content) {
+			t.Fatalf("下载内容 = %q", got)
+		}
+	}
+
+	// 上次下载的是已被替换的旧文件，ETag 不符，应从头下载而不是拼接
+	os.WriteFile(path+".part", []byte("old-content"), 0644)
+	os.WriteFile(path+".part.etag", []byte(`"v1"`), 0644)
+	if _, err := download(); err != nil {
+		t.Fatalf("下载失败: %v", err)
+	}
+	check()
+
+	// ETag 相同时从中断处继续
+	os.Remove(path)
+	os.WriteFile(path+".part", content[:7], 0644)
+	os.WriteFile(path+".part.etag", []byte(`"v2"`), 0644)
+	if resumed, err := download(); err != nil || !resumed {
+		t.Fatalf("续传 = %v, %v", resumed, err)
+	}
+	check()
+
+	// 校验值不符时不保留安装包
+	os.Remove(path)
+	asset.SHA256 = hex.EncodeToString(make([]byte, sha256.Size))
+	if _, err := download(); err == nil {
+		t.Fatal("校验值不符时应失败")
+	}
+	if _, err := os.Stat(path); err == nil {
+		t.Error("校验失败的安装包不应保留")
+	}
+}
@@ -0,0 +1,249 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+// 运行统计：记录每次隧道运行的起止时间 (会话) 以及每天隧道与各条规则的在线时长
+// 统计在内存中累加，每分钟以及隧道停止时写入数据库，只在实际运行隧道的进程中运行
+// frpc 没有对外提供按代理的流量计数，这里记录的是在线时长
+
+const (
+	usageFlushInterval  = time.Minute
+	sessionHistoryLimit = 1000 // 会话记录最多保留条数
+	sessionDefaultLimit = 100
+
+	sessionEndUser     = "user"     // 主动断开
+	sessionEndExit     = "exit"     // 进程意外退出或隧道断开
+	sessionEndShutdown = "shutdown" // 程序退出
+)
+
+// SessionSummary 一次隧道运行的汇总
+type SessionSummary struct {
+	Start     time.Time `json:"start"`
+	End       time.Time `json:"end"`
+	Seconds   int64     `json:"seconds"`
+	Transport string    `json:"transport"`
+	Rules     int       `json:"rules"`  // 启动时启用的规则数
+	Reason    string    `json:"reason"` // user / exit / shutdown
+}
+
+// DailyStats 某一天的运行统计，按本地日期归档
+type DailyStats struct {
+	Date          string                    `json:"date"` // 2006-01-02
+	TunnelSeconds int64                     `json:"tunnelSeconds"`
+	Sessions      int                       `json:"sessions"`
+	Proxies       map[string]*ProxyDayStats `json:"proxies"` // 规则 ID -> 统计
+}
+
+// ProxyDayStats 单条规则某一天的在线时长
+type ProxyDayStats struct {
+	Name          string `json:"name"`
+	OnlineSeconds int64  `json:"onlineSeconds"`
+}
+
+// usageRecorder 只在 runUsageRecorder 协程中使用
+type usageRecorder struct {
+	session *SessionSummary
+	online  map[string]string      // 当前在线的规则 ID -> 名称
+	last    time.Time              // 上次累加的时间点
+	pending map[string]*DailyStats // 尚未写入数据库的增量，按日期
+}
+
+// accumulate 把 last 到 now 之间的在线时长计入当天的增量
+func (r *usageRecorder) accumulate(now time.Time) {
+	elapsed := int64(now.Sub(r.last).Seconds())
+	r.last = now
+	if elapsed <= 0 || (r.session == nil && len(r.online) == 0) {
+		return
+	}
+	day := r.day(now)
+	if r.session != nil {
+		day.TunnelSeconds += elapsed
+	}
+	for id, name := range r.online {
+		p := day.Proxies[id]
+		if p == nil {
+			p = &ProxyDayStats{}
+			day.Proxies[id] = p
+		}
+		p.Name = name
+		p.OnlineSeconds += elapsed
+	}
+}
+
+func (r *usageRecorder) day(now time.Time) *DailyStats {
+	date := now.Format(time.DateOnly)
+	d := r.pending[date]
+	if d == nil {
+		d = &DailyStats{Date: date, Proxies: make(map[string]*ProxyDayStats)}
+		r.pending[date] = d
+	}
+	return d
+}
+
+// mergeDaily 把增量 delta 合并进 base，base 为空时新建
+func mergeDaily(base, delta *DailyStats) *DailyStats {
+	if base == nil {
+		base = &DailyStats{Date: delta.Date, Proxies: make(map[string]*ProxyDayStats)}
+	}
+	if base.Proxies == nil {
+		base.Proxies = make(map[string]*ProxyDayStats)
+	}
+	base.TunnelSeconds += delta.TunnelSeconds
+	base.Sessions += delta.Sessions
+	for id, p := range delta.Proxies {
+		cur := base.Proxies[id]
+		if cur == nil {
+			cur = &ProxyDayStats{}
+			base.Proxies[id] = cur
+		}
+		cur.Name = p.Name
+		cur.OnlineSeconds += p.OnlineSeconds
+	}
+	return base
+}
+
+// flushUsage 把增量写入数据库，会话结束时同时追加会话记录
+func (s *MoleService) flushUsage(r *usageRecorder, ended *SessionSummary) {
+	if len(r.pending) == 0 && ended == nil {
+		return
+	}
+	err := s.store.update(func(tx *bolt.Tx) error {
+		b := tx.Bucket(bucketStats)
+		for _, delta := range r.pending {
+			var cur *DailyStats
+			if v := b.Get([]byte(delta.Date)); v != nil {
+				cur = &DailyStats{}
+				if json.Unmarshal(v, cur) != nil {
+					cur = nil
+				}
+			}
+			data, err := json.Marshal(mergeDaily(cur, delta))
+			if err != nil {
+				return err
+			}
+			if err := b.Put([]byte(delta.Date), data); err != nil {
+				return err
+			}
+		}
+		if ended != nil {
+			if err := appendJSON(tx, bucketSessions, ended); err != nil {
+				return err
+			}
+			return trimBucket(tx, bucketSessions, sessionHistoryLimit)
+		}
+		return nil
+	})
+	if err != nil {
+		// 写入失败时增量保留在内存中，下个周期再试
+		log.Printf("写入运行统计失败: %v", err)
+		return
+	}
+	clear(r.pending)
+}
+
+// runUsageRecorder 根据隧道启停与规则状态事件统计在线时长
+func (s *MoleService) runUsageRecorder(ctx context.Context) {
+	events, cancel := s.bus.subscribeEvents(64)
+	defer cancel()
+
+	r := &usageRecorder{
+		online:  make(map[string]string),
+		last:    time.Now(),
+		pending: make(map[string]*DailyStats),
+	}
+	ticker := time.NewTicker(usageFlushInterval)
+	defer ticker.Stop()
+
+	endSession := func(now time.Time, reason string) *SessionSummary {
+		if r.session == nil {
+			return nil
+		}
+		ended := r.session
+		ended.End, ended.Reason = now, reason
+		ended.Seconds = int64(now.Sub(ended.Start).Seconds())
+		r.session = nil
+		clear(r.online)
+		return ended
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			now := time.Now()
+			r.accumulate(now)
+			s.flushUsage(r, endSession(now, sessionEndShutdown))
+			return
+
+		case now := <-ticker.C:
+			r.accumulate(now)
+			s.flushUsage(r, nil)
+
+		case ev := <-events:
+			now := time.Now()
+			switch ev.name {
+			case "frp-status":
+				r.accumulate(now)
+				switch ev.data {
+				case "start":
+					if r.session != nil {
+						s.flushUsage(r, endSession(now, sessionEndExit))
+					}
+					r.session = s.newSessionSummary(now)
+					r.day(now).Sessions++
+				case "stop":
+					reason := sessionEndExit
+					if s.stopRequested.Load() {
+						reason = sessionEndUser
+					}
+					s.flushUsage(r, endSession(now, reason))
+				}
+			case "proxy-state":
+				st, ok := ev.data.(ProxyState)
+				if !ok {
+					continue
+				}
+				r.accumulate(now)
+				if st.State == "running" && r.session != nil {
+					r.online[st.RuleID] = st.Name
+				} else {
+					delete(r.online, st.RuleID)
+				}
+			}
+		}
+	}
+}
+
+func (s *MoleService) newSessionSummary(now time.Time) *SessionSummary {
+	sum := &SessionSummary{Start: now, Transport: transportFrp}
+	if cfg := s.status().Config; cfg != nil {
+		if cfg.Server.Transport != "" {
+			sum.Transport = cfg.Server.Transport
+		}
+		for _, p := range cfg.Proxies {
+			if p.Enabled {
+				sum.Rules++
+			}
+		}
+	}
+	return sum
+}
+
+// GetSessionHistory 返回最近 limit 次隧道运行记录，新的在前
+func (s *MoleService) GetSessionHistory(limit int) ([]SessionSummary, error) {
+	if limit <= 0 {
+		limit = sessionDefaultLimit
+	}
+	list, err := latestJSON[SessionSummary](&s.store, bucketSessions, limit)
+	if err != nil {
+		return nil, fmt.Errorf("读取运行记录失败: %v", err)
+	}
+	return list, nil
+}
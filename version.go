@@ -0,0 +1,105 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"runtime"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// 版本信息：关于页面与问题反馈使用
+// mole 自身的版本、提交、构建时间在构建时通过 -ldflags "-X main.xxx=..." 注入
+// frpc 版本通过运行 `frpc -v` 获得：内置版本与已释放到数据目录的版本可能不同 (用户替换过或升级后尚未重新释放)
+// frp 没有独立的协议版本号，客户端与服务端的兼容性以 frp 版本为准，所以这里只报告 frpc 版本
+
+var (
+	// appVersion 构建时可通过 -ldflags "-X main.appVersion=x.y.z" 覆盖
+	appVersion = "1.0.0"
+	// buildCommit / buildDate 构建时注入，未注入时为空
+	buildCommit = ""
+	buildDate   = ""
+)
+
+const frpcVersionTimeout = 5 * time.Second
+
+var reFrpcVersion = regexp.MustCompile(`\d+\.\d+\.\d+\S*`)
+
+// VersionInfo 版本信息
+type VersionInfo struct {
+	AppVersion    string   `json:"appVersion"`
+	Commit        string   `json:"commit"`
+	BuildDate     string   `json:"buildDate"`
+	GoVersion     string   `json:"goVersion"`
+	OS            string   `json:"os"`
+	Arch          string   `json:"arch"`
+	EmbeddedFrpc  string   `json:"embeddedFrpc"`  // 安装包内置的 frpc 版本
+	InstalledFrpc string   `json:"installedFrpc"` // 数据目录中实际运行的 frpc 版本，尚未释放时为空
+	FrpcPath      string   `json:"frpcPath"`
+	Issues        []string `json:"issues"` // frpc 版本兼容性问题，见 frpcCompatIssues
+}
+
+var embeddedFrpcVersion = sync.OnceValue(func() string {
+	data, err := frpcBin.ReadFile(frpcMap[runtime.GOARCH])
+	if err != nil {
+		return ""
+	}
+	dir, err := os.MkdirTemp("", "mole-frpc-")
+	if err != nil {
+		return ""
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, frpcTargetName)
+	if err := os.WriteFile(path, data, 0755); err != nil {
+		return ""
+	}
+	return frpcVersion(path)
+})
+
+// frpcVersion 运行 frpc -v，失败时返回空字符串
+func frpcVersion(path string) string {
+	ctx, cancel := context.WithTimeout(context.Background(), frpcVersionTimeout)
+	defer cancel()
+	cmd := exec.CommandContext(ctx, path, "-v")
+	cmd.SysProcAttr = &syscall.SysProcAttr{}
+	setHideWindow(cmd.SysProcAttr)
+	out, err := cmd.Output()
+	if err != nil {
+		return ""
+	}
+	return reFrpcVersion.FindString(string(out))
+}
+
+// GetVersionInfo 返回 mole 与 frpc 的版本信息
+func (s *MoleService) GetVersionInfo() VersionInfo {
+	info := VersionInfo{
+		AppVersion: appVersion,
+		Commit:     buildCommit,
+		BuildDate:  buildDate,
+		GoVersion:  runtime.Version(),
+		OS:         runtime.GOOS,
+		Arch:       runtime.GOARCH,
+		FrpcPath:   effectiveFrpcPath(s.getFrpBinDir()),
+		Issues:     []string{},
+	}
+
+	embedded := embeddedFrpcVersion()
+	info.EmbeddedFrpc = embedded
+	installed, err := os.ReadFile(info.FrpcPath)
+	if err != nil {
+		return info
+	}
+	// 与内置文件完全一致时不必再运行一次
+	if data, err := frpcBin.ReadFile(frpcMap[runtime.GOARCH]); err == nil && bytes.Equal(data, installed) {
+		info.InstalledFrpc = embedded
+	} else {
+		info.InstalledFrpc = frpcVersion(info.FrpcPath)
+	}
+	info.Issues = frpcCompatIssues(info.InstalledFrpc, embedded)
+	return info
+}